@@ -6,8 +6,6 @@ import (
 
 	"github.com/urfave/cli"
 
-	"github.com/VillanCh/ragsync/common/aliyun"
-
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
 )
@@ -57,16 +55,19 @@ func executeAddJob(c *cli.Context) error {
 		return utils.Errorf("Please specify either file ID (--id) or file name (--name) to add to index")
 	}
 
-	client, err := aliyun.NewBailianClientFromConfig(config)
+	client, err := NewBackend(config)
 	if err != nil {
 		return err
 	}
 
+	ctx, cancel := commandContext(c)
+	defer cancel()
+
 	// 如果只提供了文件名，需要先查找对应的文件ID
 	if fileId == "" && fileName != "" {
 		// 首先列出所有匹配的文件
 		log.Infof("Searching for files with name: %s", fileName)
-		files, err := client.ListAllFiles(fileName)
+		files, err := client.ListAllFilesWithContext(ctx, fileName)
 		if err != nil {
 			return utils.Errorf("Failed to list files: %v", err)
 		}
@@ -102,7 +103,7 @@ func executeAddJob(c *cli.Context) error {
 		}
 	} else if fileId != "" && fileName == "" {
 		// 如果只提供了ID，尝试获取文件名用于确认
-		fileInfo, err := client.DescribeFile(fileId)
+		fileInfo, err := client.DescribeFileWithContext(ctx, fileId)
 		if err != nil {
 			log.Warnf("Failed to get file information: %v", err)
 			return utils.Errorf("Cannot find file with ID: %s. Please make sure the file exists.", fileId)
@@ -122,7 +123,7 @@ func executeAddJob(c *cli.Context) error {
 
 	// 执行添加到索引的操作
 	log.Infof("Adding file to knowledge index: %s", config.BailianKnowledgeIndexId)
-	jobId, err := client.AppendDocumentToIndex(fileId)
+	jobId, err := client.AppendDocumentToIndexWithContext(ctx, fileId)
 	if err != nil {
 		return utils.Errorf("Failed to add file to knowledge index: %v", err)
 	}
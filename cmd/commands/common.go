@@ -1,8 +1,20 @@
 package commands
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
 	"github.com/urfave/cli"
 
+	// 后端在各自包的 init 中注册
+	"github.com/VillanCh/ragsync/common/aliyun"
+	"github.com/VillanCh/ragsync/common/backend"
+	_ "github.com/VillanCh/ragsync/common/local"
 	"github.com/VillanCh/ragsync/common/spec"
 
 	"github.com/yaklang/yaklang/common/log"
@@ -17,7 +29,7 @@ func LoadConfig(c *cli.Context) (*spec.Config, error) {
 	}
 
 	log.Infof("Using configuration file: %s", configPath)
-	config, err := spec.LoadConfig(configPath)
+	config, err := spec.LoadConfigWithOptions(configPath, loadOptions(c, c.GlobalString("profile")))
 	if err != nil {
 		return nil, utils.Errorf("Failed to load configuration file: %v", err)
 	}
@@ -29,10 +41,117 @@ func LoadConfig(c *cli.Context) (*spec.Config, error) {
 	return config, nil
 }
 
+// OverrideFlags 覆盖配置项的全局参数，见 spec.Overrides
+func OverrideFlags() []cli.Flag {
+	var flags []cli.Flag
+	for _, o := range spec.Overrides {
+		usage := fmt.Sprintf("%s (env %s)", o.Usage, o.Env)
+		if o.List {
+			flags = append(flags, cli.StringSliceFlag{Name: o.Flag, Usage: usage})
+		} else {
+			flags = append(flags, cli.StringFlag{Name: o.Flag, Usage: usage})
+		}
+	}
+	return flags
+}
+
+// loadOptions 使用档案 profile 和命令行中指定的覆盖参数加载配置
+func loadOptions(c *cli.Context, profile string) spec.LoadOptions {
+	opts := spec.LoadOptions{Profile: profile, Flags: make(map[string]string)}
+	for _, o := range spec.Overrides {
+		if !c.GlobalIsSet(o.Flag) {
+			continue
+		}
+		if o.List {
+			opts.Flags[o.Key] = strings.Join(c.GlobalStringSlice(o.Flag), ",")
+		} else {
+			opts.Flags[o.Key] = c.GlobalString(o.Flag)
+		}
+	}
+	return opts
+}
+
+// commandContext 返回命令使用的 context：指定 --timeout 时到期取消，收到 SIGINT/SIGTERM 时取消
+// 第一次 Ctrl+C 让正在进行的操作尽快结束并输出已完成的部分，第二次 Ctrl+C 直接退出
+func commandContext(c *cli.Context) (context.Context, context.CancelFunc) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout := c.GlobalDuration("timeout"); timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			// 恢复默认的信号处理，第二次 Ctrl+C 直接退出
+			signal.Stop(signals)
+			log.Warnf("Received %v, cancelling (press Ctrl+C again to force quit)", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+// contextError 将 ctx 结束的原因转换为面向用户的错误，ctx 未结束时返回 nil
+func contextError(ctx context.Context, c *cli.Context) error {
+	switch ctx.Err() {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return utils.Errorf("Timed out after %v (--timeout)", c.GlobalDuration("timeout"))
+	default:
+		return utils.Errorf("Interrupted")
+	}
+}
+
+// NewBackend 根据配置中的 backend 字段创建知识库后端
+func NewBackend(config *spec.Config) (backend.Backend, error) {
+	client, err := backend.New(config)
+	if err != nil {
+		return nil, utils.Errorf("Failed to create %s backend: %v", backendName(config), err)
+	}
+	return client, nil
+}
+
+// backendName 返回配置使用的后端名称
+func backendName(config *spec.Config) string {
+	if config == nil || config.Backend == "" {
+		return backend.DefaultBackend
+	}
+	return config.Backend
+}
+
+// remoteFileGone 判断删除或查询文件失败是否因为远程文件已经不存在（例如已在控制台中删除）
+// 百炼删除文件前会先删除索引文档，只有 DeleteFile 或 DescribeFile 本身返回的不存在才说明文件已不存在
+func remoteFileGone(err error) bool {
+	if !errors.Is(err, backend.ErrNotFound) {
+		return false
+	}
+	var apiErr *aliyun.APIError
+	return !errors.As(err, &apiErr) || apiErr.Action == "DeleteFile" || apiErr.Action == "DescribeFile"
+}
+
+// logAuthFailure 鉴权失败时提示检查 AccessKey 和权限
+func logAuthFailure(err error) {
+	if aliyun.IsAuthFailure(err) {
+		log.Errorf("Authentication failed, please check aliyun_access_key, aliyun_secret_key and the RAM permissions of the Bailian workspace")
+	}
+}
+
 // GetCommands 获取所有命令
 func GetCommands() []cli.Command {
 	return []cli.Command{
 		CreateConfigCommand(),
+		InitCommand(),
 		SyncCommand(),
 		ListCommand(),
 		StatusCommand(),
@@ -41,5 +160,7 @@ func GetCommands() []cli.Command {
 		IndexStatusCommand(),
 		IndexJobsListCommand(),
 		AddJobCommand(),
+		StateCommand(),
+		ConfigCommand(),
 	}
 }
@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/VillanCh/ragsync/common/secret"
+	"github.com/VillanCh/ragsync/common/spec"
+
+	"github.com/yaklang/yaklang/common/log"
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// ConfigCommand 配置文件管理命令
+func ConfigCommand() cli.Command {
+	return cli.Command{
+		Name:  "config",
+		Usage: "Manage the configuration file",
+		Subcommands: []cli.Command{
+			{
+				Name:  "migrate-secrets",
+				Usage: "Move plaintext secrets in the configuration file (and its .bak backup) to the keyring or encrypt them",
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "store",
+						Usage: "Where to store the secrets: keyring (system keyring, falling back to ~/.ragsync/secrets.json) or encrypted (encrypted with RAGSYNC_SECRET_PASSPHRASE)",
+						Value: secret.StoreKeyring,
+					},
+				},
+				Action: executeConfigMigrateSecrets,
+			},
+			{
+				Name:  "show",
+				Usage: "Print the configuration file with secrets masked",
+				Flags: []cli.Flag{
+					cli.BoolFlag{
+						Name:  "resolved",
+						Usage: "Print the effective configuration after applying the profile, RAGSYNC_* environment variables and flags, with the source of each value",
+					},
+				},
+				Action: executeConfigShow,
+			},
+		},
+	}
+}
+
+// executeConfigMigrateSecrets 迁移配置文件中明文密钥的执行逻辑
+func executeConfigMigrateSecrets(c *cli.Context) error {
+	configPath := c.GlobalString("config")
+	if configPath == "" {
+		return utils.Errorf("Configuration file path not specified")
+	}
+	if utils.GetFirstExistedPath(configPath) == "" {
+		return utils.Errorf("Configuration file %s does not exist", configPath)
+	}
+	store := c.String("store")
+	if !utils.StringArrayContains(secret.Stores, store) {
+		return utils.Errorf("Invalid --store %q, expected one of: %s", store, strings.Join(secret.Stores, ", "))
+	}
+
+	if err := migrateConfigSecrets(configPath, store); err != nil {
+		return err
+	}
+	// create-config 备份的旧配置同样可能包含明文密钥
+	if backupPath := configPath + ".bak"; utils.GetFirstExistedPath(backupPath) != "" {
+		if err := migrateConfigSecrets(backupPath, store); err != nil {
+			log.Warnf("Failed to migrate secrets in backup %s: %v", backupPath, err)
+		}
+	}
+	return nil
+}
+
+// migrateConfigSecrets 将一个配置文件中的明文密钥保存到 store 并改写为引用，同时将文件权限改为 0600
+// 只改写密钥字段，不应用档案，也不写入用户没有设置过的默认值
+func migrateConfigSecrets(configPath string, store string) error {
+	migrated, err := spec.MigrateFileSecrets(configPath, store)
+	if err != nil {
+		return utils.Errorf("Failed to migrate secrets in %s: %v", configPath, err)
+	}
+	if len(migrated) == 0 {
+		if err := os.Chmod(configPath, 0600); err != nil {
+			return utils.Errorf("Failed to change permissions of %s: %v", configPath, err)
+		}
+		log.Infof("No plaintext secrets in %s", configPath)
+		return nil
+	}
+	log.Infof("Migrated %s in %s to %s storage", strings.Join(migrated, ", "), configPath, store)
+	return nil
+}
+
+// executeConfigShow 输出配置的执行逻辑
+func executeConfigShow(c *cli.Context) error {
+	configPath := c.GlobalString("config")
+	if configPath == "" {
+		return utils.Errorf("Configuration file path not specified")
+	}
+
+	if !c.Bool("resolved") {
+		if utils.GetFirstExistedPath(configPath) == "" {
+			return utils.Errorf("Configuration file %s does not exist", configPath)
+		}
+		config, err := spec.ReadConfig(configPath)
+		if err != nil {
+			return err
+		}
+		raw, err := config.Describe(maskSensitiveString, false)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# Configuration file: %s\n", configPath)
+		fmt.Print(string(raw))
+		return nil
+	}
+
+	// 展示生效的配置时不验证，便于排查不完整的配置
+	opts := loadOptions(c, c.GlobalString("profile"))
+	opts.SkipValidation = true
+	config, err := spec.LoadConfigWithOptions(configPath, opts)
+	if err != nil {
+		return err
+	}
+	raw, err := config.Describe(maskSensitiveString, true)
+	if err != nil {
+		return err
+	}
+
+	if utils.GetFirstExistedPath(configPath) != "" {
+		fmt.Printf("# Configuration file: %s\n", configPath)
+	} else {
+		fmt.Printf("# Configuration file: %s (does not exist)\n", configPath)
+	}
+	if config.Profile != "" {
+		fmt.Printf("# Profile: %s\n", config.Profile)
+	}
+	if config.UsesBailian() {
+		fmt.Printf("# Credential type: %s\n", config.ResolvedCredentialType())
+	}
+	fmt.Print(string(raw))
+	if err := config.Validate(); err != nil {
+		log.Warnf("Invalid configuration: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/VillanCh/ragsync/common/secret"
+	"github.com/VillanCh/ragsync/common/spec"
+)
+
+func TestConfigMigrateSecretsKeepsFileKeys(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(secret.EnvPassphrase, "migrate-test")
+	t.Setenv(secret.EnvPassphraseFile, "")
+
+	configPath := filepath.Join(t.TempDir(), "ragsync.yaml")
+	original := `# workspace used by the docs site
+aliyun_access_key: AKID
+aliyun_secret_key: plain-secret
+bailian_workspace_id: ws-docs
+bailian_knowledge_index_id: idx-docs
+`
+	if err := os.WriteFile(configPath, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configPath+".bak", []byte("aliyun_secret_key: old-secret\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runCommand(t, configPath, "config", "migrate-secrets", "--store", secret.StoreEncrypted); err != nil {
+		t.Fatalf("config migrate-secrets: %v", err)
+	}
+
+	raw, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "plain-secret") || !strings.Contains(string(raw), "# workspace used by the docs site") {
+		t.Fatalf("migrated configuration:\n%s\nwant the secret replaced and the comment kept", raw)
+	}
+	// 只改写密钥字段，不写入用户没有设置过的默认值
+	var keys map[string]interface{}
+	if err := yaml.Unmarshal(raw, &keys); err != nil {
+		t.Fatalf("migrated configuration is not YAML: %v", err)
+	}
+	if len(keys) != 4 {
+		t.Fatalf("migrated configuration has keys %v, want only the 4 keys of the original file", keys)
+	}
+	if ref, _ := keys["aliyun_secret_key"].(string); secret.ReferenceStore(ref) != secret.StoreEncrypted {
+		t.Fatalf("aliyun_secret_key = %q, want an encrypted reference", ref)
+	}
+	if info, err := os.Stat(configPath); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("configuration file mode = %v, %v, want 600", info.Mode().Perm(), err)
+	}
+
+	config, err := spec.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if config.AliyunSecretKey != "plain-secret" {
+		t.Fatalf("resolved aliyun_secret_key = %q, want plain-secret", config.AliyunSecretKey)
+	}
+
+	backup, err := os.ReadFile(configPath + ".bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(backup), "old-secret") || strings.Count(strings.TrimSpace(string(backup)), "\n") != 0 {
+		t.Fatalf("migrated backup:\n%s\nwant only the secret reference", backup)
+	}
+}
+
+func TestConfigShowResolved(t *testing.T) {
+	for _, o := range spec.Overrides {
+		t.Setenv(o.Env, "")
+	}
+	t.Setenv("RAGSYNC_INDEX_ID", "idx-env")
+	configPath := filepath.Join(t.TempDir(), "ragsync.yaml")
+	original := `backend: local
+bailian_workspace_id: ws-file
+bailian_knowledge_index_id: idx-file
+profiles:
+  staging:
+    bailian_files_default_category_id: cate-staging
+`
+	if err := os.WriteFile(configPath, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	out := captureStdout(t, func() {
+		err = runCommand(t, configPath, "--profile", "staging", "--workspace-id", "ws-flag",
+			"--include-path", "docs", "--include-path", "manual", "config", "show", "--resolved")
+	})
+	if err != nil {
+		t.Fatalf("config show --resolved: %v", err)
+	}
+	for _, line := range []string{
+		"# Profile: staging",
+		"bailian_workspace_id: ws-flag # flag --workspace-id",
+		"bailian_knowledge_index_id: idx-env # env RAGSYNC_INDEX_ID",
+		"bailian_files_default_category_id: cate-staging # profile staging",
+		"include_paths: # flag --include-path",
+		"- manual",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("config show --resolved does not contain %q:\n%s", line, out)
+		}
+	}
+
+	// 不带 --resolved 时输出配置文件本身
+	out = captureStdout(t, func() {
+		err = runCommand(t, configPath, "--workspace-id", "ws-flag", "config", "show")
+	})
+	if err != nil {
+		t.Fatalf("config show: %v", err)
+	}
+	if !strings.Contains(out, "bailian_workspace_id: ws-file") || !strings.Contains(out, "staging:") {
+		t.Errorf("config show does not print the configuration file:\n%s", out)
+	}
+}
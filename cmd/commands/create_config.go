@@ -9,253 +9,305 @@ import (
 	"github.com/urfave/cli"
 
 	"github.com/VillanCh/ragsync/common/aliyun"
+	"github.com/VillanCh/ragsync/common/secret"
 	"github.com/VillanCh/ragsync/common/spec"
 
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
 )
 
+// configWizardFlags create-config 和 init 共用的参数，每个提示都可以由参数提供
+func configWizardFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "output, o",
+			Usage: "Output path for the configuration file",
+			Value: "",
+		},
+		cli.StringFlag{
+			Name:  "ak, access-key",
+			Usage: "Aliyun Access Key",
+			Value: "",
+		},
+		cli.StringFlag{
+			Name:  "sk, secret-key",
+			Usage: "Aliyun Secret Key",
+			Value: "",
+		},
+		cli.StringFlag{
+			Name:  "workspace-id, wid",
+			Usage: "Bailian Workspace ID",
+			Value: "",
+		},
+		cli.StringFlag{
+			Name:  "category-id",
+			Usage: "Bailian category ID for uploaded files",
+		},
+		cli.StringFlag{
+			Name:  "create-category",
+			Usage: "Create a category with this name and use it",
+		},
+		cli.StringFlag{
+			Name:  "index-id",
+			Usage: "Bailian knowledge index ID",
+		},
+		cli.StringFlag{
+			Name:  "create-index",
+			Usage: "Create a knowledge index with this name on the selected category and use it",
+		},
+		cli.StringFlag{
+			Name:  "endpoint",
+			Usage: "Bailian API endpoint",
+		},
+		cli.StringFlag{
+			Name:  "category-type",
+			Usage: "Bailian category type",
+		},
+		cli.StringFlag{
+			Name:  "parser",
+			Usage: "Bailian file parser",
+		},
+		cli.BoolFlag{
+			Name:  "yes, y",
+			Usage: "Do not prompt: use the flags, the current values or the defaults, and answer yes to confirmations",
+		},
+		cli.StringFlag{
+			Name:  "secret-store",
+			Usage: "Where to store the secret key: keyring (system keyring, falling back to ~/.ragsync/secrets.json) or encrypted (encrypted with RAGSYNC_SECRET_PASSPHRASE)",
+			Value: secret.StoreKeyring,
+		},
+	}
+}
+
 // CreateConfigCommand creates configuration file command
 func CreateConfigCommand() cli.Command {
 	return cli.Command{
 		Name:  "create-config",
 		Usage: "Create configuration file",
-		Flags: []cli.Flag{
-			cli.StringFlag{
-				Name:  "output, o",
-				Usage: "Output path for the configuration file",
-				Value: "",
-			},
-			cli.StringFlag{
-				Name:  "ak, access-key",
-				Usage: "Aliyun Access Key",
-				Value: "",
-			},
-			cli.StringFlag{
-				Name:  "sk, secret-key",
-				Usage: "Aliyun Secret Key",
-				Value: "",
-			},
-			cli.StringFlag{
-				Name:  "workspace-id, wid",
-				Usage: "Bailian Workspace ID",
-				Value: "",
-			},
+		Flags: configWizardFlags(),
+		Action: func(c *cli.Context) error {
+			return executeConfigWizard(c, false)
+		},
+	}
+}
+
+// InitCommand 初始化配置文件，需要时创建分类和索引并直接使用新的 ID
+func InitCommand() cli.Command {
+	return cli.Command{
+		Name:  "init",
+		Usage: "Initialize the configuration file, creating the category and index when needed and selecting them",
+		Flags: configWizardFlags(),
+		Action: func(c *cli.Context) error {
+			return executeConfigWizard(c, true)
 		},
-		Action: executeCreateConfig,
 	}
 }
 
-// executeCreateConfig executes the configuration file creation logic
-func executeCreateConfig(c *cli.Context) error {
+// configWizard 生成配置文件时读取输入：参数优先，否则提示输入，指定 --yes 时不提示
+type configWizard struct {
+	reader *bufio.Reader
+	yes    bool
+}
+
+// readLine reads a single line from the console
+func (w *configWizard) readLine() string {
+	input, _ := w.reader.ReadString('\n')
+	return strings.TrimSpace(input)
+}
+
+// ask 提示输入 label，直接回车时使用 current；指定 --yes 时不提示，直接使用 current
+func (w *configWizard) ask(label string, current string) string {
+	if w.yes {
+		return current
+	}
+	if current != "" {
+		fmt.Printf("%s [%s]: ", label, current)
+	} else {
+		fmt.Printf("%s: ", label)
+	}
+	if input := w.readLine(); input != "" {
+		return input
+	}
+	return current
+}
+
+// confirm 询问 y/n，指定 --yes 时直接确认
+func (w *configWizard) confirm(prompt string) bool {
+	if w.yes {
+		return true
+	}
+	fmt.Print(prompt + " (y/n): ")
+	return strings.ToLower(w.readLine()) == "y"
+}
+
+// wizardString 返回命令参数的值，未指定时使用同名的全局参数（见 OverrideFlags），例如 ragsync --index-id X init
+func wizardString(c *cli.Context, name string) string {
+	if value := c.String(name); value != "" {
+		return value
+	}
+	return c.GlobalString(name)
+}
+
+// executeConfigWizard executes the configuration file creation logic
+// autoSelect 为 true 时（ragsync init），创建分类或索引后直接使用新的 ID，同名的分类或索引已存在时直接使用而不重复创建
+func executeConfigWizard(c *cli.Context, autoSelect bool) error {
 	configPath := c.GlobalString("config")
 	if configPath == "" {
 		return utils.Errorf("Configuration file path not specified")
 	}
 
+	command := "create-config"
+	if autoSelect {
+		command = "init"
+	}
+	w := &configWizard{reader: bufio.NewReader(os.Stdin), yes: c.Bool("yes")}
+
 	// Print help message
-	fmt.Println("\nUsage:")
-	fmt.Println("  ragsync create-config [options]")
-	fmt.Println("\nOptions:")
-	fmt.Println("  --ak, --access-key     Aliyun Access Key")
-	fmt.Println("  --sk, --secret-key     Aliyun Secret Key")
-	fmt.Println("  --workspace-id, --wid  Bailian Workspace ID")
-	fmt.Println("  --output, -o           Output path for the configuration file")
-	fmt.Println("\nExample:")
-	fmt.Println("  ragsync create-config --ak your-access-key --sk your-secret-key --wid your-workspace-id")
-	fmt.Println()
+	if !w.yes {
+		fmt.Println("\nUsage:")
+		fmt.Printf("  ragsync %s [options]\n", command)
+		fmt.Println("\nOptions:")
+		fmt.Println("  --ak, --access-key     Aliyun Access Key")
+		fmt.Println("  --sk, --secret-key     Aliyun Secret Key")
+		fmt.Println("  --workspace-id, --wid  Bailian Workspace ID")
+		fmt.Println("  --category-id          Bailian category ID (or --create-category NAME)")
+		fmt.Println("  --index-id             Bailian knowledge index ID (or --create-index NAME)")
+		fmt.Println("  --endpoint             Bailian API endpoint")
+		fmt.Println("  --category-type        Bailian category type")
+		fmt.Println("  --parser               Bailian file parser")
+		fmt.Println("  --yes, -y              Do not prompt, for CI and automation")
+		fmt.Println("  --output, -o           Output path for the configuration file")
+		fmt.Println("  --secret-store         Where to store the secret key: keyring (default) or encrypted")
+		fmt.Println("\nExample:")
+		fmt.Printf("  ragsync %s --ak your-access-key --sk your-secret-key --wid your-workspace-id\n", command)
+		fmt.Println("  ragsync init --wid your-workspace-id --create-category docs --create-index docs --yes")
+		fmt.Printf("  ragsync --profile staging %s --wid your-staging-workspace-id\n", command)
+		fmt.Println()
+	}
 
 	// Get default configuration
 	defaultCfg := spec.GetDefaultConfig()
 
-	// Create a new configuration structure
+	// 在已有配置的基础上只修改提示的字段，其余设置（include_paths、重试、限流等）和档案保持不变
+	// 指定 --profile 时只修改该档案
 	config := spec.Config{}
+	profile := c.GlobalString("profile")
+	existing, err := spec.ReadConfig(configPath)
+	if err != nil {
+		if profile != "" {
+			return err
+		}
+		if utils.GetFirstExistedPath(configPath) != "" {
+			log.Warnf("Failed to read existing configuration file, its settings will not be kept: %v", err)
+		}
+	} else {
+		if err := existing.ResolveSecrets(); err != nil {
+			return err
+		}
+		config = *existing
+		if profile != "" {
+			if err := config.UseProfile(profile, true); err != nil {
+				return err
+			}
+			log.Infof("Editing profile %q in %s, shared settings are kept", profile, configPath)
+		} else {
+			log.Infof("Updating %s, settings that are not prompted for are kept", configPath)
+		}
 
-	// Create a buffered reader for console input
-	reader := bufio.NewReader(os.Stdin)
-
-	// Function to read a single line
-	readLine := func() string {
-		input, _ := reader.ReadString('\n')
-		return strings.TrimSpace(input)
+		// 提示中的默认值使用当前的值
+		for _, field := range []struct{ current, def *string }{
+			{&config.BailianFilesDefaultCategoryId, &defaultCfg.BailianFilesDefaultCategoryId},
+			{&config.BailianEndpoint, &defaultCfg.BailianEndpoint},
+			{&config.BailianCategoryType, &defaultCfg.BailianCategoryType},
+			{&config.BailianAddFileParser, &defaultCfg.BailianAddFileParser},
+		} {
+			if *field.current != "" {
+				*field.def = *field.current
+			}
+		}
 	}
 
-	// Get Aliyun Access Key from command line or prompt
-	config.AliyunAccessKey = c.String("ak")
-	if config.AliyunAccessKey == "" {
-		fmt.Print("Aliyun Access Key (AliyunAccessKey): ")
-		config.AliyunAccessKey = readLine()
+	// Get Aliyun Access Key and Secret Key from command line or prompt (profiles share the existing credentials)
+	// 指定 --yes 时可以不提供，由默认凭据链（环境变量、~/.aliyun/config.json、ECS 实例角色等）提供
+	currentAccessKey := config.AliyunAccessKey
+	if ak := c.String("ak"); ak != "" {
+		config.AliyunAccessKey = ak
+	} else if !w.yes && (profile == "" || config.AliyunAccessKey == "") {
+		config.AliyunAccessKey = w.ask("Aliyun Access Key (AliyunAccessKey)", config.AliyunAccessKey)
 		if config.AliyunAccessKey == "" {
 			return utils.Errorf("Aliyun Access Key is required")
 		}
 	}
-
-	// Get Aliyun Secret Key from command line or prompt
-	config.AliyunSecretKey = c.String("sk")
-	if config.AliyunSecretKey == "" {
-		fmt.Print("Aliyun Secret Key (AliyunSecretKey): ")
-		config.AliyunSecretKey = readLine()
+	if sk := c.String("sk"); sk != "" {
+		config.AliyunSecretKey = sk
+	} else if config.AliyunAccessKey != currentAccessKey {
+		// 换了 AccessKey 时不能沿用原来的 Secret Key
+		config.AliyunSecretKey = ""
+	}
+	if config.AliyunSecretKey == "" && config.AliyunAccessKey != "" {
+		config.AliyunSecretKey = w.ask("Aliyun Secret Key (AliyunSecretKey)", "")
 		if config.AliyunSecretKey == "" {
-			return utils.Errorf("Aliyun Secret Key is required")
+			return utils.Errorf("Aliyun Secret Key is required (--sk)")
 		}
 	}
+	if config.AliyunAccessKey == "" {
+		log.Infof("Aliyun access key not given, credentials will be resolved from the default credential chain")
+	}
 
 	// Get Bailian Workspace ID from command line or prompt
-	config.BailianWorkspaceId = c.String("workspace-id")
+	if workspaceId := wizardString(c, "workspace-id"); workspaceId != "" {
+		config.BailianWorkspaceId = workspaceId
+	} else {
+		config.BailianWorkspaceId = w.ask("Bailian Workspace ID (BailianWorkspaceId)", config.BailianWorkspaceId)
+	}
 	if config.BailianWorkspaceId == "" {
-		fmt.Print("Bailian Workspace ID (BailianWorkspaceId): ")
-		config.BailianWorkspaceId = readLine()
-		if config.BailianWorkspaceId == "" {
-			return utils.Errorf("Bailian Workspace ID is required, plz check your workspace id in https://bailian.console.aliyun.com")
-		}
+		return utils.Errorf("Bailian Workspace ID is required (--workspace-id), plz check your workspace id in https://bailian.console.aliyun.com")
 	}
 
-	// 百炼默认分类ID
-	fmt.Println("\nAvailable categories:")
-	categories, err := aliyun.ListCategories(config.AliyunAccessKey, config.AliyunSecretKey, config.BailianWorkspaceId)
+	// 查询和创建分类、索引使用 --endpoint 指定的端点
+	if endpoint := wizardString(c, "endpoint"); endpoint != "" {
+		defaultCfg.BailianEndpoint = endpoint
+	}
+	apiConfig := config
+	apiConfig.BailianEndpoint = defaultCfg.BailianEndpoint
+	client, err := aliyun.NewBailianClientFromConfig(&apiConfig)
 	if err != nil {
-		log.Warnf("Failed to list categories: %v", err)
-		fmt.Println("Warning: Could not fetch existing categories. You can still proceed with default category.")
-	} else {
-		fmt.Printf("%-40s %-50s\n", "Category ID", "Category Name")
-		fmt.Println(strings.Repeat("-", 90))
-		for _, cat := range categories {
-			fmt.Printf("%-40s %-50s\n", cat.CategoryId, cat.CategoryName)
-		}
-		fmt.Println()
+		return err
 	}
 
-	fmt.Printf("Bailian Default Category ID (BailianFilesDefaultCategoryId) [%s]: ", defaultCfg.BailianFilesDefaultCategoryId)
-	categoryId := readLine()
-
-	if categoryId == "" {
-		fmt.Print("\nNo category ID provided. Would you like to create a new category? (y/n): ")
-		createNew := readLine()
-		if strings.ToLower(createNew) == "y" {
-			fmt.Print("Enter new category name: ")
-			categoryName := readLine()
-			if categoryName == "" {
-				return utils.Errorf("Category name cannot be empty")
-			}
-			err := aliyun.CreateCategory(config.AliyunAccessKey, config.AliyunSecretKey, config.BailianWorkspaceId, categoryName)
-			if err != nil {
-				return utils.Errorf("Failed to create category: %v", err)
-			}
-			fmt.Println("Category created successfully. Please run the command again to select the new category.")
-			return nil
-		} else {
-			fmt.Print("\nWarning: Using default category ID. This may not be suitable for all use cases.\nAre you sure you want to use the default category? (y/n): ")
-			confirm := readLine()
-			if strings.ToLower(confirm) != "y" {
-				return utils.Errorf("Please provide a valid category ID or create a new one")
-			}
-			config.BailianFilesDefaultCategoryId = defaultCfg.BailianFilesDefaultCategoryId
-		}
-	} else {
-		// 验证分类ID是否存在
-		if err == nil {
-			found := false
-			for _, cat := range categories {
-				if cat.CategoryId == categoryId {
-					found = true
-					break
-				}
-			}
-			if !found {
-				fmt.Printf("\nWarning: Category ID '%s' not found in the list of available categories.\n", categoryId)
-				fmt.Print("Do you want to proceed anyway? (y/n): ")
-				confirm := readLine()
-				if strings.ToLower(confirm) != "y" {
-					return utils.Errorf("Please provide a valid category ID")
-				}
-			}
-		}
-		config.BailianFilesDefaultCategoryId = categoryId
+	// 百炼默认分类ID
+	categoryId, err := selectCategory(w, client, c, autoSelect, defaultCfg.BailianFilesDefaultCategoryId)
+	if err != nil || categoryId == "" {
+		return err
 	}
+	config.BailianFilesDefaultCategoryId = categoryId
 
 	// 百炼知识库索引ID
-	fmt.Println("\nAvailable indices:")
-	indices, err := aliyun.ListIndices(config.AliyunAccessKey, config.AliyunSecretKey, config.BailianWorkspaceId)
-	if err != nil {
-		log.Warnf("Failed to list indices: %v", err)
-		fmt.Println("Warning: Could not fetch existing indices. You can still proceed with manual input.")
-	} else {
-		fmt.Printf("%-40s %-50s\n", "Index ID", "Index Name")
-		fmt.Println(strings.Repeat("-", 90))
-		for _, idx := range indices {
-			fmt.Printf("%-40s %-50s\n", idx.IndexId, idx.IndexName)
-		}
-		fmt.Println()
-	}
-
-	fmt.Print("Bailian Knowledge Index ID (BailianKnowledgeIndexId): ")
-	indexId := readLine()
-
-	if indexId == "" {
-		fmt.Print("\nNo index ID provided. Would you like to create a new index? (y/n): ")
-		createNew := readLine()
-		if strings.ToLower(createNew) == "y" {
-			fmt.Print("Enter new index name: ")
-			indexName := readLine()
-			if indexName == "" {
-				return utils.Errorf("Index name cannot be empty")
-			}
-			err := aliyun.CreateIndex(config.AliyunAccessKey, config.AliyunSecretKey, config.BailianWorkspaceId, indexName, "DATA_CENTER_CATEGORY", []string{config.BailianFilesDefaultCategoryId})
-			if err != nil {
-				return utils.Errorf("Failed to create index: %v", err)
-			}
-			fmt.Println("Index created successfully. Please run the command again to select the new index.")
-			return nil
-		} else {
-			return utils.Errorf("Bailian Knowledge Index ID is required, plz check your knowledge index id in https://bailian.console.aliyun.com")
-		}
-	} else {
-		// 验证索引ID是否存在
-		if err == nil {
-			found := false
-			for _, idx := range indices {
-				if idx.IndexId == indexId {
-					found = true
-					break
-				}
-			}
-			if !found {
-				fmt.Printf("\nWarning: Index ID '%s' not found in the list of available indices.\n", indexId)
-				fmt.Print("Do you want to proceed anyway? (y/n): ")
-				confirm := readLine()
-				if strings.ToLower(confirm) != "y" {
-					return utils.Errorf("Please provide a valid index ID")
-				}
-			}
-		}
-		config.BailianKnowledgeIndexId = indexId
+	indexId, err := selectIndex(w, client, c, autoSelect, config.BailianKnowledgeIndexId, categoryId)
+	if err != nil || indexId == "" {
+		return err
 	}
+	config.BailianKnowledgeIndexId = indexId
 
 	// 百炼服务端点
-	fmt.Printf("Bailian Endpoint (BailianEndpoint) [%s]: ", defaultCfg.BailianEndpoint)
-	endpoint := readLine()
-	if endpoint != "" {
+	if endpoint := wizardString(c, "endpoint"); endpoint != "" {
 		config.BailianEndpoint = endpoint
 	} else {
-		config.BailianEndpoint = defaultCfg.BailianEndpoint
+		config.BailianEndpoint = w.ask("Bailian Endpoint (BailianEndpoint)", defaultCfg.BailianEndpoint)
 	}
 
 	// 百炼分类类型
-	fmt.Printf("Bailian Category Type (BailianCategoryType) [%s]: ", defaultCfg.BailianCategoryType)
-	categoryType := readLine()
-	if categoryType != "" {
+	if categoryType := wizardString(c, "category-type"); categoryType != "" {
 		config.BailianCategoryType = categoryType
 	} else {
-		config.BailianCategoryType = defaultCfg.BailianCategoryType
+		config.BailianCategoryType = w.ask("Bailian Category Type (BailianCategoryType)", defaultCfg.BailianCategoryType)
 	}
 
 	// 百炼文件解析器
-	fmt.Printf("Bailian File Parser (BailianAddFileParser) [%s]: ", defaultCfg.BailianAddFileParser)
-	fileParser := readLine()
-	if fileParser != "" {
-		config.BailianAddFileParser = fileParser
+	if parser := wizardString(c, "parser"); parser != "" {
+		config.BailianAddFileParser = parser
 	} else {
-		config.BailianAddFileParser = defaultCfg.BailianAddFileParser
+		config.BailianAddFileParser = w.ask("Bailian File Parser (BailianAddFileParser)", defaultCfg.BailianAddFileParser)
 	}
 
 	// 验证配置
@@ -263,10 +315,10 @@ func executeCreateConfig(c *cli.Context) error {
 		return utils.Errorf("Configuration validation failed: %v", err)
 	}
 
-	// 检查配置文件是否存在，如果存在则备份
+	// 检查配置文件是否存在，如果存在则备份（旧文件可能包含明文密钥，备份同样使用 0600 权限）
 	if utils.GetFirstExistedPath(configPath) != "" {
 		backupPath := configPath + ".bak"
-		if err := utils.CopyFile(configPath, backupPath); err != nil {
+		if err := backupConfigFile(configPath, backupPath); err != nil {
 			log.Errorf("Failed to backup existing config file: %v", err)
 		} else {
 			log.Infof("Existing configuration backed up to: %s", backupPath)
@@ -276,6 +328,9 @@ func executeCreateConfig(c *cli.Context) error {
 	// 打印配置并确认
 	fmt.Println("\nPlease review your configuration:")
 	fmt.Println("----------------------------------------")
+	if config.Profile != "" {
+		fmt.Printf("Profile: %s\n", config.Profile)
+	}
 	fmt.Printf("Aliyun Access Key: %s\n", config.AliyunAccessKey)
 	fmt.Printf("Aliyun Secret Key: %s\n", strings.Repeat("*", len(config.AliyunSecretKey)))
 	fmt.Printf("Bailian Workspace ID: %s\n", config.BailianWorkspaceId)
@@ -285,12 +340,15 @@ func executeCreateConfig(c *cli.Context) error {
 	fmt.Printf("Bailian File Parser: %s\n", config.BailianAddFileParser)
 	fmt.Printf("Bailian Default Category ID: %s\n", config.BailianFilesDefaultCategoryId)
 	fmt.Println("----------------------------------------")
-	fmt.Print("\nDo you want to save this configuration? (y/n): ")
-	confirm := readLine()
-	if strings.ToLower(confirm) != "y" {
+	if !w.confirm("\nDo you want to save this configuration?") {
 		return utils.Errorf("Configuration not saved")
 	}
 
+	// 密钥保存到密钥环或加密后，配置文件中只保存引用
+	if _, err := config.StoreSecrets(c.String("secret-store")); err != nil {
+		return err
+	}
+
 	// 将配置保存到文件
 	if err := spec.SaveConfig(&config, configPath); err != nil {
 		return err
@@ -299,3 +357,191 @@ func executeCreateConfig(c *cli.Context) error {
 	log.Infof("Configuration file has been successfully saved to: %s", configPath)
 	return nil
 }
+
+// selectCategory 选择上传文件使用的分类，返回空字符串时表示已创建分类但需要重新运行命令
+func selectCategory(w *configWizard, client *aliyun.BailianClient, c *cli.Context, autoSelect bool, current string) (string, error) {
+	if !w.yes {
+		fmt.Println("\nAvailable categories:")
+	}
+	categories, listErr := client.ListCategories()
+	if listErr != nil {
+		log.Warnf("Failed to list categories: %v", listErr)
+		if !w.yes {
+			fmt.Println("Warning: Could not fetch existing categories. You can still proceed with default category.")
+		}
+	} else if !w.yes {
+		fmt.Printf("%-40s %-50s\n", "Category ID", "Category Name")
+		fmt.Println(strings.Repeat("-", 90))
+		for _, cat := range categories {
+			fmt.Printf("%-40s %-50s\n", cat.CategoryId, cat.CategoryName)
+		}
+		fmt.Println()
+	}
+
+	// 创建分类：autoSelect 时同名分类已存在则直接使用
+	create := func(name string) (string, error) {
+		if autoSelect && listErr == nil {
+			for _, cat := range categories {
+				if cat.CategoryName == name {
+					log.Infof("Using existing category %s (%s)", name, cat.CategoryId)
+					return cat.CategoryId, nil
+				}
+			}
+		}
+		return client.CreateCategory(name)
+	}
+
+	categoryId := wizardString(c, "category-id")
+	if name := c.String("create-category"); name != "" {
+		if categoryId != "" {
+			return "", utils.Errorf("--category-id and --create-category cannot be used together")
+		}
+		return create(name)
+	}
+	if categoryId == "" && !w.yes {
+		fmt.Printf("Bailian Default Category ID (BailianFilesDefaultCategoryId) [%s]: ", current)
+		categoryId = w.readLine()
+	}
+
+	if categoryId == "" {
+		if !w.yes {
+			fmt.Print("\nNo category ID provided. Would you like to create a new category? (y/n): ")
+			if strings.ToLower(w.readLine()) == "y" {
+				fmt.Print("Enter new category name: ")
+				categoryName := w.readLine()
+				if categoryName == "" {
+					return "", utils.Errorf("Category name cannot be empty")
+				}
+				categoryId, err := create(categoryName)
+				if err != nil {
+					return "", utils.Errorf("Failed to create category: %v", err)
+				}
+				if autoSelect {
+					return categoryId, nil
+				}
+				fmt.Printf("Category created successfully (%s). Please run the command again to select the new category, or use 'ragsync init' to create and select it in one step.\n", categoryId)
+				return "", nil
+			}
+		}
+		if w.yes {
+			log.Warnf("No --category-id or --create-category given, using category %s", current)
+		}
+		if !w.confirm("\nWarning: Using default category ID. This may not be suitable for all use cases.\nAre you sure you want to use the default category?") {
+			return "", utils.Errorf("Please provide a valid category ID or create a new one")
+		}
+		return current, nil
+	}
+
+	// 验证分类ID是否存在
+	if listErr == nil {
+		found := false
+		for _, cat := range categories {
+			if cat.CategoryId == categoryId {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fmt.Printf("\nWarning: Category ID '%s' not found in the list of available categories.\n", categoryId)
+			if !w.confirm("Do you want to proceed anyway?") {
+				return "", utils.Errorf("Please provide a valid category ID")
+			}
+		}
+	}
+	return categoryId, nil
+}
+
+// selectIndex 选择知识索引，新建的索引以 categoryId 为数据源，返回空字符串时表示已创建索引但需要重新运行命令
+func selectIndex(w *configWizard, client *aliyun.BailianClient, c *cli.Context, autoSelect bool, current string, categoryId string) (string, error) {
+	if !w.yes {
+		fmt.Println("\nAvailable indices:")
+	}
+	indices, listErr := client.ListIndices()
+	if listErr != nil {
+		log.Warnf("Failed to list indices: %v", listErr)
+		if !w.yes {
+			fmt.Println("Warning: Could not fetch existing indices. You can still proceed with manual input.")
+		}
+	} else if !w.yes {
+		fmt.Printf("%-40s %-50s\n", "Index ID", "Index Name")
+		fmt.Println(strings.Repeat("-", 90))
+		for _, idx := range indices {
+			fmt.Printf("%-40s %-50s\n", idx.IndexId, idx.IndexName)
+		}
+		fmt.Println()
+	}
+
+	// 创建索引：autoSelect 时同名索引已存在则直接使用
+	create := func(name string) (string, error) {
+		if autoSelect && listErr == nil {
+			for _, idx := range indices {
+				if idx.IndexName == name {
+					log.Infof("Using existing index %s (%s)", name, idx.IndexId)
+					return idx.IndexId, nil
+				}
+			}
+		}
+		return client.CreateIndex(name, "DATA_CENTER_CATEGORY", []string{categoryId})
+	}
+
+	indexId := wizardString(c, "index-id")
+	if name := c.String("create-index"); name != "" {
+		if indexId != "" {
+			return "", utils.Errorf("--index-id and --create-index cannot be used together")
+		}
+		return create(name)
+	}
+	if indexId == "" {
+		indexId = w.ask("Bailian Knowledge Index ID (BailianKnowledgeIndexId)", current)
+	}
+
+	if indexId == "" {
+		if !w.yes {
+			fmt.Print("\nNo index ID provided. Would you like to create a new index? (y/n): ")
+			if strings.ToLower(w.readLine()) == "y" {
+				fmt.Print("Enter new index name: ")
+				indexName := w.readLine()
+				if indexName == "" {
+					return "", utils.Errorf("Index name cannot be empty")
+				}
+				indexId, err := create(indexName)
+				if err != nil {
+					return "", utils.Errorf("Failed to create index: %v", err)
+				}
+				if autoSelect {
+					return indexId, nil
+				}
+				fmt.Printf("Index created successfully (%s). Please run the command again to select the new index, or use 'ragsync init' to create and select it in one step.\n", indexId)
+				return "", nil
+			}
+		}
+		return "", utils.Errorf("Bailian Knowledge Index ID is required (--index-id or --create-index), plz check your knowledge index id in https://bailian.console.aliyun.com")
+	}
+
+	// 验证索引ID是否存在
+	if listErr == nil {
+		found := false
+		for _, idx := range indices {
+			if idx.IndexId == indexId {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fmt.Printf("\nWarning: Index ID '%s' not found in the list of available indices.\n", indexId)
+			if !w.confirm("Do you want to proceed anyway?") {
+				return "", utils.Errorf("Please provide a valid index ID")
+			}
+		}
+	}
+	return indexId, nil
+}
+
+// backupConfigFile 以 0600 权限备份配置文件
+func backupConfigFile(configPath string, backupPath string) error {
+	raw, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	return secret.WriteFile(backupPath, raw)
+}
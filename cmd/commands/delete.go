@@ -6,7 +6,8 @@ import (
 
 	"github.com/urfave/cli"
 
-	"github.com/VillanCh/ragsync/common/aliyun"
+	"github.com/VillanCh/ragsync/common/spec"
+	"github.com/VillanCh/ragsync/common/syncstate"
 
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
@@ -57,16 +58,19 @@ func executeDelete(c *cli.Context) error {
 		return utils.Errorf("Please specify either file ID (--id) or file name (--name) to delete")
 	}
 
-	client, err := aliyun.NewBailianClientFromConfig(config)
+	client, err := NewBackend(config)
 	if err != nil {
 		return err
 	}
 
+	ctx, cancel := commandContext(c)
+	defer cancel()
+
 	// 如果提供了文件名，需要先查找对应的文件
 	if fileId == "" && fileName != "" {
 		// 首先列出所有匹配的文件
 		log.Infof("Searching for files with name: %s", fileName)
-		files, err := client.ListAllFiles(fileName)
+		files, err := client.ListAllFilesWithContext(ctx, fileName)
 		if err != nil {
 			return utils.Errorf("Failed to list files: %v", err)
 		}
@@ -102,7 +106,7 @@ func executeDelete(c *cli.Context) error {
 		}
 	} else if fileId != "" && fileName == "" {
 		// 如果只提供了ID，获取文件名用于确认
-		fileInfo, err := client.DescribeFile(fileId)
+		fileInfo, err := client.DescribeFileWithContext(ctx, fileId)
 		if err != nil {
 			log.Warnf("Failed to get file information: %v", err)
 			// 继续执行，但没有文件名用于确认
@@ -128,8 +132,14 @@ func executeDelete(c *cli.Context) error {
 	}
 
 	// 使用新的DeleteFileEx方法，传入skipIndexDelete参数
-	err = client.DeleteFileEx(fileId, skipIndexDelete)
+	err = client.DeleteFileExWithContext(ctx, fileId, skipIndexDelete)
+	if remoteFileGone(err) {
+		log.Infof("File %s (ID: %s) no longer exists, nothing to delete", fileName, fileId)
+		forgetSyncedFile(config, fileId)
+		return nil
+	}
 	if err != nil {
+		logAuthFailure(err)
 		// 如果跳过了索引删除，但文件删除失败，可能是索引问题
 		if skipIndexDelete {
 			log.Warnf("File deletion failed. Consider trying with index deletion enabled.")
@@ -138,5 +148,19 @@ func executeDelete(c *cli.Context) error {
 	}
 
 	log.Infof("File deleted successfully")
+	forgetSyncedFile(config, fileId)
 	return nil
 }
+
+// forgetSyncedFile 从同步状态中移除已删除的远程文件，之后的同步会重新上传本地仍然存在的文件
+func forgetSyncedFile(config *spec.Config, fileId string) {
+	state, err := syncstate.Load(config.BailianWorkspaceId, config.BailianKnowledgeIndexId)
+	if err != nil {
+		log.Warnf("Failed to load sync state, the deleted file may still be listed in it: %v", err)
+		return
+	}
+	state.RemoveByFileId(fileId)
+	if err := state.Save(); err != nil {
+		log.Warnf("Failed to save sync state: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"context"
+	"sync"
+
+	"github.com/VillanCh/ragsync/common/backend"
+	"github.com/VillanCh/ragsync/common/syncstate"
+
+	"github.com/yaklang/yaklang/common/log"
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+const (
+	// defaultIndexBatchSize 每个索引任务默认提交的文档数
+	defaultIndexBatchSize = 50
+	// maxIndexBatchSize SubmitIndexAddDocumentsJob 单次提交的文档数上限
+	maxIndexBatchSize = 100
+)
+
+// indexBatchItem 等待提交到索引的文件
+type indexBatchItem struct {
+	Path   string
+	FileId string
+
+	// 重命名时被替代的旧文件，新文件索引完成后才删除
+	OldPath   string
+	OldFileId string
+}
+
+// indexBatch 收集本次同步中需要加入知识索引的文件，在同步结束时批量提交
+type indexBatch struct {
+	mu    sync.Mutex
+	items []indexBatchItem
+}
+
+// Add 添加一个等待提交的文件
+func (b *indexBatch) Add(path, fileId string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.items = append(b.items, indexBatchItem{Path: path, FileId: fileId})
+}
+
+// AddRename 添加一个重命名后的文件，新文件索引完成后删除旧文件
+func (b *indexBatch) AddRename(path, fileId, oldPath, oldFileId string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.items = append(b.items, indexBatchItem{Path: path, FileId: fileId, OldPath: oldPath, OldFileId: oldFileId})
+}
+
+// Drain 取出所有等待提交的文件
+func (b *indexBatch) Drain() []indexBatchItem {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	items := b.items
+	b.items = nil
+	return items
+}
+
+// submitIndexBatches 将收集到的文件按批次提交到知识索引，并输出每个批次的任务 ID
+func submitIndexBatches(ctx context.Context, client backend.Backend, state *syncstate.Manifest, batch *indexBatch, batchSize int) error {
+	if batch == nil {
+		return nil
+	}
+	items := batch.Drain()
+	if len(items) == 0 {
+		log.Infof("No documents to submit to knowledge index")
+		return nil
+	}
+
+	if batchSize <= 0 {
+		batchSize = defaultIndexBatchSize
+	}
+	if batchSize > maxIndexBatchSize {
+		log.Warnf("Index batch size %d exceeds the API limit, using %d", batchSize, maxIndexBatchSize)
+		batchSize = maxIndexBatchSize
+	}
+
+	totalBatches := (len(items) + batchSize - 1) / batchSize
+	log.Infof("Submitting %d documents to knowledge index in %d batches", len(items), totalBatches)
+
+	// 这些文件已经添加到后端，即使同步被中断也提交索引任务，避免文件上传后没有进入索引
+	submitCtx := context.WithoutCancel(ctx)
+	if ctx.Err() != nil {
+		log.Warnf("Sync was interrupted, still submitting the %d documents that were already added", len(items))
+	}
+
+	type batchReport struct {
+		Documents int
+		JobId     string
+		Err       error
+	}
+	var reports []batchReport
+	var failed int
+	// 提交成功的批次中被重命名替代的旧文件，按任务 ID 分组
+	renamed := make(map[string][]indexBatchItem)
+	var renamedJobs []string
+
+	for start := 0; start < len(items); start += batchSize {
+		end := start + batchSize
+		if end > len(items) {
+			end = len(items)
+		}
+		chunk := items[start:end]
+
+		documentIds := make([]string, 0, len(chunk))
+		for _, item := range chunk {
+			documentIds = append(documentIds, item.FileId)
+		}
+
+		jobId, err := client.AppendDocumentsToIndexWithContext(submitCtx, documentIds)
+		reports = append(reports, batchReport{Documents: len(chunk), JobId: jobId, Err: err})
+		if err != nil {
+			log.Errorf("Failed to submit index batch %d/%d: %v", len(reports), totalBatches, err)
+			failed++
+			continue
+		}
+
+		for _, item := range chunk {
+			state.Update(item.Path, func(e *syncstate.Entry) {
+				e.FileId = item.FileId
+				e.IndexStatus = "RUNNING"
+				e.LastJobId = jobId
+			})
+			if item.OldFileId != "" {
+				if len(renamed[jobId]) == 0 {
+					renamedJobs = append(renamedJobs, jobId)
+				}
+				renamed[jobId] = append(renamed[jobId], item)
+			}
+		}
+	}
+
+	// 输出每个批次的结果
+	for i, report := range reports {
+		if report.Err != nil {
+			log.Errorf("Index batch %d/%d: %d documents, FAILED: %v", i+1, totalBatches, report.Documents, report.Err)
+		} else {
+			log.Infof("Index batch %d/%d: %d documents, job ID: %s", i+1, totalBatches, report.Documents, report.JobId)
+		}
+	}
+	log.Info("You can check the job status with: ragsync job --job-id <JOB_ID>")
+
+	// 重命名的文件：等待新文件索引完成后再删除旧文件，保证检索中不会出现内容缺失
+	var deleteFailed int
+	for _, jobId := range renamedJobs {
+		log.Infof("Waiting for index job %s before deleting %d renamed remote files", jobId, len(renamed[jobId]))
+		if err := waitForIndexJob(ctx, client, jobId, renameIndexTimeout); err != nil {
+			log.Warnf("%v, keeping the old remote files of renamed documents (they will be deleted by the next sync with --prune)", err)
+			continue
+		}
+		for _, item := range renamed[jobId] {
+			if err := deleteRenamedSource(ctx, client, state, item.OldPath, item.OldFileId); err != nil {
+				deleteFailed++
+			}
+		}
+	}
+
+	if deleteFailed > 0 {
+		return utils.Errorf("Failed to delete %d renamed remote files", deleteFailed)
+	}
+	if failed > 0 {
+		return utils.Errorf("%d of %d index batches failed to submit", failed, totalBatches)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/VillanCh/ragsync/common/bailianmock"
+)
+
+// writeNumberedFiles 在 dir 中写入 n 个内容不同的文件
+func writeNumberedFiles(t *testing.T, dir string, n int) {
+	t.Helper()
+	files := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		files[fmt.Sprintf("doc-%03d.md", i)] = fmt.Sprintf("document %d\n", i)
+	}
+	writeFiles(t, dir, files)
+}
+
+func TestSyncSubmitsIndexBatches(t *testing.T) {
+	cases := []struct {
+		name      string
+		files     int
+		batchSize string
+		wantJobs  int
+	}{
+		{"default size", 120, "", 3},
+		{"explicit size", 25, "10", 3},
+		// 超过接口上限的值按 100 提交
+		{"capped at the API limit", 230, "500", 3},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server, configPath := newMockWorkspace(t)
+			client := mockClient(t, configPath)
+			dir := t.TempDir()
+			writeNumberedFiles(t, dir, c.files)
+
+			args := []string{"sync", "--dir", dir, "--exclude", ""}
+			if c.batchSize != "" {
+				args = append(args, "--index-batch-size", c.batchSize)
+			}
+			if err := runCommand(t, configPath, args...); err != nil {
+				t.Fatalf("sync: %v", err)
+			}
+			if calls := server.Calls("SubmitIndexAddDocumentsJob"); calls != c.wantJobs {
+				t.Fatalf("SubmitIndexAddDocumentsJob was called %d times, want %d", calls, c.wantJobs)
+			}
+			if names, documents := remoteState(t, client); len(names) != c.files || documents != c.files {
+				t.Fatalf("remote has %d files and %d index documents, want %d of each", len(names), documents, c.files)
+			}
+		})
+	}
+}
+
+func TestSyncIndexBatchFailure(t *testing.T) {
+	server, configPath := newMockWorkspace(t)
+	dir := t.TempDir()
+	writeNumberedFiles(t, dir, 30)
+
+	// 第一个批次提交失败，其余批次仍然提交，失败批次的文件没有记录为已索引
+	server.InjectFault("SubmitIndexAddDocumentsJob", bailianmock.NoPermission(1))
+	err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", "", "--index-batch-size", "10")
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 index batches failed") {
+		t.Fatalf("sync returned %v, want a failed index batch error", err)
+	}
+	if calls := server.Calls("SubmitIndexAddDocumentsJob"); calls != 3 {
+		t.Fatalf("SubmitIndexAddDocumentsJob was called %d times, want 3", calls)
+	}
+
+	state := loadMockState(t, configPath)
+	indexed := 0
+	for _, entry := range state.EntriesUnder(dir) {
+		if entry.IsIndexed() {
+			indexed++
+		}
+	}
+	if indexed != 20 {
+		t.Fatalf("%d files are recorded as indexed, want the 20 of the submitted batches", indexed)
+	}
+
+	// 下一次同步将失败批次的文件重新加入索引
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", ""); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	state = loadMockState(t, configPath)
+	for _, entry := range state.EntriesUnder(dir) {
+		if !entry.IsIndexed() {
+			t.Fatalf("%s is still not indexed after the second sync", filepath.Base(entry.LocalPath))
+		}
+	}
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/urfave/cli"
 
+	"github.com/VillanCh/ragsync/common/backend"
+
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
 )
@@ -49,8 +51,8 @@ func executeIndexJobsList(c *cli.Context) error {
 	}
 
 	// 输出任务列表
-	fmt.Printf("\n%-40s %-25s\n", "Job ID", "Creation Time")
-	fmt.Println(strings.Repeat("-", 70))
+	fmt.Printf("\n%-40s %-25s %-10s\n", "Job ID", "Creation Time", "Documents")
+	fmt.Println(strings.Repeat("-", 80))
 
 	for _, file := range files {
 		if file.IsDir() {
@@ -67,8 +69,14 @@ func executeIndexJobsList(c *cli.Context) error {
 		// 获取创建时间
 		creationTime := fileInfo.ModTime().Format(time.RFC3339)
 
-		// 输出任务ID和创建时间
-		fmt.Printf("%-40s %-25s\n", file.Name(), creationTime)
+		// 获取任务包含的文档数（旧版本保存的任务文件为空）
+		documents := "-"
+		if documentIds, err := backend.LoadIndexJobDocuments(file.Name()); err == nil && len(documentIds) > 0 {
+			documents = fmt.Sprint(len(documentIds))
+		}
+
+		// 输出任务ID、创建时间和文档数
+		fmt.Printf("%-40s %-25s %-10s\n", file.Name(), creationTime, documents)
 	}
 
 	fmt.Printf("\nTotal jobs: %d\n", len(files))
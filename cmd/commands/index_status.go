@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -8,10 +9,9 @@ import (
 	"strings"
 	"time"
 
-	"github.com/alibabacloud-go/tea/tea"
 	"github.com/urfave/cli"
 
-	"github.com/VillanCh/ragsync/common/aliyun"
+	"github.com/VillanCh/ragsync/common/backend"
 
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
@@ -52,49 +52,58 @@ func executeIndexStatus(c *cli.Context) error {
 	}
 
 	// 创建客户端
-	client, err := aliyun.NewBailianClientFromConfig(config)
+	client, err := NewBackend(config)
 	if err != nil {
 		return err
 	}
 
+	ctx, cancel := commandContext(c)
+	defer cancel()
+
 	// 获取任务ID
 	jobId := c.String("job-id")
 	autoCleanup := c.Bool("cleanup")
 
 	// 如果提供了特定的任务ID，则只检查该任务
 	if jobId != "" {
-		return checkSingleJobStatus(client, jobId, autoCleanup)
+		return checkSingleJobStatus(ctx, client, jobId, autoCleanup)
 	}
 
 	// 否则，检查所有本地保存的任务
-	return checkAllLocalJobs(client, autoCleanup)
+	return checkAllLocalJobs(ctx, client, autoCleanup)
 }
 
 // checkSingleJobStatus 检查单个任务的状态
-func checkSingleJobStatus(client *aliyun.BailianClient, jobId string, autoCleanup bool) error {
+func checkSingleJobStatus(ctx context.Context, client backend.Backend, jobId string, autoCleanup bool) error {
 	// 查询任务状态
 	log.Infof("Querying status for job: %s", jobId)
-	response, err := client.GetIndexJobStatus(jobId)
+	job, err := client.IndexJobStatusWithContext(ctx, jobId)
 	if err != nil {
 		return utils.Errorf("Failed to query job status: %v", err)
 	}
 
 	// 显示任务状态
-	if response != nil && response.Data != nil {
+	if job != nil {
 		fmt.Printf("\n--- Index Job Status ---\n")
 		fmt.Printf("Job ID: %s\n", jobId)
 
 		// 获取状态
 		status := "Unknown"
-		if response.Data.Status != nil {
-			status = tea.StringValue(response.Data.Status)
-			fmt.Printf("Status: %s\n", status)
-		} else {
-			fmt.Printf("Status: Unknown\n")
+		if job.Status != "" {
+			status = job.Status
+		}
+		fmt.Printf("Status: %s\n", status)
+
+		// 显示本地记录的任务文档
+		if documentIds, err := backend.LoadIndexJobDocuments(jobId); err == nil && len(documentIds) > 0 {
+			fmt.Printf("Documents submitted: %d\n", len(documentIds))
+			for _, id := range documentIds {
+				fmt.Printf("  - %s\n", id)
+			}
 		}
 
 		// 将完整数据转为 JSON 显示
-		jsonData, _ := json.MarshalIndent(response.Data, "", "  ")
+		jsonData, _ := json.MarshalIndent(job.Raw, "", "  ")
 		fmt.Printf("\nDetailed Status Data:\n%s\n\n", string(jsonData))
 
 		// 如果启用了自动清理并且任务状态是 FINISH 或 DELETED，删除本地文件
@@ -113,7 +122,7 @@ func checkSingleJobStatus(client *aliyun.BailianClient, jobId string, autoCleanu
 }
 
 // checkAllLocalJobs 检查所有本地保存的任务状态
-func checkAllLocalJobs(client *aliyun.BailianClient, autoCleanup bool) error {
+func checkAllLocalJobs(ctx context.Context, client backend.Backend, autoCleanup bool) error {
 	// 获取用户主目录
 	homeDir := utils.GetHomeDirDefault(".")
 
@@ -150,8 +159,13 @@ func checkAllLocalJobs(client *aliyun.BailianClient, autoCleanup bool) error {
 			continue // 跳过目录
 		}
 
+		if ctx.Err() != nil {
+			log.Warnf("Stopped checking index jobs: %v", ctx.Err())
+			break
+		}
+
 		jobId := file.Name()
-		response, err := client.GetIndexJobStatus(jobId)
+		job, err := client.IndexJobStatusWithContext(ctx, jobId)
 
 		// 获取文件信息
 		fileInfo, _ := file.Info()
@@ -166,8 +180,8 @@ func checkAllLocalJobs(client *aliyun.BailianClient, autoCleanup bool) error {
 
 		// 获取并显示状态
 		status := "Unknown"
-		if response != nil && response.Data != nil && response.Data.Status != nil {
-			status = tea.StringValue(response.Data.Status)
+		if job != nil && job.Status != "" {
+			status = job.Status
 		}
 
 		fmt.Printf("%-40s %-15s %-25s\n", jobId, status, creationTime)
@@ -6,8 +6,6 @@ import (
 
 	"github.com/urfave/cli"
 
-	"github.com/VillanCh/ragsync/common/aliyun"
-
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
 )
@@ -37,17 +35,21 @@ func executeList(c *cli.Context) error {
 		return err
 	}
 
-	client, err := aliyun.NewBailianClientFromConfig(config)
+	client, err := NewBackend(config)
 	if err != nil {
 		return err
 	}
 
+	ctx, cancel := commandContext(c)
+	defer cancel()
+
 	fileName := c.String("name")
 
 	log.Infof("Listing files in workspace (filter: %s)...", fileName)
-	files, err := client.ListAllFiles(fileName)
+	files, err := client.ListAllFilesWithContext(ctx, fileName)
 	if err != nil {
-		return utils.Errorf("Failed to list files: %v", err)
+		logAuthFailure(err)
+		return utils.Errorf("Failed to list files: %w", err)
 	}
 
 	// 输出文件列表
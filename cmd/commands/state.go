@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/VillanCh/ragsync/common/backend"
+	"github.com/VillanCh/ragsync/common/spec"
+	"github.com/VillanCh/ragsync/common/syncstate"
+
+	"github.com/yaklang/yaklang/common/log"
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// StateCommand 本地同步状态管理命令
+func StateCommand() cli.Command {
+	return cli.Command{
+		Name:  "state",
+		Usage: "Manage the local sync-state manifest",
+		Subcommands: []cli.Command{
+			{
+				Name:   "rebuild",
+				Usage:  "Rebuild the local sync-state manifest from remote files and index documents",
+				Action: executeStateRebuild,
+			},
+			{
+				Name:   "show",
+				Usage:  "Show entries in the local sync-state manifest",
+				Action: executeStateShow,
+			},
+		},
+	}
+}
+
+// executeStateRebuild 重建本地同步状态的执行逻辑
+func executeStateRebuild(c *cli.Context) error {
+	config, err := LoadConfig(c)
+	if err != nil {
+		return err
+	}
+
+	client, err := NewBackend(config)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext(c)
+	defer cancel()
+
+	state, err := syncstate.Load(config.BailianWorkspaceId, config.BailianKnowledgeIndexId)
+	if err != nil {
+		log.Warnf("Failed to load existing sync state, starting from scratch: %v", err)
+		state = syncstate.New(config.BailianWorkspaceId, config.BailianKnowledgeIndexId)
+	}
+
+	if err := rebuildSyncState(ctx, client, config, state); err != nil {
+		return err
+	}
+
+	if err := state.Save(); err != nil {
+		return err
+	}
+
+	log.Infof("Sync state rebuilt with %d entries: %s", state.Len(), state.Path())
+	return nil
+}
+
+// executeStateShow 显示本地同步状态的执行逻辑
+func executeStateShow(c *cli.Context) error {
+	config, err := LoadConfig(c)
+	if err != nil {
+		return err
+	}
+
+	state, err := syncstate.Load(config.BailianWorkspaceId, config.BailianKnowledgeIndexId)
+	if err != nil {
+		return err
+	}
+
+	entries := state.EntriesUnder(".")
+	fmt.Printf("\n%-50s %-40s %-15s %-15s\n", "Local Path", "File ID", "File Status", "Index Status")
+	fmt.Println(strings.Repeat("-", 125))
+	for _, entry := range entries {
+		fmt.Printf("%-50s %-40s %-15s %-15s\n", entry.LocalPath, entry.FileId, entry.FileStatus, entry.IndexStatus)
+	}
+
+	if len(state.GitCommits) > 0 {
+		fmt.Println("\nLast synced git commits:")
+		for path, commit := range state.GitCommits {
+			fmt.Printf("  %-48s %s\n", path, commit)
+		}
+	}
+
+	fmt.Printf("\nTotal entries: %d\n", len(entries))
+	fmt.Printf("State file: %s\n", state.Path())
+	if !state.IsComplete() {
+		fmt.Println("The manifest has not been reconciled with the workspace yet, run 'ragsync state rebuild' to do so.")
+	}
+	return nil
+}
+
+// rebuildSyncState 使用远程文件列表和索引文档列表重建同步状态
+func rebuildSyncState(ctx context.Context, client backend.Backend, config *spec.Config, state *syncstate.Manifest) error {
+	log.Infof("Listing all remote files to rebuild sync state...")
+	files, err := client.ListAllFilesWithContext(ctx, "")
+	if err != nil {
+		return utils.Errorf("Failed to list remote files: %v", err)
+	}
+	reconcileRemoteFiles(state, files)
+
+	if config.BailianKnowledgeIndexId != "" {
+		log.Infof("Listing index documents of index %s...", config.BailianKnowledgeIndexId)
+		documents, err := client.ListAllIndexDocumentsWithContext(ctx)
+		if err != nil {
+			return utils.Errorf("Failed to list index documents: %v", err)
+		}
+
+		documentStatus := make(map[string]string, len(documents))
+		for _, doc := range documents {
+			documentStatus[doc.DocumentId] = doc.Status
+		}
+
+		for _, entry := range state.EntriesUnder(".") {
+			status := documentStatus[entry.FileId]
+			state.Update(entry.LocalPath, func(e *syncstate.Entry) {
+				e.IndexStatus = status
+			})
+		}
+	}
+
+	state.MarkComplete()
+	return nil
+}
+
+// reconcileRemoteFiles 用完整的远程文件列表校正同步状态
+// 远程已不存在的记录会被删除，文件 ID 发生变化的记录会丢弃本地指纹
+func reconcileRemoteFiles(state *syncstate.Manifest, files []*backend.FileInfo) {
+	remoteByPath := make(map[string]*backend.FileInfo, len(files))
+	for _, file := range files {
+		remoteByPath[syncstate.NormalizePath(file.FileName)] = file
+	}
+
+	for _, entry := range state.EntriesUnder(".") {
+		if _, ok := remoteByPath[entry.LocalPath]; !ok {
+			log.Infof("Remote file %s no longer exists, removing it from sync state", entry.LocalPath)
+			state.Remove(entry.LocalPath)
+		}
+	}
+
+	for path, file := range remoteByPath {
+		state.Update(path, func(e *syncstate.Entry) {
+			if e.FileId != file.FileId {
+				// 远程文件被替换过，本地指纹不再可信
+				*e = syncstate.Entry{LocalPath: e.LocalPath}
+			}
+			e.FileId = file.FileId
+			e.FileStatus = file.Status
+			e.RemoteCreateTime = file.CreateTime
+		})
+	}
+}
+
+// entryToFileInfo 将同步状态记录转换为远程文件信息
+func entryToFileInfo(entry *syncstate.Entry) *backend.FileInfo {
+	return &backend.FileInfo{
+		FileId:     entry.FileId,
+		FileName:   entry.LocalPath,
+		Status:     entry.FileStatus,
+		CreateTime: entry.RemoteCreateTime,
+	}
+}
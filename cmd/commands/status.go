@@ -2,15 +2,12 @@ package commands
 
 import (
 	"fmt"
-	"os"
-	"os/signal"
 	"strings"
-	"syscall"
 	"time"
 
 	"github.com/urfave/cli"
 
-	"github.com/VillanCh/ragsync/common/aliyun"
+	"github.com/VillanCh/ragsync/common/backend"
 
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
@@ -44,14 +41,17 @@ func executeStatus(c *cli.Context) error {
 		return utils.Errorf("Please specify the file name using --name parameter")
 	}
 
-	client, err := aliyun.NewBailianClientFromConfig(config)
+	client, err := NewBackend(config)
 	if err != nil {
 		return err
 	}
 
+	ctx, cancel := commandContext(c)
+	defer cancel()
+
 	// 首先列出所有文件
 	log.Infof("Searching for file: %s", fileName)
-	files, err := client.ListAllFiles(fileName)
+	files, err := client.ListAllFilesWithContext(ctx, fileName)
 	if err != nil {
 		return utils.Errorf("Failed to list files: %v", err)
 	}
@@ -62,7 +62,7 @@ func executeStatus(c *cli.Context) error {
 	}
 
 	// 如果找到多个文件，让用户选择
-	var targetFile *aliyun.FileInfo
+	var targetFile *backend.FileInfo
 	if len(files) > 1 {
 		fmt.Println("Multiple files found with this name:")
 		fmt.Printf("\n%-40s %-50s %-15s\n", "File ID", "File Name", "Status")
@@ -92,33 +92,25 @@ func executeStatus(c *cli.Context) error {
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
 
-	// 设置信号处理，监听Ctrl+C
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
 	// 显示初始状态
 	fmt.Printf("\nFile ID: %s\n", targetFile.FileId)
 	fmt.Printf("Name: %s\n", targetFile.FileName)
 	fmt.Printf("Status: %s\n", targetFile.Status)
 	fmt.Printf("Category ID: %s\n", targetFile.CategoryId)
 
-	// 使用变量跟踪是否应该继续循环
-	keepRunning := true
-
-	// 设置goroutine监听信号
-	go func() {
-		<-sigChan
-		keepRunning = false
-		fmt.Println("\nMonitoring stopped")
-	}()
-
-	// 循环并每2秒更新一次状态
-	for keepRunning {
+	// 循环并每2秒更新一次状态，Ctrl+C 或 --timeout 到期时停止
+	for {
 		select {
+		case <-ctx.Done():
+			fmt.Println("\nMonitoring stopped")
+			return nil
 		case <-ticker.C:
-			fileInfo, err := client.DescribeFile(targetFile.FileId)
+			fileInfo, err := client.DescribeFileWithContext(ctx, targetFile.FileId)
 			if err != nil {
-				log.Errorf("Failed to update status: %v", err)
+				// 被取消的请求在下一轮循环中退出
+				if ctx.Err() == nil {
+					log.Errorf("Failed to update status: %v", err)
+				}
 				continue
 			}
 
@@ -137,10 +129,8 @@ func executeStatus(c *cli.Context) error {
 			// 如果文件处理完成或失败，退出循环
 			if fileInfo.Status == "COMPLETED" || fileInfo.Status == "FAILED" {
 				log.Infof("File processing %s", fileInfo.Status)
-				keepRunning = false
+				return nil
 			}
 		}
 	}
-
-	return nil
 }
@@ -1,16 +1,18 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/urfave/cli"
 
-	"github.com/VillanCh/ragsync/common/aliyun"
+	"github.com/VillanCh/ragsync/common/backend"
 	"github.com/VillanCh/ragsync/common/spec"
+	"github.com/VillanCh/ragsync/common/syncstate"
 
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
@@ -38,9 +40,13 @@ func SyncCommand() cli.Command {
 			},
 			cli.StringFlag{
 				Name:  "exclude",
-				Usage: "Keywords to exclude files (comma separated, e.g. 'draft,temp,private')",
+				Usage: "Keywords or gitignore-style patterns to exclude (comma separated, e.g. 'draft,temp,private,archive/,*.bak'); keywords match whole words in file and directory names",
 				Value: "temp,private,unverified,unverified_,ignored",
 			},
+			cli.BoolFlag{
+				Name:  "respect-gitignore",
+				Usage: "Also skip files ignored by the repository's .gitignore files",
+			},
 			cli.BoolFlag{
 				Name:  "force,f",
 				Usage: "Force upload even if file exists",
@@ -57,13 +63,126 @@ func SyncCommand() cli.Command {
 				Name:  "skip-index-delete,s",
 				Usage: "When replacing files, skip removing them from the knowledge index first (preserves index entries)",
 			},
+			cli.IntFlag{
+				Name:  "concurrency",
+				Usage: "Number of files processed in parallel when syncing a directory",
+				Value: 1,
+			},
+			cli.IntFlag{
+				Name:  "lease-concurrency",
+				Usage: "Max concurrent upload lease requests (defaults to --concurrency)",
+			},
+			cli.IntFlag{
+				Name:  "upload-concurrency",
+				Usage: "Max concurrent file content uploads (defaults to --concurrency)",
+			},
+			cli.IntFlag{
+				Name:  "add-concurrency",
+				Usage: "Max concurrent AddFile requests (defaults to --concurrency)",
+			},
+			cli.IntFlag{
+				Name:  "index-batch-size",
+				Usage: fmt.Sprintf("Number of documents submitted per index job at the end of the run (max %d)", maxIndexBatchSize),
+				Value: defaultIndexBatchSize,
+			},
+			cli.StringFlag{
+				Name:  "compare",
+				Usage: "How to detect changed files: 'hash' (content digest), 'mtime' (local mtime vs remote create time) or 'both' (content changed and local is newer)",
+				Value: compareHash,
+			},
+			cli.BoolFlag{
+				Name:  "wait-parse",
+				Usage: "After adding each file, wait until it is parsed and fail the file if parsing fails",
+			},
+			cli.DurationFlag{
+				Name:  "parse-timeout",
+				Usage: "With --wait-parse, how long to wait for a file to be parsed",
+				Value: defaultParseTimeout,
+			},
+			cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Print the reconciliation plan without uploading, deleting or indexing anything",
+			},
+			cli.StringFlag{
+				Name:  "plan-format",
+				Usage: "Output format of the --dry-run plan: 'table' or 'json'",
+				Value: "table",
+			},
+			cli.StringFlag{
+				Name:  "since",
+				Usage: "Only sync files changed, added, deleted or renamed in git between this ref and HEAD",
+			},
+			cli.BoolFlag{
+				Name:  "git",
+				Usage: "Only sync files changed in git since the last successfully synced commit of each path (full sync the first time)",
+			},
+			cli.BoolFlag{
+				Name:  "watch,w",
+				Usage: "After the initial sync, keep watching --file, --dir or include_paths and sync changes as they happen",
+			},
+			cli.DurationFlag{
+				Name:  "debounce",
+				Usage: "In --watch mode, wait this long after the last change before syncing, so bursts of edits are synced once",
+				Value: defaultWatchDebounce,
+			},
+			cli.BoolFlag{
+				Name:  "prune",
+				Usage: "Delete remote files under --dir that no longer exist locally",
+			},
+			cli.IntFlag{
+				Name:  "max-delete",
+				Usage: "Abort the sync if --prune would delete more than this many remote files (0 disables the check)",
+				Value: defaultMaxDelete,
+			},
+			cli.Float64Flag{
+				Name:  "max-delete-percent",
+				Usage: "Abort the sync if --prune would delete more than this percentage of the remote files under the synced directories (0 disables the check)",
+				Value: defaultMaxDeletePercent,
+			},
+			cli.BoolFlag{
+				Name:  "yes,y",
+				Usage: "Delete remote files selected by --prune without asking for confirmation",
+			},
 		},
 		Action: executeSync,
 	}
 }
 
+// syncOptions sync 命令的处理选项
+type syncOptions struct {
+	ForceUpload        bool
+	AddToIndex         bool
+	SkipIndexDelete    bool
+	OverrideNewestData bool
+	CompareMode        string
+	Concurrency        int
+	Stages             *stageLimits
+	IndexBatch         *indexBatch
+	Prune              bool
+	PruneLimits        pruneLimits
+	AssumeYes          bool
+	Git                *gitSync
+	MaxFileSize        spec.ByteSize
+	LargeFilePolicy    string
+	WaitParse          bool
+	ParseTimeout       time.Duration
+}
+
+// syncRoot 一个同步根路径（--file、--dir 或 include_paths 中的一项）
+// 目录按过滤器筛选其中的文件，单个文件只匹配自身
+type syncRoot struct {
+	Path   string
+	IsDir  bool
+	Filter *pathFilter
+}
+
 // executeSync 上传文件的执行逻辑
-func executeSync(c *cli.Context) error {
+func executeSync(c *cli.Context) (err error) {
+	// JSON 格式的计划输出到标准输出，日志改为输出到标准错误，便于被其他程序解析
+	if c.Bool("dry-run") && c.String("plan-format") == "json" {
+		log.SetOutput(os.Stderr)
+	}
+
 	log.Infof("Starting sync operation...")
 
 	// 从配置文件加载配置
@@ -78,23 +197,25 @@ func executeSync(c *cli.Context) error {
 	dirPath := c.String("dir")
 
 	// 打印命令参数
-	log.Infof("Command parameters: file=%s, dir=%s, force=%v, override-newest-data=%v, no-index=%v, skip-index-delete=%v",
+	log.Infof("Command parameters: file=%s, dir=%s, force=%v, override-newest-data=%v, no-index=%v, skip-index-delete=%v, compare=%s, dry-run=%v",
 		filePath,
 		dirPath,
 		c.Bool("force"),
 		c.Bool("override-newest-data"),
 		c.Bool("no-index"),
-		c.Bool("skip-index-delete"))
+		c.Bool("skip-index-delete"),
+		c.String("compare"),
+		c.Bool("dry-run"))
+
+	compareMode, err := parseCompareMode(c.String("compare"))
+	if err != nil {
+		return err
+	}
 
-	// 解析排除关键字
-	excludeKeywords := []string{}
+	// 解析排除规则：关键字、gitignore 风格的通配规则以及配置文件中的 include/exclude
+	var excludes []string
 	if c.String("exclude") != "" {
-		excludeKeywords = strings.Split(c.String("exclude"), ",")
-		// 去除可能存在的空格
-		for i := range excludeKeywords {
-			excludeKeywords[i] = strings.TrimSpace(excludeKeywords[i])
-		}
-		log.Infof("Exclusion keywords: %v", excludeKeywords)
+		excludes = strings.Split(c.String("exclude"), ",")
 	}
 
 	forceUpload := c.Bool("force")
@@ -118,13 +239,104 @@ func executeSync(c *cli.Context) error {
 		return utils.Errorf("Cannot add to knowledge index: BailianKnowledgeIndexId is not configured in your config file")
 	}
 
-	log.Infof("Creating Bailian client with workspace ID: %s", config.BailianWorkspaceId)
-	client, err := aliyun.NewBailianClientFromConfig(config)
+	log.Infof("Creating %s backend with workspace ID: %s", backendName(config), config.BailianWorkspaceId)
+	client, err := NewBackend(config)
 	if err != nil {
-		log.Errorf("Failed to create Bailian client: %v", err)
+		log.Errorf("Failed to create backend: %v", err)
 		return err
 	}
-	log.Infof("Bailian client created successfully")
+	log.Infof("%s backend created successfully", client.Name())
+
+	// Ctrl+C 或 --timeout 到期时取消正在进行的同步，已完成的部分仍会写入同步状态
+	ctx, cancel := commandContext(c)
+	defer cancel()
+	// 在扫描和生成计划阶段被中断时，返回中断的原因而不是某个请求的错误
+	planned := false
+	defer func() {
+		if err != nil && !planned && ctx.Err() != nil {
+			log.Warnf("Sync stopped before any file was uploaded or deleted: %v", err)
+			err = contextError(ctx, c)
+		}
+	}()
+
+	// 加载本地同步状态，结束时写回
+	state, err := syncstate.Load(config.BailianWorkspaceId, config.BailianKnowledgeIndexId)
+	if err != nil {
+		log.Warnf("Failed to load sync state, starting with an empty manifest: %v", err)
+		state = syncstate.New(config.BailianWorkspaceId, config.BailianKnowledgeIndexId)
+	}
+	defer func() {
+		if err := state.Save(); err != nil {
+			log.Errorf("Failed to save sync state: %v", err)
+		} else {
+			log.Infof("Sync state saved to: %s", state.Path())
+		}
+	}()
+
+	opts := &syncOptions{
+		ForceUpload:        forceUpload,
+		AddToIndex:         addToIndex,
+		SkipIndexDelete:    skipIndexDelete,
+		OverrideNewestData: overrideNewestData,
+		CompareMode:        compareMode,
+		Concurrency:        c.Int("concurrency"),
+		Stages:             newStageLimits(c.Int("concurrency"), c.Int("lease-concurrency"), c.Int("upload-concurrency"), c.Int("add-concurrency")),
+	}
+	opts.Prune = c.Bool("prune")
+	opts.PruneLimits = pruneLimits{
+		MaxDelete:        c.Int("max-delete"),
+		MaxDeletePercent: c.Float64("max-delete-percent"),
+	}
+	opts.AssumeYes = c.Bool("yes")
+	opts.MaxFileSize = config.MaxFileSize
+	opts.LargeFilePolicy = config.LargeFilePolicy
+	opts.WaitParse = c.Bool("wait-parse")
+	opts.ParseTimeout = c.Duration("parse-timeout")
+	opts.Git = &gitSync{
+		Since:   c.String("since"),
+		Enabled: c.Bool("git"),
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	log.Infof("Concurrency: %d", opts.Concurrency)
+
+	dryRun := c.Bool("dry-run")
+	planFormat := c.String("plan-format")
+	if planFormat != "table" && planFormat != "json" {
+		return utils.Errorf("Invalid plan format %q, expected 'table' or 'json'", planFormat)
+	}
+
+	extensions := strings.Split(c.String("ext"), ",")
+	// 去除可能存在的空格
+	for i := range extensions {
+		extensions[i] = strings.TrimSpace(extensions[i])
+	}
+
+	filters := newFilterSettings(extensions, excludes, config, c.Bool("respect-gitignore"))
+	if len(filters.Keywords) > 0 {
+		log.Infof("Exclusion keywords: %v", filters.Keywords)
+	}
+	if len(filters.Excludes) > 0 {
+		log.Infof("Exclusion patterns: %v", filters.Excludes)
+	}
+	if len(filters.Includes) > 0 {
+		log.Infof("Inclusion patterns: %v", filters.Includes)
+	}
+
+	watch := c.Bool("watch")
+	if watch && dryRun {
+		return utils.Errorf("--watch cannot be used together with --dry-run")
+	}
+	// 监听模式在后台持续运行，不能停下来等待删除确认
+	if watch && opts.Prune && !opts.AssumeYes {
+		return utils.Errorf("--watch --prune requires --yes, deletions cannot be confirmed interactively while watching")
+	}
+
+	// 先生成完整的同步计划，dry-run 只输出计划，不修改工作空间
+	plan := &syncPlan{}
+	// 本次同步的根路径，--watch 模式下会继续监听
+	var roots []syncRoot
 
 	// 如果既没有指定文件也没有指定目录，使用配置文件中的 include_paths
 	if filePath == "" && dirPath == "" {
@@ -147,7 +359,7 @@ func executeSync(c *cli.Context) error {
 			return utils.Errorf("The following paths specified in include_paths do not exist: %v", invalidPaths)
 		}
 
-		// 处理所有有效的路径
+		// 为所有有效的路径生成计划
 		for _, path := range config.IncludePaths {
 			log.Infof("Processing include path: %s", path)
 			// 检查路径是文件还是目录
@@ -159,60 +371,134 @@ func executeSync(c *cli.Context) error {
 
 			if pathInfo.IsDir() {
 				// 如果是目录，使用目录处理逻辑
-				extensions := strings.Split(c.String("ext"), ",")
-				for i := range extensions {
-					extensions[i] = strings.TrimSpace(extensions[i])
+				filter, err := newPathFilter(path, true, filters)
+				if err != nil {
+					log.Errorf("Failed to load ignore rules for %s: %v", path, err)
+					continue
 				}
-				if err := processDirUpload(path, extensions, excludeKeywords, client, config, forceUpload, addToIndex, skipIndexDelete, overrideNewestData); err != nil {
+				root := syncRoot{Path: path, IsDir: true, Filter: filter}
+				dirPlan, err := planSyncRoot(ctx, root, client, state, opts)
+				if err != nil {
 					log.Errorf("Failed to process directory %s: %v", path, err)
 					continue
 				}
+				plan.Merge(dirPlan)
+				roots = append(roots, root)
 			} else {
 				// 如果是文件，使用文件处理逻辑
-				if containsExcludedKeywords(path, excludeKeywords) {
-					log.Infof("[File: %s] Skipped due to exclusion keywords", path)
+				filter, err := newPathFilter(path, false, filters)
+				if err != nil {
+					log.Errorf("Failed to load ignore rules for %s: %v", path, err)
+					continue
+				}
+				if !filter.Allow(path) {
+					log.Infof("[File: %s] Skipped due to exclusion rules", path)
 					continue
 				}
-				if err := processFileUpload(path, client, config, forceUpload, addToIndex, skipIndexDelete, overrideNewestData); err != nil {
+				root := syncRoot{Path: path, Filter: filter}
+				roots = append(roots, root)
+				filePlan, err := planSyncRoot(ctx, root, client, state, opts)
+				if err != nil {
 					log.Errorf("Failed to process file %s: %v", path, err)
 					continue
 				}
+				plan.Merge(filePlan)
 			}
 		}
-		return nil
-	}
-
-	// 文件和目录参数不能同时提供
-	if filePath != "" && dirPath != "" {
+	} else if filePath != "" && dirPath != "" {
+		// 文件和目录参数不能同时提供
 		log.Errorf("Both file and directory paths specified, only one is allowed")
 		return utils.Errorf("Cannot specify both --file and --dir at the same time")
+	} else if dirPath != "" {
+		// 如果指定了目录，则遍历目录并上传符合条件的文件
+		log.Infof("Processing directory upload with extensions: %v", extensions)
+		filter, err := newPathFilter(dirPath, true, filters)
+		if err != nil {
+			return err
+		}
+		root := syncRoot{Path: dirPath, IsDir: true, Filter: filter}
+		dirPlan, err := planSyncRoot(ctx, root, client, state, opts)
+		if err != nil {
+			return err
+		}
+		plan.Merge(dirPlan)
+		roots = append(roots, root)
+	} else {
+		// 处理单个文件上传
+		log.Infof("Processing single file upload: %s", filePath)
+		filter, err := newPathFilter(filePath, false, filters)
+		if err != nil {
+			return err
+		}
+		if !filter.Allow(filePath) {
+			log.Infof("[File: %s] Skipped due to exclusion rules", filePath)
+			return nil
+		}
+		root := syncRoot{Path: filePath, Filter: filter}
+		filePlan, err := planSyncRoot(ctx, root, client, state, opts)
+		if err != nil {
+			return err
+		}
+		plan.Merge(filePlan)
+		roots = append(roots, root)
 	}
 
-	// 如果指定了目录，则遍历目录并上传符合条件的文件
-	if dirPath != "" {
-		extensions := strings.Split(c.String("ext"), ",")
-		// 去除可能存在的空格
-		for i := range extensions {
-			extensions[i] = strings.TrimSpace(extensions[i])
+	detectRenames(plan, state)
+	planned = true
+
+	if dryRun {
+		log.Infof("Dry run: no files will be uploaded, deleted or indexed")
+		if err := checkPruneLimits(plan, opts.PruneLimits); err != nil {
+			log.Warnf("The real sync would abort: %v", err)
 		}
+		return plan.Print(planFormat)
+	}
+	log.Infof("Sync plan: %s", plan.Summary())
 
-		log.Infof("Processing directory upload with extensions: %v", extensions)
-		return processDirUpload(dirPath, extensions, excludeKeywords, client, config, forceUpload, addToIndex, skipIndexDelete, overrideNewestData)
+	if err := applyPruneGuardrails(plan, opts); err != nil {
+		return err
 	}
+	holdGitHeads(plan, opts.Git)
 
-	// 处理单个文件上传
-	log.Infof("Processing single file upload: %s", filePath)
-	if containsExcludedKeywords(filePath, excludeKeywords) {
-		log.Infof("[File: %s] Skipped due to exclusion keywords", filePath)
-		return nil
+	// 新加入的文件在同步结束时按批次提交索引任务（--watch 模式下每轮同步后提交）
+	if addToIndex {
+		opts.IndexBatch = &indexBatch{}
+	}
+	if !watch {
+		defer func() {
+			var batchErr error
+			if opts.IndexBatch != nil {
+				if batchErr = submitIndexBatches(ctx, client, state, opts.IndexBatch, c.Int("index-batch-size")); batchErr != nil && err == nil {
+					err = batchErr
+				}
+			}
+			// 记录已同步到的提交，有文件失败的根路径不记录，失败的文件会在下一次增量同步时重试
+			// 被中断或索引批次提交失败时无法确定每个根路径的结果，都不记录
+			if ctx.Err() == nil && batchErr == nil {
+				recordGitHeads(state, opts.Git)
+			}
+		}()
 	}
-	return processFileUpload(filePath, client, config, forceUpload, addToIndex, skipIndexDelete, overrideNewestData)
+
+	if watch {
+		// 初始同步与之后的每一轮一样，被中断时在宽限时间内完成
+		roundCtx, stop := drainContext(ctx)
+		if err := executeSyncPlan(roundCtx, plan, client, config, state, opts); err != nil {
+			log.Errorf("Initial sync finished with errors: %v", err)
+		} else {
+			recordGitHeads(state, opts.Git)
+		}
+		stop()
+		return watchAndSync(ctx, roots, client, config, state, opts, c.Int("index-batch-size"), c.Duration("debounce"))
+	}
+
+	return executeSyncPlan(ctx, plan, client, config, state, opts)
 }
 
-// processDirUpload 处理目录递归上传
-func processDirUpload(dirPath string, extensions []string, excludeKeywords []string, client *aliyun.BailianClient, config *spec.Config, forceUpload, addToIndex, skipIndexDelete bool, overrideNewestData bool) error {
+// planDirUpload 扫描目录并与远程文件对账，生成目录同步计划
+func planDirUpload(ctx context.Context, dirPath string, filter *pathFilter, client backend.Backend, state *syncstate.Manifest, opts *syncOptions) (*syncPlan, error) {
 	if strings.Trim(dirPath, "./") == "" {
-		return utils.Errorf("Directory path cannot be empty")
+		return nil, utils.Errorf("Directory path cannot be empty")
 	}
 
 	log.Infof("[Dir: %s] Starting directory processing", dirPath)
@@ -221,201 +507,84 @@ func processDirUpload(dirPath string, extensions []string, excludeKeywords []str
 	dirInfo, err := os.Stat(dirPath)
 	if err != nil {
 		log.Errorf("[Dir: %s] Failed to access directory: %v", dirPath, err)
-		return utils.Errorf("Failed to access directory: %v", err)
+		return nil, utils.Errorf("Failed to access directory: %v", err)
 	}
 
 	if !dirInfo.IsDir() {
 		log.Errorf("[Dir: %s] The specified path is not a directory", dirPath)
-		return utils.Errorf("The specified path is not a directory: %s", dirPath)
+		return nil, utils.Errorf("The specified path is not a directory: %s", dirPath)
 	}
 
-	// 获取目录的绝对路径
-	absDirPath, err := filepath.Abs(dirPath)
-	if err != nil {
-		log.Errorf("[Dir: %s] Failed to get absolute path: %v", dirPath, err)
-		return err
-	}
-
-	// 获取本地文件列表
+	// 获取本地文件列表（按扩展名、--exclude、.ragsyncignore 等规则过滤）
 	localFiles := make(map[string]bool)
-	err = filepath.Walk(absDirPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			// 检查文件扩展名是否符合要求
-			ext := strings.ToLower(filepath.Ext(path))
-			if isExtensionAllowed(ext, extensions) {
-				// 检查是否包含排除关键字
-				if !containsExcludedKeywords(path, excludeKeywords) {
-					// 使用相对路径作为键
-					relPath, err := filepath.Rel(absDirPath, path)
-					if err != nil {
-						log.Warnf("[Dir: %s] Failed to get relative path for %s: %v", dirPath, path, err)
-						return nil
-					}
-					key := filepath.Join(dirPath, relPath)
-					log.Infof("[Dir: %s] Found file: %s", dirPath, key)
-					localFiles[key] = true
-				}
-			}
-		}
+	err = filter.walk(func(path string) error {
+		key := syncstate.NormalizePath(path)
+		log.Infof("[Dir: %s] Found file: %s", dirPath, key)
+		localFiles[key] = true
 		return nil
 	})
 	if err != nil {
 		log.Errorf("[Dir: %s] Failed to scan local directory: %v", dirPath, err)
-		return err
+		return nil, err
 	}
 
-	// 获取远程文件列表
-	remoteFileRaw, err := client.ListAllFiles("")
-	if err != nil {
-		log.Errorf("[Dir: %s] Failed to list remote files: %v", dirPath, err)
-		return err
+	// 获取远程文件列表：同步状态完整时直接使用本地清单，否则拉取一次远程列表并写入清单
+	if err := ensureRemoteState(ctx, dirPath, client, state); err != nil {
+		return nil, err
 	}
 
-	remoteFiles := make([]*aliyun.FileInfo, 0, len(remoteFileRaw))
-	for _, fileDesc := range remoteFileRaw {
-		dirPathWithoutDot := dirPath
-		for strings.HasPrefix(dirPathWithoutDot, "./") {
-			dirPathWithoutDot = strings.TrimPrefix(dirPathWithoutDot, "./")
-		}
-		fileWithoutDot := fileDesc.FileName
-		for strings.HasPrefix(fileWithoutDot, "./") {
-			fileWithoutDot = strings.TrimPrefix(fileWithoutDot, "./")
-		}
-		hasPrefix := strings.HasPrefix(fileWithoutDot, dirPathWithoutDot)
-		if hasPrefix {
-			log.Infof("[Dir: %s] Found remote file: %s", dirPath, fileDesc.FileName)
-			remoteFiles = append(remoteFiles, fileDesc)
-		}
-	}
-
-	// 存储上传成功和失败的文件计数
-	successCount := 0
-	failedCount := 0
-	skippedCount := 0
-	deletedCount := 0
-
-	// 找出需要删除的远程文件
-	var filesToDelete = make(map[string]string)
-	var skippedFiles = make(map[string]bool)
-	var uploadFiles []string
-	for _, remoteFile := range remoteFiles {
-		// 检查远程文件是否在本地文件列表中
-		if have, ok := localFiles[remoteFile.FileName]; !ok || !have {
-			log.Infof("[Dir: %s] Remote file %s not found locally, will be deleted", dirPath, remoteFile.FileName)
-			filesToDelete[remoteFile.FileName] = remoteFile.FileId
-			deletedCount++
+	remoteEntries := make([]*syncstate.Entry, 0)
+	for _, entry := range state.EntriesUnder(dirPath) {
+		if entry.FileId == "" {
 			continue
-		} else if remoteFile.CreateTime != "" {
-			// 解析远程文件创建时间
-			remoteCreateTime, parseErr := time.Parse("2006-01-02 15:04:05", remoteFile.CreateTime)
-			if parseErr != nil {
-				log.Warnf("[Dir: %s] Failed to parse remote file time for %s: %v", dirPath, remoteFile.FileName, parseErr)
-			} else {
-				// 获取本地文件信息
-				localFilePath := remoteFile.FileName
-				localFileInfo, statErr := os.Stat(localFilePath)
-				if statErr != nil {
-					log.Warnf("[Dir: %s] Failed to get local file info for %s: %v", dirPath, localFilePath, statErr)
-				} else {
-					// 比较本地文件修改时间和远程文件创建时间
-					localModTime := localFileInfo.ModTime()
-					if !localModTime.After(remoteCreateTime) && !overrideNewestData {
-						log.Infof("[Dir: %s] Remote file %s is newer than local file, skipping", dirPath, remoteFile.FileName)
-						skippedFiles[remoteFile.FileName] = true
-						skippedCount++
-						continue
-					}
-				}
-			}
 		}
+		log.Infof("[Dir: %s] Found remote file: %s", dirPath, entry.LocalPath)
+		remoteEntries = append(remoteEntries, entry)
 	}
 
-	log.Infof("[Dir: %s] Found %d local files", dirPath, len(localFiles))
+	plan := &syncPlan{RemoteFiles: len(remoteEntries)}
+
+	// 本地文件：逐个判断需要执行的动作
+	localPaths := make([]string, 0, len(localFiles))
 	for localFilename := range localFiles {
-		if _, ok := skippedFiles[localFilename]; ok {
-			log.Infof("[Dir: %s] Skipping file because it was skipped earlier: %s", dirPath, localFilename)
-			continue
-		}
-		if _, ok := filesToDelete[localFilename]; ok {
-			log.Infof("[Dir: %s] Skipping file because it was marked for deletion: %s", dirPath, localFilename)
-			continue
-		}
-		log.Infof("[Dir: %s] Uploading file: %s", dirPath, localFilename)
-		uploadFiles = append(uploadFiles, localFilename)
+		localPaths = append(localPaths, localFilename)
 	}
+	sort.Strings(localPaths)
 
-	// 删除不在本地的远程文件
-	if len(filesToDelete) > 0 {
-		log.Infof("[Dir: %s] Deleting %d remote files that don't exist locally", dirPath, len(filesToDelete))
-		for _, fileId := range filesToDelete {
-			if err := client.DeleteFileEx(fileId, false); err != nil {
-				log.Errorf("[Dir: %s] Failed to delete remote file %s: %v", dirPath, fileId, err)
-				continue
-			}
-			log.Infof("[Dir: %s] Successfully deleted remote file %s", dirPath, fileId)
+	log.Infof("[Dir: %s] Found %d local files", dirPath, len(localPaths))
+	log.Infof("[Dir: %s] File extensions to process: %s", dirPath, strings.Join(filter.settings.Extensions, ", "))
+	for _, localFilename := range localPaths {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
 		}
-	}
-
-	log.Infof("[Dir: %s] Scanning directory", dirPath)
-	log.Infof("[Dir: %s] File extensions to process: %s", dirPath, strings.Join(extensions, ", "))
-
-	for _, path := range uploadFiles {
-		info, err := os.Stat(path)
+		item, err := planFileUpload(ctx, localFilename, client, state, opts)
 		if err != nil {
-			log.Warnf("[Dir: %s] Error accessing path %s: %v", dirPath, path, err)
-			failedCount++
-			continue
-		}
-
-		// 跳过目录
-		if info.IsDir() {
-			log.Infof("[Dir: %s] Skipping directory: %s", dirPath, path)
-			skippedCount++
-			continue
-		}
-
-		// 检查文件扩展名是否符合要求
-		ext := strings.ToLower(filepath.Ext(path))
-		if !isExtensionAllowed(ext, extensions) {
-			log.Infof("[Dir: %s] Skipping file with unsupported extension: %s (ext: %s)", dirPath, path, ext)
-			skippedCount++
+			log.Warnf("[Dir: %s] Failed to plan file %s: %v", dirPath, localFilename, err)
+			opts.Git.markFailed(dirPath)
 			continue
 		}
+		item.Root = dirPath
+		plan.Add(item)
+	}
 
-		// 检查是否包含排除关键字
-		if containsExcludedKeywords(path, excludeKeywords) {
-			log.Infof("[Dir: %s] Skipping file containing excluded keywords: %s", dirPath, path)
-			skippedCount++
+	// 远程文件在本地已不存在：只有指定 --prune 时才删除，否则只用于重命名检测
+	for _, entry := range remoteEntries {
+		if localFiles[entry.LocalPath] {
 			continue
 		}
-
-		log.Infof("[Dir: %s] Processing file (%d processed so far): %s", dirPath, successCount+failedCount, path)
-
-		// 使用与单文件上传相同的逻辑处理
-		err = processFileUpload(path, client, config, forceUpload, addToIndex, skipIndexDelete, overrideNewestData)
-		if err != nil {
-			log.Errorf("[Dir: %s] Failed to upload file %s: %v", dirPath, path, err)
-			failedCount++
-		} else {
-			log.Infof("[Dir: %s] Successfully processed file: %s", dirPath, path)
-			successCount++
-		}
-		// 显示进度报告
-		if (successCount+failedCount)%5 == 0 {
-			log.Infof("[Dir: %s] Progress: %d files processed (%d success, %d failed, %d skipped)",
-				dirPath, successCount+failedCount+skippedCount, successCount, failedCount, skippedCount)
+		if opts.Prune {
+			log.Infof("[Dir: %s] Remote file %s not found locally, will be deleted", dirPath, entry.LocalPath)
 		}
+		plan.AddMissing(&planItem{
+			Path:   entry.LocalPath,
+			Root:   dirPath,
+			FileId: entry.FileId,
+			Reason: "not found locally",
+		}, opts.Prune)
 	}
 
-	// 打印处理结果摘要
-	log.Infof("[Dir: %s] Directory processing completed", dirPath)
-	log.Infof("[Dir: %s] Results: %d files processed, %d uploaded successfully, %d failed, %d skipped (wrong extension), %d remote files deleted",
-		dirPath, successCount+failedCount+skippedCount, successCount, failedCount, skippedCount, len(filesToDelete))
-
-	return nil
+	log.Infof("[Dir: %s] Plan: %s", dirPath, plan.Summary())
+	return plan, nil
 }
 
 // isExtensionAllowed 检查文件扩展名是否在允许的列表中
@@ -428,256 +597,530 @@ func isExtensionAllowed(ext string, allowedExtensions []string) bool {
 	return false
 }
 
-// processFileUpload 处理单个文件上传
-func processFileUpload(filePath string, client *aliyun.BailianClient, config *spec.Config, forceUpload, addToIndex, skipIndexDelete bool, overrideNewestData bool) error {
-	log.Infof("[File: %s] Starting file upload process", filePath)
+// remoteStateMaxAge 清单与远程对账后可以代替远程文件列表的时长，超过后重新对账
+// 在控制台中删除的文件最多在这段时间之后被重新上传
+const remoteStateMaxAge = time.Hour
+
+// ensureRemoteState 同步状态尚未与远程对账或对账已过期时，拉取一次完整的远程文件列表写入清单
+func ensureRemoteState(ctx context.Context, dirPath string, client backend.Backend, state *syncstate.Manifest) error {
+	if state.ReconciledWithin(remoteStateMaxAge) {
+		return nil
+	}
+	if state.IsComplete() {
+		log.Infof("[Dir: %s] Sync state was reconciled more than %v ago, listing remote files again", dirPath, remoteStateMaxAge)
+	} else {
+		log.Infof("[Dir: %s] Sync state is not reconciled yet, listing remote files once", dirPath)
+	}
+	remoteFileRaw, err := client.ListAllFilesWithContext(ctx, "")
+	if err != nil {
+		log.Errorf("[Dir: %s] Failed to list remote files: %v", dirPath, err)
+		logAuthFailure(err)
+		return err
+	}
+	reconcileRemoteFiles(state, remoteFileRaw)
+	state.MarkComplete()
+	return nil
+}
 
+// planFileUpload 判断单个文件需要执行的同步动作
+func planFileUpload(ctx context.Context, filePath string, client backend.Backend, state *syncstate.Manifest, opts *syncOptions) (*planItem, error) {
 	// 获取本地文件信息
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
 		log.Errorf("[File: %s] Failed to get file information: %v", filePath, err)
-		return utils.Errorf("Failed to get file information: %v", err)
+		return nil, utils.Errorf("Failed to get file information: %v", err)
+	}
+	if fileInfo.IsDir() {
+		return nil, utils.Errorf("The specified path is a directory: %s", filePath)
 	}
 
 	log.Infof("[File: %s] File size: %d bytes, Last modified: %s",
 		filePath, fileInfo.Size(), fileInfo.ModTime().Format(time.RFC3339))
 
-	// 获取文件修改时间
-	fileModTime := fileInfo.ModTime()
+	item := &planItem{
+		Path:    filePath,
+		Root:    filePath,
+		Size:    fileInfo.Size(),
+		ModTime: fileInfo.ModTime(),
+	}
 
-	log.Infof("[File: %s] Reading file content", filePath)
-	fileContent, err := os.ReadFile(filePath)
-	if err != nil {
-		log.Errorf("[File: %s] Failed to read file content: %v", filePath, err)
-		return err
+	// 超过 max_file_size 的文件在计算摘要和申请租约之前处理
+	if opts.MaxFileSize > 0 && fileInfo.Size() > int64(opts.MaxFileSize) {
+		size := spec.ByteSize(fileInfo.Size())
+		if opts.LargeFilePolicy == spec.LargeFilePolicyWarn {
+			log.Warnf("[File: %s] File size %v exceeds max_file_size %v, uploading anyway (large_file_policy: warn)", filePath, size, opts.MaxFileSize)
+		} else {
+			log.Warnf("[File: %s] File size %v exceeds max_file_size %v, skipping", filePath, size, opts.MaxFileSize)
+			item.Action = actionSkipTooLarge
+			item.Reason = fmt.Sprintf("file size %v exceeds max_file_size %v", size, opts.MaxFileSize)
+			return item, nil
+		}
 	}
-	log.Infof("[File: %s] File content read successfully, size: %d bytes", filePath, len(fileContent))
+	fullOverride := opts.ForceUpload && opts.OverrideNewestData
 
-	fileName := filePath
+	// 大小和修改时间都与上次同步一致时无需计算摘要，也无需访问远程
+	entry := state.Get(filePath)
+	if entry != nil && entry.FileId != "" && !state.ReconciledWithin(remoteStateMaxAge) {
+		entry = verifyRemoteEntry(ctx, client, state, entry)
+	}
+	if entry != nil && entry.FileId != "" && entry.MatchesStat(fileInfo) && !fullOverride {
+		item.FileId = entry.FileId
+		item.Digest = syncstate.Digest{MD5: entry.MD5, SHA256: entry.SHA256}
+		return planExistingFile(item, entry, actionSkipUnchanged, "size and modification time unchanged since last sync", opts), nil
+	}
 
-	// 是否需要上传新文件（默认为true）
-	needUpload := true
-	// 需要添加到索引的文件ID
-	var fileId string
+	digest, err := syncstate.FileDigest(filePath)
+	if err != nil {
+		log.Errorf("[File: %s] Failed to compute content digest: %v", filePath, err)
+		return nil, err
+	}
+	item.Digest = digest
+
+	if entry != nil && entry.FileId != "" && !fullOverride && isUnchangedSinceSync(opts.CompareMode, entry, fileInfo, digest) {
+		item.FileId = entry.FileId
+		return planExistingFile(item, entry, actionSkipUnchanged, "content unchanged since last sync", opts), nil
+	}
 
 	// 检查文件是否已存在（无论是否为强制模式）
-	log.Infof("[File: %s] Checking if file already exists on server", fileName)
+	log.Infof("[File: %s] Checking if file already exists on server", filePath)
+	existingFiles, err := lookupExistingFiles(ctx, client, state, filePath)
+	if err != nil {
+		log.Warnf("[File: %s] Failed to check existing files: %v", filePath, err)
+		log.Infof("[File: %s] Proceeding with upload anyway...", filePath)
+		item.Action = actionUploadNew
+		item.Reason = "failed to check existing remote files"
+		return item, nil
+	}
+	if len(existingFiles) == 0 {
+		item.Action = actionUploadNew
+		item.Reason = "no remote file with the same name"
+		return item, nil
+	}
 
-	// 列出所有匹配该文件名的文件
-	existingFiles, err := client.ListAllFiles(fileName)
+	log.Infof("[File: %s] Found %d existing files with similar name", filePath, len(existingFiles))
+	for _, file := range existingFiles {
+		log.Infof("[File: %s] Existing remote file: %s (ID: %s, status: %s, created: %s)",
+			filePath, file.FileName, file.FileId, file.Status, file.CreateTime)
+		item.RemoteFileIds = append(item.RemoteFileIds, file.FileId)
+	}
+	// 使用第一个同名文件作为比较对象
+	item.FileId = existingFiles[0].FileId
+
+	if fullOverride {
+		item.Action = actionReplace
+		item.Reason = "--force --override-newest-data specified"
+		return item, nil
+	}
+
+	// 按 --compare 指定的方式判断本地文件是否需要替换远程文件
+	action, reason := detectChange(opts.CompareMode, entry, digest, fileInfo.ModTime(), existingFiles[0])
+	log.Infof("[File: %s] Change detection (%s): %s, %s", filePath, opts.CompareMode, action, reason)
+	if action == actionReplace {
+		item.Action = actionReplace
+		item.Reason = reason
+		return item, nil
+	}
+	return planExistingFile(item, entry, action, reason, opts), nil
+}
+
+// verifyRemoteEntry 清单没有及时对账时确认记录的远程文件仍然存在，已被删除时移除记录并返回 nil
+func verifyRemoteEntry(ctx context.Context, client backend.Backend, state *syncstate.Manifest, entry *syncstate.Entry) *syncstate.Entry {
+	_, err := client.DescribeFileWithContext(ctx, entry.FileId)
+	if remoteFileGone(err) {
+		log.Infof("[File: %s] Remote file %s no longer exists, removing it from sync state", entry.LocalPath, entry.FileId)
+		state.Remove(entry.LocalPath)
+		return nil
+	}
 	if err != nil {
-		log.Warnf("[File: %s] Failed to check existing files: %v", fileName, err)
-		log.Info("[File: %s] Proceeding with upload anyway...", fileName)
-	} else if len(existingFiles) > 0 {
-		log.Infof("[File: %s] Found %d existing files with similar name", fileName, len(existingFiles))
-
-		// 显示找到的文件
-		fmt.Println("Found existing files with similar name:")
-		fmt.Printf("\n%-40s %-50s %-15s\n", "File ID", "File Name", "Status")
-		fmt.Println(strings.Repeat("-", 105))
-
-		for _, file := range existingFiles {
-			fmt.Printf("%-40s %-50s %-15s\n", file.FileId, file.FileName, file.Status)
-		}
-
-		// 显示本地文件的修改时间
-		fmt.Printf("\nLocal file last modified: %s\n", fileModTime.Format(time.RFC3339))
-
-		// 远程文件时间信息
-		var remoteCreateTime time.Time
-		var remoteFileTimeStr string
-		var parseTimeErr error
-
-		if len(existingFiles) > 0 {
-			// 使用第一个文件的创建时间
-			remoteFileTimeStr = existingFiles[0].CreateTime
-			// 解析远程文件创建时间，格式通常为: "2023-08-18 11:03:35"
-			remoteCreateTime, parseTimeErr = time.Parse("2006-01-02 15:04:05", remoteFileTimeStr)
-			if parseTimeErr != nil {
-				log.Warnf("Failed to parse remote file time: %v (value: %s)", parseTimeErr, remoteFileTimeStr)
-				// 继续使用默认逻辑，假设本地文件更新
-				remoteCreateTime = time.Time{}
+		log.Warnf("[File: %s] Failed to check remote file %s: %v", entry.LocalPath, entry.FileId, err)
+	}
+	return entry
+}
+
+// planExistingFile 远程文件无需替换时，根据索引状态决定跳过还是重新加入索引
+func planExistingFile(item *planItem, entry *syncstate.Entry, action planAction, reason string, opts *syncOptions) *planItem {
+	item.Action = action
+	item.Reason = reason
+	if opts.AddToIndex && (entry == nil || !entry.IsIndexed()) {
+		item.Action = actionReindex
+		item.Reason = reason + ", not known to be indexed"
+	}
+	return item
+}
+
+// executeSyncPlan 执行同步计划：先删除本地已不存在的远程文件，再并发处理上传和索引
+func executeSyncPlan(ctx context.Context, plan *syncPlan, client backend.Backend, config *spec.Config, state *syncstate.Manifest, opts *syncOptions) error {
+	// 存储上传成功和失败的文件计数
+	successCount := 0
+	failedCount := 0
+	skippedCount := 0
+	deletedCount := 0
+	renamedCount := 0
+	// 被 Ctrl+C 或 --timeout 中断的文件，以及还没有开始处理的文件
+	interruptedCount := 0
+	notStartedCount := 0
+
+	var deletions []*planItem
+	var work []*planItem
+	for _, item := range plan.Items {
+		switch item.Action {
+		case actionDeleteRemote:
+			deletions = append(deletions, item)
+		case actionSkipUnchanged, actionSkipRemoteNewer, actionSkipTooLarge:
+			if err := executePlanItem(ctx, item, client, config, state, opts); err != nil {
+				log.Warnf("[File: %s] %v", item.Path, err)
 			}
+			skippedCount++
+		default:
+			work = append(work, item)
 		}
+	}
 
-		// 输出远程文件时间信息
-		if remoteFileTimeStr != "" {
-			fmt.Printf("Remote file created: %s\n", remoteFileTimeStr)
-			if parseTimeErr == nil {
-				fmt.Printf("Remote file time (parsed): %s\n", remoteCreateTime.Format(time.RFC3339))
+	// 删除不在本地的远程文件
+	if len(deletions) > 0 {
+		log.Infof("Deleting %d remote files that don't exist locally", len(deletions))
+		for _, item := range deletions {
+			if ctx.Err() != nil {
+				notStartedCount++
+				continue
+			}
+			if err := executePlanItem(ctx, item, client, config, state, opts); err != nil {
+				log.Errorf("[Dir: %s] Failed to delete remote file %s (ID: %s): %v", item.Root, item.Path, item.FileId, err)
+				opts.Git.markFailed(item.Root)
+				continue
 			}
+			deletedCount++
+		}
+	}
+
+	// 使用有界工作池并发处理文件
+	results := runPlanPool(ctx, work, opts.Concurrency, func(item *planItem) error {
+		log.Infof("[File: %s] Processing (%s): %s", item.Path, item.Action, item.Reason)
+		return executePlanItem(ctx, item, client, config, state, opts)
+	}, func(res *fileResult) {
+		if res.Err != nil {
+			opts.Git.markFailed(res.Item.Root)
+		}
+		if res.Err != nil && ctx.Err() != nil {
+			log.Warnf("[File: %s] Interrupted: %v", res.Item.Path, res.Err)
+			interruptedCount++
+		} else if res.Err != nil {
+			log.Errorf("[File: %s] Failed to sync file: %v", res.Item.Path, res.Err)
+			failedCount++
 		} else {
-			fmt.Println("Remote file timestamp not available.")
+			log.Infof("[File: %s] Successfully processed file", res.Item.Path)
+			successCount++
+			if res.Item.Action == actionRename {
+				renamedCount++
+			}
 		}
+		// 显示进度报告
+		if (successCount+failedCount)%5 == 0 {
+			log.Infof("Progress: %d/%d files processed (%d success, %d failed, %d skipped)",
+				successCount+failedCount, len(work), successCount, failedCount, skippedCount)
+		}
+	})
 
-		// 比较本地文件修改时间和远程文件创建时间
-		// 只有当解析远程时间成功时才进行实际比较
-		isLocalNewer := true
-		if !remoteCreateTime.IsZero() {
-			// 时区处理：remoteCreateTime 是北京时间 (UTC+8)
-			// 如果需要调整时区，可以在这里进行
-			isLocalNewer = fileModTime.After(remoteCreateTime)
+	for _, res := range results {
+		if res.NotStarted {
+			notStartedCount++
+		}
+	}
 
-			if isLocalNewer {
-				log.Infof("Local file (%s) is newer than remote file (%s)",
-					fileModTime.Format(time.RFC3339),
-					remoteCreateTime.Format(time.RFC3339))
+	// 按计划顺序输出每个文件的处理结果
+	if len(results) > 1 {
+		log.Infof("Per-file results:")
+		for i, res := range results {
+			if res.NotStarted {
+				log.Infof("[%d/%d] SKIPPED %-18s %s: not started before cancellation", i+1, len(results), res.Item.Action, res.Item.Path)
+			} else if res.Err != nil {
+				log.Infof("[%d/%d] FAILED  %-18s %s: %v", i+1, len(results), res.Item.Action, res.Item.Path, res.Err)
 			} else {
-				log.Infof("Remote file (%s) is newer than local file (%s)",
-					remoteCreateTime.Format(time.RFC3339),
-					fileModTime.Format(time.RFC3339))
+				log.Infof("[%d/%d] OK      %-18s %s", i+1, len(results), res.Item.Action, res.Item.Path)
 			}
-		} else {
-			log.Infof("Cannot compare file times. Assuming local file is newer.")
 		}
+	}
 
-		// 如果设置了 --override-newest-data，则忽略时间比较结果
-		if overrideNewestData && forceUpload {
-			log.Infof("Overriding time comparison due to --override-newest-data flag")
-			isLocalNewer = true
-		}
+	// 打印处理结果摘要，被中断时输出已完成的部分
+	if ctx.Err() != nil {
+		log.Warnf("Sync interrupted: %d files processed, %d succeeded, %d failed, %d interrupted, %d not started, %d skipped, %d renamed, %d/%d remote files deleted",
+			successCount+failedCount+interruptedCount+skippedCount, successCount, failedCount, interruptedCount, notStartedCount, skippedCount, renamedCount, deletedCount, len(deletions))
+		return utils.Errorf("Sync interrupted (%v): %d files were not synced, run sync again to continue", ctx.Err(), failedCount+interruptedCount+notStartedCount)
+	}
+	log.Infof("Sync completed: %d files processed, %d succeeded, %d failed, %d skipped, %d renamed, %d/%d remote files deleted",
+		successCount+failedCount+skippedCount, successCount, failedCount, skippedCount, renamedCount, deletedCount, len(deletions))
 
-		// 如果本地文件不比远程文件新，除非设置了强制覆盖+忽略时间，否则不上传
-		if !isLocalNewer && !(forceUpload && overrideNewestData) {
-			log.Infof("Local file appears to not be newer than remote file. Skipping upload.")
-			needUpload = false
+	if failedCount == 1 && len(results) == 1 {
+		return results[0].Err
+	}
+	if failedCount > 0 {
+		return utils.Errorf("%d files failed to sync", failedCount)
+	}
+	return nil
+}
 
-			// 使用最新的文件ID（如果有多个同名文件，使用第一个）
-			if len(existingFiles) > 0 {
-				fileId = existingFiles[0].FileId
-				log.Infof("Using existing file ID: %s", fileId)
-			} else {
-				return utils.Errorf("No valid file ID found among existing files")
-			}
-		} else if forceUpload {
-			// 本地文件较新或已设置强制覆盖+忽略时间，并且设置了强制上传
-			if skipIndexDelete {
-				log.Info("Force mode enabled. Deleting existing files (skipping index deletion)...")
-			} else {
-				log.Info("Force mode enabled. Deleting existing files and their index entries...")
-			}
+// executePlanItem 执行单个计划项
+func executePlanItem(ctx context.Context, item *planItem, client backend.Backend, config *spec.Config, state *syncstate.Manifest, opts *syncOptions) error {
+	filePath := item.Path
 
-			for _, file := range existingFiles {
-				log.Infof("Deleting file: %s (ID: %s)", file.FileName, file.FileId)
-				// 使用DeleteFileEx方法，可以控制是否跳过索引删除
-				err := client.DeleteFileEx(file.FileId, skipIndexDelete)
-				if err != nil {
-					log.Warnf("Failed to delete file %s: %v", file.FileId, err)
-				} else {
-					log.Infof("File deleted successfully: %s", file.FileId)
-				}
+	switch item.Action {
+	case actionSkipUnchanged, actionSkipRemoteNewer, actionSkipTooLarge:
+		log.Infof("[File: %s] Skipping (%s): %s", filePath, item.Action, item.Reason)
+		refreshFingerprint(item, state)
+		return nil
+
+	case actionDeleteRemote:
+		if err := client.DeleteFileExWithContext(ctx, item.FileId, false); err != nil {
+			if !remoteFileGone(err) {
+				return err
 			}
+			log.Infof("[Dir: %s] Remote file %s (ID: %s) no longer exists", item.Root, filePath, item.FileId)
 		} else {
-			// 本地文件较新，但未设置强制模式，询问用户是否要覆盖
-			deleteMsg := "File with the same name already exists. Local file appears to be newer."
-			if skipIndexDelete {
+			log.Infof("[Dir: %s] Successfully deleted remote file %s (ID: %s)", item.Root, filePath, item.FileId)
+		}
+		state.Remove(filePath)
+		return nil
+
+	case actionReindex:
+		log.Infof("[File: %s] Using existing file (ID: %s), skipping upload: %s", filePath, item.FileId, item.Reason)
+		refreshFingerprint(item, state)
+		return indexSyncedFile(ctx, filePath, item.FileId, false, client, config, state, opts)
+
+	case actionReplace:
+		if !opts.ForceUpload {
+			// 本地文件已变化，但未设置强制模式，询问用户是否要覆盖
+			deleteMsg := fmt.Sprintf("[File: %s] File with the same name already exists and the local file has changed (%s).", filePath, item.Reason)
+			if opts.SkipIndexDelete {
 				deleteMsg += " Do you want to delete it and upload a new version? (Index entries will be preserved)"
 			} else {
 				deleteMsg += " Do you want to delete it and upload a new version? (This will also update index entries)"
 			}
 
 			if !askForConfirmation(deleteMsg) {
-				log.Info("Upload cancelled. Using existing file.")
-				needUpload = false
-
-				// 使用最新的文件ID（如果有多个同名文件，使用第一个）
-				if len(existingFiles) > 0 {
-					fileId = existingFiles[0].FileId
-					log.Infof("Using existing file ID: %s", fileId)
-				} else {
-					return utils.Errorf("No valid file ID found among existing files")
+				log.Infof("[File: %s] Upload cancelled. Using existing file ID: %s", filePath, item.FileId)
+				return indexSyncedFile(ctx, filePath, item.FileId, false, client, config, state, opts)
+			}
+		}
+
+		if opts.SkipIndexDelete {
+			log.Infof("[File: %s] Deleting existing files (skipping index deletion)...", filePath)
+		} else {
+			log.Infof("[File: %s] Deleting existing files and their index entries...", filePath)
+		}
+
+		for _, fileId := range item.RemoteFileIds {
+			log.Infof("[File: %s] Deleting remote file ID: %s", filePath, fileId)
+			// 使用DeleteFileEx方法，可以控制是否跳过索引删除
+			err := client.DeleteFileExWithContext(ctx, fileId, opts.SkipIndexDelete)
+			if remoteFileGone(err) {
+				log.Infof("[File: %s] Remote file %s no longer exists", filePath, fileId)
+			} else if err != nil {
+				log.Warnf("[File: %s] Failed to delete file %s: %v", filePath, fileId, err)
+				if !opts.ForceUpload {
+					return err
 				}
+				continue
 			} else {
-				// 用户确认删除并上传新版本
-				for _, file := range existingFiles {
-					log.Infof("Deleting file: %s (ID: %s)", file.FileName, file.FileId)
-					// 使用DeleteFileEx方法，可以控制是否跳过索引删除
-					err := client.DeleteFileEx(file.FileId, skipIndexDelete)
-					if err != nil {
-						log.Warnf("Failed to delete file %s: %v", file.FileId, err)
-						return err
-					}
-					log.Infof("File deleted successfully: %s", file.FileId)
-				}
+				log.Infof("[File: %s] File deleted successfully: %s", filePath, fileId)
 			}
+			state.RemoveByFileId(fileId)
 		}
-	}
+		fallthrough
 
-	// 如果需要上传新文件
-	if needUpload {
-		log.Infof("[File: %s] Initiating file upload process", filePath)
-		log.Infof("[File: %s] Applying for file upload lease", filePath)
-		lis, err := client.ApplyFileUploadLease(filePath, fileContent)
+	case actionUploadNew:
+		fileId, err := uploadSyncedFile(ctx, filePath, client, state, opts)
 		if err != nil {
-			log.Errorf("[File: %s] Failed to apply for upload lease: %v", filePath, err)
 			return err
 		}
-		log.Infof("[File: %s] Upload lease acquired successfully", filePath)
+		return indexSyncedFile(ctx, filePath, fileId, true, client, config, state, opts)
 
-		headers := utils.InterfaceToGeneralMap(lis.Headers)
-		bailianExtra, ok := headers["X-bailian-extra"]
-		if !ok {
-			log.Errorf("[File: %s] X-bailian-extra header not found in lease response", filePath)
-			return utils.Errorf("X-bailian-extra does not exist")
+	case actionRename:
+		// 先上传并索引新路径，再删除旧的远程文件
+		log.Infof("[File: %s] Renamed from %s (ID: %s), uploading the new path before deleting the old file", filePath, item.OldPath, item.FileId)
+		fileId, err := uploadSyncedFile(ctx, filePath, client, state, opts)
+		if err != nil {
+			return err
 		}
-		contentType, ok := headers["Content-Type"]
-		if !ok {
-			log.Errorf("[File: %s] Content-Type header not found in lease response", filePath)
-			return utils.Errorf("Content-Type does not exist")
+		if opts.AddToIndex && opts.IndexBatch != nil {
+			opts.IndexBatch.AddRename(filePath, fileId, item.OldPath, item.FileId)
+			log.Infof("[File: %s] File (ID: %s) queued for batch submission, %s will be deleted after indexing finishes",
+				filePath, fileId, item.OldPath)
+			return nil
 		}
-
-		log.Infof("[File: %s] Uploading file to URL: %s", filePath, lis.UploadURL)
-		log.Infof("[File: %s] Upload method: %s, Content-Type: %s", filePath, lis.Method, contentType)
-
-		// Upload file
-		err = aliyun.UploadFile(lis.Method, lis.UploadURL, filePath, fmt.Sprint(contentType), fileContent, fmt.Sprintf("%s", bailianExtra))
-		if err != nil {
-			log.Errorf("[File: %s] File upload failed: %v", filePath, err)
+		if err := indexSyncedFile(ctx, filePath, fileId, true, client, config, state, opts); err != nil {
+			log.Warnf("[File: %s] Keeping old remote file %s because indexing the new file failed", filePath, item.OldPath)
 			return err
 		}
-		log.Infof("[File: %s] File content uploaded successfully", filePath)
+		return deleteRenamedSource(ctx, client, state, item.OldPath, item.FileId)
 
-		log.Infof("[File: %s] Adding file to Bailian RAG with lease ID: %s", filePath, lis.LeaseId)
-		fileId, err = client.AddFile(lis.LeaseId)
-		if err != nil {
-			log.Errorf("[File: %s] Failed to add file to Bailian RAG: %v", filePath, err)
-			return err
+	default:
+		return utils.Errorf("Unknown plan action: %s", item.Action)
+	}
+}
+
+// refreshFingerprint 内容未变但修改时间变化时（如 git checkout），刷新同步状态中的本地指纹
+func refreshFingerprint(item *planItem, state *syncstate.Manifest) {
+	entry := state.Get(item.Path)
+	if entry == nil || entry.FileId != item.FileId {
+		return
+	}
+	if same, known := entry.SameContent(item.Digest); !known || !same {
+		return
+	}
+	state.Update(item.Path, func(e *syncstate.Entry) {
+		e.Size = item.Size
+		e.ModTime = item.ModTime
+	})
+}
+
+// uploadSyncedFile 申请租约、上传文件内容并添加到知识库后端，返回新的文件 ID
+func uploadSyncedFile(ctx context.Context, filePath string, client backend.Backend, state *syncstate.Manifest, opts *syncOptions) (string, error) {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		log.Errorf("[File: %s] Failed to get file information: %v", filePath, err)
+		return "", utils.Errorf("Failed to get file information: %v", err)
+	}
+
+	// 以流的方式计算大小和摘要，上传时再重新读取文件，不会把整个文件读入内存
+	log.Infof("[File: %s] Computing content digest", filePath)
+	fileContent, err := backend.FileContent(filePath)
+	if err != nil {
+		log.Errorf("[File: %s] Failed to read file content: %v", filePath, err)
+		return "", err
+	}
+	log.Infof("[File: %s] Content digest computed, size: %d bytes, MD5: %s", filePath, fileContent.Size, fileContent.MD5)
+
+	log.Infof("[File: %s] Initiating file upload process", filePath)
+	lis, err := leaseAndUpload(ctx, filePath, client, fileContent, opts)
+	if err != nil {
+		return "", err
+	}
+
+	// 内容上传后即使被中断也要完成添加，避免留下已上传但未添加的租约
+	log.Infof("[File: %s] Adding file to %s with lease ID: %s", filePath, client.Name(), lis.LeaseId)
+	releaseAdd := opts.Stages.Add()
+	fileId, err := client.AddFileWithContext(context.WithoutCancel(ctx), lis.LeaseId)
+	releaseAdd()
+	if err != nil {
+		log.Errorf("[File: %s] Failed to add file to %s: %v", filePath, client.Name(), err)
+		return "", err
+	}
+
+	log.Infof("[File: %s] File added successfully with ID: %s", filePath, fileId)
+
+	// 记录本次同步的本地指纹和远程文件 ID，AddFile 不返回创建时间，使用添加完成的时间代替，
+	// 否则同步状态完整时 --compare mtime/both 缺少远程时间，总是替换
+	state.Put(&syncstate.Entry{
+		LocalPath:        filePath,
+		MD5:              fileContent.MD5,
+		SHA256:           fileContent.SHA256,
+		Size:             fileContent.Size,
+		ModTime:          fileInfo.ModTime(),
+		FileId:           fileId,
+		RemoteCreateTime: time.Now().Format(time.RFC3339),
+	})
+
+	// 同样的内容重新上传也会解析失败，因此解析失败时保留同步状态，只报告错误
+	if opts.WaitParse {
+		if err := waitForFileParse(ctx, client, filePath, fileId, opts.ParseTimeout); err != nil {
+			return "", err
 		}
+	}
+	return fileId, nil
+}
 
-		log.Infof("[File: %s] File added successfully with ID: %s", filePath, fileId)
-	} else {
-		log.Infof("[File: %s] Using existing file, skipping upload", filePath)
+// indexSyncedFile 将文件加入知识索引：批量模式下加入本次运行的索引批次，否则立即提交
+func indexSyncedFile(ctx context.Context, filePath string, fileId string, newlyUploaded bool, client backend.Backend, config *spec.Config, state *syncstate.Manifest, opts *syncOptions) error {
+	if !opts.AddToIndex {
+		log.Infof("[File: %s] Skipping knowledge index step (--no-index was specified)", filePath)
+		log.Infof("[File: %s] File processing completed successfully", filePath)
+		return nil
+	}
+	if fileId == "" {
+		log.Warnf("[File: %s] Cannot add to index: file ID is empty", filePath)
+		return nil
 	}
 
-	// 无论是新上传的文件还是使用已有文件，如果需要添加到索引，就执行索引步骤
-	if addToIndex && fileId != "" {
-		log.Infof("[File: %s] Adding file (ID: %s) to knowledge index: %s",
+	if opts.IndexBatch != nil {
+		// 批量模式：已存在的文件先确认是否已在索引中，再加入本次运行的索引批次
+		if !newlyUploaded {
+			indexed, err := client.DocumentIndexedWithContext(ctx, filePath)
+			if err != nil {
+				log.Warnf("[File: %s] Failed to check if document is already indexed: %v", filePath, err)
+			} else if indexed {
+				log.Infof("[File: %s] Document is already being indexed or has been indexed, skipping index addition", filePath)
+				state.Update(filePath, func(e *syncstate.Entry) {
+					e.FileId = fileId
+					e.IndexStatus = "RUNNING"
+				})
+				log.Infof("[File: %s] File processing completed successfully", filePath)
+				return nil
+			}
+		}
+		opts.IndexBatch.Add(filePath, fileId)
+		log.Infof("[File: %s] File (ID: %s) queued for batch submission to knowledge index: %s",
 			filePath, fileId, config.BailianKnowledgeIndexId)
+		log.Infof("[File: %s] File processing completed successfully", filePath)
+		return nil
+	}
 
-		jobId, err := client.AppendDocumentToIndex(fileId)
-		if err != nil {
-			log.Errorf("[File: %s] Failed to add file to knowledge index: %v", filePath, err)
-			return err
-		}
+	log.Infof("[File: %s] Adding file (ID: %s) to knowledge index: %s",
+		filePath, fileId, config.BailianKnowledgeIndexId)
 
+	jobId, err := client.AppendDocumentToIndexWithContext(ctx, fileId)
+	if err != nil {
+		log.Errorf("[File: %s] Failed to add file to knowledge index: %v", filePath, err)
+		return err
+	}
+
+	state.Update(filePath, func(e *syncstate.Entry) {
+		e.FileId = fileId
+		e.IndexStatus = "RUNNING"
 		if jobId != "" {
-			log.Infof("[File: %s] File added to knowledge index successfully. Job ID: %s", filePath, jobId)
-			log.Infof("[File: %s] You can check the job status with: ragsync job --job-id %s", filePath, jobId)
-		} else {
-			log.Warnf("[File: %s] File was processed, but no job ID was returned. The file may still be added to the index.", filePath)
+			e.LastJobId = jobId
 		}
-	} else if !addToIndex {
-		log.Infof("[File: %s] Skipping knowledge index step (--no-index was specified)", filePath)
+	})
+
+	if jobId != "" {
+		log.Infof("[File: %s] File added to knowledge index successfully. Job ID: %s", filePath, jobId)
+		log.Infof("[File: %s] You can check the job status with: ragsync job --job-id %s", filePath, jobId)
 	} else {
-		log.Warnf("[File: %s] Cannot add to index: file ID is empty", filePath)
+		log.Warnf("[File: %s] File was processed, but no job ID was returned. The file may still be added to the index.", filePath)
 	}
 
 	log.Infof("[File: %s] File processing completed successfully", filePath)
 	return nil
 }
 
+// isUnchangedSinceSync 根据同步状态判断本地文件自上次同步后是否未变化
+func isUnchangedSinceSync(mode string, entry *syncstate.Entry, info os.FileInfo, digest syncstate.Digest) bool {
+	if entry.MatchesStat(info) {
+		return true
+	}
+	if mode == compareMtime {
+		return false
+	}
+	same, known := entry.SameContent(digest)
+	return known && same
+}
+
+// lookupExistingFiles 查找与本地文件同名的远程文件，同步状态完整时直接使用本地清单
+func lookupExistingFiles(ctx context.Context, client backend.Backend, state *syncstate.Manifest, fileName string) ([]*backend.FileInfo, error) {
+	if state.IsComplete() {
+		entry := state.Get(fileName)
+		if entry == nil || entry.FileId == "" {
+			return nil, nil
+		}
+		return []*backend.FileInfo{entryToFileInfo(entry)}, nil
+	}
+	return client.ListAllFilesWithContext(ctx, fileName)
+}
+
 // askForConfirmation 请求用户确认
 func askForConfirmation(s string) bool {
+	consoleMu.Lock()
+	defer consoleMu.Unlock()
+
 	fmt.Printf("%s [y/N]: ", s)
 
 	var response string
@@ -689,23 +1132,3 @@ func askForConfirmation(s string) bool {
 	response = strings.ToLower(strings.TrimSpace(response))
 	return response == "y" || response == "yes"
 }
-
-// containsExcludedKeywords 检查文件名是否包含排除关键字
-func containsExcludedKeywords(filePath string, excludeKeywords []string) bool {
-	if len(excludeKeywords) == 0 {
-		return false
-	}
-
-	fileName := filepath.Base(filePath)
-	fileNameLower := strings.ToLower(fileName)
-
-	for _, keyword := range excludeKeywords {
-		if keyword == "" {
-			continue
-		}
-		if strings.Contains(fileNameLower, strings.ToLower(keyword)) {
-			return true
-		}
-	}
-	return false
-}
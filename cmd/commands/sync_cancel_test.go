@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/VillanCh/ragsync/common/backend"
+)
+
+// cancelAfterAdd 第一个文件添加完成后取消同步，模拟在同步过程中按下 Ctrl+C
+type cancelAfterAdd struct {
+	backend.Backend
+	cancel context.CancelFunc
+	adds   int32
+}
+
+func (b *cancelAfterAdd) AddFileWithContext(ctx context.Context, leaseId string) (string, error) {
+	fileId, err := b.Backend.AddFileWithContext(ctx, leaseId)
+	if atomic.AddInt32(&b.adds, 1) == 1 {
+		b.cancel()
+	}
+	return fileId, err
+}
+
+func TestSyncCancelSummary(t *testing.T) {
+	server, configPath := newMockWorkspace(t)
+	client := mockClient(t, configPath)
+	config := loadMockConfig(t, configPath)
+	state := loadMockState(t, configPath)
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.md": "a\n", "b.md": "b\n", "c.md": "c\n"})
+	plan := &syncPlan{}
+	for _, name := range []string{"a.md", "b.md", "c.md"} {
+		plan.Add(&planItem{Action: actionUploadNew, Path: filepath.Join(dir, name), Root: dir, Reason: "not found in remote"})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupting := &cancelAfterAdd{Backend: client, cancel: cancel}
+	opts := &syncOptions{AddToIndex: true, Concurrency: 1, IndexBatch: &indexBatch{}, Git: &gitSync{}}
+
+	// 已经开始的文件完成处理，其余文件计入摘要并返回中断错误
+	err := executeSyncPlan(ctx, plan, interrupting, config, state, opts)
+	if err == nil || !strings.Contains(err.Error(), "Sync interrupted") || !strings.Contains(err.Error(), "2 files were not synced") {
+		t.Fatalf("executeSyncPlan returned %v, want an interrupted error for the 2 remaining files", err)
+	}
+	if entry := state.Get(filepath.Join(dir, "a.md")); entry == nil || entry.FileId == "" {
+		t.Fatalf("sync state entry of the completed file = %+v, want its file ID", entry)
+	}
+	for _, name := range []string{"b.md", "c.md"} {
+		if entry := state.Get(filepath.Join(dir, name)); entry != nil {
+			t.Fatalf("%s was recorded in the sync state after the cancel: %+v", name, entry)
+		}
+	}
+	if calls := server.Calls("AddFile"); calls != 1 {
+		t.Fatalf("AddFile was called %d times, want only the file started before the cancel", calls)
+	}
+
+	// 已添加的文件在中断后仍然提交到索引
+	if err := submitIndexBatches(ctx, interrupting, state, opts.IndexBatch, 0); err != nil {
+		t.Fatalf("submitIndexBatches after the cancel: %v", err)
+	}
+	if names, documents := remoteState(t, client); len(names) != 1 || !names["a.md"] || documents != 1 {
+		t.Fatalf("remote files = %v with %d index documents, want only a.md indexed", names, documents)
+	}
+}
+
+func TestSyncTimeout(t *testing.T) {
+	_, configPath := newMockWorkspace(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.md": "a\n"})
+
+	err := runCommand(t, configPath, "--timeout", "1ns", "sync", "--dir", dir, "--exclude", "")
+	if err == nil || !strings.Contains(err.Error(), "--timeout") {
+		t.Fatalf("sync with an expired --timeout returned %v, want a timeout error", err)
+	}
+}
@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/VillanCh/ragsync/common/backend"
+	"github.com/VillanCh/ragsync/common/syncstate"
+
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// 本地文件变化检测方式
+const (
+	compareHash  = "hash"  // 比较内容摘要
+	compareMtime = "mtime" // 比较本地修改时间与远程创建时间
+	compareBoth  = "both"  // 内容变化且本地较新时才替换
+)
+
+// parseCompareMode 解析 --compare 参数
+func parseCompareMode(mode string) (string, error) {
+	switch mode {
+	case "", compareHash:
+		return compareHash, nil
+	case compareMtime, compareBoth:
+		return mode, nil
+	default:
+		return "", utils.Errorf("Invalid compare mode %q, expected one of: hash, mtime, both", mode)
+	}
+}
+
+// detectChange 判断本地文件相对于远程文件是否需要替换，返回计划动作
+// （actionReplace、actionSkipUnchanged 或 actionSkipRemoteNewer）和原因
+// entry 为同步状态中的记录，可能为 nil；remote 为远程文件信息
+func detectChange(mode string, entry *syncstate.Entry, local syncstate.Digest, localModTime time.Time, remote *backend.FileInfo) (planAction, string) {
+	// 内容摘要比较：只有记录对应的仍是同一个远程文件时才可信
+	hashKnown := false
+	sameContent := false
+	if entry != nil && remote != nil && entry.FileId == remote.FileId {
+		sameContent, hashKnown = entry.SameContent(local)
+	}
+
+	// 修改时间比较
+	timeKnown := false
+	localNewer := false
+	var remoteTime time.Time
+	if remote != nil {
+		if t, err := backend.ParseCreateTime(remote.CreateTime); err == nil {
+			remoteTime = t
+			timeKnown = true
+			localNewer = localModTime.After(remoteTime)
+		}
+	}
+
+	describeTime := func() string {
+		if localNewer {
+			return fmt.Sprintf("local file (%s) is newer than remote file (%s)",
+				localModTime.Format(time.RFC3339), remoteTime.Format(time.RFC3339))
+		}
+		return fmt.Sprintf("remote file (%s) is newer than local file (%s)",
+			remoteTime.Format(time.RFC3339), localModTime.Format(time.RFC3339))
+	}
+
+	timeAction := func() planAction {
+		if localNewer {
+			return actionReplace
+		}
+		return actionSkipRemoteNewer
+	}
+
+	switch mode {
+	case compareMtime:
+		if timeKnown {
+			return timeAction(), describeTime()
+		}
+		return actionReplace, "remote create time not available, assuming local file is newer"
+	case compareBoth:
+		if hashKnown && sameContent {
+			return actionSkipUnchanged, "content digest unchanged"
+		}
+		if timeKnown && !localNewer {
+			return actionSkipRemoteNewer, describeTime()
+		}
+		if hashKnown {
+			return actionReplace, "content digest changed"
+		}
+		return actionReplace, "content digest not recorded and local file is not older than remote"
+	default:
+		if hashKnown {
+			if sameContent {
+				return actionSkipUnchanged, "content digest unchanged"
+			}
+			return actionReplace, "content digest changed"
+		}
+		// 没有记录摘要（例如首次同步或 state rebuild 之后），退化为时间比较
+		if timeKnown {
+			return timeAction(), "content digest not recorded, " + describeTime()
+		}
+		return actionReplace, "content digest and remote create time not available, assuming changed"
+	}
+}
@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/VillanCh/ragsync/common/backend"
+	"github.com/VillanCh/ragsync/common/syncstate"
+)
+
+func TestParseCompareMode(t *testing.T) {
+	for mode, want := range map[string]string{"": compareHash, "hash": compareHash, "mtime": compareMtime, "both": compareBoth} {
+		if got, err := parseCompareMode(mode); err != nil || got != want {
+			t.Errorf("parseCompareMode(%q) = %q, %v, want %q", mode, got, err, want)
+		}
+	}
+	if _, err := parseCompareMode("size"); err == nil {
+		t.Fatal("parseCompareMode accepted an unknown mode")
+	}
+}
+
+func TestDetectChange(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	remote := &backend.FileInfo{FileId: "file_1", CreateTime: created.Format(time.RFC3339)}
+	noTime := &backend.FileInfo{FileId: "file_1"}
+	older := created.Add(-time.Hour)
+	newer := created.Add(time.Hour)
+
+	same := syncstate.ContentDigest([]byte("same"))
+	changed := syncstate.ContentDigest([]byte("changed"))
+	recorded := &syncstate.Entry{FileId: "file_1"}
+	recorded.SetDigest(same)
+	// 记录对应的是另一个远程文件时摘要不可信
+	otherFile := &syncstate.Entry{FileId: "file_other"}
+	otherFile.SetDigest(same)
+
+	cases := []struct {
+		name    string
+		mode    string
+		entry   *syncstate.Entry
+		digest  syncstate.Digest
+		modTime time.Time
+		remote  *backend.FileInfo
+		want    planAction
+	}{
+		{"hash unchanged but local newer", compareHash, recorded, same, newer, remote, actionSkipUnchanged},
+		{"hash changed but remote newer", compareHash, recorded, changed, older, remote, actionReplace},
+		{"hash without digest falls back to local newer", compareHash, nil, changed, newer, remote, actionReplace},
+		{"hash without digest falls back to remote newer", compareHash, nil, changed, older, remote, actionSkipRemoteNewer},
+		{"hash digest of another remote file", compareHash, otherFile, same, older, remote, actionSkipRemoteNewer},
+		{"hash without digest or time", compareHash, nil, same, older, noTime, actionReplace},
+		{"mtime local newer with the same content", compareMtime, recorded, same, newer, remote, actionReplace},
+		{"mtime remote newer with changed content", compareMtime, recorded, changed, older, remote, actionSkipRemoteNewer},
+		{"mtime without remote time", compareMtime, recorded, same, older, noTime, actionReplace},
+		{"both unchanged", compareBoth, recorded, same, newer, remote, actionSkipUnchanged},
+		{"both changed and remote newer", compareBoth, recorded, changed, older, remote, actionSkipRemoteNewer},
+		{"both changed and local newer", compareBoth, recorded, changed, newer, remote, actionReplace},
+		{"both without digest and local newer", compareBoth, nil, changed, newer, remote, actionReplace},
+		{"both without digest or time", compareBoth, nil, changed, older, noTime, actionReplace},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got, reason := detectChange(c.mode, c.entry, c.digest, c.modTime, c.remote); got != c.want {
+				t.Fatalf("detectChange = %s (%s), want %s", got, reason, c.want)
+			}
+		})
+	}
+}
+
+func TestSyncCompareModes(t *testing.T) {
+	_, configPath := newMockWorkspace(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"edited.md": "original\n", "touched.md": "touched\n"})
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", ""); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+
+	// edited.md 内容变化但修改时间早于远程文件，touched.md 内容不变但修改时间更新
+	edited := filepath.Join(dir, "edited.md")
+	touched := filepath.Join(dir, "touched.md")
+	if err := os.WriteFile(edited, []byte("edited\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-24 * time.Hour)
+	if err := os.Chtimes(edited, past, past); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(24 * time.Hour)
+	if err := os.Chtimes(touched, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		mode    string
+		edited  planAction
+		touched planAction
+	}{
+		{compareHash, actionReplace, actionSkipUnchanged},
+		{compareMtime, actionSkipRemoteNewer, actionReplace},
+		{compareBoth, actionSkipRemoteNewer, actionSkipUnchanged},
+	}
+	for _, c := range cases {
+		t.Run(c.mode, func(t *testing.T) {
+			plan := dryRunPlan(t, configPath, "--dir", dir, "--exclude", "", "--compare", c.mode)
+			actions := map[string]planAction{}
+			for _, item := range plan.Items {
+				actions[filepath.Base(item.Path)] = item.Action
+			}
+			if actions["edited.md"] != c.edited || actions["touched.md"] != c.touched {
+				t.Fatalf("--compare %s planned edited.md as %s and touched.md as %s, want %s and %s",
+					c.mode, actions["edited.md"], actions["touched.md"], c.edited, c.touched)
+			}
+		})
+	}
+
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--compare", "size"); err == nil {
+		t.Fatal("sync accepted an unknown --compare mode")
+	}
+}
@@ -0,0 +1,214 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/VillanCh/ragsync/common/ignore"
+	"github.com/VillanCh/ragsync/common/spec"
+
+	"github.com/yaklang/yaklang/common/log"
+)
+
+// filterSettings 本地文件过滤设置，来自命令行参数和配置文件
+type filterSettings struct {
+	Extensions       []string
+	Keywords         []string // --exclude 中的关键字，按文件名和目录名中的单词匹配
+	Excludes         []string // --exclude 中的通配规则以及配置文件中的 exclude（gitignore 语法）
+	Includes         []string // 配置文件中的 include（gitignore 语法）
+	RespectGitignore bool
+}
+
+// newFilterSettings 解析 --exclude 参数：包含通配符或 / 的值按 gitignore 规则处理，其他值按关键字处理
+func newFilterSettings(extensions []string, excludes []string, config *spec.Config, respectGitignore bool) *filterSettings {
+	settings := &filterSettings{
+		Extensions:       extensions,
+		RespectGitignore: respectGitignore,
+	}
+	for _, value := range excludes {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if strings.ContainsAny(value, "*?[/!") {
+			settings.Excludes = append(settings.Excludes, value)
+		} else {
+			settings.Keywords = append(settings.Keywords, value)
+		}
+	}
+	if config != nil {
+		settings.Excludes = append(settings.Excludes, config.Exclude...)
+		settings.Includes = append(settings.Includes, config.Include...)
+	}
+	return settings
+}
+
+// pathFilter 决定一个同步根路径（目录或单个文件）下哪些文件参与同步
+type pathFilter struct {
+	settings *filterSettings
+	root     string
+	isDir    bool
+	matcher  *ignore.Matcher
+}
+
+// newPathFilter 为同步根路径创建过滤器
+// 忽略文件从根路径（指定 --respect-gitignore 时从 git 仓库根目录）开始逐级加载
+func newPathFilter(root string, isDir bool, settings *filterSettings) (*pathFilter, error) {
+	base := root
+	if !isDir {
+		base = filepath.Dir(root)
+	}
+
+	ignoreRoot := base
+	ignoreFiles := []string{ignore.RagsyncIgnoreFile}
+	if settings.RespectGitignore {
+		ignoreFiles = []string{ignore.GitIgnoreFile, ignore.RagsyncIgnoreFile}
+		if gitRoot, ok := ignore.FindGitRoot(base); ok {
+			ignoreRoot = gitRoot
+		} else {
+			log.Warnf("--respect-gitignore is set but %s is not inside a git repository", root)
+		}
+	}
+
+	matcher, err := ignore.NewMatcher(ignore.Options{
+		Root:        ignoreRoot,
+		Base:        base,
+		IgnoreFiles: ignoreFiles,
+		Excludes:    settings.Excludes,
+		Includes:    settings.Includes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pathFilter{
+		settings: settings,
+		root:     root,
+		isDir:    isDir,
+		matcher:  matcher,
+	}, nil
+}
+
+// SkipDir 判断扫描时是否跳过整个目录
+func (f *pathFilter) SkipDir(dirPath string) bool {
+	if filepath.Base(dirPath) == ".git" {
+		return true
+	}
+	if f.matcher.Ignored(dirPath, true) {
+		return true
+	}
+	return containsExcludedKeywords(f.relative(dirPath), f.settings.Keywords)
+}
+
+// Allow 判断文件是否参与同步；单个文件的根路径不检查扩展名和包含规则
+func (f *pathFilter) Allow(filePath string) bool {
+	if f.matcher.Ignored(filePath, false) {
+		log.Debugf("[File: %s] Ignored by ignore rules", filePath)
+		return false
+	}
+	if containsExcludedKeywords(f.relative(filePath), f.settings.Keywords) {
+		log.Debugf("[File: %s] Skipped due to exclusion keywords", filePath)
+		return false
+	}
+	if !f.isDir {
+		return true
+	}
+	if !isExtensionAllowed(strings.ToLower(filepath.Ext(filePath)), f.settings.Extensions) {
+		return false
+	}
+	return f.matcher.Included(filePath)
+}
+
+// DirAllowed 判断目录及其所有上级目录（直到根路径）都未被排除
+func (f *pathFilter) DirAllowed(dirPath string) bool {
+	if !f.isDir {
+		return true
+	}
+	rel := f.relative(dirPath)
+	if rel == "." {
+		return true
+	}
+	parts := strings.Split(rel, "/")
+	for i := 1; i <= len(parts); i++ {
+		dir := filepath.Join(f.root, filepath.FromSlash(strings.Join(parts[:i], "/")))
+		if f.SkipDir(dir) {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowTree 判断文件及其所有上级目录都未被排除，用于监听模式下的单个变化
+func (f *pathFilter) AllowTree(filePath string) bool {
+	return f.DirAllowed(filepath.Dir(filePath)) && f.Allow(filePath)
+}
+
+// relative 返回相对于根路径的路径，单个文件时只返回文件名
+func (f *pathFilter) relative(filePath string) string {
+	if !f.isDir {
+		return filepath.Base(filePath)
+	}
+	rel, err := filepath.Rel(f.root, filePath)
+	if err != nil {
+		return filepath.Base(filePath)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// walk 遍历目录中参与同步的文件
+func (f *pathFilter) walk(fn func(filePath string) error) error {
+	return filepath.Walk(f.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != f.root && f.SkipDir(path) {
+				log.Debugf("[Dir: %s] Skipping directory %s", f.root, path)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !f.Allow(path) {
+			return nil
+		}
+		return fn(path)
+	})
+}
+
+// containsExcludedKeywords 检查路径中的文件名或目录名是否包含排除关键字
+// 关键字按单词匹配（以非字母数字字符分隔），因此 "temp" 会排除 "temp_notes.md" 和 "temp/"，但不会排除 "template.md"
+func containsExcludedKeywords(relPath string, excludeKeywords []string) bool {
+	if len(excludeKeywords) == 0 {
+		return false
+	}
+
+	words := splitWords(relPath)
+	for _, keyword := range excludeKeywords {
+		keywordWords := splitWords(keyword)
+		if len(keywordWords) == 0 {
+			continue
+		}
+		for i := 0; i+len(keywordWords) <= len(words); i++ {
+			matched := true
+			for j, word := range keywordWords {
+				if words[i+j] != word {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitWords 将路径按非字母数字字符拆分为小写单词
+func splitWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,263 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/VillanCh/ragsync/common/backend"
+	"github.com/VillanCh/ragsync/common/gitdiff"
+	"github.com/VillanCh/ragsync/common/syncstate"
+
+	"github.com/yaklang/yaklang/common/log"
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// gitSync --since / --git 增量同步设置
+type gitSync struct {
+	Since   string // --since 指定的起始引用
+	Enabled bool   // --git：从上次成功同步的提交开始
+
+	// heads 本次同步中计划成功的根路径对应的 HEAD 提交，同步成功后写入同步状态
+	heads map[string]string
+	// failed 有文件计划或同步失败的根路径，不记录其 HEAD，下一次增量同步时重试
+	failed map[string]bool
+	// deletions git 中删除的文件对应的计划项，没有在本次同步中删除或重命名时不能记录 HEAD
+	deletions []*planItem
+}
+
+// active 是否启用了增量同步
+func (g *gitSync) active() bool {
+	return g != nil && (g.Since != "" || g.Enabled)
+}
+
+// markFailed 记录有文件计划或同步失败的根路径
+func (g *gitSync) markFailed(root string) {
+	if !g.active() {
+		return
+	}
+	if g.failed == nil {
+		g.failed = make(map[string]bool)
+	}
+	g.failed[root] = true
+}
+
+// planSyncRoot 为一个同步根路径生成计划：启用 --since/--git 且能确定起始提交时只处理变化的文件，否则完整扫描
+// 计划成功后才记录该根路径的 HEAD，计划失败的根路径下一次增量同步时仍从原来的提交开始
+func planSyncRoot(ctx context.Context, root syncRoot, client backend.Backend, state *syncstate.Manifest, opts *syncOptions) (*syncPlan, error) {
+	base, head, err := resolveGitBase(root, state, opts.Git)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan *syncPlan
+	switch {
+	case base != "":
+		plan, err = planGitChanges(ctx, root, base, head, client, state, opts)
+	case root.IsDir:
+		plan, err = planDirUpload(ctx, root.Path, root.Filter, client, state, opts)
+	default:
+		var item *planItem
+		item, err = planFileUpload(ctx, root.Path, client, state, opts)
+		if err == nil {
+			item.Root = root.Path
+			plan = &syncPlan{Items: []*planItem{item}}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if head != "" {
+		if opts.Git.heads == nil {
+			opts.Git.heads = make(map[string]string)
+		}
+		opts.Git.heads[root.Path] = head
+	}
+	return plan, nil
+}
+
+// resolveGitBase 返回增量同步的起始提交和当前 HEAD；起始提交为空表示需要完整同步，HEAD 为空表示不在 git 仓库中
+func resolveGitBase(root syncRoot, state *syncstate.Manifest, git *gitSync) (string, string, error) {
+	if !git.active() {
+		return "", "", nil
+	}
+
+	dir := root.Path
+	if !root.IsDir {
+		dir = filepath.Dir(root.Path)
+	}
+	if !gitdiff.IsRepository(dir) {
+		if git.Since != "" {
+			return "", "", utils.Errorf("--since requires %s to be inside a git repository", root.Path)
+		}
+		log.Warnf("[Git: %s] Not inside a git repository, falling back to a full sync", root.Path)
+		return "", "", nil
+	}
+
+	head, err := gitdiff.ResolveCommit(dir, "HEAD")
+	if err != nil {
+		return "", "", err
+	}
+
+	if git.Since != "" {
+		base, err := gitdiff.ResolveCommit(dir, git.Since)
+		if err != nil {
+			return "", "", err
+		}
+		log.Infof("[Git: %s] Syncing changes between %s (%s) and HEAD (%s)", root.Path, git.Since, shortCommit(base), shortCommit(head))
+		return base, head, nil
+	}
+
+	last := state.GitCommit(root.Path)
+	if last == "" {
+		log.Infof("[Git: %s] No previously synced commit recorded, running a full sync", root.Path)
+		return "", head, nil
+	}
+	base, err := gitdiff.ResolveCommit(dir, last)
+	if err != nil {
+		log.Warnf("[Git: %s] Last synced commit %s is no longer available (%v), running a full sync", root.Path, shortCommit(last), err)
+		return "", head, nil
+	}
+	log.Infof("[Git: %s] Syncing changes between last synced commit %s and HEAD (%s)", root.Path, shortCommit(base), shortCommit(head))
+	return base, head, nil
+}
+
+// planGitChanges 根据两个提交之间的文件变化生成计划
+func planGitChanges(ctx context.Context, root syncRoot, base, head string, client backend.Backend, state *syncstate.Manifest, opts *syncOptions) (*syncPlan, error) {
+	dir := root.Path
+	var pathspecs []string
+	if !root.IsDir {
+		dir = filepath.Dir(root.Path)
+		pathspecs = []string{filepath.Base(root.Path)}
+	}
+
+	if err := ensureRemoteState(ctx, dir, client, state); err != nil {
+		return nil, err
+	}
+
+	plan := &syncPlan{}
+	if root.IsDir {
+		plan.RemoteFiles = len(state.EntriesUnder(root.Path))
+	}
+	if base == head {
+		log.Infof("[Git: %s] No new commits since %s", root.Path, shortCommit(base))
+		return plan, nil
+	}
+
+	changes, err := gitdiff.Diff(dir, base, head, pathspecs...)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("[Git: %s] %d files changed between %s and %s", root.Path, len(changes), shortCommit(base), shortCommit(head))
+
+	planned := make(map[string]bool)
+	var planErrors []string
+	addChanged := func(rel string) {
+		localPath := syncstate.NormalizePath(filepath.Join(dir, filepath.FromSlash(rel)))
+		if planned[localPath] {
+			return
+		}
+		planned[localPath] = true
+		if root.IsDir && !root.Filter.AllowTree(localPath) {
+			return
+		}
+		if _, err := os.Stat(localPath); err != nil {
+			// 提交中存在但工作区中已被删除：本次跳过，不记录 HEAD，下一次增量同步时重试
+			log.Warnf("[Git: %s] %s changed in git but is missing from the working tree, skipping it until the next sync", root.Path, localPath)
+			opts.Git.markFailed(root.Path)
+			return
+		}
+		item, err := planFileUpload(ctx, localPath, client, state, opts)
+		if err != nil {
+			log.Errorf("[Git: %s] Failed to plan file %s: %v", root.Path, localPath, err)
+			planErrors = append(planErrors, localPath)
+			return
+		}
+		item.Root = root.Path
+		plan.Add(item)
+	}
+	addDeleted := func(rel string) {
+		localPath := syncstate.NormalizePath(filepath.Join(dir, filepath.FromSlash(rel)))
+		if planned[localPath] {
+			return
+		}
+		planned[localPath] = true
+		entry := state.Get(localPath)
+		if entry == nil || entry.FileId == "" {
+			return
+		}
+		item := &planItem{
+			Path:   localPath,
+			Root:   root.Path,
+			FileId: entry.FileId,
+			Reason: "deleted in git",
+		}
+		plan.AddMissing(item, opts.Prune)
+		opts.Git.deletions = append(opts.Git.deletions, item)
+	}
+
+	for _, change := range changes {
+		switch change.Type {
+		case gitdiff.Deleted:
+			addDeleted(change.Path)
+		case gitdiff.Renamed:
+			addDeleted(change.OldPath)
+			addChanged(change.Path)
+		default:
+			addChanged(change.Path)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if len(planErrors) > 0 {
+		return nil, utils.Errorf("Failed to plan %d changed files under %s: %s", len(planErrors), root.Path, strings.Join(planErrors, ", "))
+	}
+	log.Infof("[Git: %s] Plan: %s", root.Path, plan.Summary())
+	return plan, nil
+}
+
+// holdGitHeads 在重命名检测和删除确认之后调用：git 中删除的文件既不会被删除（没有 --prune 或删除被取消）
+// 也没有合并为重命名时，其根路径不记录 HEAD，否则下一次增量同步的 diff 中不再包含这次删除，远程文件会一直保留
+func holdGitHeads(plan *syncPlan, git *gitSync) {
+	if git == nil || len(git.deletions) == 0 {
+		return
+	}
+	planned := make(map[*planItem]bool, len(plan.Items))
+	for _, item := range plan.Items {
+		planned[item] = true
+	}
+	for _, item := range git.deletions {
+		if planned[item] || item.Action == actionRename {
+			continue
+		}
+		log.Warnf("[Git: %s] %s was deleted in git but its remote file is kept, keeping the last synced commit until it is deleted with --prune", item.Root, item.Path)
+		git.markFailed(item.Root)
+	}
+}
+
+// recordGitHeads 记录各根路径已同步到的提交，有文件计划或同步失败的根路径不记录，下一次增量同步时重试
+func recordGitHeads(state *syncstate.Manifest, git *gitSync) {
+	if git == nil {
+		return
+	}
+	for path, head := range git.heads {
+		if git.failed[path] {
+			log.Warnf("[Git: %s] Some files were not synced, keeping the last synced commit so they are retried", path)
+			continue
+		}
+		state.SetGitCommit(path, head)
+		log.Infof("[Git: %s] Recorded last synced commit %s", path, shortCommit(head))
+	}
+}
+
+// shortCommit 返回提交哈希的短格式
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitCommand 在 dir 中执行 git 命令并返回去掉空白的输出
+func gitCommand(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newGitDocs 创建包含 docs 目录的 git 仓库并提交 files，返回 docs 目录
+func newGitDocs(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "ragsync")
+	t.Setenv("GIT_COMMITTER_NAME", "ragsync")
+	t.Setenv("GIT_AUTHOR_EMAIL", "ragsync@example.com")
+	t.Setenv("GIT_COMMITTER_EMAIL", "ragsync@example.com")
+
+	repo := t.TempDir()
+	gitCommand(t, repo, "init", "-q")
+	dir := filepath.Join(repo, "docs")
+	writeFiles(t, dir, files)
+	gitCommand(t, repo, "add", "-A")
+	gitCommand(t, repo, "commit", "-q", "-m", "initial")
+	return dir
+}
+
+// lastSyncedCommit 返回同步状态中记录的 dir 最近一次同步的提交
+func lastSyncedCommit(t *testing.T, configPath string, dir string) string {
+	t.Helper()
+	return loadMockState(t, configPath).GitCommit(dir)
+}
+
+func TestSyncGitKeepsCommitForUnappliedDeletions(t *testing.T) {
+	_, configPath := newMockWorkspace(t)
+	client := mockClient(t, configPath)
+	dir := newGitDocs(t, map[string]string{"a.md": "a\n", "b.md": "b\n"})
+
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", "", "--git"); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	first := gitCommand(t, dir, "rev-parse", "HEAD")
+	if commit := lastSyncedCommit(t, configPath, dir); commit != first {
+		t.Fatalf("last synced commit = %q, want %q", commit, first)
+	}
+
+	gitCommand(t, dir, "rm", "-q", "b.md")
+	gitCommand(t, dir, "commit", "-q", "-m", "remove b")
+	second := gitCommand(t, dir, "rev-parse", "HEAD")
+
+	// 没有 --prune 时远程文件保留，也不记录新的提交，否则之后的 diff 中不再包含这次删除
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", "", "--git"); err != nil {
+		t.Fatalf("sync without --prune: %v", err)
+	}
+	if names, _ := remoteState(t, client); !names["b.md"] {
+		t.Fatalf("remote files = %v, want b.md kept without --prune", names)
+	}
+	if commit := lastSyncedCommit(t, configPath, dir); commit != first {
+		t.Fatalf("last synced commit after an unapplied deletion = %q, want %q", commit, first)
+	}
+
+	// 之后指定 --prune 的增量同步仍然能看到这次删除
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", "", "--git", "--prune", "--yes", "--max-delete-percent", "0"); err != nil {
+		t.Fatalf("sync --prune: %v", err)
+	}
+	names, documents := remoteState(t, client)
+	if len(names) != 1 || !names["a.md"] || documents != 1 {
+		t.Fatalf("remote files = %v with %d index documents, want only a.md", names, documents)
+	}
+	if commit := lastSyncedCommit(t, configPath, dir); commit != second {
+		t.Fatalf("last synced commit after pruning = %q, want %q", commit, second)
+	}
+}
+
+func TestSyncGitRenameAdvancesCommit(t *testing.T) {
+	_, configPath := newMockWorkspace(t)
+	client := mockClient(t, configPath)
+	dir := newGitDocs(t, map[string]string{"a.md": "a document that is moved\n"})
+
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", "", "--git"); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "moved"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	gitCommand(t, dir, "mv", "a.md", "moved/a.md")
+	gitCommand(t, dir, "commit", "-q", "-m", "move a")
+	head := gitCommand(t, dir, "rev-parse", "HEAD")
+
+	// git 中的重命名被合并为 rename，不需要 --prune 也会删除旧文件并记录提交
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", "", "--git"); err != nil {
+		t.Fatalf("sync after the move: %v", err)
+	}
+	names, documents := remoteState(t, client)
+	if len(names) != 1 || !names["a.md"] || documents != 1 {
+		t.Fatalf("remote files = %v with %d index documents, want the moved a.md only", names, documents)
+	}
+	if commit := lastSyncedCommit(t, configPath, dir); commit != head {
+		t.Fatalf("last synced commit after the rename = %q, want %q", commit, head)
+	}
+}
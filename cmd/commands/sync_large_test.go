@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/VillanCh/ragsync/common/spec"
+)
+
+// setLargeFilePolicy 修改 newMockWorkspace 写入的配置中的 max_file_size 和 large_file_policy
+func setLargeFilePolicy(t *testing.T, configPath string, maxFileSize spec.ByteSize, policy string) {
+	t.Helper()
+	config := loadMockConfig(t, configPath)
+	config.MaxFileSize = maxFileSize
+	config.LargeFilePolicy = policy
+	if err := spec.SaveConfig(config, configPath); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+}
+
+func TestSyncSkipsLargeFiles(t *testing.T) {
+	server, configPath := newMockWorkspace(t)
+	client := mockClient(t, configPath)
+	setLargeFilePolicy(t, configPath, 16, spec.LargeFilePolicySkip)
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"small.md": "small\n", "large.md": strings.Repeat("large\n", 10)})
+
+	plan := dryRunPlan(t, configPath, "--dir", dir, "--exclude", "")
+	actions := map[string]planAction{}
+	for _, item := range plan.Items {
+		actions[filepath.Base(item.Path)] = item.Action
+	}
+	if actions["large.md"] != actionSkipTooLarge || actions["small.md"] != actionUploadNew {
+		t.Fatalf("planned large.md as %s and small.md as %s, want %s and %s", actions["large.md"], actions["small.md"], actionSkipTooLarge, actionUploadNew)
+	}
+
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", ""); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	// 跳过的文件不申请租约也不上传
+	if calls := server.Calls("ApplyFileUploadLease"); calls != 1 {
+		t.Fatalf("ApplyFileUploadLease was called %d times, want 1", calls)
+	}
+	if names, documents := remoteState(t, client); len(names) != 1 || !names["small.md"] || documents != 1 {
+		t.Fatalf("remote files = %v with %d index documents, want only small.md", names, documents)
+	}
+}
+
+func TestSyncKeepsRemoteFileThatGrewTooLarge(t *testing.T) {
+	server, configPath := newMockWorkspace(t)
+	client := mockClient(t, configPath)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.md": "a\n", "grown.md": "short\n"})
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", ""); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+
+	// 文件变大后超过上限，跳过上传，本地文件仍然存在，--prune 也不删除远程的旧版本
+	setLargeFilePolicy(t, configPath, 16, spec.LargeFilePolicySkip)
+	if err := os.WriteFile(filepath.Join(dir, "grown.md"), []byte(strings.Repeat("grown\n", 10)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", "", "--force", "--prune", "--yes"); err != nil {
+		t.Fatalf("sync after the file grew: %v", err)
+	}
+	if calls := server.Calls("ApplyFileUploadLease"); calls != 2 {
+		t.Fatalf("ApplyFileUploadLease was called %d times, want only the 2 uploads of the first sync", calls)
+	}
+	if names, _ := remoteState(t, client); len(names) != 2 || !names["grown.md"] {
+		t.Fatalf("remote files = %v, want the old grown.md kept", names)
+	}
+}
+
+func TestSyncWarnsAboutLargeFiles(t *testing.T) {
+	server, configPath := newMockWorkspace(t)
+	client := mockClient(t, configPath)
+	setLargeFilePolicy(t, configPath, 16, spec.LargeFilePolicyWarn)
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"small.md": "small\n", "large.md": strings.Repeat("large\n", 10)})
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", ""); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	// warn 只输出警告，照常上传
+	if calls := server.Calls("ApplyFileUploadLease"); calls != 2 {
+		t.Fatalf("ApplyFileUploadLease was called %d times, want 2", calls)
+	}
+	if names, documents := remoteState(t, client); len(names) != 2 || documents != 2 {
+		t.Fatalf("remote files = %v with %d index documents, want both files", names, documents)
+	}
+}
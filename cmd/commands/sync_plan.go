@@ -0,0 +1,140 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/VillanCh/ragsync/common/syncstate"
+
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// planAction 同步计划中的动作
+type planAction string
+
+const (
+	actionUploadNew       planAction = "upload-new"        // 远程不存在，上传新文件
+	actionReplace         planAction = "replace"           // 本地已变化，删除远程文件后重新上传
+	actionSkipUnchanged   planAction = "skip-unchanged"    // 内容未变化，跳过
+	actionSkipRemoteNewer planAction = "skip-remote-newer" // 远程文件较新，跳过
+	actionSkipTooLarge    planAction = "skip-too-large"    // 超过 max_file_size，不申请租约也不上传
+	actionDeleteRemote    planAction = "delete-remote"     // 本地已不存在，删除远程文件
+	actionReindex         planAction = "reindex"           // 文件无需上传，但需要加入知识索引
+	actionRename          planAction = "rename"            // 文件被移动，先上传并索引新路径，再删除旧的远程文件
+)
+
+// allPlanActions 计划输出时的动作顺序
+var allPlanActions = []planAction{
+	actionUploadNew,
+	actionReplace,
+	actionRename,
+	actionReindex,
+	actionDeleteRemote,
+	actionSkipUnchanged,
+	actionSkipRemoteNewer,
+	actionSkipTooLarge,
+}
+
+// planItem 同步计划中的一项
+type planItem struct {
+	Action        planAction       `json:"action"`
+	Path          string           `json:"path"`
+	Root          string           `json:"root"`                    // 来源的目录或文件参数，用于日志前缀
+	FileId        string           `json:"fileId,omitempty"`        // 相关的远程文件 ID
+	RemoteFileIds []string         `json:"remoteFileIds,omitempty"` // replace 时需要删除的所有远程文件
+	OldPath       string           `json:"oldPath,omitempty"`       // rename 前的路径，FileId 为其远程文件 ID
+	Reason        string           `json:"reason"`
+	Size          int64            `json:"size,omitempty"`
+	ModTime       time.Time        `json:"-"`
+	Digest        syncstate.Digest `json:"-"`
+}
+
+// syncPlan 一次同步的完整计划，dry-run 与实际执行使用同一个计划
+type syncPlan struct {
+	Items       []*planItem `json:"items"`
+	RemoteFiles int         `json:"remoteFiles"` // 扫描的目录下已有的远程文件数量，用于计算删除比例
+
+	// Missing 本地已不存在、但没有指定 --prune 而不会删除的远程文件，只作为重命名检测的候选
+	Missing []*planItem `json:"-"`
+}
+
+// Add 添加计划项
+func (p *syncPlan) Add(items ...*planItem) {
+	p.Items = append(p.Items, items...)
+}
+
+// AddMissing 记录本地已不存在的远程文件：指定 --prune 时计划删除，否则只作为重命名检测的候选
+func (p *syncPlan) AddMissing(item *planItem, prune bool) {
+	item.Action = actionDeleteRemote
+	if prune {
+		p.Items = append(p.Items, item)
+		return
+	}
+	p.Missing = append(p.Missing, item)
+}
+
+// Merge 合并另一个根路径的计划
+func (p *syncPlan) Merge(other *syncPlan) {
+	p.Items = append(p.Items, other.Items...)
+	p.Missing = append(p.Missing, other.Missing...)
+	p.RemoteFiles += other.RemoteFiles
+}
+
+// RemoveAction 移除指定动作的所有计划项
+func (p *syncPlan) RemoveAction(action planAction) {
+	items := p.Items[:0]
+	for _, item := range p.Items {
+		if item.Action != action {
+			items = append(items, item)
+		}
+	}
+	p.Items = items
+}
+
+// Count 统计指定动作的计划项数量
+func (p *syncPlan) Count(action planAction) int {
+	count := 0
+	for _, item := range p.Items {
+		if item.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Summary 返回各动作数量的摘要
+func (p *syncPlan) Summary() string {
+	var parts []string
+	for _, action := range allPlanActions {
+		parts = append(parts, fmt.Sprintf("%s=%d", action, p.Count(action)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Print 以表格或 JSON 格式输出计划
+func (p *syncPlan) Print(format string) error {
+	switch format {
+	case "json":
+		raw, err := json.MarshalIndent(p, "", "  ")
+		if err != nil {
+			return utils.Errorf("Failed to serialize sync plan: %v", err)
+		}
+		fmt.Println(string(raw))
+	case "", "table":
+		fmt.Printf("\n%-18s %-60s %-40s %s\n", "Action", "Path", "Remote File ID", "Reason")
+		fmt.Println(strings.Repeat("-", 150))
+		for _, action := range allPlanActions {
+			for _, item := range p.Items {
+				if item.Action == action {
+					fmt.Printf("%-18s %-60s %-40s %s\n", item.Action, item.Path, item.FileId, item.Reason)
+				}
+			}
+		}
+		fmt.Printf("\nPlan summary: %s\n", p.Summary())
+	default:
+		return utils.Errorf("Invalid plan format %q, expected 'table' or 'json'", format)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"context"
+	"sync"
+)
+
+// consoleMu 串行化交互式输出（表格、确认提示），避免并发处理时互相交错
+var consoleMu sync.Mutex
+
+// stageLimits 上传流水线中各阶段（申请租约、上传内容、添加文件）的并发上限
+type stageLimits struct {
+	lease  chan struct{}
+	upload chan struct{}
+	add    chan struct{}
+}
+
+// newStageLimits 创建各阶段的并发上限，小于等于 0 的值表示使用 fallback
+func newStageLimits(fallback, lease, upload, add int) *stageLimits {
+	size := func(n int) int {
+		if n <= 0 {
+			n = fallback
+		}
+		if n <= 0 {
+			n = 1
+		}
+		return n
+	}
+	return &stageLimits{
+		lease:  make(chan struct{}, size(lease)),
+		upload: make(chan struct{}, size(upload)),
+		add:    make(chan struct{}, size(add)),
+	}
+}
+
+// acquire 占用一个并发名额，返回释放函数；未设置上限时不做限制
+func acquire(ch chan struct{}) func() {
+	if ch == nil {
+		return func() {}
+	}
+	ch <- struct{}{}
+	return func() { <-ch }
+}
+
+// Lease 占用申请租约阶段的名额
+func (s *stageLimits) Lease() func() {
+	if s == nil {
+		return func() {}
+	}
+	return acquire(s.lease)
+}
+
+// Upload 占用上传文件内容阶段的名额
+func (s *stageLimits) Upload() func() {
+	if s == nil {
+		return func() {}
+	}
+	return acquire(s.upload)
+}
+
+// Add 占用添加文件阶段的名额
+func (s *stageLimits) Add() func() {
+	if s == nil {
+		return func() {}
+	}
+	return acquire(s.add)
+}
+
+// fileResult 单个计划项的处理结果
+type fileResult struct {
+	Item *planItem
+	Err  error
+	// NotStarted 为 true 时表示 ctx 结束前该计划项还没有开始处理
+	NotStarted bool
+}
+
+// runPlanPool 使用有界工作池并发处理计划项，返回的结果与输入顺序一致
+// onDone 在每个计划项处理完成后被串行调用，可用于输出进度
+// ctx 结束后不再分发新的计划项，未开始的计划项以 NotStarted 的结果返回（不会调用 onDone）
+func runPlanPool(ctx context.Context, items []*planItem, concurrency int, process func(item *planItem) error, onDone func(res *fileResult)) []*fileResult {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := make([]*fileResult, len(items))
+	indexes := make(chan int)
+	var doneMu sync.Mutex
+	var wg sync.WaitGroup
+
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				res := &fileResult{Item: items[i], Err: process(items[i])}
+				results[i] = res
+				if onDone != nil {
+					doneMu.Lock()
+					onDone(res)
+					doneMu.Unlock()
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for i := range items {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i, res := range results {
+		if res == nil {
+			results[i] = &fileResult{Item: items[i], Err: ctx.Err(), NotStarted: true}
+		}
+	}
+	return results
+}
@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// peakTracker 记录同时处于临界区的最大数量
+type peakTracker struct {
+	current int32
+	peak    int32
+}
+
+// enter 进入临界区并停留一段时间，让其他 goroutine 有机会同时进入
+func (p *peakTracker) enter() {
+	n := atomic.AddInt32(&p.current, 1)
+	for {
+		peak := atomic.LoadInt32(&p.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&p.peak, peak, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	atomic.AddInt32(&p.current, -1)
+}
+
+func TestNewStageLimits(t *testing.T) {
+	limits := newStageLimits(4, 2, 0, -1)
+	if cap(limits.lease) != 2 || cap(limits.upload) != 4 || cap(limits.add) != 4 {
+		t.Fatalf("stage limits = lease %d, upload %d, add %d, want 2, 4, 4", cap(limits.lease), cap(limits.upload), cap(limits.add))
+	}
+	// fallback 也没有设置时每个阶段至少允许一个
+	limits = newStageLimits(0, 0, 0, 0)
+	if cap(limits.lease) != 1 || cap(limits.upload) != 1 || cap(limits.add) != 1 {
+		t.Fatalf("stage limits without values = lease %d, upload %d, add %d, want 1 each", cap(limits.lease), cap(limits.upload), cap(limits.add))
+	}
+}
+
+func TestStageLimitsBoundEachStage(t *testing.T) {
+	limits := newStageLimits(8, 2, 3, 1)
+	stages := []struct {
+		name    string
+		acquire func() func()
+		want    int32
+	}{
+		{"lease", limits.Lease, 2},
+		{"upload", limits.Upload, 3},
+		{"add", limits.Add, 1},
+	}
+	for _, stage := range stages {
+		t.Run(stage.name, func(t *testing.T) {
+			tracker := &peakTracker{}
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					release := stage.acquire()
+					defer release()
+					tracker.enter()
+				}()
+			}
+			wg.Wait()
+			if tracker.peak != stage.want {
+				t.Fatalf("%s stage ran %d at once, want %d", stage.name, tracker.peak, stage.want)
+			}
+		})
+	}
+}
+
+func TestStageLimitsNil(t *testing.T) {
+	// 没有设置阶段上限时（例如单文件同步）不做限制
+	var limits *stageLimits
+	for _, acquire := range []func() func(){limits.Lease, limits.Upload, limits.Add} {
+		acquire()()
+	}
+}
+
+func TestRunPlanPool(t *testing.T) {
+	var items []*planItem
+	for i := 0; i < 12; i++ {
+		items = append(items, &planItem{Path: fmt.Sprintf("file-%02d.md", i)})
+	}
+	failing := errors.New("failed")
+
+	tracker := &peakTracker{}
+	var done int32
+	results := runPlanPool(context.Background(), items, 3, func(item *planItem) error {
+		tracker.enter()
+		if item.Path == "file-05.md" {
+			return failing
+		}
+		return nil
+	}, func(res *fileResult) {
+		atomic.AddInt32(&done, 1)
+	})
+
+	if tracker.peak != 3 {
+		t.Fatalf("pool ran %d items at once, want 3", tracker.peak)
+	}
+	if done != int32(len(items)) {
+		t.Fatalf("onDone was called %d times, want %d", done, len(items))
+	}
+	// 结果与输入顺序一致
+	for i, res := range results {
+		if res.Item != items[i] || res.NotStarted {
+			t.Fatalf("result %d is for %s (not started %v), want %s", i, res.Item.Path, res.NotStarted, items[i].Path)
+		}
+		if (res.Err != nil) != (res.Item.Path == "file-05.md") {
+			t.Fatalf("result for %s has error %v", res.Item.Path, res.Err)
+		}
+	}
+}
+
+func TestRunPlanPoolCancel(t *testing.T) {
+	var items []*planItem
+	for i := 0; i < 5; i++ {
+		items = append(items, &planItem{Path: fmt.Sprintf("file-%d.md", i)})
+	}
+
+	// 第二个计划项处理时取消，之后最多再分发一个已经在等待的计划项
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var processed int32
+	results := runPlanPool(ctx, items, 1, func(item *planItem) error {
+		atomic.AddInt32(&processed, 1)
+		if item == items[1] {
+			cancel()
+		}
+		return nil
+	}, nil)
+
+	if processed < 2 || processed > 3 {
+		t.Fatalf("pool processed %d items around the cancel, want 2 or 3", processed)
+	}
+	for i, res := range results {
+		notStarted := int32(i) >= processed
+		if res.NotStarted != notStarted || (notStarted && !errors.Is(res.Err, context.Canceled)) {
+			t.Fatalf("result %d: not started %v, error %v, want not started %v", i, res.NotStarted, res.Err, notStarted)
+		}
+	}
+
+	// ctx 已经结束时不处理任何计划项
+	results = runPlanPool(ctx, items, 2, func(item *planItem) error {
+		t.Errorf("%s was processed after the cancel", item.Path)
+		return nil
+	}, nil)
+	for i, res := range results {
+		if !res.NotStarted {
+			t.Fatalf("result %d was started after the cancel", i)
+		}
+	}
+}
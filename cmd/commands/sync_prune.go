@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yaklang/yaklang/common/log"
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// 默认的删除上限，避免 --ext 写错或 --exclude 过宽时清空知识库
+const (
+	defaultMaxDelete        = 20
+	defaultMaxDeletePercent = 10.0
+)
+
+// pruneLimits 删除远程文件时的安全阈值，小于等于 0 表示不限制
+type pruneLimits struct {
+	MaxDelete        int
+	MaxDeletePercent float64
+}
+
+// checkPruneLimits 检查计划中的删除数量是否超过阈值，超过时返回错误
+func checkPruneLimits(plan *syncPlan, limits pruneLimits) error {
+	deleteCount := plan.Count(actionDeleteRemote)
+	if deleteCount == 0 {
+		return nil
+	}
+
+	if limits.MaxDelete > 0 && deleteCount > limits.MaxDelete {
+		return utils.Errorf("Refusing to delete %d remote files: exceeds --max-delete=%d", deleteCount, limits.MaxDelete)
+	}
+
+	if limits.MaxDeletePercent > 0 && plan.RemoteFiles > 0 {
+		percent := float64(deleteCount) * 100 / float64(plan.RemoteFiles)
+		if percent > limits.MaxDeletePercent {
+			return utils.Errorf("Refusing to delete %d of %d remote files (%.1f%%): exceeds --max-delete-percent=%.1f",
+				deleteCount, plan.RemoteFiles, percent, limits.MaxDeletePercent)
+		}
+	}
+	return nil
+}
+
+// confirmPrune 列出将被删除的远程文件，并在未指定 --yes 时请求用户确认
+func confirmPrune(plan *syncPlan, assumeYes bool) bool {
+	var deletions []*planItem
+	for _, item := range plan.Items {
+		if item.Action == actionDeleteRemote {
+			deletions = append(deletions, item)
+		}
+	}
+	if len(deletions) == 0 {
+		return true
+	}
+
+	consoleMu.Lock()
+	fmt.Printf("\nThe following %d remote files don't exist locally and will be deleted:\n", len(deletions))
+	fmt.Printf("%-60s %-40s\n", "Path", "File ID")
+	fmt.Println(strings.Repeat("-", 100))
+	for _, item := range deletions {
+		fmt.Printf("%-60s %-40s\n", item.Path, item.FileId)
+	}
+	fmt.Println()
+	consoleMu.Unlock()
+
+	if assumeYes {
+		log.Infof("--yes specified, deleting %d remote files without confirmation", len(deletions))
+		return true
+	}
+	return askForConfirmation(fmt.Sprintf("Delete these %d remote files?", len(deletions)))
+}
+
+// applyPruneGuardrails 执行计划前检查删除阈值并确认删除
+// 超过阈值时中止整个同步；用户拒绝删除时只跳过删除，其他动作照常执行
+func applyPruneGuardrails(plan *syncPlan, opts *syncOptions) error {
+	if plan.Count(actionDeleteRemote) == 0 {
+		return nil
+	}
+	if err := checkPruneLimits(plan, opts.PruneLimits); err != nil {
+		log.Errorf("%v", err)
+		log.Errorf("Check --ext and --exclude, or raise --max-delete / --max-delete-percent if the deletions are intended")
+		return err
+	}
+	if !confirmPrune(plan, opts.AssumeYes) {
+		log.Infof("Deletion cancelled, remote files that don't exist locally will be kept")
+		plan.RemoveAction(actionDeleteRemote)
+	}
+	return nil
+}
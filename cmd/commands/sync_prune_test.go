@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pruneTestPlan 返回包含 deletions 个删除项的计划，扫描的目录下共有 remoteFiles 个远程文件
+func pruneTestPlan(deletions, remoteFiles int) *syncPlan {
+	plan := &syncPlan{RemoteFiles: remoteFiles}
+	plan.Add(&planItem{Action: actionUploadNew, Path: "new.md"})
+	for i := 0; i < deletions; i++ {
+		plan.Add(&planItem{Action: actionDeleteRemote, Path: "deleted.md"})
+	}
+	return plan
+}
+
+func TestCheckPruneLimits(t *testing.T) {
+	defaults := pruneLimits{MaxDelete: defaultMaxDelete, MaxDeletePercent: defaultMaxDeletePercent}
+	cases := []struct {
+		name        string
+		deletions   int
+		remoteFiles int
+		limits      pruneLimits
+		wantErr     string
+	}{
+		{"no deletions", 0, 0, pruneLimits{MaxDelete: 1, MaxDeletePercent: 1}, ""},
+		{"at the count limit", 20, 1000, defaults, ""},
+		{"over the count limit", 21, 1000, defaults, "exceeds --max-delete=20"},
+		{"at the percent limit", 10, 100, defaults, ""},
+		{"over the percent limit", 11, 100, defaults, "exceeds --max-delete-percent=10.0"},
+		{"small directory over the percent limit", 1, 4, defaults, "1 of 4 remote files (25.0%)"},
+		{"count limit disabled", 500, 1000, pruneLimits{MaxDeletePercent: 60}, ""},
+		{"percent limit disabled", 4, 4, pruneLimits{MaxDelete: 20}, ""},
+		{"both limits disabled", 1000, 1000, pruneLimits{}, ""},
+		// 不知道远程文件总数时无法计算比例，只检查数量
+		{"unknown remote file count", 5, 0, defaults, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := checkPruneLimits(pruneTestPlan(c.deletions, c.remoteFiles), c.limits)
+			if c.wantErr == "" {
+				if err != nil {
+					t.Fatalf("checkPruneLimits: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Fatalf("checkPruneLimits returned %v, want an error containing %q", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyPruneGuardrails(t *testing.T) {
+	opts := &syncOptions{PruneLimits: pruneLimits{MaxDelete: 1}, AssumeYes: true}
+
+	// 超过阈值时中止整个同步，计划不变
+	plan := pruneTestPlan(2, 10)
+	if err := applyPruneGuardrails(plan, opts); err == nil {
+		t.Fatal("applyPruneGuardrails accepted deletions over the limit")
+	}
+	if n := plan.Count(actionDeleteRemote); n != 2 {
+		t.Fatalf("plan has %d deletions after the refused guardrail, want 2", n)
+	}
+
+	// 指定 --yes 时保留删除
+	plan = pruneTestPlan(1, 10)
+	captureStdout(t, func() {
+		if err := applyPruneGuardrails(plan, opts); err != nil {
+			t.Errorf("applyPruneGuardrails: %v", err)
+		}
+	})
+	if n := plan.Count(actionDeleteRemote); n != 1 {
+		t.Fatalf("plan has %d deletions after --yes, want 1", n)
+	}
+}
+
+func TestSyncPruneLimits(t *testing.T) {
+	_, configPath := newMockWorkspace(t)
+	client := mockClient(t, configPath)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.md": "a\n", "b.md": "b\n", "c.md": "c\n", "d.md": "d\n"})
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", ""); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	if err := os.Remove(filepath.Join(dir, "d.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 删除 4 个远程文件中的 1 个超过默认的 10%，同步被中止
+	err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", "", "--prune", "--yes")
+	if err == nil || !strings.Contains(err.Error(), "--max-delete-percent") {
+		t.Fatalf("sync --prune returned %v, want the percent limit error", err)
+	}
+	if names, _ := remoteState(t, client); len(names) != 4 {
+		t.Fatalf("remote files after the refused prune = %v, want all 4 kept", names)
+	}
+
+	err = runCommand(t, configPath, "sync", "--dir", dir, "--exclude", "", "--prune", "--yes", "--max-delete-percent", "0", "--max-delete", "0")
+	if err != nil {
+		t.Fatalf("sync --prune with the limits disabled: %v", err)
+	}
+	if names, _ := remoteState(t, client); len(names) != 3 || names["d.md"] {
+		t.Fatalf("remote files after the prune = %v, want d.md deleted", names)
+	}
+}
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"context"
+	"time"
+
+	"github.com/VillanCh/ragsync/common/backend"
+	"github.com/VillanCh/ragsync/common/syncstate"
+
+	"github.com/yaklang/yaklang/common/log"
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+const (
+	// renameIndexTimeout 重命名时等待新文件索引完成的最长时间，超时后保留旧文件
+	renameIndexTimeout = 10 * time.Minute
+	// renameIndexPollInterval 查询索引任务状态的间隔
+	renameIndexPollInterval = 10 * time.Second
+)
+
+// detectRenames 使用内容摘要把内容相同的"上传新文件"和"本地已不存在的远程文件"合并为一个 rename 动作
+// 候选的远程文件包括计划删除的文件和没有指定 --prune 而保留的文件，移动文件不需要 --prune
+// rename 会先上传并索引新文件，再删除旧文件，避免检索中出现内容缺失的窗口
+func detectRenames(plan *syncPlan, state *syncstate.Manifest) {
+	var candidates []*planItem
+	for _, item := range plan.Items {
+		if item.Action == actionDeleteRemote {
+			candidates = append(candidates, item)
+		}
+	}
+	candidates = append(candidates, plan.Missing...)
+
+	paired := make(map[*planItem]bool)
+	for _, item := range plan.Items {
+		if item.Action != actionUploadNew || len(paired) == len(candidates) {
+			continue
+		}
+		for _, candidate := range candidates {
+			if paired[candidate] {
+				continue
+			}
+			entry := state.Get(candidate.Path)
+			if entry == nil || entry.FileId != candidate.FileId || entry.Size != item.Size {
+				continue
+			}
+			if same, known := entry.SameContent(item.Digest); !known || !same {
+				continue
+			}
+
+			// 候选项标记为 rename，表示旧文件由重命名删除
+			paired[candidate] = true
+			candidate.Action = actionRename
+			item.Action = actionRename
+			item.OldPath = candidate.Path
+			item.FileId = candidate.FileId
+			item.Reason = "renamed from " + candidate.Path
+			log.Infof("[File: %s] Detected rename from %s (ID: %s)", item.Path, candidate.Path, candidate.FileId)
+			break
+		}
+	}
+
+	if len(paired) > 0 {
+		plan.Items = withoutPaired(plan.Items, paired)
+		plan.Missing = withoutPaired(plan.Missing, paired)
+	}
+	if len(plan.Missing) > 0 {
+		log.Infof("%d remote files don't exist locally, use --prune to delete them", len(plan.Missing))
+	}
+}
+
+// withoutPaired 移除已经合并为 rename 的计划项
+func withoutPaired(items []*planItem, paired map[*planItem]bool) []*planItem {
+	kept := items[:0]
+	for _, item := range items {
+		if !paired[item] {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// deleteRenamedSource 删除重命名前的远程文件及其索引文档
+func deleteRenamedSource(ctx context.Context, client backend.Backend, state *syncstate.Manifest, oldPath, oldFileId string) error {
+	err := client.DeleteFileExWithContext(ctx, oldFileId, false)
+	if remoteFileGone(err) {
+		log.Infof("[File: %s] Renamed remote file (ID: %s) no longer exists", oldPath, oldFileId)
+	} else if err != nil {
+		log.Errorf("[File: %s] Failed to delete renamed remote file (ID: %s): %v", oldPath, oldFileId, err)
+		return err
+	} else {
+		log.Infof("[File: %s] Deleted renamed remote file (ID: %s)", oldPath, oldFileId)
+	}
+	state.Remove(oldPath)
+	return nil
+}
+
+// waitForIndexJob 等待索引任务结束，任务成功完成时返回 nil
+func waitForIndexJob(ctx context.Context, client backend.Backend, jobId string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		job, err := client.IndexJobStatusWithContext(ctx, jobId)
+		if err != nil {
+			log.Warnf("Failed to query index job %s: %v", jobId, err)
+		} else {
+			switch job.Status {
+			case "FINISH", "COMPLETED":
+				return nil
+			case "FAILED", "DELETED":
+				return utils.Errorf("Index job %s ended with status %s", jobId, job.Status)
+			}
+			log.Infof("Index job %s status: %s, waiting...", jobId, job.Status)
+		}
+
+		if time.Now().After(deadline) {
+			return utils.Errorf("Timed out after %v waiting for index job %s", timeout, jobId)
+		}
+		select {
+		case <-time.After(renameIndexPollInterval):
+		case <-ctx.Done():
+			return utils.Errorf("Stopped waiting for index job %s: %v", jobId, ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncDetectsMoveWithoutPrune(t *testing.T) {
+	server, configPath := newMockWorkspace(t)
+	client := mockClient(t, configPath)
+
+	dir := filepath.Join(t.TempDir(), "docs")
+	writeFiles(t, dir, map[string]string{"guide.md": "# guide\n", "faq.md": "# faq\n"})
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", ""); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+
+	// 移动文件不需要 --prune，新旧路径被合并为一个 rename 动作
+	if err := os.MkdirAll(filepath.Join(dir, "manual"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "guide.md"), filepath.Join(dir, "manual", "guide.md")); err != nil {
+		t.Fatal(err)
+	}
+	plan := dryRunPlan(t, configPath, "--dir", dir, "--exclude", "")
+	if plan.Count(actionRename) != 1 || plan.Count(actionUploadNew) != 0 || plan.Count(actionDeleteRemote) != 0 {
+		t.Fatalf("plan = %s, want exactly one rename", plan.Summary())
+	}
+	for _, item := range plan.Items {
+		if item.Action == actionRename && filepath.Base(item.OldPath) != "guide.md" {
+			t.Fatalf("rename of %s comes from %s, want the old guide.md", item.Path, item.OldPath)
+		}
+	}
+
+	callsBefore := len(server.CallLog())
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", ""); err != nil {
+		t.Fatalf("sync after the move: %v", err)
+	}
+	names, documents := remoteState(t, client)
+	if len(names) != 2 || !names["guide.md"] || !names["faq.md"] || documents != 2 {
+		t.Fatalf("remote files = %v with %d index documents, want guide.md and faq.md", names, documents)
+	}
+	files, err := client.ListAllFiles("")
+	if err != nil {
+		t.Fatalf("ListAllFiles: %v", err)
+	}
+	for _, f := range files {
+		if filepath.Base(f.FileName) == "guide.md" && filepath.Base(filepath.Dir(f.FileName)) != "manual" {
+			t.Fatalf("remote file %s is still at the old path", f.FileName)
+		}
+	}
+
+	// 旧文件只在新文件的索引任务完成后删除
+	jobFinished := false
+	deletes := 0
+	for _, action := range server.CallLog()[callsBefore:] {
+		switch action {
+		case "GetIndexJobStatus":
+			jobFinished = true
+		case "DeleteFile", "DeleteIndexDocument":
+			if !jobFinished {
+				t.Fatalf("%s was called before the index job status was checked", action)
+			}
+			if action == "DeleteFile" {
+				deletes++
+			}
+		}
+	}
+	if deletes != 1 {
+		t.Fatalf("DeleteFile was called %d times, want 1", deletes)
+	}
+}
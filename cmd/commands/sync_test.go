@@ -0,0 +1,391 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/VillanCh/ragsync/common/aliyun"
+	"github.com/VillanCh/ragsync/common/backend"
+	"github.com/VillanCh/ragsync/common/bailianmock"
+	"github.com/VillanCh/ragsync/common/spec"
+	"github.com/VillanCh/ragsync/common/syncstate"
+)
+
+// newMockWorkspace 启动百炼模拟服务，在临时目录中写入指向它的配置文件，返回模拟服务和配置文件路径
+// HOME 指向临时目录，同步状态和索引任务记录不会写到真实的 ~/.ragsync 中
+func newMockWorkspace(t *testing.T) (*bailianmock.Server, string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	server := bailianmock.NewServer()
+	t.Cleanup(server.Close)
+
+	config := server.TestConfig("ws-test", server.AddIndex("docs"))
+	configPath := filepath.Join(home, "ragsync.yaml")
+	if err := spec.SaveConfig(config, configPath); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+	return server, configPath
+}
+
+// runCommand 以与 ragsync 相同的全局参数运行一个子命令
+func runCommand(t *testing.T, configPath string, args ...string) error {
+	t.Helper()
+	app := cli.NewApp()
+	app.Name = "ragsync"
+	app.Flags = append([]cli.Flag{
+		cli.StringFlag{Name: "config, c", Value: configPath},
+		cli.StringFlag{Name: "profile"},
+		cli.DurationFlag{Name: "timeout"},
+	}, OverrideFlags()...)
+	app.Commands = GetCommands()
+	return app.Run(append([]string{"ragsync"}, args...))
+}
+
+// loadMockConfig 读取 newMockWorkspace 写入的配置
+func loadMockConfig(t *testing.T, configPath string) *spec.Config {
+	t.Helper()
+	config, err := spec.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	return config
+}
+
+// loadMockState 读取 newMockWorkspace 对应的同步状态
+func loadMockState(t *testing.T, configPath string) *syncstate.Manifest {
+	t.Helper()
+	config := loadMockConfig(t, configPath)
+	state, err := syncstate.Load(config.BailianWorkspaceId, config.BailianKnowledgeIndexId)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return state
+}
+
+// mockClient 返回直接访问模拟服务的客户端，用于检查同步的结果
+func mockClient(t *testing.T, configPath string) *aliyun.BailianClient {
+	t.Helper()
+	client, err := aliyun.NewBailianClientFromConfig(loadMockConfig(t, configPath))
+	if err != nil {
+		t.Fatalf("NewBailianClientFromConfig: %v", err)
+	}
+	return client
+}
+
+// captureStdout 运行 fn 并返回其写到标准输出的内容
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		raw, _ := io.ReadAll(r)
+		output <- string(raw)
+	}()
+	fn()
+	w.Close()
+	return <-output
+}
+
+// dryRunPlan 以 --dry-run --plan-format json 运行 sync 并解析输出的计划
+func dryRunPlan(t *testing.T, configPath string, args ...string) *syncPlan {
+	t.Helper()
+	var runErr error
+	output := captureStdout(t, func() {
+		runErr = runCommand(t, configPath, append([]string{"sync", "--dry-run", "--plan-format", "json"}, args...)...)
+	})
+	if runErr != nil {
+		t.Fatalf("sync --dry-run: %v", runErr)
+	}
+	plan := &syncPlan{}
+	if err := json.Unmarshal([]byte(output), plan); err != nil {
+		t.Fatalf("failed to parse the dry-run plan %q: %v", output, err)
+	}
+	return plan
+}
+
+// writeFiles 在 dir 中写入文件，键为相对路径
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// remoteState 返回模拟服务中的文件名（不含目录）集合和索引文档数
+func remoteState(t *testing.T, client *aliyun.BailianClient) (map[string]bool, int) {
+	t.Helper()
+	files, err := client.ListAllFiles("")
+	if err != nil {
+		t.Fatalf("ListAllFiles: %v", err)
+	}
+	names := make(map[string]bool)
+	for _, f := range files {
+		names[filepath.Base(f.FileName)] = true
+	}
+	documents, err := client.ListAllIndexDocuments()
+	if err != nil {
+		t.Fatalf("ListAllIndexDocuments: %v", err)
+	}
+	return names, len(documents)
+}
+
+func TestSyncDirEndToEnd(t *testing.T) {
+	server, configPath := newMockWorkspace(t)
+	client := mockClient(t, configPath)
+
+	dir := filepath.Join(t.TempDir(), "docs")
+	writeFiles(t, dir, map[string]string{
+		"guide.md":       "# guide\n",
+		"faq.md":         "# faq\n",
+		"api/errors.txt": "error codes\n",
+		"notes.bin":      "not synced\n",
+	})
+
+	// 第一次同步上传所有匹配扩展名的文件并加入索引
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", ""); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	names, documents := remoteState(t, client)
+	if len(names) != 3 || !names["guide.md"] || !names["faq.md"] || !names["errors.txt"] || documents != 3 {
+		t.Fatalf("after the first sync remote files = %v with %d index documents, want guide.md, faq.md and errors.txt indexed", names, documents)
+	}
+	if calls := server.Calls("AddFile"); calls != 3 {
+		t.Fatalf("first sync called AddFile %d times, want 3", calls)
+	}
+
+	// 没有变化时不再上传
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", ""); err != nil {
+		t.Fatalf("unchanged sync: %v", err)
+	}
+	if calls := server.Calls("AddFile"); calls != 3 {
+		t.Fatalf("unchanged sync called AddFile %d more times, want none", calls-3)
+	}
+
+	// 修改的文件被替换（--force 不询问确认），远端仍然只有一份
+	writeFiles(t, dir, map[string]string{"guide.md": "# guide\n\nupdated\n"})
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", "", "--force"); err != nil {
+		t.Fatalf("sync after an edit: %v", err)
+	}
+	names, documents = remoteState(t, client)
+	if len(names) != 3 || documents != 3 {
+		t.Fatalf("after replacing guide.md remote files = %v with %d index documents, want 3 and 3", names, documents)
+	}
+	if calls := server.Calls("AddFile"); calls != 4 {
+		t.Fatalf("sync after an edit called AddFile %d times in total, want 4", calls)
+	}
+
+	// --prune 删除本地已经不存在的文件
+	if err := os.Remove(filepath.Join(dir, "faq.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", "", "--prune", "--yes", "--max-delete-percent", "0"); err != nil {
+		t.Fatalf("sync --prune: %v", err)
+	}
+	names, documents = remoteState(t, client)
+	if len(names) != 2 || names["faq.md"] || documents != 2 {
+		t.Fatalf("after pruning faq.md remote files = %v with %d index documents, want guide.md and errors.txt", names, documents)
+	}
+}
+
+func TestSyncRetriesInjectedFaults(t *testing.T) {
+	server, configPath := newMockWorkspace(t)
+	client := mockClient(t, configPath)
+
+	dir := filepath.Join(t.TempDir(), "docs")
+	writeFiles(t, dir, map[string]string{"a.md": "a\n", "b.md": "b\n"})
+
+	server.InjectFault("ApplyFileUploadLease", bailianmock.Throttling(1))
+	server.InjectFault(bailianmock.UploadAction, bailianmock.ServerError(1))
+	server.InjectFault("AddFile", bailianmock.Throttling(1))
+	server.InjectFault("SubmitIndexAddDocumentsJob", bailianmock.Throttling(1))
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", ""); err != nil {
+		t.Fatalf("sync with transient faults: %v", err)
+	}
+	names, documents := remoteState(t, client)
+	if len(names) != 2 || documents != 2 {
+		t.Fatalf("remote files = %v with %d index documents, want a.md and b.md indexed", names, documents)
+	}
+}
+
+func TestSyncReportsFailedFiles(t *testing.T) {
+	server, configPath := newMockWorkspace(t)
+
+	dir := filepath.Join(t.TempDir(), "docs")
+	writeFiles(t, dir, map[string]string{"a.md": "a\n"})
+
+	// AddFile 不是幂等的，5xx 不重试，文件同步失败
+	server.InjectFault("AddFile", bailianmock.ServerError(1))
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", ""); err == nil {
+		t.Fatal("sync succeeded although AddFile failed")
+	}
+
+	// 下一次同步重新上传失败的文件
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", ""); err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	names, documents := remoteState(t, mockClient(t, configPath))
+	if len(names) != 1 || !names["a.md"] || documents != 1 {
+		t.Fatalf("remote files = %v with %d index documents, want a.md indexed", names, documents)
+	}
+}
+
+// remoteFileId 返回模拟服务中名为 name（不含目录）的文件 ID
+func remoteFileId(t *testing.T, client *aliyun.BailianClient, name string) string {
+	t.Helper()
+	files, err := client.ListAllFiles("")
+	if err != nil {
+		t.Fatalf("ListAllFiles: %v", err)
+	}
+	for _, f := range files {
+		if filepath.Base(f.FileName) == name {
+			return f.FileId
+		}
+	}
+	t.Fatalf("no remote file named %s", name)
+	return ""
+}
+
+func TestSyncAfterDeleteCommand(t *testing.T) {
+	server, configPath := newMockWorkspace(t)
+	client := mockClient(t, configPath)
+
+	dir := filepath.Join(t.TempDir(), "docs")
+	writeFiles(t, dir, map[string]string{"a.md": "a\n", "b.md": "b\n"})
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", ""); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+
+	// ragsync delete 删除远程文件后同步状态中不再有这条记录，下一次同步重新上传
+	if err := runCommand(t, configPath, "delete", "--id", remoteFileId(t, client, "a.md"), "--force"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", ""); err != nil {
+		t.Fatalf("sync after delete: %v", err)
+	}
+	names, documents := remoteState(t, client)
+	if len(names) != 2 || !names["a.md"] || documents != 2 {
+		t.Fatalf("remote files = %v with %d index documents, want a.md uploaded again", names, documents)
+	}
+	if calls := server.Calls("AddFile"); calls != 3 {
+		t.Fatalf("AddFile was called %d times, want 3", calls)
+	}
+}
+
+func TestSyncAfterConsoleDelete(t *testing.T) {
+	server, configPath := newMockWorkspace(t)
+	client := mockClient(t, configPath)
+
+	dir := filepath.Join(t.TempDir(), "docs")
+	writeFiles(t, dir, map[string]string{"a.md": "a\n", "b.md": "b\n"})
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", ""); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+
+	// 在控制台中删除文件，同步状态不知道这次删除
+	if err := client.DeleteFile(remoteFileId(t, client, "a.md")); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	// 对账过期后下一次同步重新列出远程文件并上传缺少的文件
+	state := loadMockState(t, configPath)
+	state.RemoteSync = time.Now().Add(-2 * remoteStateMaxAge)
+	if err := state.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", ""); err != nil {
+		t.Fatalf("sync after the console delete: %v", err)
+	}
+	names, documents := remoteState(t, client)
+	if len(names) != 2 || !names["a.md"] || documents != 2 {
+		t.Fatalf("remote files = %v with %d index documents, want a.md uploaded again", names, documents)
+	}
+	if calls := server.Calls("AddFile"); calls != 3 {
+		t.Fatalf("AddFile was called %d times, want 3", calls)
+	}
+}
+
+func TestSyncFileAfterConsoleDelete(t *testing.T) {
+	_, configPath := newMockWorkspace(t)
+	client := mockClient(t, configPath)
+
+	dir := filepath.Join(t.TempDir(), "docs")
+	writeFiles(t, dir, map[string]string{"a.md": "a\n"})
+	filePath := filepath.Join(dir, "a.md")
+	if err := runCommand(t, configPath, "sync", "--file", filePath); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	if err := client.DeleteFile(remoteFileId(t, client, "a.md")); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+
+	// 单文件同步不会对账整个清单，跳过之前先确认远程文件仍然存在
+	if err := runCommand(t, configPath, "sync", "--file", filePath); err != nil {
+		t.Fatalf("sync after the console delete: %v", err)
+	}
+	names, documents := remoteState(t, client)
+	if len(names) != 1 || !names["a.md"] || documents != 1 {
+		t.Fatalf("remote files = %v with %d index documents, want a.md uploaded again", names, documents)
+	}
+}
+
+func TestSyncLocalBackend(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	config := spec.GetDefaultConfig()
+	config.Backend = "local"
+	config.LocalBackendDir = filepath.Join(home, "backend")
+	configPath := filepath.Join(home, "ragsync.yaml")
+	if err := spec.SaveConfig(&config, configPath); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "docs")
+	writeFiles(t, dir, map[string]string{"a.md": "a\n", "b.md": "b\n"})
+	if err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", ""); err != nil {
+		t.Fatalf("sync with the local backend: %v", err)
+	}
+
+	// backend: local 选择本地后端，没有配置的工作空间使用后端名称
+	client, err := backend.New(loadMockConfig(t, configPath))
+	if err != nil {
+		t.Fatalf("backend.New: %v", err)
+	}
+	if client.Name() != "local" {
+		t.Fatalf("config with backend: local created the %s backend", client.Name())
+	}
+	files, err := client.ListAllFilesWithContext(context.Background(), "")
+	if err != nil {
+		t.Fatalf("ListAllFiles: %v", err)
+	}
+	documents, err := client.ListAllIndexDocumentsWithContext(context.Background())
+	if err != nil {
+		t.Fatalf("ListAllIndexDocuments: %v", err)
+	}
+	if len(files) != 2 || len(documents) != 2 {
+		t.Fatalf("local backend has %d files and %d index documents, want 2 of each", len(files), len(documents))
+	}
+	if _, err := os.Stat(filepath.Join(home, "backend", "local", "store.json")); err != nil {
+		t.Fatalf("local backend store: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"context"
+	"time"
+
+	"github.com/VillanCh/ragsync/common/aliyun"
+	"github.com/VillanCh/ragsync/common/backend"
+
+	"github.com/yaklang/yaklang/common/log"
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// 百炼文件的解析状态
+const (
+	fileStatusParseSuccess = "PARSE_SUCCESS"
+	fileStatusParseFailed  = "PARSE_FAILED"
+)
+
+// maxUploadLeases 上传租约过期时最多申请的租约数（包括第一次）
+const maxUploadLeases = 3
+
+// defaultParseTimeout --wait-parse 等待单个文件解析完成的默认时间
+const defaultParseTimeout = 10 * time.Minute
+
+// parsePollInterval 等待文件解析时查询状态的间隔
+const parsePollInterval = 3 * time.Second
+
+// leaseAndUpload 申请上传租约并上传文件内容
+// 租约在上传前过期（例如等待上传并发额度的时间过长）时重新申请租约
+func leaseAndUpload(ctx context.Context, filePath string, client backend.Backend, content *backend.Content, opts *syncOptions) (*backend.UploadLease, error) {
+	for attempt := 1; ; attempt++ {
+		log.Infof("[File: %s] Applying for file upload lease", filePath)
+		releaseLease := opts.Stages.Lease()
+		lease, err := client.ApplyUploadLeaseWithContext(ctx, filePath, content)
+		releaseLease()
+		if err != nil {
+			log.Errorf("[File: %s] Failed to apply for upload lease: %v", filePath, err)
+			return nil, err
+		}
+		log.Infof("[File: %s] Upload lease acquired successfully", filePath)
+
+		log.Infof("[File: %s] Uploading file to URL: %s", filePath, lease.UploadURL)
+		log.Infof("[File: %s] Upload method: %s", filePath, lease.Method)
+		releaseUpload := opts.Stages.Upload()
+		err = client.UploadContentWithContext(ctx, lease, filePath, content)
+		releaseUpload()
+		if err == nil {
+			log.Infof("[File: %s] File content uploaded successfully", filePath)
+			return lease, nil
+		}
+		if !aliyun.IsLeaseExpired(err) || attempt >= maxUploadLeases {
+			log.Errorf("[File: %s] File upload failed: %v", filePath, err)
+			return nil, err
+		}
+		log.Warnf("[File: %s] Upload lease %s expired, applying for a new lease (%d/%d)", filePath, lease.LeaseId, attempt+1, maxUploadLeases)
+	}
+}
+
+// waitForFileParse 等待文件解析完成，解析失败时返回服务端给出的原因
+func waitForFileParse(ctx context.Context, client backend.Backend, filePath string, fileId string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		info, err := client.DescribeFileWithContext(ctx, fileId)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			log.Warnf("[File: %s] Failed to query parse status of file %s: %v", filePath, fileId, err)
+		} else {
+			switch info.Status {
+			case fileStatusParseSuccess:
+				log.Infof("[File: %s] File %s parsed successfully", filePath, fileId)
+				return nil
+			case fileStatusParseFailed:
+				reason := info.Message
+				if reason == "" {
+					reason = "no reason given"
+				}
+				log.Errorf("[File: %s] %s failed to parse file %s: %s", filePath, client.Name(), fileId, reason)
+				return utils.Errorf("Failed to parse %s (file ID: %s): %s", filePath, fileId, reason)
+			}
+			log.Infof("[File: %s] File %s status: %s, waiting for parsing to finish...", filePath, fileId, info.Status)
+		}
+
+		if time.Now().After(deadline) {
+			return utils.Errorf("Timed out after %v waiting for %s (file ID: %s) to be parsed", timeout, filePath, fileId)
+		}
+		select {
+		case <-time.After(parsePollInterval):
+		case <-ctx.Done():
+			return utils.Errorf("Stopped waiting for %s to be parsed: %v", filePath, ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/VillanCh/ragsync/common/aliyun"
+	"github.com/VillanCh/ragsync/common/backend"
+)
+
+// slowUpload 前 slow 次上传内容之前等待 delay，模拟等待上传并发额度时租约过期
+type slowUpload struct {
+	backend.Backend
+	slow    int32
+	delay   time.Duration
+	uploads int32
+}
+
+func (b *slowUpload) UploadContentWithContext(ctx context.Context, lease *backend.UploadLease, fileName string, content *backend.Content) error {
+	if atomic.AddInt32(&b.uploads, 1) <= b.slow {
+		time.Sleep(b.delay)
+	}
+	return b.Backend.UploadContentWithContext(ctx, lease, fileName, content)
+}
+
+func TestLeaseAndUploadRenewsExpiredLease(t *testing.T) {
+	server, configPath := newMockWorkspace(t)
+	server.LeaseTTL = 20 * time.Millisecond
+	filePath := filepath.Join(t.TempDir(), "a.md")
+	writeFiles(t, filepath.Dir(filePath), map[string]string{"a.md": "a\n"})
+	content, err := backend.FileContent(filePath)
+	if err != nil {
+		t.Fatalf("FileContent: %v", err)
+	}
+
+	// 第一个租约在上传前过期，重新申请租约后上传成功
+	client := &slowUpload{Backend: mockClient(t, configPath), slow: 1, delay: 40 * time.Millisecond}
+	lease, err := leaseAndUpload(context.Background(), filePath, client, content, &syncOptions{})
+	if err != nil {
+		t.Fatalf("leaseAndUpload: %v", err)
+	}
+	if calls := server.Calls("ApplyFileUploadLease"); calls != 2 {
+		t.Fatalf("ApplyFileUploadLease was called %d times, want 2", calls)
+	}
+	if _, err := client.AddFileWithContext(context.Background(), lease.LeaseId); err != nil {
+		t.Fatalf("AddFile with the renewed lease: %v", err)
+	}
+}
+
+func TestLeaseAndUploadGivesUp(t *testing.T) {
+	server, configPath := newMockWorkspace(t)
+	server.LeaseTTL = 10 * time.Millisecond
+	filePath := filepath.Join(t.TempDir(), "a.md")
+	writeFiles(t, filepath.Dir(filePath), map[string]string{"a.md": "a\n"})
+	content, err := backend.FileContent(filePath)
+	if err != nil {
+		t.Fatalf("FileContent: %v", err)
+	}
+
+	// 每个租约都过期时最多申请 maxUploadLeases 次
+	client := &slowUpload{Backend: mockClient(t, configPath), slow: maxUploadLeases, delay: 20 * time.Millisecond}
+	_, err = leaseAndUpload(context.Background(), filePath, client, content, &syncOptions{})
+	if !aliyun.IsLeaseExpired(err) {
+		t.Fatalf("leaseAndUpload returned %v, want a lease expired error", err)
+	}
+	if calls := server.Calls("ApplyFileUploadLease"); calls != maxUploadLeases {
+		t.Fatalf("ApplyFileUploadLease was called %d times, want %d", calls, maxUploadLeases)
+	}
+}
+
+func TestSyncWaitParseReportsFailure(t *testing.T) {
+	server, configPath := newMockWorkspace(t)
+	client := mockClient(t, configPath)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"good.md": "good\n", "broken.pdf": "not a pdf\n"})
+	server.FailParse(filepath.Join(dir, "broken.pdf"), "unsupported file format")
+
+	// --wait-parse 报告解析失败的原因，解析失败的文件不加入索引
+	err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", "", "--ext", ".md,.pdf", "--wait-parse")
+	if err == nil || !strings.Contains(err.Error(), "1 files failed to sync") {
+		t.Fatalf("sync --wait-parse returned %v, want one failed file", err)
+	}
+	if _, documents := remoteState(t, client); documents != 1 {
+		t.Fatalf("index has %d documents, want only good.md", documents)
+	}
+	entry := loadMockState(t, configPath).Get(filepath.Join(dir, "broken.pdf"))
+	if entry == nil || entry.FileId == "" {
+		t.Fatalf("sync state entry of the unparsable file = %+v, want it kept so the same content is not uploaded again", entry)
+	}
+}
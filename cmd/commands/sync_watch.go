@@ -0,0 +1,338 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/fsnotify.v1"
+
+	"github.com/VillanCh/ragsync/common/backend"
+	"github.com/VillanCh/ragsync/common/spec"
+	"github.com/VillanCh/ragsync/common/syncstate"
+
+	"github.com/yaklang/yaklang/common/log"
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+const (
+	// defaultWatchDebounce 最后一次文件变化后等待多久再开始同步，用于合并连续的编辑
+	defaultWatchDebounce = 2 * time.Second
+	// watchDrainTimeout 收到 Ctrl+C 或 --timeout 到期后，等待正在进行的一轮同步完成的最长时间
+	watchDrainTimeout = 30 * time.Second
+)
+
+// syncWatcher 监听本地文件变化，并把变化的文件交给同步计划执行
+type syncWatcher struct {
+	watcher        *fsnotify.Watcher
+	roots          []syncRoot
+	client         backend.Backend
+	config         *spec.Config
+	state          *syncstate.Manifest
+	opts           *syncOptions
+	indexBatchSize int
+	debounce       time.Duration
+
+	// pending 等待同步的路径（文件或目录），同一路径的多次变化只处理一次
+	pending map[string]struct{}
+}
+
+// watchAndSync 持续监听本地文件变化并同步，ctx 结束（Ctrl+C 或 --timeout 到期）后不再开始新的一轮
+// 正在进行的一轮最多再运行 watchDrainTimeout，以便提交已上传文件的索引并保存同步状态
+func watchAndSync(ctx context.Context, roots []syncRoot, client backend.Backend, config *spec.Config, state *syncstate.Manifest, opts *syncOptions, indexBatchSize int, debounce time.Duration) error {
+	if len(roots) == 0 {
+		return utils.Errorf("Nothing to watch: specify --file, --dir or include_paths")
+	}
+	if debounce <= 0 {
+		debounce = defaultWatchDebounce
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return utils.Errorf("Failed to create file watcher: %v", err)
+	}
+	defer watcher.Close()
+
+	w := &syncWatcher{
+		watcher:        watcher,
+		roots:          roots,
+		client:         client,
+		config:         config,
+		state:          state,
+		opts:           opts,
+		indexBatchSize: indexBatchSize,
+		debounce:       debounce,
+		pending:        make(map[string]struct{}),
+	}
+
+	for _, root := range roots {
+		if root.IsDir {
+			if err := w.addDir(root, root.Path); err != nil {
+				return err
+			}
+			continue
+		}
+		// 单个文件监听其所在目录，这样编辑器"写临时文件再重命名"的保存方式也能被捕获
+		if err := watcher.Add(filepath.Dir(root.Path)); err != nil {
+			return utils.Errorf("Failed to watch %s: %v", root.Path, err)
+		}
+		log.Infof("[Watch] Watching file: %s", root.Path)
+	}
+
+	// 初始同步中加入的文件先提交索引
+	roundCtx, stop := drainContext(ctx)
+	w.finishRound(roundCtx)
+	stop()
+
+	log.Infof("[Watch] Watching for changes (debounce: %v), press Ctrl+C to stop", debounce)
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if len(w.pending) > 0 {
+				log.Warnf("[Watch] %d pending changes were not synced, they will be picked up by the next sync", len(w.pending))
+			}
+			log.Infof("[Watch] Stopped")
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if w.handleEvent(event) {
+				// 每次变化都重新计时，连续的编辑会被合并为一次同步
+				timer.Reset(debounce)
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Warnf("[Watch] File watcher error: %v", err)
+
+		case <-timer.C:
+			w.flush(ctx)
+		}
+	}
+}
+
+// addDir 递归监听目录及其所有未被忽略的子目录
+func (w *syncWatcher) addDir(root syncRoot, dirPath string) error {
+	return filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			log.Warnf("[Watch] Failed to access %s: %v", path, err)
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != root.Path && root.Filter.SkipDir(path) {
+			return filepath.SkipDir
+		}
+		if err := w.watcher.Add(path); err != nil {
+			return utils.Errorf("Failed to watch directory %s: %v", path, err)
+		}
+		log.Infof("[Watch] Watching directory: %s", path)
+		return nil
+	})
+}
+
+// handleEvent 记录一次文件变化，返回该变化是否需要同步
+func (w *syncWatcher) handleEvent(event fsnotify.Event) bool {
+	path := syncstate.NormalizePath(event.Name)
+
+	// 新建的目录需要加入监听，其中已有的文件也需要同步
+	if event.Op&fsnotify.Create == fsnotify.Create {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			root, ok := w.rootOf(path)
+			if !ok || !root.IsDir || !root.Filter.DirAllowed(path) {
+				return false
+			}
+			if err := w.addDir(root, path); err != nil {
+				log.Warnf("[Watch] %v", err)
+			}
+			w.pending[path] = struct{}{}
+			return true
+		}
+	}
+
+	// 删除或重命名的目录：旧路径下的所有文件都视为已删除
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 && len(w.syncedUnder(path)) > 0 {
+		w.pending[path] = struct{}{}
+		return true
+	}
+
+	if !w.matches(path) {
+		return false
+	}
+	log.Debugf("[Watch] %s", event)
+	w.pending[path] = struct{}{}
+	return true
+}
+
+// rootOf 返回路径所属的监听根路径
+func (w *syncWatcher) rootOf(path string) (syncRoot, bool) {
+	for _, root := range w.roots {
+		rootPath := syncstate.NormalizePath(root.Path)
+		if !root.IsDir {
+			if path == rootPath {
+				return root, true
+			}
+			continue
+		}
+		if rootPath == "." || path == rootPath || strings.HasPrefix(path, rootPath+"/") {
+			return root, true
+		}
+	}
+	return syncRoot{}, false
+}
+
+// matches 判断文件是否在同步范围内
+func (w *syncWatcher) matches(path string) bool {
+	root, ok := w.rootOf(path)
+	if !ok {
+		return false
+	}
+	if !root.IsDir {
+		return true
+	}
+	return root.Filter.AllowTree(path)
+}
+
+// syncedUnder 返回路径下在同步范围内且已上传的同步状态记录
+// 清单中还有其他根路径或被过滤的文件，删除事件不能影响它们
+func (w *syncWatcher) syncedUnder(path string) []*syncstate.Entry {
+	var entries []*syncstate.Entry
+	for _, entry := range w.state.EntriesUnder(path) {
+		if entry.FileId != "" && w.matches(entry.LocalPath) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// drainContext 返回不随 ctx 取消的上下文，ctx 结束后最多再等待 watchDrainTimeout 才取消
+// stop 在这一轮结束后调用，释放等待的 goroutine
+func drainContext(ctx context.Context) (context.Context, func()) {
+	roundCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			log.Warnf("[Watch] Stopping, waiting up to %v for the current round to finish (press Ctrl+C again to quit immediately)", watchDrainTimeout)
+			select {
+			case <-time.After(watchDrainTimeout):
+				log.Warnf("[Watch] The current round did not finish within %v, cancelling it", watchDrainTimeout)
+				cancel()
+			case <-done:
+			}
+		case <-done:
+		}
+	}()
+	return roundCtx, func() {
+		close(done)
+		cancel()
+	}
+}
+
+// flush 为所有等待同步的路径生成计划并执行，ctx 已结束时不再开始新的一轮
+func (w *syncWatcher) flush(ctx context.Context) {
+	if len(w.pending) == 0 || ctx.Err() != nil {
+		return
+	}
+	ctx, stop := drainContext(ctx)
+	defer stop()
+
+	paths := make([]string, 0, len(w.pending))
+	for path := range w.pending {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	w.pending = make(map[string]struct{})
+
+	log.Infof("[Watch] Syncing %d changed paths", len(paths))
+
+	plan := &syncPlan{}
+	planned := make(map[string]bool)
+	addFile := func(path string) {
+		if planned[path] {
+			return
+		}
+		planned[path] = true
+		item, err := planFileUpload(ctx, path, w.client, w.state, w.opts)
+		if err != nil {
+			log.Warnf("[Watch] Failed to plan file %s: %v", path, err)
+			return
+		}
+		plan.Add(item)
+	}
+	addDeleted := func(entry *syncstate.Entry) {
+		if planned[entry.LocalPath] {
+			return
+		}
+		planned[entry.LocalPath] = true
+		plan.AddMissing(&planItem{
+			Path:   entry.LocalPath,
+			Root:   entry.LocalPath,
+			FileId: entry.FileId,
+			Reason: "removed locally",
+		}, w.opts.Prune)
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			// 文件或目录已被删除或重命名
+			for _, entry := range w.syncedUnder(path) {
+				addDeleted(entry)
+			}
+		case info.IsDir():
+			// 新建或移入的目录：同步其中的所有文件
+			filepath.Walk(path, func(filePath string, fileInfo os.FileInfo, err error) error {
+				if err == nil && !fileInfo.IsDir() {
+					filePath = syncstate.NormalizePath(filePath)
+					if w.matches(filePath) {
+						addFile(filePath)
+					}
+				}
+				return nil
+			})
+		default:
+			addFile(path)
+		}
+	}
+
+	for _, root := range w.roots {
+		plan.RemoteFiles += len(w.state.EntriesUnder(root.Path))
+	}
+
+	detectRenames(plan, w.state)
+	log.Infof("[Watch] Sync plan: %s", plan.Summary())
+	if err := applyPruneGuardrails(plan, w.opts); err != nil {
+		log.Errorf("[Watch] Skipping this round: %v", err)
+		return
+	}
+	if err := executeSyncPlan(ctx, plan, w.client, w.config, w.state, w.opts); err != nil {
+		log.Errorf("[Watch] %v", err)
+	}
+	w.finishRound(ctx)
+}
+
+// finishRound 提交本轮加入的索引批次并保存同步状态
+func (w *syncWatcher) finishRound(ctx context.Context) {
+	if w.opts.IndexBatch != nil {
+		if err := submitIndexBatches(ctx, w.client, w.state, w.opts.IndexBatch, w.indexBatchSize); err != nil {
+			log.Errorf("[Watch] %v", err)
+		}
+	}
+	if err := w.state.Save(); err != nil {
+		log.Errorf("[Watch] Failed to save sync state: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VillanCh/ragsync/common/syncstate"
+)
+
+func TestSyncWatchPruneRequiresYes(t *testing.T) {
+	server, configPath := newMockWorkspace(t)
+	dir := filepath.Join(t.TempDir(), "docs")
+	writeFiles(t, dir, map[string]string{"a.md": "a\n"})
+
+	err := runCommand(t, configPath, "sync", "--dir", dir, "--exclude", "", "--watch", "--prune")
+	if err == nil || !strings.Contains(err.Error(), "--yes") {
+		t.Fatalf("sync --watch --prune returned %v, want an error asking for --yes", err)
+	}
+	if calls := server.Calls("AddFile"); calls != 0 {
+		t.Fatalf("AddFile was called %d times before the flags were rejected, want 0", calls)
+	}
+}
+
+func TestWatchSyncedUnderScopesEntries(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	base := t.TempDir()
+	dir := syncstate.NormalizePath(filepath.Join(base, "docs"))
+	writeFiles(t, dir, map[string]string{"keep.txt": "keep\n"})
+
+	filter, err := newPathFilter(dir, true, newFilterSettings([]string{".md"}, []string{"drafts/**"}, nil, false))
+	if err != nil {
+		t.Fatalf("newPathFilter: %v", err)
+	}
+	state := syncstate.New("ws-test", "idx-test")
+	for _, path := range []string{
+		dir + "/guide.md",        // 在同步范围内
+		dir + "/api/errors.md",   // 在同步范围内
+		dir + "/drafts/wip.md",   // 被 --exclude 排除
+		dir + "/notes.txt",       // 扩展名不匹配
+		base + "/docs-old/a.md",  // 不在同步根路径下
+		dir + "/pending/note.md", // 还没有上传
+	} {
+		entry := &syncstate.Entry{LocalPath: path, FileId: "id-" + filepath.Base(path)}
+		if strings.Contains(path, "pending") {
+			entry.FileId = ""
+		}
+		state.Put(entry)
+	}
+
+	w := &syncWatcher{roots: []syncRoot{{Path: dir, IsDir: true, Filter: filter}}, state: state}
+	var paths []string
+	for _, entry := range w.syncedUnder(dir) {
+		paths = append(paths, entry.LocalPath)
+	}
+	want := []string{dir + "/api/errors.md", dir + "/guide.md"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Fatalf("syncedUnder(%s) = %v, want %v", dir, paths, want)
+	}
+	if entries := w.syncedUnder(base + "/docs-old"); len(entries) != 0 {
+		t.Fatalf("syncedUnder outside the watched roots returned %d entries, want 0", len(entries))
+	}
+}
+
+func TestDrainContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	roundCtx, stop := drainContext(ctx)
+
+	// 中断后正在进行的一轮继续运行
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	if err := roundCtx.Err(); err != nil {
+		t.Fatalf("round context was cancelled together with the watch context: %v", err)
+	}
+	stop()
+	if roundCtx.Err() == nil {
+		t.Fatal("round context is still active after stop")
+	}
+}
+
+func TestWatchFlushSkipsNewRoundAfterCancel(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := filepath.Join(t.TempDir(), "docs")
+	writeFiles(t, dir, map[string]string{"a.md": "a\n"})
+
+	// 没有客户端，开始新的一轮会直接失败
+	w := &syncWatcher{
+		state:   syncstate.New("ws-test", "idx-test"),
+		opts:    &syncOptions{},
+		pending: map[string]struct{}{syncstate.NormalizePath(filepath.Join(dir, "a.md")): {}},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w.flush(ctx)
+	if len(w.pending) != 1 {
+		t.Fatalf("flush after cancellation dropped the pending changes, %d left", len(w.pending))
+	}
+}
@@ -18,8 +18,13 @@ func ValidateConfigCommand() cli.Command {
 		Name:    "validate",
 		Aliases: []string{"check"},
 		Usage:   "Validate configuration file and exit with non-zero code if invalid",
-		Flags:   []cli.Flag{},
-		Action:  executeValidateConfig,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "online",
+				Usage: "Also check credentials, workspace, category, index and API permissions against Bailian",
+			},
+		},
+		Action: executeValidateConfig,
 	}
 }
 
@@ -33,30 +38,98 @@ func executeValidateConfig(c *cli.Context) error {
 	}
 
 	log.Infof("Validating configuration file: %s", configPath)
-	config, err := spec.LoadConfig(configPath)
+	profile := c.GlobalString("profile")
+	if profile != "" {
+		if !validateProfile(c, configPath, profile) {
+			os.Exit(1)
+		}
+		log.Info("Configuration is valid")
+		return nil
+	}
+
+	raw, err := spec.ReadConfig(configPath)
 	if err != nil {
 		log.Errorf("Failed to load configuration file: %v", err)
 		os.Exit(1)
 		return nil
 	}
 
-	if err := config.Validate(); err != nil {
-		log.Errorf("Invalid configuration: %v", err)
+	// 没有指定档案时验证顶层配置（或 default_profile）以及配置文件中的所有档案
+	// 有档案但没有 default_profile 时，顶层配置只是档案共用的默认值，可以不完整
+	failed := 0
+	if len(raw.Profiles) == 0 || raw.DefaultProfile != "" {
+		if !validateProfile(c, configPath, "") {
+			failed++
+		}
+	}
+	for _, name := range raw.ProfileNames() {
+		if name == raw.DefaultProfile {
+			continue
+		}
+		fmt.Println()
+		if !validateProfile(c, configPath, name) {
+			failed++
+		}
+	}
+	if failed > 0 {
 		os.Exit(1)
 		return nil
 	}
 
+	log.Info("Configuration is valid")
+	return nil
+}
+
+// validateProfile 验证使用档案 profile 的配置并输出配置详情，profile 为空时使用 default_profile
+// 指定 --online 时还会访问百炼进行在线检查，有检查失败时返回 false
+func validateProfile(c *cli.Context, configPath string, profile string) bool {
+	config, err := spec.LoadConfigWithOptions(configPath, loadOptions(c, profile))
+	if err != nil {
+		if profile != "" {
+			log.Errorf("Failed to load profile %s: %v", profile, err)
+		} else {
+			log.Errorf("Failed to load configuration file: %v", err)
+		}
+		return false
+	}
+
+	if err := config.Validate(); err != nil {
+		log.Errorf("Invalid configuration: %v", err)
+		return false
+	}
+
 	// 输出具体的配置信息
 	fmt.Println("配置验证成功！配置详情：")
+	if config.Profile != "" {
+		fmt.Printf("Profile: %s\n", config.Profile)
+	}
+	fmt.Printf("Backend: %s\n", backendName(config))
+	if config.UsesBailian() {
+		fmt.Printf("Credential Type: %s\n", config.ResolvedCredentialType())
+	}
 	fmt.Printf("Aliyun Access Key: %s\n", maskSensitiveString(config.AliyunAccessKey))
 	fmt.Printf("Bailian Endpoint: %s\n", config.BailianEndpoint)
 	fmt.Printf("Bailian Workspace ID: %s\n", config.BailianWorkspaceId)
+	fmt.Printf("Bailian Knowledge Index ID: %s\n", config.BailianKnowledgeIndexId)
 	fmt.Printf("Bailian Category Type: %s\n", config.BailianCategoryType)
 	fmt.Printf("Bailian File Parser: %s\n", config.BailianAddFileParser)
 	fmt.Printf("Bailian Default Category ID: %s\n", config.BailianFilesDefaultCategoryId)
 
-	log.Info("Configuration is valid")
-	return nil
+	if !c.Bool("online") {
+		return true
+	}
+	fmt.Println()
+	if !config.UsesBailian() {
+		fmt.Printf("Online checks skipped: the %s backend does not use Bailian\n", backendName(config))
+		return true
+	}
+	ctx, cancel := commandContext(c)
+	defer cancel()
+	if printOnlineChecks(runOnlineChecks(ctx, config)) > 0 {
+		log.Error("Online checks failed")
+		return false
+	}
+	return true
 }
 
 // maskSensitiveString 脱敏敏感字符串
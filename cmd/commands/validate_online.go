@@ -0,0 +1,252 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/VillanCh/ragsync/common/aliyun"
+	"github.com/VillanCh/ragsync/common/backend"
+	"github.com/VillanCh/ragsync/common/spec"
+)
+
+// 在线检查的结果
+const (
+	checkPass = "PASS"
+	checkFail = "FAIL"
+	checkWarn = "WARN"
+	checkSkip = "SKIP"
+)
+
+// probeId 权限探测使用的不存在的租约、文件和任务 ID，探测调用因资源不存在被拒绝时说明有权限
+const probeId = "ragsync-validate-probe"
+
+// onlineCheck validate --online 中的一项检查
+type onlineCheck struct {
+	Name      string
+	Status    string
+	Detail    string
+	Hint      string // 修复建议
+	Recommend string // 百炼返回的诊断建议
+}
+
+// checkFromError 根据接口返回的错误生成失败的检查项，hint 为修复建议
+func checkFromError(name string, err error, hint string) onlineCheck {
+	check := onlineCheck{Name: name, Status: checkFail, Detail: err.Error(), Hint: hint}
+	var apiErr *aliyun.APIError
+	if errors.As(err, &apiErr) {
+		check.Recommend = apiErr.Recommend
+	}
+	return check
+}
+
+// probeCheck 权限探测的结果：调用成功或因参数错误、资源不存在被拒绝都说明 RAM 用户有该接口的权限
+func probeCheck(action string, err error) onlineCheck {
+	name := "Permission " + action
+	hint := fmt.Sprintf("Grant the RAM user access to the Bailian API %s (for example with the AliyunBailianDataFullAccess system policy) and make sure it is a member of the workspace in the Bailian console", action)
+	if err == nil {
+		return onlineCheck{Name: name, Status: checkPass, Detail: "allowed"}
+	}
+	if aliyun.IsAuthFailure(err) {
+		return checkFromError(name, err, hint)
+	}
+	var apiErr *aliyun.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError {
+		detail := "allowed (probe rejected as expected"
+		if apiErr.Code != "" {
+			detail += ": " + apiErr.Code
+		}
+		return onlineCheck{Name: name, Status: checkPass, Detail: detail + ")"}
+	}
+	return checkFromError(name, err, "The probe did not reach a conclusion, check the network and the endpoint and run the check again")
+}
+
+// runOnlineChecks 使用配置访问百炼，检查凭据、工作空间、分类和索引以及 sync 需要的接口权限
+// 权限通过对不存在的资源调用接口来探测，不会创建文件或修改索引（申请的上传租约不上传内容，会自动过期）
+func runOnlineChecks(ctx context.Context, config *spec.Config) []onlineCheck {
+	var checks []onlineCheck
+	skipRest := func(reason string, names ...string) []onlineCheck {
+		for _, name := range names {
+			checks = append(checks, onlineCheck{Name: name, Status: checkSkip, Detail: reason})
+		}
+		return checks
+	}
+	probeNames := []string{"Permission ApplyFileUploadLease", "Permission AddFile", "Permission DeleteFile", "Permission SubmitIndexAddDocumentsJob", "Permission GetIndexJobStatus", "Permission DeleteIndexDocument"}
+
+	// 凭据
+	credential, err := aliyun.NewCredential(config)
+	if err == nil {
+		_, err = credential.GetCredential()
+	}
+	if err != nil {
+		checks = append(checks, checkFromError("Credentials", err, "Check credential_type and its settings, or run 'ragsync config show --resolved' to see which values are used"))
+		return skipRest("credentials are not available", append([]string{"Workspace", "Category", "Index", "Category and index linked"}, probeNames...)...)
+	}
+	checks = append(checks, onlineCheck{Name: "Credentials", Status: checkPass, Detail: fmt.Sprintf("%s from %s", credential.Type, credential.Source)})
+
+	client, err := aliyun.NewBailianClientFromConfig(config)
+	if err != nil {
+		checks = append(checks, checkFromError("Workspace", err, "Check aliyun_bailian_endpoint"))
+		return skipRest("no client", append([]string{"Category", "Index", "Category and index linked"}, probeNames...)...)
+	}
+
+	// 工作空间：能列出其中的分类说明工作空间存在且凭据有效
+	categories, err := client.ListCategoriesWithContext(ctx)
+	if err != nil {
+		hint := fmt.Sprintf("Check bailian_workspace_id (%s) and aliyun_bailian_endpoint in https://bailian.console.aliyun.com", config.BailianWorkspaceId)
+		if aliyun.IsAuthFailure(err) {
+			hint = fmt.Sprintf("Check the access key, and that the RAM user is allowed to call ListCategory and is a member of workspace %s in the Bailian console", config.BailianWorkspaceId)
+		}
+		checks = append(checks, checkFromError("Workspace", err, hint))
+		return skipRest("workspace is not accessible", append([]string{"Category", "Index", "Category and index linked"}, probeNames...)...)
+	}
+	checks = append(checks, onlineCheck{Name: "Workspace", Status: checkPass, Detail: fmt.Sprintf("%s (%d categories)", config.BailianWorkspaceId, len(categories))})
+
+	// 分类
+	categoryFound := false
+	var categoryIds []string
+	for _, cat := range categories {
+		categoryIds = append(categoryIds, cat.CategoryId)
+		if cat.CategoryId == config.BailianFilesDefaultCategoryId {
+			categoryFound = true
+			checks = append(checks, onlineCheck{Name: "Category", Status: checkPass, Detail: fmt.Sprintf("%s (%s)", cat.CategoryId, cat.CategoryName)})
+		}
+	}
+	if !categoryFound {
+		if len(categoryIds) > 5 {
+			categoryIds = append(categoryIds[:5], "...")
+		}
+		checks = append(checks, onlineCheck{
+			Name:   "Category",
+			Status: checkFail,
+			Detail: fmt.Sprintf("bailian_files_default_category_id %s not found in workspace %s", config.BailianFilesDefaultCategoryId, config.BailianWorkspaceId),
+			Hint:   fmt.Sprintf("Set bailian_files_default_category_id to one of: %s, or create one with 'ragsync init --create-category NAME'", strings.Join(categoryIds, ", ")),
+		})
+	}
+
+	// 索引：列出索引中的文档，索引不存在时返回 NotFound
+	documents, _, err := client.ListIndexDocumentsWithContext(ctx, 1, 50)
+	indexFound := err == nil
+	switch {
+	case err == nil:
+		checks = append(checks, onlineCheck{Name: "Index", Status: checkPass, Detail: config.BailianKnowledgeIndexId})
+	case aliyun.IsNotFound(err):
+		checks = append(checks, checkFromError("Index", err, fmt.Sprintf("bailian_knowledge_index_id %s does not exist in workspace %s, create one with 'ragsync init --create-index NAME'", config.BailianKnowledgeIndexId, config.BailianWorkspaceId)))
+	default:
+		checks = append(checks, checkFromError("Index", err, "Check bailian_knowledge_index_id and that the RAM user is allowed to call ListIndexDocuments"))
+	}
+
+	// 分类和索引：抽样检查索引中的文档是否来自该分类
+	switch {
+	case !categoryFound || !indexFound:
+		checks = append(checks, onlineCheck{Name: "Category and index linked", Status: checkSkip, Detail: "category or index not found"})
+	case len(documents) == 0:
+		checks = append(checks, onlineCheck{Name: "Category and index linked", Status: checkPass, Detail: "index has no documents yet"})
+	default:
+		linked, err := countDocumentsInCategory(ctx, client, documents)
+		if err != nil {
+			checks = append(checks, checkFromError("Category and index linked", err, "Check that the RAM user is allowed to call ListFile"))
+			break
+		}
+		if linked > 0 {
+			checks = append(checks, onlineCheck{Name: "Category and index linked", Status: checkPass, Detail: fmt.Sprintf("%d of %d sampled index documents are in category %s", linked, len(documents), config.BailianFilesDefaultCategoryId)})
+		} else {
+			checks = append(checks, onlineCheck{
+				Name:   "Category and index linked",
+				Status: checkWarn,
+				Detail: fmt.Sprintf("none of %d sampled index documents are in category %s", len(documents), config.BailianFilesDefaultCategoryId),
+				Hint:   "sync uploads files to bailian_files_default_category_id and adds them to bailian_knowledge_index_id, check that the two belong together",
+			})
+		}
+	}
+
+	// 权限探测
+	probe := backend.BytesContent([]byte("ragsync validate probe\n"))
+	_, err = client.ApplyFileUploadLeaseWithContext(ctx, probeId+".txt", probe)
+	if err != nil && !aliyun.IsAuthFailure(err) {
+		// 申请租约应当成功，其他错误不能说明有权限
+		checks = append(checks, checkFromError("Permission ApplyFileUploadLease", err, "Check bailian_files_default_category_id and the network, then run the check again"))
+	} else {
+		checks = append(checks, probeCheck("ApplyFileUploadLease", err))
+	}
+	_, err = client.AddFileWithContext(ctx, probeId)
+	checks = append(checks, probeCheck("AddFile", err))
+	err = client.DeleteFileExWithContext(ctx, probeId, true)
+	checks = append(checks, probeCheck("DeleteFile", err))
+	if indexFound {
+		_, err = client.AppendDocumentsToIndexWithContext(ctx, []string{probeId})
+		checks = append(checks, probeCheck("SubmitIndexAddDocumentsJob", err))
+		_, err = client.GetIndexJobStatusWithContext(ctx, probeId)
+		checks = append(checks, probeCheck("GetIndexJobStatus", err))
+		err = client.DeleteIndexDocumentWithContext(ctx, probeId)
+		checks = append(checks, probeCheck("DeleteIndexDocument", err))
+	} else {
+		skipRest("index not found", "Permission SubmitIndexAddDocumentsJob", "Permission GetIndexJobStatus", "Permission DeleteIndexDocument")
+	}
+	return checks
+}
+
+// countDocumentsInCategory 分页列出分类中的文件，返回抽样的索引文档中有多少来自该分类，全部找到后不再继续分页
+func countDocumentsInCategory(ctx context.Context, client *aliyun.BailianClient, documents []*aliyun.IndexDocumentRecord) (int, error) {
+	sampled := make(map[string]bool, len(documents))
+	for _, doc := range documents {
+		sampled[doc.DocumentId] = true
+	}
+
+	linked := 0
+	nextToken := ""
+	for {
+		files, err := client.ListFileWithContext(ctx, 100, nextToken, "")
+		if err != nil {
+			return 0, err
+		}
+		for _, f := range files.Files {
+			if sampled[f.FileId] {
+				delete(sampled, f.FileId)
+				linked++
+			}
+		}
+		if len(sampled) == 0 || files.NextToken == "" {
+			return linked, nil
+		}
+		nextToken = files.NextToken
+	}
+}
+
+// printOnlineChecks 输出检查清单，返回失败的项数
+func printOnlineChecks(checks []onlineCheck) int {
+	fmt.Println("在线检查：")
+	failed, warned := 0, 0
+	for _, check := range checks {
+		fmt.Printf("  [%s] %s: %s\n", check.Status, check.Name, check.Detail)
+		if check.Status != checkPass && check.Status != checkSkip {
+			if check.Hint != "" {
+				fmt.Printf("         hint: %s\n", check.Hint)
+			}
+			if check.Recommend != "" {
+				fmt.Printf("         recommend: %s\n", check.Recommend)
+			}
+		}
+		switch check.Status {
+		case checkFail:
+			failed++
+		case checkWarn:
+			warned++
+		}
+	}
+	fmt.Printf("Online checks: %d passed, %d failed, %d warnings\n", len(checks)-failed-warned-countSkipped(checks), failed, warned)
+	return failed
+}
+
+// countSkipped 返回跳过的检查项数
+func countSkipped(checks []onlineCheck) int {
+	skipped := 0
+	for _, check := range checks {
+		if check.Status == checkSkip {
+			skipped++
+		}
+	}
+	return skipped
+}
@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/VillanCh/ragsync/common/aliyun"
+	"github.com/VillanCh/ragsync/common/backend"
+	"github.com/VillanCh/ragsync/common/bailianmock"
+)
+
+// addIndexedFile 上传文件并加入客户端配置的索引，返回文件 ID
+func addIndexedFile(t *testing.T, client *aliyun.BailianClient, fileName string, index bool) string {
+	t.Helper()
+	content := backend.BytesContent([]byte("content of " + fileName))
+	lease, err := client.ApplyUploadLease(fileName, content)
+	if err != nil {
+		t.Fatalf("ApplyUploadLease(%s): %v", fileName, err)
+	}
+	if err := client.UploadContent(lease, fileName, content); err != nil {
+		t.Fatalf("UploadContent(%s): %v", fileName, err)
+	}
+	fileId, err := client.AddFile(lease.LeaseId)
+	if err != nil {
+		t.Fatalf("AddFile(%s): %v", fileName, err)
+	}
+	if index {
+		if _, err := client.AppendDocumentToIndex(fileId); err != nil {
+			t.Fatalf("AppendDocumentToIndex(%s): %v", fileName, err)
+		}
+	}
+	return fileId
+}
+
+// checkStatus 返回名为 name 的检查项，不存在时测试失败
+func checkStatus(t *testing.T, checks []onlineCheck, name string) onlineCheck {
+	t.Helper()
+	for _, check := range checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	t.Fatalf("no check named %q in %+v", name, checks)
+	return onlineCheck{}
+}
+
+func TestOnlineChecksPass(t *testing.T) {
+	_, configPath := newMockWorkspace(t)
+	addIndexedFile(t, mockClient(t, configPath), "guide.md", true)
+
+	checks := runOnlineChecks(context.Background(), loadMockConfig(t, configPath))
+	for _, check := range checks {
+		if check.Status != checkPass {
+			t.Errorf("check %s = %s (%s), want PASS", check.Name, check.Status, check.Detail)
+		}
+	}
+	if failed := printOnlineChecks(checks); failed != 0 {
+		t.Fatalf("printOnlineChecks reported %d failures, want 0", failed)
+	}
+}
+
+func TestOnlineChecksLinkedBeyondFirstPage(t *testing.T) {
+	_, configPath := newMockWorkspace(t)
+	client := mockClient(t, configPath)
+
+	// 只有分类中的第 121 个文件在索引中，需要翻页才能找到
+	for i := 0; i < 120; i++ {
+		addIndexedFile(t, client, fmt.Sprintf("unindexed-%03d.md", i), false)
+	}
+	addIndexedFile(t, client, "indexed.md", true)
+
+	checks := runOnlineChecks(context.Background(), loadMockConfig(t, configPath))
+	linked := checkStatus(t, checks, "Category and index linked")
+	if linked.Status != checkPass {
+		t.Fatalf("Category and index linked = %s (%s), want PASS", linked.Status, linked.Detail)
+	}
+}
+
+func TestOnlineChecksWarnWhenIndexUsesAnotherCategory(t *testing.T) {
+	_, configPath := newMockWorkspace(t)
+	client := mockClient(t, configPath)
+	addIndexedFile(t, client, "in-default.md", false)
+
+	// 索引中的文档都来自另一个分类
+	otherCategory, err := client.CreateCategory("other")
+	if err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	otherConfig := loadMockConfig(t, configPath)
+	otherConfig.BailianFilesDefaultCategoryId = otherCategory
+	otherClient, err := aliyun.NewBailianClientFromConfig(otherConfig)
+	if err != nil {
+		t.Fatalf("NewBailianClientFromConfig: %v", err)
+	}
+	addIndexedFile(t, otherClient, "in-other.md", true)
+
+	checks := runOnlineChecks(context.Background(), loadMockConfig(t, configPath))
+	linked := checkStatus(t, checks, "Category and index linked")
+	if linked.Status != checkWarn || linked.Hint == "" {
+		t.Fatalf("Category and index linked = %s (%s), want WARN with a hint", linked.Status, linked.Detail)
+	}
+	if failed := printOnlineChecks(checks); failed != 0 {
+		t.Fatalf("printOnlineChecks reported %d failures, want warnings only", failed)
+	}
+}
+
+func TestOnlineChecksNoPermission(t *testing.T) {
+	server, configPath := newMockWorkspace(t)
+	server.InjectFault("AddFile", bailianmock.NoPermission(1))
+
+	checks := runOnlineChecks(context.Background(), loadMockConfig(t, configPath))
+	check := checkStatus(t, checks, "Permission AddFile")
+	if check.Status != checkFail || check.Recommend == "" {
+		t.Fatalf("Permission AddFile = %s (%s, recommend %q), want FAIL with the recommendation", check.Status, check.Detail, check.Recommend)
+	}
+	if other := checkStatus(t, checks, "Permission DeleteFile"); other.Status != checkPass {
+		t.Fatalf("Permission DeleteFile = %s (%s), want PASS", other.Status, other.Detail)
+	}
+	if failed := printOnlineChecks(checks); failed != 1 {
+		t.Fatalf("printOnlineChecks reported %d failures, want 1", failed)
+	}
+}
+
+func TestOnlineChecksWorkspaceNotAccessible(t *testing.T) {
+	server, configPath := newMockWorkspace(t)
+	server.InjectFault("ListCategory", bailianmock.NoPermission(1))
+
+	checks := runOnlineChecks(context.Background(), loadMockConfig(t, configPath))
+	if check := checkStatus(t, checks, "Workspace"); check.Status != checkFail {
+		t.Fatalf("Workspace = %s (%s), want FAIL", check.Status, check.Detail)
+	}
+	for _, name := range []string{"Category", "Index", "Category and index linked", "Permission AddFile"} {
+		if check := checkStatus(t, checks, name); check.Status != checkSkip {
+			t.Errorf("%s = %s, want SKIP after the workspace check failed", name, check.Status)
+		}
+	}
+}
+
+func TestOnlineChecksMissingCategoryAndIndex(t *testing.T) {
+	_, configPath := newMockWorkspace(t)
+	config := loadMockConfig(t, configPath)
+	config.BailianFilesDefaultCategoryId = "cate_missing"
+	config.BailianKnowledgeIndexId = "idx_missing"
+
+	checks := runOnlineChecks(context.Background(), config)
+	if check := checkStatus(t, checks, "Category"); check.Status != checkFail || check.Hint == "" {
+		t.Fatalf("Category = %s (%s), want FAIL with a hint", check.Status, check.Detail)
+	}
+	if check := checkStatus(t, checks, "Index"); check.Status != checkFail {
+		t.Fatalf("Index = %s (%s), want FAIL", check.Status, check.Detail)
+	}
+	for _, name := range []string{"Category and index linked", "Permission SubmitIndexAddDocumentsJob", "Permission GetIndexJobStatus", "Permission DeleteIndexDocument"} {
+		if check := checkStatus(t, checks, name); check.Status != checkSkip {
+			t.Errorf("%s = %s, want SKIP when the category or index is missing", name, check.Status)
+		}
+	}
+}
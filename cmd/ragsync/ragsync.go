@@ -29,14 +29,25 @@ func main() {
 
 	defaultConfigPath := filepath.Join(baseConfigDir, "ragsync.yaml")
 
-	// 只接受一个配置文件路径的参数
+	// 全局参数：配置文件路径、档案以及所有命令共用的超时设置
 	app.Flags = []cli.Flag{
 		cli.StringFlag{
 			Name:  "config, c",
 			Usage: "Configuration file path",
 			Value: defaultConfigPath,
 		},
+		cli.StringFlag{
+			Name:   "profile",
+			Usage:  "Named profile in the configuration file to use (e.g. staging or prod)",
+			EnvVar: "RAGSYNC_PROFILE",
+		},
+		cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "Overall deadline for the command (e.g. 30m); when it expires in-flight work is cancelled and a partial summary is printed (0 means no deadline)",
+		},
 	}
+	// 覆盖配置文件中的工作空间、索引、端点等配置项，优先级高于 RAGSYNC_* 环境变量
+	app.Flags = append(app.Flags, commands.OverrideFlags()...)
 
 	// 设置命令
 	app.Commands = commands.GetCommands()
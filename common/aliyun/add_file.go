@@ -1,11 +1,9 @@
 package aliyun
 
 import (
-	"encoding/json"
-	"strings"
+	"context"
 
 	bailian20231229 "github.com/alibabacloud-go/bailian-20231229/v2/client"
-	util "github.com/alibabacloud-go/tea-utils/v2/service"
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
@@ -13,6 +11,11 @@ import (
 
 // AddFile 将已上传的文件添加到百炼服务
 func (client *BailianClient) AddFile(leaseId string) (string, error) {
+	return client.AddFileWithContext(context.Background(), leaseId)
+}
+
+// AddFileWithContext 与 AddFile 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) AddFileWithContext(ctx context.Context, leaseId string) (string, error) {
 	if client.config == nil {
 		return "", utils.Error("Client configuration is not set")
 	}
@@ -33,36 +36,23 @@ func (client *BailianClient) AddFile(leaseId string) (string, error) {
 		CategoryType: tea.String(client.config.BailianCategoryType),
 	}
 
-	runtime := &util.RuntimeOptions{}
+	runtime := client.runtimeOptions()
 	headers := make(map[string]*string)
 
 	// 调用API
-	response, err := client.Client.AddFileWithOptions(
-		tea.String(client.config.BailianWorkspaceId),
-		addFileRequest,
-		headers,
-		runtime,
-	)
+	var response *bailian20231229.AddFileResponse
+	err := client.retry(ctx, "AddFile", func() (err error) {
+		response, err = client.Client.AddFileWithOptions(
+			tea.String(client.config.BailianWorkspaceId),
+			addFileRequest,
+			headers,
+			runtime,
+		)
+		return err
+	})
 
 	if err != nil {
-		var sdkErr *tea.SDKError
-		if teaErr, ok := err.(*tea.SDKError); ok {
-			sdkErr = teaErr
-			// 尝试解析更详细的错误信息
-			if sdkErr.Data != nil {
-				var data interface{}
-				decoder := json.NewDecoder(strings.NewReader(tea.StringValue(sdkErr.Data)))
-				if decodeErr := decoder.Decode(&data); decodeErr == nil {
-					if m, ok := data.(map[string]interface{}); ok {
-						recommend, ok := m["Recommend"]
-						if ok {
-							log.Errorf("Detailed error information: %v", recommend)
-						}
-					}
-				}
-			}
-		}
-		return "", utils.Errorf("Failed to add file: %v", err)
+		return "", utils.Errorf("Failed to add file: %w", newAPIError("AddFile", err))
 	}
 
 	// 解析响应
@@ -71,7 +61,7 @@ func (client *BailianClient) AddFile(leaseId string) (string, error) {
 	}
 
 	if tea.StringValue(response.Body.Success) != "true" {
-		return "", utils.Errorf("Failed to add file: %v", response.Body.Message)
+		return "", utils.Errorf("Failed to add file: %w", newResponseError("AddFile", response.Body.Code, response.Body.Message, response.Body.RequestId))
 	}
 
 	fileId := tea.StringValue(response.Body.Data.FileId)
@@ -0,0 +1,123 @@
+package aliyun
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/VillanCh/ragsync/common/backend"
+	"github.com/VillanCh/ragsync/common/spec"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// FileInfo 文件信息结构体
+type FileInfo = backend.FileInfo
+
+// IndexDocumentRecord 索引文档记录
+type IndexDocumentRecord = backend.IndexDocumentRecord
+
+// BackendName 百炼后端在配置中的名称
+const BackendName = "bailian"
+
+func init() {
+	backend.Register(BackendName, func(config *spec.Config) (backend.Backend, error) {
+		return NewBailianClientFromConfig(config)
+	})
+}
+
+// 确保 BailianClient 实现了 backend.Backend
+var _ backend.Backend = (*BailianClient)(nil)
+
+// Name 返回后端名称
+func (client *BailianClient) Name() string {
+	return BackendName
+}
+
+// ApplyUploadLease 申请文件上传租约
+func (client *BailianClient) ApplyUploadLease(fileName string, content *backend.Content) (*backend.UploadLease, error) {
+	return client.ApplyUploadLeaseWithContext(context.Background(), fileName, content)
+}
+
+// ApplyUploadLeaseWithContext 与 ApplyUploadLease 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) ApplyUploadLeaseWithContext(ctx context.Context, fileName string, content *backend.Content) (*backend.UploadLease, error) {
+	lease, err := client.ApplyFileUploadLeaseWithContext(ctx, fileName, content)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]string)
+	for key, value := range utils.InterfaceToGeneralMap(lease.Headers) {
+		headers[key] = fmt.Sprint(value)
+	}
+	return &backend.UploadLease{
+		LeaseId:   lease.LeaseId,
+		UploadURL: lease.UploadURL,
+		Method:    lease.Method,
+		Headers:   headers,
+		Raw:       lease.Raw,
+	}, nil
+}
+
+// UploadContent 按租约将文件内容以流的方式上传到百炼提供的 OSS 地址
+func (client *BailianClient) UploadContent(lease *backend.UploadLease, fileName string, content *backend.Content) error {
+	return client.UploadContentWithContext(context.Background(), lease, fileName, content)
+}
+
+// UploadContentWithContext 与 UploadContent 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) UploadContentWithContext(ctx context.Context, lease *backend.UploadLease, fileName string, content *backend.Content) error {
+	if lease == nil {
+		return utils.Error("Upload lease cannot be nil")
+	}
+	if content == nil {
+		return utils.Error("File content cannot be nil")
+	}
+	bailianExtra, ok := lease.Headers["X-bailian-extra"]
+	if !ok {
+		return utils.Errorf("X-bailian-extra does not exist")
+	}
+	contentType, ok := lease.Headers["Content-Type"]
+	if !ok {
+		return utils.Errorf("Content-Type does not exist")
+	}
+	// 每次尝试都重新打开内容，失败的上传可能已经读取了部分内容
+	err := client.retry(ctx, "Upload", func() error {
+		body, err := content.Open()
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+		return UploadFileFromReader(ctx, lease.Method, lease.UploadURL, fileName, contentType, body, content.Size, bailianExtra)
+	})
+	return newAPIError("Upload", err)
+}
+
+// DocumentIndexed 判断文档是否已在知识索引中（或正在建立索引）
+func (client *BailianClient) DocumentIndexed(documentName string) (bool, error) {
+	return client.DocumentIndexedWithContext(context.Background(), documentName)
+}
+
+// DocumentIndexedWithContext 与 DocumentIndexed 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) DocumentIndexedWithContext(ctx context.Context, documentName string) (bool, error) {
+	return client.CheckAndWaitForExistingIndexJobWithContext(ctx, documentName)
+}
+
+// IndexJobStatus 查询索引任务状态
+func (client *BailianClient) IndexJobStatus(jobId string) (*backend.IndexJob, error) {
+	return client.IndexJobStatusWithContext(context.Background(), jobId)
+}
+
+// IndexJobStatusWithContext 与 IndexJobStatus 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) IndexJobStatusWithContext(ctx context.Context, jobId string) (*backend.IndexJob, error) {
+	response, err := client.GetIndexJobStatusWithContext(ctx, jobId)
+	if err != nil {
+		return nil, err
+	}
+	if response == nil || response.Data == nil || response.Data.Status == nil {
+		return nil, utils.Errorf("Empty or invalid response from service")
+	}
+	return &backend.IndexJob{
+		JobId:  jobId,
+		Status: tea.StringValue(response.Data.Status),
+		Raw:    response.Data,
+	}, nil
+}
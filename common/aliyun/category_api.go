@@ -1,97 +1,76 @@
 package aliyun
 
 import (
-	"encoding/json"
-	"strings"
+	"context"
 
 	"github.com/VillanCh/ragsync/common/spec"
 	bailian20231229 "github.com/alibabacloud-go/bailian-20231229/v2/client"
-	util "github.com/alibabacloud-go/tea-utils/v2/service"
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
 )
 
-func CreateCategory(accessKey, secretKey, workspaceId, name string) error {
-	config := &spec.Config{
-		AliyunAccessKey:    accessKey,
-		AliyunSecretKey:    secretKey,
-		BailianWorkspaceId: workspaceId,
-	}
+// CreateCategory 使用 AccessKey 在工作空间中创建分类，返回新分类的 ID
+func CreateCategory(accessKey, secretKey, workspaceId, name string) (string, error) {
+	return CreateCategoryWithContext(context.Background(), accessKey, secretKey, workspaceId, name)
+}
 
-	client, err := NewBailianClientFromConfig(config)
+// CreateCategoryWithContext 与 CreateCategory 相同，ctx 被取消或超时后立即返回
+func CreateCategoryWithContext(ctx context.Context, accessKey, secretKey, workspaceId, name string) (string, error) {
+	client, err := newWorkspaceClient(accessKey, secretKey, workspaceId)
 	if err != nil {
-		return utils.Errorf("Failed to create client: %v", err)
+		return "", err
 	}
+	return client.CreateCategoryWithContext(ctx, name)
+}
+
+// CreateCategory 在客户端的工作空间中创建分类，返回新分类的 ID
+func (client *BailianClient) CreateCategory(name string) (string, error) {
+	return client.CreateCategoryWithContext(context.Background(), name)
+}
 
+// CreateCategoryWithContext 与 CreateCategory 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) CreateCategoryWithContext(ctx context.Context, name string) (string, error) {
+	workspaceId := client.config.BailianWorkspaceId
 	request := &bailian20231229.AddCategoryRequest{
 		CategoryName: tea.String(name),
 		CategoryType: tea.String("UNSTRUCTURED"),
 	}
-	runtime := &util.RuntimeOptions{}
+	runtime := client.runtimeOptions()
 	headers := make(map[string]*string)
 
-	response, err := client.Client.AddCategoryWithOptions(
-		tea.String(workspaceId),
-		request,
-		headers,
-		runtime,
-	)
+	var response *bailian20231229.AddCategoryResponse
+	err := client.retry(ctx, "AddCategory", func() (err error) {
+		response, err = client.Client.AddCategoryWithOptions(
+			tea.String(workspaceId),
+			request,
+			headers,
+			runtime,
+		)
+		return err
+	})
 	if err != nil {
-		var sdkErr *tea.SDKError
-		if teaErr, ok := err.(*tea.SDKError); ok {
-			sdkErr = teaErr
-			log.Errorf("SDK Error Code: %s", tea.StringValue(sdkErr.Code))
-			log.Errorf("SDK Error Message: %s", tea.StringValue(sdkErr.Message))
-
-			if sdkErr.Data != nil {
-				log.Errorf("Raw Error Data: %s", tea.StringValue(sdkErr.Data))
-				var data interface{}
-				decoder := json.NewDecoder(strings.NewReader(tea.StringValue(sdkErr.Data)))
-				if decodeErr := decoder.Decode(&data); decodeErr == nil {
-					log.Errorf("Parsed Error Data: %v", data)
-					if m, ok := data.(map[string]interface{}); ok {
-						// Print all possible error fields
-						for k, v := range m {
-							log.Errorf("Error Field %s: %v", k, v)
-						}
-
-						if recommend, ok := m["Recommend"]; ok {
-							log.Errorf("Recommended Solution: %v", recommend)
-						}
-						if code, ok := m["Code"]; ok {
-							log.Errorf("Error Code: %v", code)
-						}
-						if message, ok := m["Message"]; ok {
-							log.Errorf("Error Message: %v", message)
-						}
-						if requestId, ok := m["RequestId"]; ok {
-							log.Errorf("Request ID: %v", requestId)
-						}
-					}
-				} else {
-					log.Errorf("Failed to parse error data: %v", decodeErr)
-				}
-			}
-		} else {
-			log.Errorf("Non-SDK Error: %v", err)
-		}
-		return utils.Errorf("Failed to create category: %v", err)
+		return "", utils.Errorf("Failed to create category: %w", newAPIError("AddCategory", err))
 	}
 
 	if response == nil || response.Body == nil {
-		return utils.Errorf("Create category response is empty")
+		return "", utils.Errorf("Create category response is empty")
 	}
 
 	if !tea.BoolValue(response.Body.Success) {
-		log.Errorf("Request ID: %s", tea.StringValue(response.Body.RequestId))
-		log.Errorf("Error Code: %s", tea.StringValue(response.Body.Code))
-		log.Errorf("Error Message: %s", tea.StringValue(response.Body.Message))
-		return utils.Errorf("Failed to create category: %s (Request ID: %s)", tea.StringValue(response.Body.Message), tea.StringValue(response.Body.RequestId))
+		return "", utils.Errorf("Failed to create category: %w", newResponseError("AddCategory", response.Body.Code, response.Body.Message, response.Body.RequestId))
+	}
+
+	var categoryId string
+	if response.Body.Data != nil {
+		categoryId = tea.StringValue(response.Body.Data.CategoryId)
+	}
+	if categoryId == "" {
+		return "", utils.Errorf("Create category response has no CategoryId (RequestId: %s)", tea.StringValue(response.Body.RequestId))
 	}
 
-	log.Infof("Category created successfully: %s", name)
-	return nil
+	log.Infof("Category created successfully: %s (%s)", name, categoryId)
+	return categoryId, nil
 }
 
 type Category struct {
@@ -101,47 +80,44 @@ type Category struct {
 }
 
 func ListCategories(accessKey, secretKey, workspaceId string) ([]Category, error) {
-	config := &spec.Config{
-		AliyunAccessKey:    accessKey,
-		AliyunSecretKey:    secretKey,
-		BailianWorkspaceId: workspaceId,
-	}
+	return ListCategoriesWithContext(context.Background(), accessKey, secretKey, workspaceId)
+}
 
-	client, err := NewBailianClientFromConfig(config)
+// ListCategoriesWithContext 与 ListCategories 相同，ctx 被取消或超时后立即返回
+func ListCategoriesWithContext(ctx context.Context, accessKey, secretKey, workspaceId string) ([]Category, error) {
+	client, err := newWorkspaceClient(accessKey, secretKey, workspaceId)
 	if err != nil {
-		return nil, utils.Errorf("Failed to create client: %v", err)
+		return nil, err
 	}
+	return client.ListCategoriesWithContext(ctx)
+}
 
+// ListCategories 列出客户端工作空间中的非结构化数据分类
+func (client *BailianClient) ListCategories() ([]Category, error) {
+	return client.ListCategoriesWithContext(context.Background())
+}
+
+// ListCategoriesWithContext 与 ListCategories 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) ListCategoriesWithContext(ctx context.Context) ([]Category, error) {
+	workspaceId := client.config.BailianWorkspaceId
 	request := &bailian20231229.ListCategoryRequest{
 		CategoryType: tea.String("UNSTRUCTURED"),
 	}
-	runtime := &util.RuntimeOptions{}
+	runtime := client.runtimeOptions()
 	headers := make(map[string]*string)
 
-	response, err := client.Client.ListCategoryWithOptions(
-		tea.String(workspaceId),
-		request,
-		headers,
-		runtime,
-	)
+	var response *bailian20231229.ListCategoryResponse
+	err := client.retry(ctx, "ListCategory", func() (err error) {
+		response, err = client.Client.ListCategoryWithOptions(
+			tea.String(workspaceId),
+			request,
+			headers,
+			runtime,
+		)
+		return err
+	})
 	if err != nil {
-		var sdkErr *tea.SDKError
-		if teaErr, ok := err.(*tea.SDKError); ok {
-			sdkErr = teaErr
-			if sdkErr.Data != nil {
-				var data interface{}
-				decoder := json.NewDecoder(strings.NewReader(tea.StringValue(sdkErr.Data)))
-				if decodeErr := decoder.Decode(&data); decodeErr == nil {
-					if m, ok := data.(map[string]interface{}); ok {
-						recommend, ok := m["Recommend"]
-						if ok {
-							log.Errorf("Detailed error information: %v", recommend)
-						}
-					}
-				}
-			}
-		}
-		return nil, utils.Errorf("Failed to list categories: %v", err)
+		return nil, utils.Errorf("Failed to list categories: %w", newAPIError("ListCategory", err))
 	}
 
 	if response == nil || response.Body == nil {
@@ -149,7 +125,7 @@ func ListCategories(accessKey, secretKey, workspaceId string) ([]Category, error
 	}
 
 	if !tea.BoolValue(response.Body.Success) {
-		return nil, utils.Errorf("Failed to list categories: %v", response.Body.Message)
+		return nil, utils.Errorf("Failed to list categories: %w", newResponseError("ListCategory", response.Body.Code, response.Body.Message, response.Body.RequestId))
 	}
 
 	var categories []Category
@@ -165,3 +141,16 @@ func ListCategories(accessKey, secretKey, workspaceId string) ([]Category, error
 
 	return categories, nil
 }
+
+// newWorkspaceClient 使用 AccessKey 创建访问工作空间 workspaceId 的客户端
+func newWorkspaceClient(accessKey, secretKey, workspaceId string) (*BailianClient, error) {
+	client, err := NewBailianClientFromConfig(&spec.Config{
+		AliyunAccessKey:    accessKey,
+		AliyunSecretKey:    secretKey,
+		BailianWorkspaceId: workspaceId,
+	})
+	if err != nil {
+		return nil, utils.Errorf("Failed to create client: %v", err)
+	}
+	return client, nil
+}
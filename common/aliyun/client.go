@@ -1,7 +1,7 @@
 package aliyun
 
 import (
-	"os"
+	"strings"
 
 	"github.com/VillanCh/ragsync/common/spec"
 	bailian20231229 "github.com/alibabacloud-go/bailian-20231229/v2/client"
@@ -14,6 +14,11 @@ import (
 type BailianClient struct {
 	config *spec.Config
 	Client *bailian20231229.Client
+
+	// retryPolicy 所有 API 调用和文件上传共用的重试策略
+	retryPolicy *RetryPolicy
+	// rateLimiter 按接口限制调用频率，同一账号的客户端共用
+	rateLimiter *RateLimiter
 }
 
 // NewBailianClientFromConfig 从配置创建新的百炼客户端
@@ -22,18 +27,8 @@ func NewBailianClientFromConfig(config *spec.Config) (*BailianClient, error) {
 		return nil, utils.Error("Configuration cannot be nil")
 	}
 
-	// 获取访问密钥
-	accessKeyID := config.AliyunAccessKey
-	accessKeySecret := config.AliyunSecretKey
 	endpoint := config.BailianEndpoint
 
-	// 如果未提供 accessKeyID 或 accessKeySecret，则从环境变量获取
-	if accessKeyID == "" {
-		accessKeyID = os.Getenv("ALIBABA_CLOUD_ACCESS_KEY_ID")
-	}
-	if accessKeySecret == "" {
-		accessKeySecret = os.Getenv("ALIBABA_CLOUD_ACCESS_KEY_SECRET")
-	}
 	// 如果未提供 endpoint，则使用默认值
 	if endpoint == "" {
 		endpoint = "bailian.cn-beijing.aliyuncs.com"
@@ -51,11 +46,23 @@ func NewBailianClientFromConfig(config *spec.Config) (*BailianClient, error) {
 		config.BailianFilesDefaultCategoryId = defaultCfg.BailianFilesDefaultCategoryId
 	}
 
-	// 创建 OpenAPI 配置
+	// 按 credential_type 解析凭据，STS 临时凭据和扮演的角色在过期前自动刷新
+	credential, err := NewCredential(config)
+	if err != nil {
+		return nil, err
+	}
+	logCredential(credential)
+
+	// 创建 OpenAPI 配置，endpoint 可以带 http:// 前缀（例如指向本地的模拟服务）
 	openapiConfig := &openapi.Config{
-		AccessKeyId:     tea.String(accessKeyID),
-		AccessKeySecret: tea.String(accessKeySecret),
-		Endpoint:        tea.String(endpoint),
+		Credential: credential,
+		Endpoint:   tea.String(endpoint),
+	}
+	if strings.HasPrefix(endpoint, "http://") {
+		openapiConfig.Endpoint = tea.String(strings.TrimPrefix(endpoint, "http://"))
+		openapiConfig.Protocol = tea.String("HTTP")
+	} else if strings.HasPrefix(endpoint, "https://") {
+		openapiConfig.Endpoint = tea.String(strings.TrimPrefix(endpoint, "https://"))
 	}
 
 	client, err := bailian20231229.NewClient(openapiConfig)
@@ -63,20 +70,15 @@ func NewBailianClientFromConfig(config *spec.Config) (*BailianClient, error) {
 		return nil, utils.Errorf("Failed to create Bailian client: %v", err)
 	}
 
-	// 更新配置中的访问密钥（如果是从环境变量中获取的）
-	if config.AliyunAccessKey == "" {
-		config.AliyunAccessKey = accessKeyID
-	}
-	if config.AliyunSecretKey == "" {
-		config.AliyunSecretKey = accessKeySecret
-	}
 	if config.BailianEndpoint == "" {
 		config.BailianEndpoint = endpoint
 	}
 
 	return &BailianClient{
-		Client: client,
-		config: config,
+		Client:      client,
+		config:      config,
+		retryPolicy: NewRetryPolicy(config.Retry),
+		rateLimiter: sharedRateLimiter(credential.identity, config.RateLimit),
 	}, nil
 }
 
@@ -0,0 +1,332 @@
+package aliyun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/VillanCh/ragsync/common/backend"
+	"github.com/VillanCh/ragsync/common/bailianmock"
+	"github.com/VillanCh/ragsync/common/spec"
+)
+
+// newMockConfig 启动模拟服务并返回指向它的测试配置
+func newMockConfig(t *testing.T) (*bailianmock.Server, *spec.Config) {
+	t.Helper()
+	server := bailianmock.NewServer()
+	t.Cleanup(server.Close)
+	return server, server.TestConfig("ws-test", server.AddIndex("docs"))
+}
+
+// newMockClient 启动模拟服务并创建使用 AccessKey 访问它的客户端
+func newMockClient(t *testing.T) (*bailianmock.Server, *BailianClient) {
+	t.Helper()
+	server, config := newMockConfig(t)
+	client, err := NewBailianClientFromConfig(config)
+	if err != nil {
+		t.Fatalf("NewBailianClientFromConfig: %v", err)
+	}
+	return server, client
+}
+
+// addMockFile 按 sync 的顺序申请租约、上传内容并添加文件，返回文件 ID
+func addMockFile(t *testing.T, client *BailianClient, fileName string, content []byte) string {
+	t.Helper()
+	body := backend.BytesContent(content)
+	lease, err := client.ApplyUploadLease(fileName, body)
+	if err != nil {
+		t.Fatalf("ApplyUploadLease(%s): %v", fileName, err)
+	}
+	if err := client.UploadContent(lease, fileName, body); err != nil {
+		t.Fatalf("UploadContent(%s): %v", fileName, err)
+	}
+	fileId, err := client.AddFile(lease.LeaseId)
+	if err != nil {
+		t.Fatalf("AddFile(%s): %v", fileName, err)
+	}
+	return fileId
+}
+
+func TestClientName(t *testing.T) {
+	_, client := newMockClient(t)
+	if client.Name() != BackendName {
+		t.Fatalf("Name() = %q, want %q", client.Name(), BackendName)
+	}
+}
+
+func TestClientFileLifecycle(t *testing.T) {
+	server, client := newMockClient(t)
+	server.FailParse("broken.pdf", "unsupported layout")
+
+	fileId := addMockFile(t, client, "notes.md", []byte("# notes\n"))
+	info, err := client.DescribeFile(fileId)
+	if err != nil {
+		t.Fatalf("DescribeFile: %v", err)
+	}
+	if info.FileName != "notes.md" || info.Status != "PARSE_SUCCESS" || info.SizeInBytes != 8 {
+		t.Fatalf("DescribeFile = %+v", info)
+	}
+
+	brokenId := addMockFile(t, client, "broken.pdf", []byte("%PDF"))
+	broken, err := client.DescribeFile(brokenId)
+	if err != nil {
+		t.Fatalf("DescribeFile(broken): %v", err)
+	}
+	if broken.Status != "PARSE_FAILED" || broken.Message != "unsupported layout" {
+		t.Fatalf("DescribeFile(broken) = %+v, want PARSE_FAILED with the parse error", broken)
+	}
+
+	files, err := client.ListAllFiles("notes.md")
+	if err != nil {
+		t.Fatalf("ListAllFiles: %v", err)
+	}
+	if len(files) != 1 || files[0].FileId != fileId {
+		t.Fatalf("ListAllFiles(notes.md) = %+v, want only %s", files, fileId)
+	}
+
+	if err := client.DeleteFile(brokenId); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	_, err = client.DescribeFile(brokenId)
+	if !IsNotFound(err) || !errors.Is(err, backend.ErrNotFound) {
+		t.Fatalf("DescribeFile after DeleteFile returned %v, want a not found error", err)
+	}
+	err = client.DeleteFileEx(brokenId, true)
+	if !errors.Is(err, backend.ErrNotFound) {
+		t.Fatalf("DeleteFileEx of a deleted file returned %v, want backend.ErrNotFound", err)
+	}
+}
+
+func TestClientApplyFileUploadLease(t *testing.T) {
+	_, client := newMockClient(t)
+
+	lease, err := client.ApplyFileUploadLease("a.txt", backend.BytesContent([]byte("a")))
+	if err != nil {
+		t.Fatalf("ApplyFileUploadLease: %v", err)
+	}
+	if lease.LeaseId == "" || lease.UploadURL == "" || lease.Method != "PUT" {
+		t.Fatalf("ApplyFileUploadLease = %+v", lease)
+	}
+
+	if _, err := client.ApplyFileUploadLease("no-extension", backend.BytesContent([]byte("a"))); err == nil {
+		t.Fatal("ApplyFileUploadLease accepted a file name without an extension")
+	}
+}
+
+func TestClientIndexLifecycle(t *testing.T) {
+	_, client := newMockClient(t)
+
+	first := addMockFile(t, client, "first.md", []byte("first"))
+	second := addMockFile(t, client, "second.md", []byte("second"))
+	third := addMockFile(t, client, "third.md", []byte("third"))
+
+	jobId, err := client.AppendDocumentsToIndex([]string{first, second})
+	if err != nil {
+		t.Fatalf("AppendDocumentsToIndex: %v", err)
+	}
+	status, err := client.GetIndexJobStatus(jobId)
+	if err != nil {
+		t.Fatalf("GetIndexJobStatus: %v", err)
+	}
+	if len(status.Data.Documents) != 2 {
+		t.Fatalf("GetIndexJobStatus returned %d documents, want 2", len(status.Data.Documents))
+	}
+	job, err := client.IndexJobStatus(jobId)
+	if err != nil {
+		t.Fatalf("IndexJobStatus: %v", err)
+	}
+	if job.JobId != jobId || job.Status != "COMPLETED" {
+		t.Fatalf("IndexJobStatus = %+v, want COMPLETED", job)
+	}
+
+	if _, err := client.AppendDocumentToIndex(third); err != nil {
+		t.Fatalf("AppendDocumentToIndex: %v", err)
+	}
+	records, total, err := client.ListIndexDocuments(1, 2)
+	if err != nil {
+		t.Fatalf("ListIndexDocuments: %v", err)
+	}
+	if len(records) != 2 || total != 3 {
+		t.Fatalf("ListIndexDocuments(1, 2) returned %d records of %d, want 2 of 3", len(records), total)
+	}
+	all, err := client.ListAllIndexDocuments()
+	if err != nil {
+		t.Fatalf("ListAllIndexDocuments: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("ListAllIndexDocuments returned %d records, want 3", len(all))
+	}
+
+	// 索引文档名不带扩展名，查询时会去掉扩展名
+	matched, err := client.QueryIndexRecordFromDocumentName("first")
+	if err != nil {
+		t.Fatalf("QueryIndexRecordFromDocumentName: %v", err)
+	}
+	if len(matched) != 1 || matched[0].DocumentId != first {
+		t.Fatalf("QueryIndexRecordFromDocumentName(first) = %+v, want %s", matched, first)
+	}
+	indexed, err := client.DocumentIndexed("first.md")
+	if err != nil || !indexed {
+		t.Fatalf("DocumentIndexed(first.md) = %v, %v, want true", indexed, err)
+	}
+	indexed, err = client.CheckAndWaitForExistingIndexJob("missing.md")
+	if err != nil || indexed {
+		t.Fatalf("CheckAndWaitForExistingIndexJob(missing.md) = %v, %v, want false", indexed, err)
+	}
+
+	if err := client.DeleteIndexDocument(second); err != nil {
+		t.Fatalf("DeleteIndexDocument: %v", err)
+	}
+	// DeleteFileEx 先从索引中删除文档，再删除文件
+	if err := client.DeleteFileEx(third, false); err != nil {
+		t.Fatalf("DeleteFileEx: %v", err)
+	}
+	all, err = client.ListAllIndexDocuments()
+	if err != nil {
+		t.Fatalf("ListAllIndexDocuments: %v", err)
+	}
+	if len(all) != 1 || all[0].DocumentId != first {
+		t.Fatalf("index documents after deletion = %+v, want only %s", all, first)
+	}
+
+	_, err = client.GetIndexJobStatus("job_missing")
+	if !IsNotFound(err) {
+		t.Fatalf("GetIndexJobStatus of an unknown job returned %v, want a not found error", err)
+	}
+}
+
+func TestClientCategoriesAndIndices(t *testing.T) {
+	_, client := newMockClient(t)
+
+	categoryId, err := client.CreateCategory("handbook")
+	if err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	categories, err := client.ListCategories()
+	if err != nil {
+		t.Fatalf("ListCategories: %v", err)
+	}
+	found := false
+	for _, c := range categories {
+		found = found || (c.CategoryId == categoryId && c.CategoryName == "handbook")
+	}
+	if !found {
+		t.Fatalf("ListCategories = %+v, want it to include %s", categories, categoryId)
+	}
+
+	indexId, err := client.CreateIndex("handbook-index", "DATA_CENTER_CATEGORY", []string{categoryId})
+	if err != nil {
+		t.Fatalf("CreateIndex: %v", err)
+	}
+	indices, err := client.ListIndices()
+	if err != nil {
+		t.Fatalf("ListIndices: %v", err)
+	}
+	names := make(map[string]string)
+	for _, idx := range indices {
+		names[idx.IndexId] = idx.IndexName
+	}
+	if len(indices) != 2 || names[indexId] != "handbook-index" {
+		t.Fatalf("ListIndices = %+v, want the preset index and %s", indices, indexId)
+	}
+}
+
+func TestClientUpdateConfig(t *testing.T) {
+	server, client := newMockClient(t)
+	other := server.AddIndex("other")
+
+	config := *client.config
+	config.BailianKnowledgeIndexId = other
+	client.UpdateConfig(&config)
+	client.UpdateConfig(nil)
+
+	fileId := addMockFile(t, client, "moved.md", []byte("moved"))
+	if _, err := client.AppendDocumentToIndex(fileId); err != nil {
+		t.Fatalf("AppendDocumentToIndex: %v", err)
+	}
+	records, _, err := client.ListIndexDocuments(1, 10)
+	if err != nil {
+		t.Fatalf("ListIndexDocuments: %v", err)
+	}
+	if len(records) != 1 || records[0].IndexId != other {
+		t.Fatalf("ListIndexDocuments after UpdateConfig = %+v, want the document in %s", records, other)
+	}
+}
+
+func TestClientPagination(t *testing.T) {
+	_, client := newMockClient(t)
+
+	var fileIds []string
+	for i := 0; i < 120; i++ {
+		fileIds = append(fileIds, addMockFile(t, client, fmt.Sprintf("page-%03d.md", i), []byte(fmt.Sprintf("page %d", i))))
+	}
+
+	first, err := client.ListFile(100, "", "")
+	if err != nil {
+		t.Fatalf("ListFile: %v", err)
+	}
+	if len(first.Files) != 100 || first.NextToken == "" {
+		t.Fatalf("first page has %d files and NextToken %q, want 100 files and a token", len(first.Files), first.NextToken)
+	}
+	second, err := client.ListFile(100, first.NextToken, "")
+	if err != nil {
+		t.Fatalf("ListFile(next): %v", err)
+	}
+	if len(second.Files) != 20 || second.NextToken != "" {
+		t.Fatalf("second page has %d files and NextToken %q, want 20 files and no token", len(second.Files), second.NextToken)
+	}
+
+	all, err := client.ListAllFiles("")
+	if err != nil {
+		t.Fatalf("ListAllFiles: %v", err)
+	}
+	if len(all) != 120 {
+		t.Fatalf("ListAllFiles returned %d files, want 120", len(all))
+	}
+
+	fileChan, errChan := client.ListAllFilesAsync("")
+	streamed := 0
+	for range fileChan {
+		streamed++
+	}
+	if err := <-errChan; err != nil {
+		t.Fatalf("ListAllFilesAsync: %v", err)
+	}
+	if streamed != 120 {
+		t.Fatalf("ListAllFilesAsync streamed %d files, want 120", streamed)
+	}
+
+	// 单个索引任务最多提交 100 个文档
+	if _, err := client.AppendDocumentsToIndex(fileIds); err == nil {
+		t.Fatal("AppendDocumentsToIndex accepted 120 documents in one job")
+	}
+	for _, chunk := range [][]string{fileIds[:100], fileIds[100:]} {
+		if _, err := client.AppendDocumentsToIndex(chunk); err != nil {
+			t.Fatalf("AppendDocumentsToIndex: %v", err)
+		}
+	}
+	records, err := client.ListAllIndexDocuments()
+	if err != nil {
+		t.Fatalf("ListAllIndexDocuments: %v", err)
+	}
+	if len(records) != 120 {
+		t.Fatalf("ListAllIndexDocuments returned %d records, want 120", len(records))
+	}
+}
+
+func TestClientListAllFilesAsyncCancelled(t *testing.T) {
+	_, client := newMockClient(t)
+	addMockFile(t, client, "a.md", []byte("a"))
+	addMockFile(t, client, "b.md", []byte("b"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fileChan, errChan := client.ListAllFilesAsyncWithContext(ctx, "")
+	<-fileChan
+	cancel()
+	for range fileChan {
+	}
+	if err := <-errChan; err != nil && !errors.Is(err, context.Canceled) {
+		t.Fatalf("ListAllFilesAsync after cancel returned %v, want nil or context.Canceled", err)
+	}
+}
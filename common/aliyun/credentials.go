@@ -0,0 +1,310 @@
+package aliyun
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/VillanCh/ragsync/common/spec"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/aliyun/credentials-go/credentials"
+	"github.com/yaklang/yaklang/common/log"
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// 阿里云 SDK 和 CLI 通用的凭据环境变量
+const (
+	envAccessKeyId     = "ALIBABA_CLOUD_ACCESS_KEY_ID"
+	envAccessKeySecret = "ALIBABA_CLOUD_ACCESS_KEY_SECRET"
+	envSecurityToken   = "ALIBABA_CLOUD_SECURITY_TOKEN"
+	envRoleArn         = "ALIBABA_CLOUD_ROLE_ARN"
+	envRoleSessionName = "ALIBABA_CLOUD_ROLE_SESSION_NAME"
+	envOIDCProviderArn = "ALIBABA_CLOUD_OIDC_PROVIDER_ARN"
+	envOIDCTokenFile   = "ALIBABA_CLOUD_OIDC_TOKEN_FILE"
+	envECSMetadata     = "ALIBABA_CLOUD_ECS_METADATA" // ECS 实例 RAM 角色名称
+	envProfile         = "ALIBABA_CLOUD_PROFILE"
+)
+
+// defaultRoleSessionName 扮演 RAM 角色时默认的会话名称
+const defaultRoleSessionName = "ragsync"
+
+// Credential 解析后的阿里云凭据，可以直接作为 openapi.Config 的 Credential 使用
+type Credential struct {
+	credentials.Credential
+
+	Type   string // 实际使用的凭据类型，例如 access_key
+	Source string // 凭据来源，用于日志，例如 environment variables
+
+	// identity 区分账号或角色的标识，同一标识的客户端共用限流器
+	identity string
+}
+
+// NewCredential 按配置中的 credential_type 解析阿里云凭据
+// 未配置 credential_type 时优先使用配置文件中的 AccessKey，否则依次尝试环境变量、OIDC、~/.aliyun/config.json 和 ECS 实例角色
+func NewCredential(config *spec.Config) (*Credential, error) {
+	switch typ := config.ResolvedCredentialType(); typ {
+	case spec.CredentialTypeDefault:
+		return defaultCredentialChain(config)
+	case spec.CredentialTypeAccessKey, spec.CredentialTypeSTS:
+		return staticCredential(config, typ)
+	case spec.CredentialTypeRAMRoleArn:
+		return ramRoleArnCredential(config)
+	case spec.CredentialTypeOIDCRoleArn:
+		return oidcRoleArnCredential(config)
+	case spec.CredentialTypeECSRAMRole:
+		return ecsRAMRoleCredentialFromConfig(config)
+	case spec.CredentialTypeCLIProfile:
+		return cliProfileCredential(config)
+	default:
+		return nil, utils.Errorf("Unsupported credential type %q, expected one of: %s", typ, strings.Join(spec.CredentialTypes, ", "))
+	}
+}
+
+// defaultCredentialChain 依次尝试环境变量中的 AccessKey、OIDC、阿里云 CLI 配置文件和 ECS 实例角色，使用第一个已配置的来源
+func defaultCredentialChain(config *spec.Config) (*Credential, error) {
+	if os.Getenv(envAccessKeyId) != "" && os.Getenv(envAccessKeySecret) != "" {
+		typ := spec.CredentialTypeAccessKey
+		if os.Getenv(envSecurityToken) != "" {
+			typ = spec.CredentialTypeSTS
+		}
+		return staticCredential(config, typ)
+	}
+	if os.Getenv(envRoleArn) != "" && os.Getenv(envOIDCProviderArn) != "" && os.Getenv(envOIDCTokenFile) != "" {
+		return oidcRoleArnCredential(config)
+	}
+	if utils.GetFirstExistedPath(cliConfigPath()) != "" {
+		return cliProfileCredential(config)
+	}
+	if os.Getenv(envECSMetadata) != "" || config.AliyunECSRoleName != "" || config.AliyunECSMetadataEndpoint != "" {
+		return ecsRAMRoleCredentialFromConfig(config)
+	}
+	return nil, utils.Errorf("No Alibaba Cloud credentials found: set aliyun_access_key and aliyun_secret_key (or credential_type) in the configuration file, "+
+		"set %s and %s, or configure a profile in %s", envAccessKeyId, envAccessKeySecret, cliConfigPath())
+}
+
+// staticCredential 使用配置文件或环境变量中的 AccessKey，typ 为 sts 时还需要 SecurityToken
+func staticCredential(config *spec.Config, typ string) (*Credential, error) {
+	accessKeyId, accessKeySecret, source := accessKeyFromConfigOrEnv(config)
+	if accessKeyId == "" || accessKeySecret == "" {
+		return nil, utils.Errorf("Aliyun access key not set: set aliyun_access_key and aliyun_secret_key in the configuration file, or %s and %s", envAccessKeyId, envAccessKeySecret)
+	}
+
+	credentialConfig := &credentials.Config{
+		Type:            tea.String(typ),
+		AccessKeyId:     tea.String(accessKeyId),
+		AccessKeySecret: tea.String(accessKeySecret),
+	}
+	if typ == spec.CredentialTypeSTS {
+		securityToken := firstNonEmpty(config.AliyunSecurityToken, os.Getenv(envSecurityToken))
+		if securityToken == "" {
+			return nil, utils.Errorf("Security token not set: set aliyun_security_token in the configuration file, or %s", envSecurityToken)
+		}
+		credentialConfig.SecurityToken = tea.String(securityToken)
+	}
+
+	cred, err := credentials.NewCredential(credentialConfig)
+	if err != nil {
+		return nil, utils.Errorf("Failed to create %s credential: %v", typ, err)
+	}
+	return &Credential{Credential: cred, Type: typ, Source: source, identity: accessKeyId}, nil
+}
+
+// accessKeyFromConfigOrEnv 配置文件中没有 AccessKey 时从环境变量读取
+func accessKeyFromConfigOrEnv(config *spec.Config) (accessKeyId, accessKeySecret, source string) {
+	if config.AliyunAccessKey != "" && config.AliyunSecretKey != "" {
+		return config.AliyunAccessKey, config.AliyunSecretKey, "configuration file"
+	}
+	return os.Getenv(envAccessKeyId), os.Getenv(envAccessKeySecret), "environment variables"
+}
+
+// ramRoleArnCredential 使用 AccessKey（或 STS 凭据）扮演 RAM 角色，临时凭据过期前由 SDK 自动刷新
+func ramRoleArnCredential(config *spec.Config) (*Credential, error) {
+	roleArn := firstNonEmpty(config.AliyunRoleArn, os.Getenv(envRoleArn))
+	if roleArn == "" {
+		return nil, utils.Errorf("Role ARN not set: set aliyun_role_arn in the configuration file, or %s", envRoleArn)
+	}
+	accessKeyId, accessKeySecret, source := accessKeyFromConfigOrEnv(config)
+	if accessKeyId == "" || accessKeySecret == "" {
+		return nil, utils.Errorf("Aliyun access key required to assume role %s: set aliyun_access_key and aliyun_secret_key in the configuration file, or %s and %s", roleArn, envAccessKeyId, envAccessKeySecret)
+	}
+
+	credentialConfig := &credentials.Config{
+		Type:            tea.String(spec.CredentialTypeRAMRoleArn),
+		AccessKeyId:     tea.String(accessKeyId),
+		AccessKeySecret: tea.String(accessKeySecret),
+		RoleArn:         tea.String(roleArn),
+		RoleSessionName: tea.String(roleSessionName(config)),
+	}
+	if securityToken := firstNonEmpty(config.AliyunSecurityToken, os.Getenv(envSecurityToken)); securityToken != "" {
+		credentialConfig.SecurityToken = tea.String(securityToken)
+	}
+	if config.AliyunSTSEndpoint != "" {
+		credentialConfig.STSEndpoint = tea.String(config.AliyunSTSEndpoint)
+	}
+
+	cred, err := credentials.NewCredential(credentialConfig)
+	if err != nil {
+		return nil, utils.Errorf("Failed to create RAM role credential for %s: %v", roleArn, err)
+	}
+	return &Credential{
+		Credential: cred,
+		Type:       spec.CredentialTypeRAMRoleArn,
+		Source:     "role " + roleArn + " assumed with the access key from " + source,
+		identity:   roleArn,
+	}, nil
+}
+
+// oidcRoleArnCredential 使用 CI 签发的 OIDC Token 扮演 RAM 角色（例如 GitHub Actions 或 ACK 的 RRSA）
+func oidcRoleArnCredential(config *spec.Config) (*Credential, error) {
+	roleArn := firstNonEmpty(config.AliyunRoleArn, os.Getenv(envRoleArn))
+	providerArn := firstNonEmpty(config.AliyunOIDCProviderArn, os.Getenv(envOIDCProviderArn))
+	tokenFile := firstNonEmpty(config.AliyunOIDCTokenFile, os.Getenv(envOIDCTokenFile))
+	if roleArn == "" || providerArn == "" || tokenFile == "" {
+		return nil, utils.Errorf("OIDC credential requires a role ARN, an OIDC provider ARN and a token file: set aliyun_role_arn, aliyun_oidc_provider_arn and aliyun_oidc_token_file in the configuration file, or %s, %s and %s",
+			envRoleArn, envOIDCProviderArn, envOIDCTokenFile)
+	}
+
+	credentialConfig := &credentials.Config{
+		Type:              tea.String(spec.CredentialTypeOIDCRoleArn),
+		RoleArn:           tea.String(roleArn),
+		OIDCProviderArn:   tea.String(providerArn),
+		OIDCTokenFilePath: tea.String(tokenFile),
+		RoleSessionName:   tea.String(roleSessionName(config)),
+	}
+	if config.AliyunSTSEndpoint != "" {
+		credentialConfig.STSEndpoint = tea.String(config.AliyunSTSEndpoint)
+	}
+
+	cred, err := credentials.NewCredential(credentialConfig)
+	if err != nil {
+		return nil, utils.Errorf("Failed to create OIDC credential for %s: %v", roleArn, err)
+	}
+	return &Credential{
+		Credential: cred,
+		Type:       spec.CredentialTypeOIDCRoleArn,
+		Source:     "role " + roleArn + " assumed with the OIDC token in " + tokenFile,
+		identity:   roleArn,
+	}, nil
+}
+
+// ecsRAMRoleCredentialFromConfig 通过 ECS 元数据服务获取实例 RAM 角色的临时凭据
+func ecsRAMRoleCredentialFromConfig(config *spec.Config) (*Credential, error) {
+	endpoint := firstNonEmpty(config.AliyunECSMetadataEndpoint, defaultECSMetadataEndpoint)
+	roleName := firstNonEmpty(config.AliyunECSRoleName, os.Getenv(envECSMetadata))
+	cred := newECSRAMRoleCredential(endpoint, roleName)
+
+	source := "ECS metadata service " + endpoint
+	if roleName != "" {
+		source = "role " + roleName + " from " + source
+	}
+	return &Credential{
+		Credential: cred,
+		Type:       spec.CredentialTypeECSRAMRole,
+		Source:     source,
+		identity:   "ecs:" + endpoint + "/" + roleName,
+	}, nil
+}
+
+// cliProfile 阿里云 CLI 配置文件中的 profile
+type cliProfile struct {
+	Name            string `json:"name"`
+	Mode            string `json:"mode"`
+	AccessKeyId     string `json:"access_key_id"`
+	AccessKeySecret string `json:"access_key_secret"`
+	StsToken        string `json:"sts_token"`
+	RoleArn         string `json:"ram_role_arn"`
+	RoleSessionName string `json:"ram_session_name"`
+	RoleName        string `json:"ram_role_name"`
+	OIDCProviderArn string `json:"oidc_provider_arn"`
+	OIDCTokenFile   string `json:"oidc_token_file"`
+}
+
+// cliConfiguration 阿里云 CLI 配置文件 ~/.aliyun/config.json 的格式
+type cliConfiguration struct {
+	Current  string        `json:"current"`
+	Profiles []*cliProfile `json:"profiles"`
+}
+
+// cliConfigPath 阿里云 CLI 配置文件的路径
+func cliConfigPath() string {
+	return filepath.Join(utils.GetHomeDirDefault("."), ".aliyun", "config.json")
+}
+
+// cliProfileCredential 使用阿里云 CLI 配置文件中的 profile
+// profile 依次取 aliyun_cli_profile、ALIBABA_CLOUD_PROFILE 和配置文件中的 current
+func cliProfileCredential(config *spec.Config) (*Credential, error) {
+	path := cliConfigPath()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, utils.Errorf("Failed to read Aliyun CLI configuration %s: %v", path, err)
+	}
+	var cliConfig cliConfiguration
+	if err := json.Unmarshal(raw, &cliConfig); err != nil {
+		return nil, utils.Errorf("Failed to parse Aliyun CLI configuration %s: %v", path, err)
+	}
+
+	name := firstNonEmpty(config.AliyunCLIProfile, os.Getenv(envProfile), cliConfig.Current, "default")
+	var profile *cliProfile
+	for _, p := range cliConfig.Profiles {
+		if p.Name == name {
+			profile = p
+			break
+		}
+	}
+	if profile == nil {
+		return nil, utils.Errorf("Profile %q not found in Aliyun CLI configuration %s", name, path)
+	}
+
+	// 将 profile 转换为对应凭据类型的配置，Bailian 相关的配置保持不变
+	profileConfig := *config
+	profileConfig.AliyunAccessKey = profile.AccessKeyId
+	profileConfig.AliyunSecretKey = profile.AccessKeySecret
+	profileConfig.AliyunSecurityToken = profile.StsToken
+	profileConfig.AliyunRoleArn = profile.RoleArn
+	profileConfig.AliyunRoleSessionName = profile.RoleSessionName
+	profileConfig.AliyunOIDCProviderArn = profile.OIDCProviderArn
+	profileConfig.AliyunOIDCTokenFile = profile.OIDCTokenFile
+	profileConfig.AliyunECSRoleName = profile.RoleName
+	switch profile.Mode {
+	case "AK", "":
+		profileConfig.CredentialType = spec.CredentialTypeAccessKey
+	case "StsToken":
+		profileConfig.CredentialType = spec.CredentialTypeSTS
+	case "RamRoleArn":
+		profileConfig.CredentialType = spec.CredentialTypeRAMRoleArn
+	case "EcsRamRole":
+		profileConfig.CredentialType = spec.CredentialTypeECSRAMRole
+	case "OIDC":
+		profileConfig.CredentialType = spec.CredentialTypeOIDCRoleArn
+	default:
+		return nil, utils.Errorf("Profile %q in %s uses mode %s, which is not supported (supported: AK, StsToken, RamRoleArn, EcsRamRole, OIDC)", name, path, profile.Mode)
+	}
+
+	cred, err := NewCredential(&profileConfig)
+	if err != nil {
+		return nil, utils.Errorf("Invalid profile %q in %s: %v", name, path, err)
+	}
+	cred.Source = "profile " + name + " in " + path
+	return cred, nil
+}
+
+// roleSessionName 扮演角色时使用的会话名称
+func roleSessionName(config *spec.Config) string {
+	return firstNonEmpty(config.AliyunRoleSessionName, os.Getenv(envRoleSessionName), defaultRoleSessionName)
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// logCredential 输出正在使用的凭据，不包含任何密钥
+func logCredential(cred *Credential) {
+	log.Infof("Using Alibaba Cloud credential: %s (%s)", cred.Type, cred.Source)
+}
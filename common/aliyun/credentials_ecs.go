@@ -0,0 +1,186 @@
+package aliyun
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VillanCh/ragsync/common/spec"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/aliyun/credentials-go/credentials"
+	"github.com/yaklang/yaklang/common/log"
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// defaultECSMetadataEndpoint ECS 实例元数据服务的地址
+const defaultECSMetadataEndpoint = "http://100.100.100.200"
+
+const (
+	// ecsCredentialRefreshAhead 临时凭据在过期前多久刷新
+	ecsCredentialRefreshAhead = 3 * time.Minute
+	// ecsMetadataTokenTTL 加固模式（IMDSv2）下申请的元数据 Token 的有效期（秒）
+	ecsMetadataTokenTTL = "21600"
+)
+
+// ecsRAMRoleCredential 通过 ECS 元数据服务获取实例 RAM 角色的 STS 临时凭据，过期前自动刷新
+// 元数据服务地址可以配置，便于在本地使用模拟的元数据服务
+type ecsRAMRoleCredential struct {
+	endpoint string
+	client   *http.Client
+
+	mu         sync.Mutex
+	roleName   string
+	current    *credentials.CredentialModel
+	expiration time.Time
+}
+
+// ecsCredentialResponse 元数据服务返回的临时凭据
+type ecsCredentialResponse struct {
+	Code            string `json:"Code"`
+	AccessKeyId     string `json:"AccessKeyId"`
+	AccessKeySecret string `json:"AccessKeySecret"`
+	SecurityToken   string `json:"SecurityToken"`
+	Expiration      string `json:"Expiration"`
+}
+
+func newECSRAMRoleCredential(endpoint string, roleName string) *ecsRAMRoleCredential {
+	return &ecsRAMRoleCredential{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		roleName: roleName,
+		client:   &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// GetCredential 返回当前有效的临时凭据，即将过期时重新获取
+func (c *ecsRAMRoleCredential) GetCredential() (*credentials.CredentialModel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.current != nil && time.Until(c.expiration) > ecsCredentialRefreshAhead {
+		return c.current, nil
+	}
+	if err := c.refresh(); err != nil {
+		return nil, err
+	}
+	return c.current, nil
+}
+
+func (c *ecsRAMRoleCredential) GetAccessKeyId() (*string, error) {
+	cred, err := c.GetCredential()
+	if err != nil {
+		return nil, err
+	}
+	return cred.AccessKeyId, nil
+}
+
+func (c *ecsRAMRoleCredential) GetAccessKeySecret() (*string, error) {
+	cred, err := c.GetCredential()
+	if err != nil {
+		return nil, err
+	}
+	return cred.AccessKeySecret, nil
+}
+
+func (c *ecsRAMRoleCredential) GetSecurityToken() (*string, error) {
+	cred, err := c.GetCredential()
+	if err != nil {
+		return nil, err
+	}
+	return cred.SecurityToken, nil
+}
+
+func (c *ecsRAMRoleCredential) GetBearerToken() *string {
+	return tea.String("")
+}
+
+func (c *ecsRAMRoleCredential) GetType() *string {
+	return tea.String(spec.CredentialTypeECSRAMRole)
+}
+
+// refresh 从元数据服务获取临时凭据，调用方需要持有锁
+func (c *ecsRAMRoleCredential) refresh() error {
+	// 优先使用加固模式，元数据服务不支持时退回普通模式
+	token := c.metadataToken()
+
+	if c.roleName == "" {
+		body, err := c.get("/latest/meta-data/ram/security-credentials/", token)
+		if err != nil {
+			return utils.Errorf("Failed to get the RAM role of the ECS instance: %v", err)
+		}
+		c.roleName = strings.TrimSpace(strings.SplitN(strings.TrimSpace(body), "\n", 2)[0])
+		if c.roleName == "" {
+			return utils.Errorf("No RAM role is attached to the ECS instance")
+		}
+	}
+
+	body, err := c.get("/latest/meta-data/ram/security-credentials/"+c.roleName, token)
+	if err != nil {
+		return utils.Errorf("Failed to get credentials of ECS RAM role %s: %v", c.roleName, err)
+	}
+	var rsp ecsCredentialResponse
+	if err := json.Unmarshal([]byte(body), &rsp); err != nil {
+		return utils.Errorf("Failed to parse credentials of ECS RAM role %s: %v", c.roleName, err)
+	}
+	if rsp.Code != "Success" || rsp.AccessKeyId == "" || rsp.AccessKeySecret == "" || rsp.SecurityToken == "" {
+		return utils.Errorf("Metadata service returned no credentials for ECS RAM role %s (code: %s)", c.roleName, rsp.Code)
+	}
+	expiration, err := time.Parse(time.RFC3339, rsp.Expiration)
+	if err != nil {
+		return utils.Errorf("Invalid expiration %q of ECS RAM role %s credentials: %v", rsp.Expiration, c.roleName, err)
+	}
+
+	c.current = &credentials.CredentialModel{
+		AccessKeyId:     tea.String(rsp.AccessKeyId),
+		AccessKeySecret: tea.String(rsp.AccessKeySecret),
+		SecurityToken:   tea.String(rsp.SecurityToken),
+		Type:            tea.String(spec.CredentialTypeECSRAMRole),
+	}
+	c.expiration = expiration
+	log.Infof("[Credential] Fetched credentials of ECS RAM role %s, expiring at %s", c.roleName, expiration.Local().Format(time.RFC3339))
+	return nil
+}
+
+// metadataToken 申请加固模式的元数据 Token，失败时返回空字符串
+func (c *ecsRAMRoleCredential) metadataToken() string {
+	req, err := http.NewRequest(http.MethodPut, c.endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("X-aliyun-ecs-metadata-token-ttl-seconds", ecsMetadataTokenTTL)
+	rsp, err := c.client.Do(req)
+	if err != nil {
+		log.Debugf("[Credential] Failed to get ECS metadata token, falling back to normal mode: %v", err)
+		return ""
+	}
+	defer rsp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(rsp.Body, 4<<10))
+	if rsp.StatusCode != http.StatusOK {
+		log.Debugf("[Credential] ECS metadata token request returned HTTP %d, falling back to normal mode", rsp.StatusCode)
+		return ""
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// get 请求元数据服务
+func (c *ecsRAMRoleCredential) get(path string, token string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, c.endpoint+path, nil)
+	if err != nil {
+		return "", err
+	}
+	if token != "" {
+		req.Header.Set("X-aliyun-ecs-metadata-token", token)
+	}
+	rsp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer rsp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(rsp.Body, 64<<10))
+	if rsp.StatusCode != http.StatusOK {
+		return "", &HTTPStatusError{StatusCode: rsp.StatusCode, Body: string(body)}
+	}
+	return string(body), nil
+}
@@ -0,0 +1,251 @@
+package aliyun
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VillanCh/ragsync/common/bailianmock"
+	"github.com/VillanCh/ragsync/common/spec"
+)
+
+// clearCredentialEnv 清除凭据相关的环境变量，HOME 指向没有阿里云 CLI 配置的临时目录
+func clearCredentialEnv(t *testing.T) string {
+	t.Helper()
+	for _, name := range []string{envAccessKeyId, envAccessKeySecret, envSecurityToken, envRoleArn, envRoleSessionName, envOIDCProviderArn, envOIDCTokenFile, envECSMetadata, envProfile} {
+		t.Setenv(name, "")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	return home
+}
+
+// newECSConfig 返回通过模拟的元数据服务获取凭据、访问模拟百炼服务的配置
+func newECSConfig(t *testing.T, roleName string) (*bailianmock.Server, *bailianmock.MetadataServer, *spec.Config) {
+	t.Helper()
+	clearCredentialEnv(t)
+	server, config := newMockConfig(t)
+	metadata := bailianmock.NewMetadataServer(roleName)
+	t.Cleanup(metadata.Close)
+
+	config.AliyunAccessKey = ""
+	config.AliyunSecretKey = ""
+	config.CredentialType = spec.CredentialTypeECSRAMRole
+	config.AliyunECSMetadataEndpoint = metadata.Endpoint()
+	return server, metadata, config
+}
+
+// writeCLIConfig 在 HOME 下写入阿里云 CLI 配置文件
+func writeCLIConfig(t *testing.T, home string, content string) {
+	t.Helper()
+	dir := filepath.Join(home, ".aliyun")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestECSRAMRoleCredential(t *testing.T) {
+	server, metadata, config := newECSConfig(t, "ragsync-role")
+	config.AliyunECSRoleName = "ragsync-role"
+
+	client, err := NewBailianClientFromConfig(config)
+	if err != nil {
+		t.Fatalf("NewBailianClientFromConfig: %v", err)
+	}
+	if _, err := client.ListFile(10, "", ""); err != nil {
+		t.Fatalf("ListFile: %v", err)
+	}
+	if token := server.LastSecurityToken(); token != metadata.SecurityToken(1) {
+		t.Fatalf("ListFile used security token %q, want %q", token, metadata.SecurityToken(1))
+	}
+
+	// 凭据还没有过期时不再请求元数据服务
+	if _, err := client.ListCategories(); err != nil {
+		t.Fatalf("ListCategories: %v", err)
+	}
+	if issued := metadata.Issued(); issued != 1 {
+		t.Fatalf("metadata service issued %d credentials, want 1", issued)
+	}
+}
+
+func TestECSRAMRoleDiscoveredInHardenedMode(t *testing.T) {
+	server, metadata, config := newECSConfig(t, "discovered-role")
+	metadata.RequireToken = true
+
+	credential, err := NewCredential(config)
+	if err != nil {
+		t.Fatalf("NewCredential: %v", err)
+	}
+	if credential.Type != spec.CredentialTypeECSRAMRole {
+		t.Fatalf("credential type = %s, want %s", credential.Type, spec.CredentialTypeECSRAMRole)
+	}
+	client, err := NewBailianClientFromConfig(config)
+	if err != nil {
+		t.Fatalf("NewBailianClientFromConfig: %v", err)
+	}
+	if _, err := client.ListFile(10, "", ""); err != nil {
+		t.Fatalf("ListFile: %v", err)
+	}
+	if token := server.LastSecurityToken(); token != metadata.SecurityToken(1) {
+		t.Fatalf("ListFile used security token %q, want %q", token, metadata.SecurityToken(1))
+	}
+}
+
+func TestECSRAMRoleNotAttached(t *testing.T) {
+	_, _, config := newECSConfig(t, "")
+
+	credential, err := NewCredential(config)
+	if err != nil {
+		t.Fatalf("NewCredential: %v", err)
+	}
+	_, err = credential.GetCredential()
+	if err == nil || !strings.Contains(err.Error(), "RAM role") {
+		t.Fatalf("GetCredential without an attached role returned %v, want a RAM role error", err)
+	}
+}
+
+func TestECSRAMRoleCredentialRefresh(t *testing.T) {
+	server, metadata, config := newECSConfig(t, "ragsync-role")
+	// 有效期短于提前刷新的时间，每次使用前都会重新获取
+	metadata.CredentialTTL = time.Minute
+
+	client, err := NewBailianClientFromConfig(config)
+	if err != nil {
+		t.Fatalf("NewBailianClientFromConfig: %v", err)
+	}
+	for i := 1; i <= 2; i++ {
+		if _, err := client.ListFile(10, "", ""); err != nil {
+			t.Fatalf("ListFile: %v", err)
+		}
+		if token := server.LastSecurityToken(); token != metadata.SecurityToken(metadata.Issued()) {
+			t.Fatalf("call %d used security token %q, want the latest one %q", i, token, metadata.SecurityToken(metadata.Issued()))
+		}
+	}
+	if issued := metadata.Issued(); issued < 2 {
+		t.Fatalf("metadata service issued %d credentials, want the credential to be refreshed", issued)
+	}
+}
+
+func TestSTSCredential(t *testing.T) {
+	clearCredentialEnv(t)
+	server, config := newMockConfig(t)
+	config.AliyunSecurityToken = "configured-sts-token"
+
+	// 配置了 SecurityToken 而没有指定 credential_type 时作为 STS 凭据使用
+	if typ := config.ResolvedCredentialType(); typ != spec.CredentialTypeSTS {
+		t.Fatalf("ResolvedCredentialType = %s, want %s", typ, spec.CredentialTypeSTS)
+	}
+	client, err := NewBailianClientFromConfig(config)
+	if err != nil {
+		t.Fatalf("NewBailianClientFromConfig: %v", err)
+	}
+	if _, err := client.ListFile(10, "", ""); err != nil {
+		t.Fatalf("ListFile: %v", err)
+	}
+	if token := server.LastSecurityToken(); token != "configured-sts-token" {
+		t.Fatalf("ListFile used security token %q, want configured-sts-token", token)
+	}
+
+	config.CredentialType = spec.CredentialTypeSTS
+	config.AliyunSecurityToken = ""
+	if _, err := NewCredential(config); err == nil {
+		t.Fatal("NewCredential accepted an sts credential without a security token")
+	}
+}
+
+func TestDefaultCredentialChainOrder(t *testing.T) {
+	metadata := bailianmock.NewMetadataServer("chain-role")
+	defer metadata.Close()
+	chainConfig := func() *spec.Config {
+		config := spec.GetDefaultConfig()
+		config.AliyunECSMetadataEndpoint = metadata.Endpoint()
+		return &config
+	}
+	cliProfile := `{"current": "ci", "profiles": [{"name": "ci", "mode": "StsToken", "access_key_id": "cli-ak", "access_key_secret": "cli-sk", "sts_token": "cli-token"}]}`
+
+	t.Run("environment first", func(t *testing.T) {
+		home := clearCredentialEnv(t)
+		writeCLIConfig(t, home, cliProfile)
+		t.Setenv(envAccessKeyId, "env-ak")
+		t.Setenv(envAccessKeySecret, "env-sk")
+		t.Setenv(envSecurityToken, "env-token")
+
+		credential, err := NewCredential(chainConfig())
+		if err != nil {
+			t.Fatalf("NewCredential: %v", err)
+		}
+		if credential.Type != spec.CredentialTypeSTS || credential.Source != "environment variables" {
+			t.Fatalf("credential = %s from %s, want sts from environment variables", credential.Type, credential.Source)
+		}
+	})
+
+	t.Run("cli profile before ecs", func(t *testing.T) {
+		home := clearCredentialEnv(t)
+		writeCLIConfig(t, home, cliProfile)
+
+		credential, err := NewCredential(chainConfig())
+		if err != nil {
+			t.Fatalf("NewCredential: %v", err)
+		}
+		if credential.Type != spec.CredentialTypeSTS || !strings.HasPrefix(credential.Source, "profile ci in ") {
+			t.Fatalf("credential = %s from %s, want sts from profile ci", credential.Type, credential.Source)
+		}
+		token, err := credential.GetSecurityToken()
+		if err != nil || *token != "cli-token" {
+			t.Fatalf("GetSecurityToken = %v, %v, want cli-token", token, err)
+		}
+	})
+
+	t.Run("ecs last", func(t *testing.T) {
+		clearCredentialEnv(t)
+		issued := metadata.Issued()
+
+		credential, err := NewCredential(chainConfig())
+		if err != nil {
+			t.Fatalf("NewCredential: %v", err)
+		}
+		if credential.Type != spec.CredentialTypeECSRAMRole {
+			t.Fatalf("credential type = %s, want %s", credential.Type, spec.CredentialTypeECSRAMRole)
+		}
+		token, err := credential.GetSecurityToken()
+		if err != nil || *token != metadata.SecurityToken(issued+1) {
+			t.Fatalf("GetSecurityToken = %v, %v, want %s", token, err, metadata.SecurityToken(issued+1))
+		}
+	})
+
+	t.Run("nothing configured", func(t *testing.T) {
+		clearCredentialEnv(t)
+		config := spec.GetDefaultConfig()
+		if _, err := NewCredential(&config); err == nil || !strings.Contains(err.Error(), "No Alibaba Cloud credentials found") {
+			t.Fatalf("NewCredential returned %v, want a no credentials error", err)
+		}
+	})
+}
+
+func TestCLIProfileECSRAMRole(t *testing.T) {
+	home := clearCredentialEnv(t)
+	server, config := newMockConfig(t)
+	metadata := bailianmock.NewMetadataServer("profile-role")
+	defer metadata.Close()
+	writeCLIConfig(t, home, `{"current": "ecs", "profiles": [{"name": "ecs", "mode": "EcsRamRole", "ram_role_name": "profile-role"}]}`)
+
+	config.AliyunAccessKey = ""
+	config.AliyunSecretKey = ""
+	config.CredentialType = spec.CredentialTypeCLIProfile
+	config.AliyunECSMetadataEndpoint = metadata.Endpoint()
+	client, err := NewBailianClientFromConfig(config)
+	if err != nil {
+		t.Fatalf("NewBailianClientFromConfig: %v", err)
+	}
+	if _, err := client.ListFile(10, "", ""); err != nil {
+		t.Fatalf("ListFile: %v", err)
+	}
+	if token := server.LastSecurityToken(); token != metadata.SecurityToken(1) {
+		t.Fatalf("ListFile used security token %q, want %q", token, metadata.SecurityToken(1))
+	}
+}
@@ -2,10 +2,9 @@
 package aliyun
 
 import (
-	"encoding/json"
-	"strings"
+	"context"
 
-	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	bailian20231229 "github.com/alibabacloud-go/bailian-20231229/v2/client"
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
@@ -13,12 +12,22 @@ import (
 
 // DeleteFile 删除指定ID的文件（先尝试删除索引，再删除文件）
 func (client *BailianClient) DeleteFile(fileId string) error {
+	return client.DeleteFileWithContext(context.Background(), fileId)
+}
+
+// DeleteFileWithContext 与 DeleteFile 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) DeleteFileWithContext(ctx context.Context, fileId string) error {
 	// 默认执行索引删除
-	return client.DeleteFileEx(fileId, false)
+	return client.DeleteFileExWithContext(ctx, fileId, false)
 }
 
 // DeleteFileEx 删除指定ID的文件，可选是否跳过索引删除
 func (client *BailianClient) DeleteFileEx(fileId string, skipDeleteIndex bool) error {
+	return client.DeleteFileExWithContext(context.Background(), fileId, skipDeleteIndex)
+}
+
+// DeleteFileExWithContext 与 DeleteFileEx 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) DeleteFileExWithContext(ctx context.Context, fileId string, skipDeleteIndex bool) error {
 	if client.config == nil {
 		return utils.Error("Client configuration is not set")
 	}
@@ -36,51 +45,41 @@ func (client *BailianClient) DeleteFileEx(fileId string, skipDeleteIndex bool) e
 		log.Infof("Attempting to delete document from index before deleting the file...")
 
 		// 尝试从索引中删除文档
-		err := client.DeleteIndexDocument(fileId)
-		if err != nil {
+		err := client.DeleteIndexDocumentWithContext(ctx, fileId)
+		if IsIndexDocumentMissing(err) {
+			// 文档已经不在索引中，不影响删除文件
+			log.Infof("Document %s is not in the index, proceeding to delete the file...", fileId)
+		} else if err != nil {
 			log.Errorf("Failed to delete document from index: %v", err)
-			return utils.Errorf("Cannot delete file because index document deletion failed: %v. Please resolve index issues first.", err)
+			return utils.Errorf("Cannot delete file because index document deletion failed: %w. Please resolve index issues first.", err)
+		} else {
+			log.Infof("Successfully deleted document from index, proceeding to delete the file...")
 		}
-
-		log.Infof("Successfully deleted document from index, proceeding to delete the file...")
 	} else if skipDeleteIndex {
 		log.Infof("Skipping index document deletion step as requested")
 	} else {
 		log.Infof("Knowledge Index ID not configured, skipping index document deletion step")
 	}
 
-	runtime := &util.RuntimeOptions{}
+	runtime := client.runtimeOptions()
 	headers := make(map[string]*string)
 
 	log.Infof("Deleting file with ID: %#v in workspace: %#v", fileId, client.config.BailianWorkspaceId)
 
 	// 调用API删除文件
-	response, err := client.Client.DeleteFileWithOptions(
-		tea.String(fileId),
-		tea.String(client.config.BailianWorkspaceId),
-		headers,
-		runtime,
-	)
+	var response *bailian20231229.DeleteFileResponse
+	err := client.retry(ctx, "DeleteFile", func() (err error) {
+		response, err = client.Client.DeleteFileWithOptions(
+			tea.String(fileId),
+			tea.String(client.config.BailianWorkspaceId),
+			headers,
+			runtime,
+		)
+		return err
+	})
 
 	if err != nil {
-		var sdkErr *tea.SDKError
-		if teaErr, ok := err.(*tea.SDKError); ok {
-			sdkErr = teaErr
-			// 尝试解析更详细的错误信息
-			if sdkErr.Data != nil {
-				var data interface{}
-				decoder := json.NewDecoder(strings.NewReader(tea.StringValue(sdkErr.Data)))
-				if decodeErr := decoder.Decode(&data); decodeErr == nil {
-					if m, ok := data.(map[string]interface{}); ok {
-						recommend, ok := m["Recommend"]
-						if ok {
-							log.Errorf("Detailed error information: %v", recommend)
-						}
-					}
-				}
-			}
-		}
-		return utils.Errorf("Failed to delete file: %v", err)
+		return utils.Errorf("Failed to delete file: %w", newAPIError("DeleteFile", err))
 	}
 
 	// 验证响应
@@ -90,11 +89,7 @@ func (client *BailianClient) DeleteFileEx(fileId string, skipDeleteIndex bool) e
 
 	// 检查响应是否成功
 	if response.Body.Success == nil || !*response.Body.Success {
-		errorMsg := "Unknown error"
-		if response.Body.Message != nil {
-			errorMsg = *response.Body.Message
-		}
-		return utils.Errorf("Failed to delete file: %v", errorMsg)
+		return utils.Errorf("Failed to delete file: %w", newResponseError("DeleteFile", response.Body.Code, response.Body.Message, response.Body.RequestId))
 	}
 
 	log.Infof("File deleted successfully: %s", fileId)
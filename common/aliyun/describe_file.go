@@ -2,27 +2,21 @@
 package aliyun
 
 import (
-	"encoding/json"
-	"strings"
+	"context"
 
-	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	bailian20231229 "github.com/alibabacloud-go/bailian-20231229/v2/client"
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
 )
 
-// FileInfo 文件信息结构体
-type FileInfo struct {
-	FileId     string `json:"fileId"`
-	FileName   string `json:"fileName"`
-	Status     string `json:"status"`
-	CategoryId string `json:"categoryId"`
-	CreateTime string `json:"createTime"` // 文件创建时间
-	Raw        any    `json:"raw"`
-}
-
 // DescribeFile 查询文件信息
 func (client *BailianClient) DescribeFile(fileId string) (*FileInfo, error) {
+	return client.DescribeFileWithContext(context.Background(), fileId)
+}
+
+// DescribeFileWithContext 与 DescribeFile 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) DescribeFileWithContext(ctx context.Context, fileId string) (*FileInfo, error) {
 	if client.config == nil {
 		return nil, utils.Error("Client configuration is not set")
 	}
@@ -35,36 +29,23 @@ func (client *BailianClient) DescribeFile(fileId string) (*FileInfo, error) {
 		return nil, utils.Error("File ID cannot be empty")
 	}
 
-	runtime := &util.RuntimeOptions{}
+	runtime := client.runtimeOptions()
 	headers := make(map[string]*string)
 
 	// 调用 API
-	response, err := client.Client.DescribeFileWithOptions(
-		tea.String(client.config.BailianWorkspaceId),
-		tea.String(fileId),
-		headers,
-		runtime,
-	)
+	var response *bailian20231229.DescribeFileResponse
+	err := client.retry(ctx, "DescribeFile", func() (err error) {
+		response, err = client.Client.DescribeFileWithOptions(
+			tea.String(client.config.BailianWorkspaceId),
+			tea.String(fileId),
+			headers,
+			runtime,
+		)
+		return err
+	})
 
 	if err != nil {
-		var sdkErr *tea.SDKError
-		if teaErr, ok := err.(*tea.SDKError); ok {
-			sdkErr = teaErr
-			// 尝试解析更详细的错误信息
-			if sdkErr.Data != nil {
-				var data interface{}
-				decoder := json.NewDecoder(strings.NewReader(tea.StringValue(sdkErr.Data)))
-				if decodeErr := decoder.Decode(&data); decodeErr == nil {
-					if m, ok := data.(map[string]interface{}); ok {
-						recommend, ok := m["Recommend"]
-						if ok {
-							log.Errorf("Detailed error information: %v", recommend)
-						}
-					}
-				}
-			}
-		}
-		return nil, utils.Errorf("Failed to describe file: %v", err)
+		return nil, utils.Errorf("Failed to describe file: %w", newAPIError("DescribeFile", err))
 	}
 
 	// 解析响应
@@ -74,11 +55,7 @@ func (client *BailianClient) DescribeFile(fileId string) (*FileInfo, error) {
 
 	// 检查响应是否成功
 	if response.Body.Success == nil || !*response.Body.Success {
-		errorMsg := "Unknown error"
-		if response.Body.Message != nil {
-			errorMsg = *response.Body.Message
-		}
-		return nil, utils.Errorf("Failed to describe file: %v", errorMsg)
+		return nil, utils.Errorf("Failed to describe file: %w", newResponseError("DescribeFile", response.Body.Code, response.Body.Message, response.Body.RequestId))
 	}
 
 	// 构造文件信息结构体
@@ -90,6 +67,13 @@ func (client *BailianClient) DescribeFile(fileId string) (*FileInfo, error) {
 		CategoryId: tea.StringValue(response.Body.Data.CategoryId),
 		CreateTime: tea.StringValue(response.Body.Data.CreateTime),
 	}
+	if response.Body.Data.SizeInBytes != nil {
+		fileInfo.SizeInBytes = *response.Body.Data.SizeInBytes
+	}
+	// 解析失败时响应的 Message 中是失败原因
+	if fileInfo.Status == "PARSE_FAILED" {
+		fileInfo.Message = tea.StringValue(response.Body.Message)
+	}
 
 	log.Infof("File information retrieved successfully, file ID: %s, name: %s", fileInfo.FileId, fileInfo.FileName)
 	return fileInfo, nil
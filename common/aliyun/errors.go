@@ -0,0 +1,217 @@
+package aliyun
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/VillanCh/ragsync/common/backend"
+
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/yaklang/yaklang/common/log"
+)
+
+// APIError 百炼 API 返回的错误，可以通过 errors.As 从各接口返回的错误中取出
+type APIError struct {
+	Action     string // 接口名称，例如 AddFile
+	StatusCode int    // HTTP 状态码，响应成功但 Success 为 false 时为 200
+	Code       string // 错误码，例如 Throttling.User
+	Message    string // 错误信息
+	RequestId  string // 请求 ID，联系阿里云支持时需要提供
+	Recommend  string // 百炼给出的诊断建议（通常是诊断页面的地址）
+
+	err error // SDK 或上传返回的原始错误
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Action)
+	b.WriteString(" failed")
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, " (HTTP %d)", e.StatusCode)
+	}
+	if e.Code != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Code)
+	}
+	if e.Message != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Message)
+	}
+	if e.RequestId != "" {
+		fmt.Fprintf(&b, " (Request ID: %s)", e.RequestId)
+	}
+	return b.String()
+}
+
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// Is 使资源不存在的错误可以用 errors.Is(err, backend.ErrNotFound) 判断
+func (e *APIError) Is(target error) bool {
+	return target == backend.ErrNotFound && IsNotFound(e)
+}
+
+// newAPIError 将 SDK 或上传返回的错误转换为 *APIError，并记录百炼给出的诊断建议
+// 网络错误以及 ctx 被取消或超时等不是由服务端返回的错误原样返回
+func newAPIError(action string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var sdkErr *tea.SDKError
+	if errors.As(err, &sdkErr) {
+		apiErr := &APIError{
+			Action:     action,
+			StatusCode: tea.IntValue(sdkErr.StatusCode),
+			Code:       tea.StringValue(sdkErr.Code),
+			Message:    tea.StringValue(sdkErr.Message),
+			err:        err,
+		}
+		// SDK 的 Message 中已经拼接了错误码和请求 ID，优先使用响应体中的原始字段
+		if data := tea.StringValue(sdkErr.Data); data != "" {
+			var body map[string]interface{}
+			if json.NewDecoder(strings.NewReader(data)).Decode(&body) == nil {
+				if message, ok := body["Message"].(string); ok && message != "" {
+					apiErr.Message = message
+				}
+				if requestId, ok := body["RequestId"].(string); ok {
+					apiErr.RequestId = requestId
+				}
+				if recommend, ok := body["Recommend"]; ok && recommend != nil {
+					apiErr.Recommend = fmt.Sprint(recommend)
+				}
+			}
+		}
+		if apiErr.Recommend != "" {
+			log.Errorf("[%s] Detailed error information: %s", action, apiErr.Recommend)
+		}
+		return apiErr
+	}
+
+	var ossErr *OSSError
+	if errors.As(err, &ossErr) {
+		return &APIError{
+			Action:     action,
+			StatusCode: ossErr.StatusCode,
+			Code:       ossErr.Code,
+			Message:    ossErr.Message,
+			RequestId:  ossErr.RequestId,
+			err:        err,
+		}
+	}
+
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return &APIError{
+			Action:     action,
+			StatusCode: statusErr.StatusCode,
+			Message:    strings.TrimSpace(statusErr.Body),
+			err:        err,
+		}
+	}
+	return err
+}
+
+// newResponseError 响应成功但 Success 为 false 时构造的错误
+func newResponseError(action string, code, message, requestId *string) *APIError {
+	apiErr := &APIError{
+		Action:     action,
+		StatusCode: http.StatusOK,
+		Code:       tea.StringValue(code),
+		Message:    tea.StringValue(message),
+		RequestId:  tea.StringValue(requestId),
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = "Unknown error"
+	}
+	return apiErr
+}
+
+// notFoundCodes 表示资源不存在的错误码片段
+var notFoundCodes = []string{"NotFound", "NotExist", "NoSuch"}
+
+// authFailureCodes 表示鉴权失败的错误码（前缀匹配）
+var authFailureCodes = []string{"InvalidAccessKeyId", "SignatureDoesNotMatch", "IncompleteSignature", "InvalidSecurityToken", "Forbidden", "NoPermission", "Unauthorized", "AccessDenied"}
+
+// asAPIError 从错误链中取出 *APIError
+func asAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	return nil, false
+}
+
+// IsNotFound 判断错误是否表示请求的资源（文件、索引、文档或任务）不存在
+// InvalidAccessKeyId.NotFound 等鉴权错误码不算，否则凭证错误会被当作远程文件已删除
+func IsNotFound(err error) bool {
+	apiErr, ok := asAPIError(err)
+	if !ok || hasCodePrefix(apiErr.Code, authFailureCodes) {
+		return false
+	}
+	if apiErr.StatusCode == http.StatusNotFound {
+		return true
+	}
+	for _, fragment := range notFoundCodes {
+		if strings.Contains(apiErr.Code, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsThrottled 判断错误是否为限流
+func IsThrottled(err error) bool {
+	if apiErr, ok := asAPIError(err); ok {
+		if apiErr.StatusCode == http.StatusTooManyRequests || hasCodePrefix(apiErr.Code, throttlingCodes) {
+			return true
+		}
+	}
+	var sdkErr *tea.SDKError
+	if errors.As(err, &sdkErr) {
+		if tea.IntValue(sdkErr.StatusCode) == http.StatusTooManyRequests {
+			return true
+		}
+		return hasCodePrefix(tea.StringValue(sdkErr.Code), throttlingCodes)
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// IsAuthFailure 判断错误是否为鉴权失败（AccessKey 无效、签名错误或没有权限）
+func IsAuthFailure(err error) bool {
+	apiErr, ok := asAPIError(err)
+	if !ok || IsLeaseExpired(err) {
+		return false
+	}
+	if apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden {
+		return true
+	}
+	return hasCodePrefix(apiErr.Code, authFailureCodes)
+}
+
+// IsLeaseExpired 判断上传失败是否因为上传租约（预签名地址）已经过期，需要重新申请租约
+func IsLeaseExpired(err error) bool {
+	apiErr, ok := asAPIError(err)
+	if !ok || apiErr.StatusCode != http.StatusForbidden {
+		return false
+	}
+	return strings.Contains(strings.ToLower(apiErr.Message), "expired")
+}
+
+// IsIndexDocumentMissing 判断错误是否表示文档不在知识索引中
+// 索引本身不存在（例如 Index.NotFound）不属于这种情况
+func IsIndexDocumentMissing(err error) bool {
+	if !IsNotFound(err) {
+		return false
+	}
+	apiErr, _ := asAPIError(err)
+	return strings.Contains(apiErr.Code, "Document")
+}
@@ -0,0 +1,137 @@
+package aliyun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/VillanCh/ragsync/common/backend"
+
+	"github.com/alibabacloud-go/tea/tea"
+)
+
+func TestAPIErrorClassifiers(t *testing.T) {
+	cases := []struct {
+		name         string
+		err          *APIError
+		notFound     bool
+		throttled    bool
+		authFailure  bool
+		leaseExpired bool
+		documentGone bool
+	}{
+		{"file not found by status", &APIError{StatusCode: http.StatusNotFound, Code: "File.NotFound"}, true, false, false, false, false},
+		{"not exist code on 400", &APIError{StatusCode: http.StatusBadRequest, Code: "FileNotExist"}, true, false, false, false, false},
+		{"missing index document", &APIError{StatusCode: http.StatusBadRequest, Code: "Index.Document.NotFound"}, true, false, false, false, true},
+		// 索引本身不存在不是文档缺失
+		{"missing index", &APIError{StatusCode: http.StatusNotFound, Code: "Index.NotFound"}, true, false, false, false, false},
+		{"throttled by status", &APIError{StatusCode: http.StatusTooManyRequests}, false, true, false, false, false},
+		{"throttled by code", &APIError{StatusCode: http.StatusBadRequest, Code: "Throttling.User"}, false, true, false, false, false},
+		{"throttled service unavailable", &APIError{StatusCode: http.StatusServiceUnavailable, Code: "ServiceUnavailable.Throttling"}, false, true, false, false, false},
+		{"server error", &APIError{StatusCode: http.StatusServiceUnavailable, Code: "ServiceUnavailable"}, false, false, false, false, false},
+		{"no permission", &APIError{StatusCode: http.StatusForbidden, Code: "NoPermission"}, false, false, true, false, false},
+		{"unauthorized", &APIError{StatusCode: http.StatusUnauthorized}, false, false, true, false, false},
+		// 错误码中的 NotFound 指的是 AccessKey，不是请求的资源
+		{"invalid access key on 404", &APIError{StatusCode: http.StatusNotFound, Code: "InvalidAccessKeyId.NotFound"}, false, false, true, false, false},
+		{"signature mismatch", &APIError{StatusCode: http.StatusBadRequest, Code: "SignatureDoesNotMatch"}, false, false, true, false, false},
+		// 预签名地址过期时 OSS 返回 403 AccessDenied，但需要重新申请租约而不是检查凭证
+		{"expired upload lease", &APIError{StatusCode: http.StatusForbidden, Code: "AccessDenied", Message: "Request has expired."}, false, false, false, true, false},
+		{"denied upload", &APIError{StatusCode: http.StatusForbidden, Code: "AccessDenied", Message: "Access denied."}, false, false, true, false, false},
+		{"business error", &APIError{StatusCode: http.StatusOK, Code: "InvalidParameter", Message: "bad"}, false, false, false, false, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// 各判断函数能从包装过的错误中取出 APIError
+			err := fmt.Errorf("Failed to sync: %w", c.err)
+			got := []bool{IsNotFound(err), IsThrottled(err), IsAuthFailure(err), IsLeaseExpired(err), IsIndexDocumentMissing(err)}
+			want := []bool{c.notFound, c.throttled, c.authFailure, c.leaseExpired, c.documentGone}
+			names := []string{"IsNotFound", "IsThrottled", "IsAuthFailure", "IsLeaseExpired", "IsIndexDocumentMissing"}
+			for i := range names {
+				if got[i] != want[i] {
+					t.Errorf("%s(%v) = %v, want %v", names[i], c.err, got[i], want[i])
+				}
+			}
+			if errors.Is(err, backend.ErrNotFound) != c.notFound {
+				t.Errorf("errors.Is(%v, backend.ErrNotFound) = %v, want %v", c.err, !c.notFound, c.notFound)
+			}
+		})
+	}
+}
+
+func TestClassifiersIgnoreOtherErrors(t *testing.T) {
+	for _, err := range []error{nil, errors.New("File.NotFound"), context.Canceled} {
+		if IsNotFound(err) || IsThrottled(err) || IsAuthFailure(err) || IsLeaseExpired(err) || IsIndexDocumentMissing(err) {
+			t.Errorf("%v is classified as an API error", err)
+		}
+	}
+	// 上传地址返回的 429 没有转换为 APIError 时也算作限流
+	if !IsThrottled(&HTTPStatusError{StatusCode: http.StatusTooManyRequests}) {
+		t.Error("an HTTP 429 from the upload URL is not throttled")
+	}
+}
+
+func TestNewAPIError(t *testing.T) {
+	sdkErr := &tea.SDKError{
+		StatusCode: tea.Int(http.StatusForbidden),
+		Code:       tea.String("NoPermission"),
+		Message:    tea.String("code: 403, You are not authorized request id: abc"),
+		Data:       tea.String(`{"Message": "You are not authorized", "RequestId": "req-1", "Recommend": "https://api.aliyun.com/troubleshoot"}`),
+	}
+	err := newAPIError("ListFile", sdkErr)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("newAPIError returned %T, want *APIError", err)
+	}
+	// 优先使用响应体中的原始字段，而不是 SDK 拼接后的信息
+	if apiErr.Action != "ListFile" || apiErr.Code != "NoPermission" || apiErr.Message != "You are not authorized" ||
+		apiErr.RequestId != "req-1" || apiErr.Recommend != "https://api.aliyun.com/troubleshoot" {
+		t.Fatalf("APIError = %+v, want the fields of the response body", apiErr)
+	}
+	if want := "ListFile failed (HTTP 403): NoPermission: You are not authorized (Request ID: req-1)"; apiErr.Error() != want {
+		t.Fatalf("Error() = %q, want %q", apiErr.Error(), want)
+	}
+	if !errors.Is(err, sdkErr) {
+		t.Fatal("APIError does not unwrap to the SDK error")
+	}
+
+	ossErr := &OSSError{HTTPStatusError: HTTPStatusError{StatusCode: http.StatusBadRequest}, Code: "InvalidDigest", Message: "digest mismatch", RequestId: "oss-1"}
+	if !errors.As(newAPIError("Upload", ossErr), &apiErr) || apiErr.Code != "InvalidDigest" || apiErr.RequestId != "oss-1" || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("APIError from an OSS error = %+v, want its code, request ID and status", apiErr)
+	}
+
+	statusErr := &HTTPStatusError{StatusCode: http.StatusBadGateway, Body: " bad gateway \n"}
+	if !errors.As(newAPIError("Upload", statusErr), &apiErr) || apiErr.Message != "bad gateway" || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("APIError from an HTTP status error = %+v, want the trimmed body", apiErr)
+	}
+
+	// 不是服务端返回的错误原样返回
+	if err := newAPIError("ListFile", context.DeadlineExceeded); err != context.DeadlineExceeded {
+		t.Fatalf("newAPIError(context.DeadlineExceeded) = %v, want it unchanged", err)
+	}
+	if newAPIError("ListFile", nil) != nil {
+		t.Fatal("newAPIError(nil) is not nil")
+	}
+}
+
+func TestNewResponseError(t *testing.T) {
+	err := newResponseError("AddFile", tea.String("InvalidParameter"), nil, tea.String("req-2"))
+	if err.StatusCode != http.StatusOK || err.Message != "Unknown error" {
+		t.Fatalf("newResponseError = %+v, want status 200 with the default message", err)
+	}
+	if !strings.Contains(err.Error(), "AddFile failed (HTTP 200): InvalidParameter") {
+		t.Fatalf("Error() = %q, want the action, status and code", err.Error())
+	}
+}
+
+func TestMockServerNotFoundIsClassified(t *testing.T) {
+	_, client := newMockClient(t)
+	if _, err := client.DescribeFile("file_missing"); !IsNotFound(err) || !errors.Is(err, backend.ErrNotFound) {
+		t.Fatalf("DescribeFile of a missing file returned %v, want a not found error", err)
+	}
+	if _, err := client.IndexJobStatus("job_missing"); !IsNotFound(err) {
+		t.Fatalf("IndexJobStatus of a missing job returned %v, want a not found error", err)
+	}
+}
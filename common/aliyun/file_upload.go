@@ -1,15 +1,13 @@
 package aliyun
 
 import (
-	"encoding/json"
+	"context"
 	"path/filepath"
 	"strconv"
-	"strings"
 
+	"github.com/VillanCh/ragsync/common/backend"
 	bailian20231229 "github.com/alibabacloud-go/bailian-20231229/v2/client"
-	util "github.com/alibabacloud-go/tea-utils/v2/service"
 	"github.com/alibabacloud-go/tea/tea"
-	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
 )
 
@@ -22,8 +20,13 @@ type FileUploadLease struct {
 	Raw       any    `json:"raw"`
 }
 
-// ApplyFileUploadLease 申请文件上传租约
-func (client *BailianClient) ApplyFileUploadLease(fileName string, content []byte) (*FileUploadLease, error) {
+// ApplyFileUploadLease 申请文件上传租约，content 的 MD5 和大小已以流的方式计算好
+func (client *BailianClient) ApplyFileUploadLease(fileName string, content *backend.Content) (*FileUploadLease, error) {
+	return client.ApplyFileUploadLeaseWithContext(context.Background(), fileName, content)
+}
+
+// ApplyFileUploadLeaseWithContext 与 ApplyFileUploadLease 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) ApplyFileUploadLeaseWithContext(ctx context.Context, fileName string, content *backend.Content) (*FileUploadLease, error) {
 	if client.config == nil {
 		return nil, utils.Error("Client configuration is not set")
 	}
@@ -37,43 +40,33 @@ func (client *BailianClient) ApplyFileUploadLease(fileName string, content []byt
 		return nil, utils.Error("File extension cannot be empty")
 	}
 
-	md5Str := utils.CalcMd5(string(content))
+	if content == nil {
+		return nil, utils.Error("File content cannot be nil")
+	}
+
 	request := &bailian20231229.ApplyFileUploadLeaseRequest{
 		CategoryType: tea.String(client.config.BailianCategoryType),
 		FileName:     tea.String(fileName),
-		Md5:          tea.String(md5Str),
-		SizeInBytes:  tea.String(strconv.Itoa(len(content))),
+		Md5:          tea.String(content.MD5),
+		SizeInBytes:  tea.String(strconv.FormatInt(content.Size, 10)),
 	}
-	runtime := &util.RuntimeOptions{}
+	runtime := client.runtimeOptions()
 	headers := make(map[string]*string)
 
 	// 调用 API
-	response, err := client.Client.ApplyFileUploadLeaseWithOptions(
-		tea.String(client.config.BailianFilesDefaultCategoryId),
-		tea.String(client.config.BailianWorkspaceId),
-		request,
-		headers,
-		runtime,
-	)
+	var response *bailian20231229.ApplyFileUploadLeaseResponse
+	err := client.retry(ctx, "ApplyFileUploadLease", func() (err error) {
+		response, err = client.Client.ApplyFileUploadLeaseWithOptions(
+			tea.String(client.config.BailianFilesDefaultCategoryId),
+			tea.String(client.config.BailianWorkspaceId),
+			request,
+			headers,
+			runtime,
+		)
+		return err
+	})
 	if err != nil {
-		var sdkErr *tea.SDKError
-		if teaErr, ok := err.(*tea.SDKError); ok {
-			sdkErr = teaErr
-			// 尝试解析更详细的错误信息
-			if sdkErr.Data != nil {
-				var data interface{}
-				decoder := json.NewDecoder(strings.NewReader(tea.StringValue(sdkErr.Data)))
-				if decodeErr := decoder.Decode(&data); decodeErr == nil {
-					if m, ok := data.(map[string]interface{}); ok {
-						recommend, ok := m["Recommend"]
-						if ok {
-							log.Errorf("Detailed error information: %v", recommend)
-						}
-					}
-				}
-			}
-		}
-		return nil, utils.Errorf("Failed to apply for file upload lease: %v", err)
+		return nil, utils.Errorf("Failed to apply for file upload lease: %w", newAPIError("ApplyFileUploadLease", err))
 	}
 
 	// 解析响应
@@ -82,7 +75,7 @@ func (client *BailianClient) ApplyFileUploadLease(fileName string, content []byt
 	}
 
 	if !tea.BoolValue(response.Body.Success) {
-		return nil, utils.Errorf("Failed to apply for file upload lease: %v", response.Body.Message)
+		return nil, utils.Errorf("Failed to apply for file upload lease: %w", newResponseError("ApplyFileUploadLease", response.Body.Code, response.Body.Message, response.Body.RequestId))
 	}
 
 	lease := &FileUploadLease{
@@ -2,11 +2,10 @@
 package aliyun
 
 import (
-	"encoding/json"
+	"context"
 	"strings"
 
 	bailian20231229 "github.com/alibabacloud-go/bailian-20231229/v2/client"
-	util "github.com/alibabacloud-go/tea-utils/v2/service"
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
@@ -39,6 +38,11 @@ func removeFileExtension(fileName string) string {
 
 // ListFile 列出工作空间下的文件
 func (client *BailianClient) ListFile(maxResults int32, nextToken string, fileName string) (*ListFilesResult, error) {
+	return client.ListFileWithContext(context.Background(), maxResults, nextToken, fileName)
+}
+
+// ListFileWithContext 与 ListFile 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) ListFileWithContext(ctx context.Context, maxResults int32, nextToken string, fileName string) (*ListFilesResult, error) {
 	if client.config == nil {
 		return nil, utils.Error("Client configuration is not set")
 	}
@@ -63,36 +67,23 @@ func (client *BailianClient) ListFile(maxResults int32, nextToken string, fileNa
 		MaxResults: tea.Int32(maxResults),
 	}
 
-	runtime := &util.RuntimeOptions{}
+	runtime := client.runtimeOptions()
 	headers := make(map[string]*string)
 
 	// 调用 API
-	response, err := client.Client.ListFileWithOptions(
-		tea.String(client.config.BailianWorkspaceId),
-		listFileRequest,
-		headers,
-		runtime,
-	)
+	var response *bailian20231229.ListFileResponse
+	err := client.retry(ctx, "ListFile", func() (err error) {
+		response, err = client.Client.ListFileWithOptions(
+			tea.String(client.config.BailianWorkspaceId),
+			listFileRequest,
+			headers,
+			runtime,
+		)
+		return err
+	})
 
 	if err != nil {
-		var sdkErr *tea.SDKError
-		if teaErr, ok := err.(*tea.SDKError); ok {
-			sdkErr = teaErr
-			// 尝试解析更详细的错误信息
-			if sdkErr.Data != nil {
-				var data interface{}
-				decoder := json.NewDecoder(strings.NewReader(tea.StringValue(sdkErr.Data)))
-				if decodeErr := decoder.Decode(&data); decodeErr == nil {
-					if m, ok := data.(map[string]interface{}); ok {
-						recommend, ok := m["Recommend"]
-						if ok {
-							log.Errorf("Detailed error information: %v", recommend)
-						}
-					}
-				}
-			}
-		}
-		return nil, utils.Errorf("Failed to list files: %v", err)
+		return nil, utils.Errorf("Failed to list files: %w", newAPIError("ListFile", err))
 	}
 
 	// 解析响应
@@ -102,11 +93,7 @@ func (client *BailianClient) ListFile(maxResults int32, nextToken string, fileNa
 
 	// 检查响应是否成功
 	if response.Body.Success == nil || !*response.Body.Success {
-		errorMsg := "Unknown error"
-		if response.Body.Message != nil {
-			errorMsg = *response.Body.Message
-		}
-		return nil, utils.Errorf("Failed to list files: %v", errorMsg)
+		return nil, utils.Errorf("Failed to list files: %w", newResponseError("ListFile", response.Body.Code, response.Body.Message, response.Body.RequestId))
 	}
 
 	// 构造结果
@@ -126,6 +113,9 @@ func (client *BailianClient) ListFile(maxResults int32, nextToken string, fileNa
 			CategoryId: tea.StringValue(fileItem.CategoryId),
 			CreateTime: tea.StringValue(fileItem.CreateTime),
 		}
+		if fileItem.SizeInBytes != nil {
+			fileInfo.SizeInBytes = *fileItem.SizeInBytes
+		}
 		result.Files = append(result.Files, fileInfo)
 	}
 
@@ -135,6 +125,11 @@ func (client *BailianClient) ListFile(maxResults int32, nextToken string, fileNa
 
 // ListAllFiles 列出所有文件（自动处理分页）
 func (client *BailianClient) ListAllFiles(fileName string) ([]*FileInfo, error) {
+	return client.ListAllFilesWithContext(context.Background(), fileName)
+}
+
+// ListAllFilesWithContext 与 ListAllFiles 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) ListAllFilesWithContext(ctx context.Context, fileName string) ([]*FileInfo, error) {
 	// 如果提供了文件名，移除扩展名
 	if fileName != "" {
 		fileName = removeFileExtension(fileName)
@@ -144,7 +139,7 @@ func (client *BailianClient) ListAllFiles(fileName string) ([]*FileInfo, error)
 	nextToken := ""
 
 	for {
-		result, err := client.ListFile(100, nextToken, fileName)
+		result, err := client.ListFileWithContext(ctx, 100, nextToken, fileName)
 		if err != nil {
 			return nil, err
 		}
@@ -164,6 +159,11 @@ func (client *BailianClient) ListAllFiles(fileName string) ([]*FileInfo, error)
 
 // ListAllFilesAsync 异步列出所有文件（自动处理分页），返回一个 channel
 func (client *BailianClient) ListAllFilesAsync(fileName string) (<-chan *FileInfo, <-chan error) {
+	return client.ListAllFilesAsyncWithContext(context.Background(), fileName)
+}
+
+// ListAllFilesAsyncWithContext 与 ListAllFilesAsync 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) ListAllFilesAsyncWithContext(ctx context.Context, fileName string) (<-chan *FileInfo, <-chan error) {
 	// 如果提供了文件名，移除扩展名
 	if fileName != "" {
 		fileName = removeFileExtension(fileName)
@@ -180,7 +180,7 @@ func (client *BailianClient) ListAllFilesAsync(fileName string) (<-chan *FileInf
 		totalFiles := 0
 
 		for {
-			result, err := client.ListFile(100, nextToken, fileName)
+			result, err := client.ListFileWithContext(ctx, 100, nextToken, fileName)
 			if err != nil {
 				errChan <- err
 				return
@@ -188,8 +188,13 @@ func (client *BailianClient) ListAllFilesAsync(fileName string) (<-chan *FileInf
 
 			// 发送文件到 channel
 			for _, file := range result.Files {
-				fileChan <- file
-				totalFiles++
+				select {
+				case fileChan <- file:
+					totalFiles++
+				case <-ctx.Done():
+					errChan <- ctx.Err()
+					return
+				}
 			}
 
 			// 检查是否有更多页
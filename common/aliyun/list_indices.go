@@ -1,12 +1,9 @@
 package aliyun
 
 import (
-	"encoding/json"
-	"strings"
+	"context"
 
-	"github.com/VillanCh/ragsync/common/spec"
 	bailian20231229 "github.com/alibabacloud-go/bailian-20231229/v2/client"
-	util "github.com/alibabacloud-go/tea-utils/v2/service"
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
@@ -19,45 +16,42 @@ type Index struct {
 }
 
 func ListIndices(accessKey, secretKey, workspaceId string) ([]Index, error) {
-	config := &spec.Config{
-		AliyunAccessKey:    accessKey,
-		AliyunSecretKey:    secretKey,
-		BailianWorkspaceId: workspaceId,
-	}
+	return ListIndicesWithContext(context.Background(), accessKey, secretKey, workspaceId)
+}
 
-	client, err := NewBailianClientFromConfig(config)
+// ListIndicesWithContext 与 ListIndices 相同，ctx 被取消或超时后立即返回
+func ListIndicesWithContext(ctx context.Context, accessKey, secretKey, workspaceId string) ([]Index, error) {
+	client, err := newWorkspaceClient(accessKey, secretKey, workspaceId)
 	if err != nil {
-		return nil, utils.Errorf("Failed to create client: %v", err)
+		return nil, err
 	}
+	return client.ListIndicesWithContext(ctx)
+}
+
+// ListIndices 列出客户端工作空间中的知识索引
+func (client *BailianClient) ListIndices() ([]Index, error) {
+	return client.ListIndicesWithContext(context.Background())
+}
 
+// ListIndicesWithContext 与 ListIndices 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) ListIndicesWithContext(ctx context.Context) ([]Index, error) {
+	workspaceId := client.config.BailianWorkspaceId
 	request := &bailian20231229.ListIndicesRequest{}
-	runtime := &util.RuntimeOptions{}
+	runtime := client.runtimeOptions()
 	headers := make(map[string]*string)
 
-	response, err := client.Client.ListIndicesWithOptions(
-		tea.String(workspaceId),
-		request,
-		headers,
-		runtime,
-	)
+	var response *bailian20231229.ListIndicesResponse
+	err := client.retry(ctx, "ListIndices", func() (err error) {
+		response, err = client.Client.ListIndicesWithOptions(
+			tea.String(workspaceId),
+			request,
+			headers,
+			runtime,
+		)
+		return err
+	})
 	if err != nil {
-		var sdkErr *tea.SDKError
-		if teaErr, ok := err.(*tea.SDKError); ok {
-			sdkErr = teaErr
-			if sdkErr.Data != nil {
-				var data interface{}
-				decoder := json.NewDecoder(strings.NewReader(tea.StringValue(sdkErr.Data)))
-				if decodeErr := decoder.Decode(&data); decodeErr == nil {
-					if m, ok := data.(map[string]interface{}); ok {
-						recommend, ok := m["Recommend"]
-						if ok {
-							log.Errorf("Detailed error information: %v", recommend)
-						}
-					}
-				}
-			}
-		}
-		return nil, utils.Errorf("Failed to list indices: %v", err)
+		return nil, utils.Errorf("Failed to list indices: %w", newAPIError("ListIndices", err))
 	}
 
 	if response == nil || response.Body == nil {
@@ -65,7 +59,7 @@ func ListIndices(accessKey, secretKey, workspaceId string) ([]Index, error) {
 	}
 
 	if !tea.BoolValue(response.Body.Success) {
-		return nil, utils.Errorf("Failed to list indices: %v", response.Body.Message)
+		return nil, utils.Errorf("Failed to list indices: %w", newResponseError("ListIndices", response.Body.Code, response.Body.Message, response.Body.RequestId))
 	}
 
 	var indices []Index
@@ -82,18 +76,28 @@ func ListIndices(accessKey, secretKey, workspaceId string) ([]Index, error) {
 	return indices, nil
 }
 
-func CreateIndex(accessKey, secretKey, workspaceId, name, sourceType string, categoryIds []string) error {
-	config := &spec.Config{
-		AliyunAccessKey:    accessKey,
-		AliyunSecretKey:    secretKey,
-		BailianWorkspaceId: workspaceId,
-	}
+// CreateIndex 使用 AccessKey 在工作空间中创建知识索引，返回新索引的 ID
+func CreateIndex(accessKey, secretKey, workspaceId, name, sourceType string, categoryIds []string) (string, error) {
+	return CreateIndexWithContext(context.Background(), accessKey, secretKey, workspaceId, name, sourceType, categoryIds)
+}
 
-	client, err := NewBailianClientFromConfig(config)
+// CreateIndexWithContext 与 CreateIndex 相同，ctx 被取消或超时后立即返回
+func CreateIndexWithContext(ctx context.Context, accessKey, secretKey, workspaceId, name, sourceType string, categoryIds []string) (string, error) {
+	client, err := newWorkspaceClient(accessKey, secretKey, workspaceId)
 	if err != nil {
-		return utils.Errorf("Failed to create client: %v", err)
+		return "", err
 	}
+	return client.CreateIndexWithContext(ctx, name, sourceType, categoryIds)
+}
+
+// CreateIndex 在客户端的工作空间中创建以 categoryIds 为数据源的知识索引，返回新索引的 ID
+func (client *BailianClient) CreateIndex(name, sourceType string, categoryIds []string) (string, error) {
+	return client.CreateIndexWithContext(context.Background(), name, sourceType, categoryIds)
+}
 
+// CreateIndexWithContext 与 CreateIndex 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) CreateIndexWithContext(ctx context.Context, name, sourceType string, categoryIds []string) (string, error) {
+	workspaceId := client.config.BailianWorkspaceId
 	request := &bailian20231229.CreateIndexRequest{
 		Name:          tea.String(name),
 		StructureType: tea.String("unstructured"),
@@ -101,43 +105,39 @@ func CreateIndex(accessKey, secretKey, workspaceId, name, sourceType string, cat
 		CategoryIds:   tea.StringSlice(categoryIds),
 		SinkType:      tea.String("BUILT_IN"),
 	}
-	runtime := &util.RuntimeOptions{}
+	runtime := client.runtimeOptions()
 	headers := make(map[string]*string)
 
-	response, err := client.Client.CreateIndexWithOptions(
-		tea.String(workspaceId),
-		request,
-		headers,
-		runtime,
-	)
+	var response *bailian20231229.CreateIndexResponse
+	err := client.retry(ctx, "CreateIndex", func() (err error) {
+		response, err = client.Client.CreateIndexWithOptions(
+			tea.String(workspaceId),
+			request,
+			headers,
+			runtime,
+		)
+		return err
+	})
 	if err != nil {
-		var sdkErr *tea.SDKError
-		if teaErr, ok := err.(*tea.SDKError); ok {
-			sdkErr = teaErr
-			if sdkErr.Data != nil {
-				var data interface{}
-				decoder := json.NewDecoder(strings.NewReader(tea.StringValue(sdkErr.Data)))
-				if decodeErr := decoder.Decode(&data); decodeErr == nil {
-					if m, ok := data.(map[string]interface{}); ok {
-						recommend, ok := m["Recommend"]
-						if ok {
-							log.Errorf("Detailed error information: %v", recommend)
-						}
-					}
-				}
-			}
-		}
-		return utils.Errorf("Failed to create index: %v", err)
+		return "", utils.Errorf("Failed to create index: %w", newAPIError("CreateIndex", err))
 	}
 
 	if response == nil || response.Body == nil {
-		return utils.Errorf("Create index response is empty")
+		return "", utils.Errorf("Create index response is empty")
 	}
 
 	if !tea.BoolValue(response.Body.Success) {
-		return utils.Errorf("Failed to create index: %v", response.Body.Message)
+		return "", utils.Errorf("Failed to create index: %w", newResponseError("CreateIndex", response.Body.Code, response.Body.Message, response.Body.RequestId))
+	}
+
+	var indexId string
+	if response.Body.Data != nil {
+		indexId = tea.StringValue(response.Body.Data.Id)
+	}
+	if indexId == "" {
+		return "", utils.Errorf("Create index response has no index Id (RequestId: %s)", tea.StringValue(response.Body.RequestId))
 	}
 
-	log.Infof("Index created successfully: %s", name)
-	return nil
+	log.Infof("Index created successfully: %s (%s)", name, indexId)
+	return indexId, nil
 }
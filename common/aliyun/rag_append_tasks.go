@@ -2,11 +2,9 @@
 package aliyun
 
 import (
-	"os"
-	"path/filepath"
-
+	"context"
+	"github.com/VillanCh/ragsync/common/backend"
 	bailian20231229 "github.com/alibabacloud-go/bailian-20231229/v2/client"
-	util "github.com/alibabacloud-go/tea-utils/v2/service"
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
@@ -14,6 +12,11 @@ import (
 
 // AppendDocumentsToIndex 将文档添加到知识库索引，并返回任务ID
 func (client *BailianClient) AppendDocumentsToIndex(documentIds []string) (string, error) {
+	return client.AppendDocumentsToIndexWithContext(context.Background(), documentIds)
+}
+
+// AppendDocumentsToIndexWithContext 与 AppendDocumentsToIndex 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) AppendDocumentsToIndexWithContext(ctx context.Context, documentIds []string) (string, error) {
 	if client.config.BailianKnowledgeIndexId == "" {
 		return "", utils.Errorf("Bailian knowledge index ID (BailianKnowledgeIndexId) is not configured")
 	}
@@ -32,20 +35,24 @@ func (client *BailianClient) AppendDocumentsToIndex(documentIds []string) (strin
 	}
 
 	// 运行时选项和请求头
-	runtime := &util.RuntimeOptions{}
+	runtime := client.runtimeOptions()
 	headers := make(map[string]*string)
 
 	// 发送请求
 	log.Infof("Adding %d documents to knowledge index: %s", len(documentIds), client.config.BailianKnowledgeIndexId)
-	response, err := client.Client.SubmitIndexAddDocumentsJobWithOptions(
-		tea.String(client.config.BailianWorkspaceId),
-		submitIndexAddDocumentsJobRequest,
-		headers,
-		runtime,
-	)
+	var response *bailian20231229.SubmitIndexAddDocumentsJobResponse
+	err := client.retry(ctx, "SubmitIndexAddDocumentsJob", func() (err error) {
+		response, err = client.Client.SubmitIndexAddDocumentsJobWithOptions(
+			tea.String(client.config.BailianWorkspaceId),
+			submitIndexAddDocumentsJobRequest,
+			headers,
+			runtime,
+		)
+		return err
+	})
 
 	if err != nil {
-		return "", utils.Errorf("Failed to add documents to index: %v", err)
+		return "", utils.Errorf("Failed to add documents to index: %w", newAPIError("SubmitIndexAddDocumentsJob", err))
 	}
 
 	// 打印响应信息
@@ -61,8 +68,8 @@ func (client *BailianClient) AppendDocumentsToIndex(documentIds []string) (strin
 			if jobId != "" {
 				log.Infof("Job ID: %s", jobId)
 
-				// 保存任务ID到本地文件
-				if err := saveJobIdToFile(jobId); err != nil {
+				// 保存任务ID及其包含的文档到本地文件
+				if err := backend.SaveIndexJobDocuments(jobId, documentIds); err != nil {
 					log.Warnf("Failed to save job ID to file: %v", err)
 				}
 			}
@@ -78,18 +85,23 @@ func (client *BailianClient) AppendDocumentsToIndex(documentIds []string) (strin
 
 // AppendDocumentToIndex 将单个文档添加到知识库索引 (便捷方法)
 func (client *BailianClient) AppendDocumentToIndex(documentId string) (string, error) {
+	return client.AppendDocumentToIndexWithContext(context.Background(), documentId)
+}
+
+// AppendDocumentToIndexWithContext 与 AppendDocumentToIndex 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) AppendDocumentToIndexWithContext(ctx context.Context, documentId string) (string, error) {
 	log.Infof("Start to appending document to knowledge index: %s, checking if it's already indexed...", documentId)
 
 	log.Infof("Getting file[%v] info...", documentId)
 	// 获取文档信息
-	fileInfo, err := client.DescribeFile(documentId)
+	fileInfo, err := client.DescribeFileWithContext(ctx, documentId)
 	if err != nil {
 		return "", utils.Errorf("Failed to get file info for document ID %s: %v", documentId, err)
 	}
 
 	log.Infof("Checking if file[%v] is already indexed...", fileInfo.FileName)
 	// 检查文档是否已经在索引中
-	isExisting, err := client.CheckAndWaitForExistingIndexJob(fileInfo.FileName)
+	isExisting, err := client.CheckAndWaitForExistingIndexJobWithContext(ctx, fileInfo.FileName)
 	if err != nil {
 		log.Warnf("Failed to check if document is already indexed: %v", err)
 		// 即使检查失败，仍然继续添加文档到索引
@@ -100,30 +112,5 @@ func (client *BailianClient) AppendDocumentToIndex(documentId string) (string, e
 	}
 
 	// 添加文档到索引
-	return client.AppendDocumentsToIndex([]string{documentId})
-}
-
-// saveJobIdToFile 将任务ID保存到本地文件
-func saveJobIdToFile(jobId string) error {
-	// 获取用户主目录
-	homeDir := utils.GetHomeDirDefault(".")
-
-	// 创建 ~/.ragsync/index-jobs/ 目录
-	jobsDir := filepath.Join(homeDir, ".ragsync", "index-jobs")
-	if err := os.MkdirAll(jobsDir, 0755); err != nil {
-		return utils.Errorf("Failed to create directory %s: %v", jobsDir, err)
-	}
-
-	// 创建文件，文件名为任务ID
-	jobFilePath := filepath.Join(jobsDir, jobId)
-
-	// 创建空文件
-	file, err := os.Create(jobFilePath)
-	if err != nil {
-		return utils.Errorf("Failed to create job file %s: %v", jobFilePath, err)
-	}
-	defer file.Close()
-
-	log.Infof("Job ID saved to file: %s", jobFilePath)
-	return nil
+	return client.AppendDocumentsToIndexWithContext(ctx, []string{documentId})
 }
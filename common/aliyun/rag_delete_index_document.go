@@ -2,11 +2,9 @@
 package aliyun
 
 import (
-	"encoding/json"
-	"strings"
+	"context"
 
 	bailian20231229 "github.com/alibabacloud-go/bailian-20231229/v2/client"
-	util "github.com/alibabacloud-go/tea-utils/v2/service"
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
@@ -14,6 +12,11 @@ import (
 
 // DeleteIndexDocument 从知识库索引中删除指定文档
 func (client *BailianClient) DeleteIndexDocument(documentId string) error {
+	return client.DeleteIndexDocumentWithContext(context.Background(), documentId)
+}
+
+// DeleteIndexDocumentWithContext 与 DeleteIndexDocument 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) DeleteIndexDocumentWithContext(ctx context.Context, documentId string) error {
 	if client.config == nil {
 		return utils.Error("Client configuration is not set")
 	}
@@ -36,7 +39,7 @@ func (client *BailianClient) DeleteIndexDocument(documentId string) error {
 		DocumentIds: []*string{tea.String(documentId)},
 	}
 
-	runtime := &util.RuntimeOptions{}
+	runtime := client.runtimeOptions()
 	headers := make(map[string]*string)
 
 	log.Infof("Deleting document with ID: %s from index: %s", documentId, client.config.BailianKnowledgeIndexId)
@@ -53,12 +56,15 @@ func (client *BailianClient) DeleteIndexDocument(documentId string) error {
 		}()
 
 		// 发送请求
-		response, err = client.Client.DeleteIndexDocumentWithOptions(
-			tea.String(client.config.BailianWorkspaceId),
-			deleteIndexDocumentRequest,
-			headers,
-			runtime,
-		)
+		err = client.retry(ctx, "DeleteIndexDocument", func() (err error) {
+			response, err = client.Client.DeleteIndexDocumentWithOptions(
+				tea.String(client.config.BailianWorkspaceId),
+				deleteIndexDocumentRequest,
+				headers,
+				runtime,
+			)
+			return err
+		})
 
 		if err != nil {
 			return err
@@ -68,24 +74,7 @@ func (client *BailianClient) DeleteIndexDocument(documentId string) error {
 	}()
 
 	if tryErr != nil {
-		var sdkErr *tea.SDKError
-		if teaErr, ok := tryErr.(*tea.SDKError); ok {
-			sdkErr = teaErr
-			// 尝试解析更详细的错误信息
-			if sdkErr.Data != nil {
-				var data interface{}
-				decoder := json.NewDecoder(strings.NewReader(tea.StringValue(sdkErr.Data)))
-				if decodeErr := decoder.Decode(&data); decodeErr == nil {
-					if m, ok := data.(map[string]interface{}); ok {
-						recommend, ok := m["Recommend"]
-						if ok {
-							log.Errorf("Detailed error information: %v", recommend)
-						}
-					}
-				}
-			}
-		}
-		return utils.Errorf("Failed to delete document from index: %v", tryErr)
+		return utils.Errorf("Failed to delete document from index: %w", newAPIError("DeleteIndexDocument", tryErr))
 	}
 
 	// 验证响应
@@ -95,11 +84,7 @@ func (client *BailianClient) DeleteIndexDocument(documentId string) error {
 
 	// 检查响应是否成功
 	if response.Body.Success == nil || !*response.Body.Success {
-		errorMsg := "Unknown error"
-		if response.Body.Message != nil {
-			errorMsg = *response.Body.Message
-		}
-		return utils.Errorf("Failed to delete document from index: %v", errorMsg)
+		return utils.Errorf("Failed to delete document from index: %w", newResponseError("DeleteIndexDocument", response.Body.Code, response.Body.Message, response.Body.RequestId))
 	}
 
 	log.Infof("Document deleted successfully from index: %s", documentId)
@@ -2,12 +2,10 @@
 package aliyun
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"strings"
 
 	bailian20231229 "github.com/alibabacloud-go/bailian-20231229/v2/client"
-	util "github.com/alibabacloud-go/tea-utils/v2/service"
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
@@ -15,6 +13,11 @@ import (
 
 // GetIndexJobStatus 获取索引任务状态
 func (client *BailianClient) GetIndexJobStatus(jobId string) (*bailian20231229.GetIndexJobStatusResponseBody, error) {
+	return client.GetIndexJobStatusWithContext(context.Background(), jobId)
+}
+
+// GetIndexJobStatusWithContext 与 GetIndexJobStatus 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) GetIndexJobStatusWithContext(ctx context.Context, jobId string) (*bailian20231229.GetIndexJobStatusResponseBody, error) {
 	if client.config.BailianKnowledgeIndexId == "" {
 		return nil, utils.Errorf("Bailian knowledge index ID (BailianKnowledgeIndexId) is not configured")
 	}
@@ -30,7 +33,7 @@ func (client *BailianClient) GetIndexJobStatus(jobId string) (*bailian20231229.G
 	}
 
 	// 运行时选项和请求头
-	runtime := &util.RuntimeOptions{}
+	runtime := client.runtimeOptions()
 	headers := make(map[string]*string)
 
 	// 记录请求日志
@@ -48,12 +51,15 @@ func (client *BailianClient) GetIndexJobStatus(jobId string) (*bailian20231229.G
 		}()
 
 		// 发送请求
-		response, err = client.Client.GetIndexJobStatusWithOptions(
-			tea.String(client.config.BailianWorkspaceId),
-			getIndexJobStatusRequest,
-			headers,
-			runtime,
-		)
+		err = client.retry(ctx, "GetIndexJobStatus", func() (err error) {
+			response, err = client.Client.GetIndexJobStatusWithOptions(
+				tea.String(client.config.BailianWorkspaceId),
+				getIndexJobStatusRequest,
+				headers,
+				runtime,
+			)
+			return err
+		})
 
 		if err != nil {
 			return err
@@ -64,30 +70,8 @@ func (client *BailianClient) GetIndexJobStatus(jobId string) (*bailian20231229.G
 
 	// 处理错误
 	if tryErr != nil {
-		var sdkErr = &tea.SDKError{}
-		if t, ok := tryErr.(*tea.SDKError); ok {
-			sdkErr = t
-		} else {
-			sdkErr.Message = tea.String(tryErr.Error())
-		}
-
-		// 记录错误信息
-		log.Errorf("Failed to get job status: %s", tea.StringValue(sdkErr.Message))
-
-		// 处理诊断信息
-		if sdkErr.Data != nil {
-			var data interface{}
-			d := json.NewDecoder(strings.NewReader(tea.StringValue(sdkErr.Data)))
-			if d.Decode(&data) == nil {
-				if m, ok := data.(map[string]interface{}); ok {
-					if recommend, ok := m["Recommend"]; ok {
-						log.Errorf("Error recommendation: %v", recommend)
-					}
-				}
-			}
-		}
-
-		return nil, utils.Errorf("Failed to get job status: %v", tryErr)
+		log.Errorf("Failed to get job status: %v", tryErr)
+		return nil, utils.Errorf("Failed to get job status: %w", newAPIError("GetIndexJobStatus", tryErr))
 	}
 
 	// 处理响应
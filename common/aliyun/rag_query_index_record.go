@@ -2,31 +2,22 @@
 package aliyun
 
 import (
+	"context"
 	"time"
 
 	bailian20231229 "github.com/alibabacloud-go/bailian-20231229/v2/client"
-	util "github.com/alibabacloud-go/tea-utils/v2/service"
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
 )
 
-// IndexDocumentRecord 索引文档记录
-type IndexDocumentRecord struct {
-	DocumentName string `json:"documentName"`
-	DocumentId   string `json:"documentId"`
-	Status       string `json:"status"`
-	IndexId      string `json:"indexId"`
-	DocumentType string `json:"documentType"`
-	Code         string `json:"code"`
-	Message      string `json:"message"`
-	Size         int32  `json:"size"`
-	SourceId     string `json:"sourceId"`
-	Raw          any    `json:"raw"`
-}
-
 // QueryIndexRecordFromDocumentName 根据文档名查询索引记录
 func (client *BailianClient) QueryIndexRecordFromDocumentName(documentName string) ([]*IndexDocumentRecord, error) {
+	return client.QueryIndexRecordFromDocumentNameWithContext(context.Background(), documentName)
+}
+
+// QueryIndexRecordFromDocumentNameWithContext 与 QueryIndexRecordFromDocumentName 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) QueryIndexRecordFromDocumentNameWithContext(ctx context.Context, documentName string) ([]*IndexDocumentRecord, error) {
 	if client.config == nil {
 		return nil, utils.Error("Client configuration is not set")
 	}
@@ -50,19 +41,23 @@ func (client *BailianClient) QueryIndexRecordFromDocumentName(documentName strin
 		DocumentStatus: tea.String(""),
 	}
 
-	runtime := &util.RuntimeOptions{}
+	runtime := client.runtimeOptions()
 	headers := make(map[string]*string)
 
 	// 调用 API
-	response, err := client.Client.ListIndexDocumentsWithOptions(
-		tea.String(client.config.BailianWorkspaceId),
-		listIndexDocumentsRequest,
-		headers,
-		runtime,
-	)
+	var response *bailian20231229.ListIndexDocumentsResponse
+	err := client.retry(ctx, "ListIndexDocuments", func() (err error) {
+		response, err = client.Client.ListIndexDocumentsWithOptions(
+			tea.String(client.config.BailianWorkspaceId),
+			listIndexDocumentsRequest,
+			headers,
+			runtime,
+		)
+		return err
+	})
 
 	if err != nil {
-		return nil, utils.Errorf("Failed to query index records: %v", err)
+		return nil, utils.Errorf("Failed to query index records: %w", newAPIError("ListIndexDocuments", err))
 	}
 
 	// 解析响应
@@ -72,11 +67,7 @@ func (client *BailianClient) QueryIndexRecordFromDocumentName(documentName strin
 
 	// 检查响应是否成功
 	if response.Body.Success == nil || !*response.Body.Success {
-		errorMsg := "Unknown error"
-		if response.Body.Message != nil {
-			errorMsg = *response.Body.Message
-		}
-		return nil, utils.Errorf("Failed to query index records: %v", errorMsg)
+		return nil, utils.Errorf("Failed to query index records: %w", newResponseError("ListIndexDocuments", response.Body.Code, response.Body.Message, response.Body.RequestId))
 	}
 
 	// 解析结果
@@ -114,6 +105,11 @@ func (client *BailianClient) QueryIndexRecordFromDocumentName(documentName strin
 
 // CheckAndWaitForExistingIndexJob 检查文档是否已在索引中，如果是，则等待并返回true
 func (client *BailianClient) CheckAndWaitForExistingIndexJob(documentName string) (bool, error) {
+	return client.CheckAndWaitForExistingIndexJobWithContext(context.Background(), documentName)
+}
+
+// CheckAndWaitForExistingIndexJobWithContext 与 CheckAndWaitForExistingIndexJob 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) CheckAndWaitForExistingIndexJobWithContext(ctx context.Context, documentName string) (bool, error) {
 	log.Infof("Checking if document[%v] is already indexed... QueryIndexRecordFromDocumentName", documentName)
 
 	// 如果提供了文件名，移除扩展名
@@ -121,7 +117,7 @@ func (client *BailianClient) CheckAndWaitForExistingIndexJob(documentName string
 		documentName = removeFileExtension(documentName)
 		log.Infof("Checking index records for document with name: %s (extension removed)", documentName)
 	}
-	records, err := client.QueryIndexRecordFromDocumentName(documentName)
+	records, err := client.QueryIndexRecordFromDocumentNameWithContext(ctx, documentName)
 	if err != nil {
 		return false, err
 	}
@@ -136,9 +132,120 @@ func (client *BailianClient) CheckAndWaitForExistingIndexJob(documentName string
 				i+1, record.DocumentId, record.Status)
 		}
 
-		time.Sleep(1 * time.Second)
+		if err := sleepContext(ctx, 1*time.Second); err != nil {
+			return false, err
+		}
 		return true, nil
 	}
 
 	return false, nil
 }
+
+// ListIndexDocuments 分页列出知识库索引中的文档
+func (client *BailianClient) ListIndexDocuments(pageNumber, pageSize int32) ([]*IndexDocumentRecord, int64, error) {
+	return client.ListIndexDocumentsWithContext(context.Background(), pageNumber, pageSize)
+}
+
+// ListIndexDocumentsWithContext 与 ListIndexDocuments 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) ListIndexDocumentsWithContext(ctx context.Context, pageNumber, pageSize int32) ([]*IndexDocumentRecord, int64, error) {
+	if client.config == nil {
+		return nil, 0, utils.Error("Client configuration is not set")
+	}
+
+	if client.config.BailianWorkspaceId == "" {
+		return nil, 0, utils.Error("Workspace ID is not set")
+	}
+
+	if client.config.BailianKnowledgeIndexId == "" {
+		return nil, 0, utils.Error("Knowledge Index ID is not set")
+	}
+
+	// 创建请求
+	listIndexDocumentsRequest := &bailian20231229.ListIndexDocumentsRequest{
+		IndexId:    tea.String(client.config.BailianKnowledgeIndexId),
+		PageNumber: tea.Int32(pageNumber),
+		PageSize:   tea.Int32(pageSize),
+	}
+
+	runtime := client.runtimeOptions()
+	headers := make(map[string]*string)
+
+	// 调用 API
+	var response *bailian20231229.ListIndexDocumentsResponse
+	err := client.retry(ctx, "ListIndexDocuments", func() (err error) {
+		response, err = client.Client.ListIndexDocumentsWithOptions(
+			tea.String(client.config.BailianWorkspaceId),
+			listIndexDocumentsRequest,
+			headers,
+			runtime,
+		)
+		return err
+	})
+
+	if err != nil {
+		return nil, 0, utils.Errorf("Failed to list index documents: %w", newAPIError("ListIndexDocuments", err))
+	}
+
+	// 解析响应
+	if response == nil || response.Body == nil {
+		return nil, 0, utils.Errorf("List index documents response is empty")
+	}
+
+	// 检查响应是否成功
+	if response.Body.Success == nil || !*response.Body.Success {
+		return nil, 0, utils.Errorf("Failed to list index documents: %w", newResponseError("ListIndexDocuments", response.Body.Code, response.Body.Message, response.Body.RequestId))
+	}
+
+	var records []*IndexDocumentRecord
+	var totalCount int64
+	if response.Body.Data != nil {
+		totalCount = tea.Int64Value(response.Body.Data.TotalCount)
+		for _, doc := range response.Body.Data.Documents {
+			record := &IndexDocumentRecord{
+				Raw:          doc,
+				DocumentName: tea.StringValue(doc.Name),
+				DocumentId:   tea.StringValue(doc.Id),
+				Status:       tea.StringValue(doc.Status),
+				DocumentType: tea.StringValue(doc.DocumentType),
+				Code:         tea.StringValue(doc.Code),
+				Message:      tea.StringValue(doc.Message),
+				SourceId:     tea.StringValue(doc.SourceId),
+				IndexId:      client.config.BailianKnowledgeIndexId,
+			}
+			if doc.Size != nil {
+				record.Size = *doc.Size
+			}
+			records = append(records, record)
+		}
+	}
+
+	return records, totalCount, nil
+}
+
+// ListAllIndexDocuments 列出知识库索引中的所有文档（自动处理分页）
+func (client *BailianClient) ListAllIndexDocuments() ([]*IndexDocumentRecord, error) {
+	return client.ListAllIndexDocumentsWithContext(context.Background())
+}
+
+// ListAllIndexDocumentsWithContext 与 ListAllIndexDocuments 相同，ctx 被取消或超时后立即返回
+func (client *BailianClient) ListAllIndexDocumentsWithContext(ctx context.Context) ([]*IndexDocumentRecord, error) {
+	var pageSize int32 = 100
+	allRecords := make([]*IndexDocumentRecord, 0)
+
+	for pageNumber := int32(1); ; pageNumber++ {
+		records, totalCount, err := client.ListIndexDocumentsWithContext(ctx, pageNumber, pageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		allRecords = append(allRecords, records...)
+
+		// 检查是否有更多页
+		if len(records) < int(pageSize) || int64(len(allRecords)) >= totalCount {
+			break
+		}
+	}
+
+	log.Infof("Retrieved %d index documents in total", len(allRecords))
+	return allRecords, nil
+}
@@ -0,0 +1,132 @@
+package aliyun
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/yaklang/yaklang/common/log"
+)
+
+// defaultRateLimits 各接口默认的 QPS 上限，参考百炼 API 文档中的单账号流控并留有余量
+// 配置文件中的 rate_limit 可以覆盖单个接口的值，0 表示不限制
+var defaultRateLimits = map[string]float64{
+	"ApplyFileUploadLease":       10,
+	"AddFile":                    10,
+	"DescribeFile":               10,
+	"ListFile":                   5,
+	"DeleteFile":                 10,
+	"AddCategory":                5,
+	"ListCategory":               5,
+	"ListIndices":                5,
+	"CreateIndex":                2,
+	"ListIndexDocuments":         5,
+	"SubmitIndexAddDocumentsJob": 5,
+	"DeleteIndexDocument":        5,
+	"GetIndexJobStatus":          10,
+}
+
+// tokenBucket 单个接口的令牌桶，令牌可以被预支，等待者按调用顺序依次放行
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // 每秒补充的令牌数
+	burst  float64 // 桶容量
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	burst := math.Max(1, math.Ceil(rate))
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// reserve 取走一个令牌，返回需要等待的时间
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel 归还预支但没有使用的令牌，使后面的调用不必为被取消的调用等待
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
+
+// RateLimiter 按接口限制百炼 API 的调用频率
+type RateLimiter struct {
+	buckets map[string]*tokenBucket
+}
+
+// NewRateLimiter 使用默认 QPS 和配置中的覆盖值创建限流器
+func NewRateLimiter(overrides map[string]float64) *RateLimiter {
+	limits := make(map[string]float64, len(defaultRateLimits))
+	for action, qps := range defaultRateLimits {
+		limits[action] = qps
+	}
+	for action, qps := range overrides {
+		limits[action] = qps
+	}
+
+	limiter := &RateLimiter{buckets: make(map[string]*tokenBucket)}
+	for action, qps := range limits {
+		if qps > 0 {
+			limiter.buckets[action] = newTokenBucket(qps)
+		}
+	}
+	return limiter
+}
+
+// Wait 阻塞直到可以调用 action，未限制的接口立即返回，ctx 结束时归还令牌并返回 ctx 的错误
+func (l *RateLimiter) Wait(ctx context.Context, action string) error {
+	if l == nil {
+		return nil
+	}
+	bucket, ok := l.buckets[action]
+	if !ok {
+		return nil
+	}
+	wait := bucket.reserve()
+	if wait <= 0 {
+		return nil
+	}
+	if wait >= time.Second {
+		log.Infof("[RateLimit] %s is limited to %v QPS, waiting %v", action, bucket.rate, wait.Round(time.Millisecond))
+	}
+	if err := sleepContext(ctx, wait); err != nil {
+		bucket.cancel()
+		return err
+	}
+	return nil
+}
+
+var (
+	sharedRateLimitersMu sync.Mutex
+	sharedRateLimiters   = make(map[string]*RateLimiter)
+)
+
+// sharedRateLimiter 返回同一凭据身份（AccessKey 或扮演的角色）、同一限流配置共用的限流器
+// 流控按账号计算，因此并发的 worker 以及 watch 等长时间运行的模式中创建的多个客户端需要共用令牌桶
+func sharedRateLimiter(identity string, overrides map[string]float64) *RateLimiter {
+	key := identity + "|" + fmt.Sprint(overrides)
+
+	sharedRateLimitersMu.Lock()
+	defer sharedRateLimitersMu.Unlock()
+	if limiter, ok := sharedRateLimiters[key]; ok {
+		return limiter
+	}
+	limiter := NewRateLimiter(overrides)
+	sharedRateLimiters[key] = limiter
+	return limiter
+}
@@ -0,0 +1,50 @@
+package aliyun
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterWaitReturnsTokenOnCancel(t *testing.T) {
+	limiter := NewRateLimiter(map[string]float64{"ListFile": 1})
+	bucket := limiter.buckets["ListFile"]
+
+	// 第一次调用取走桶中唯一的令牌
+	if err := limiter.Wait(context.Background(), "ListFile"); err != nil {
+		t.Fatalf("first Wait: %v", err)
+	}
+
+	// 第二次调用需要等待约 1 秒，在等待中取消
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, "ListFile"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("cancelled Wait returned %v, want context.DeadlineExceeded", err)
+	}
+
+	// 被取消的调用归还了令牌，下一次调用的等待时间不会因此变长
+	wait := bucket.reserve()
+	if wait > time.Second {
+		t.Fatalf("wait after a cancelled call is %v, want at most 1s", wait)
+	}
+}
+
+func TestRateLimiterUnlimitedAction(t *testing.T) {
+	limiter := NewRateLimiter(map[string]float64{"ListFile": 0})
+	for i := 0; i < 100; i++ {
+		if err := limiter.Wait(context.Background(), "ListFile"); err != nil {
+			t.Fatalf("Wait on an unlimited action: %v", err)
+		}
+	}
+}
+
+func TestMockConfigDisablesRateLimits(t *testing.T) {
+	// 模拟服务的测试配置需要覆盖所有有默认限流的接口，新增接口时测试不会被限流拖慢
+	_, config := newMockConfig(t)
+	if limiter := NewRateLimiter(config.RateLimit); len(limiter.buckets) != 0 {
+		for action := range limiter.buckets {
+			t.Errorf("the mock test config still rate limits %s", action)
+		}
+	}
+}
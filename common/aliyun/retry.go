@@ -0,0 +1,299 @@
+package aliyun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/VillanCh/ragsync/common/spec"
+	util "github.com/alibabacloud-go/tea-utils/v2/service"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/yaklang/yaklang/common/log"
+)
+
+// RetryPolicy 百炼 API 调用的重试策略：指数退避加随机抖动
+type RetryPolicy struct {
+	MaxAttempts    int           // 最多尝试次数（包括第一次），1 表示不重试
+	InitialBackoff time.Duration // 第一次重试前的最长等待时间
+	MaxBackoff     time.Duration // 单次等待的上限
+	MaxElapsed     time.Duration // 从第一次调用开始的总时间上限，0 表示不限制
+	CallTimeout    time.Duration // 单次调用的超时时间，0 表示只使用 SDK 的默认超时
+}
+
+// 默认重试策略
+const (
+	defaultRetryMaxAttempts    = 5
+	defaultRetryInitialBackoff = 500 * time.Millisecond
+	defaultRetryMaxBackoff     = 30 * time.Second
+	defaultRetryMaxElapsed     = 2 * time.Minute
+)
+
+// NewRetryPolicy 根据配置创建重试策略，未配置的项使用默认值
+func NewRetryPolicy(config spec.RetryConfig) *RetryPolicy {
+	policy := &RetryPolicy{
+		MaxAttempts:    config.MaxAttempts,
+		InitialBackoff: config.InitialBackoff,
+		MaxBackoff:     config.MaxBackoff,
+		MaxElapsed:     config.MaxElapsed,
+		CallTimeout:    config.CallTimeout,
+	}
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = defaultRetryMaxAttempts
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = defaultRetryInitialBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = defaultRetryMaxBackoff
+	}
+	if policy.MaxBackoff < policy.InitialBackoff {
+		policy.MaxBackoff = policy.InitialBackoff
+	}
+	if policy.MaxElapsed == 0 {
+		policy.MaxElapsed = defaultRetryMaxElapsed
+	}
+	return policy
+}
+
+// nonIdempotentActions 重复执行会产生重复数据的接口，只在被限流时重试
+// 限流时请求不会被执行，而 5xx 或网络错误时请求可能已经生效
+var nonIdempotentActions = map[string]bool{
+	"AddFile":                    true,
+	"AddCategory":                true,
+	"CreateIndex":                true,
+	"SubmitIndexAddDocumentsJob": true,
+}
+
+// throttlingCodes 百炼返回的限流错误码（前缀匹配）
+var throttlingCodes = []string{"Throttling", "RequestThrottled", "ServiceUnavailable.Throttling", "TooManyRequests"}
+
+// transientCodes 百炼返回的临时错误码（前缀匹配）
+var transientCodes = []string{"ServiceUnavailable", "InternalError", "InternalServerError", "RequestTimeout", "Timeout", "SystemBusy"}
+
+// HTTPStatusError 非 API 调用（例如上传文件内容）返回的 HTTP 错误
+type HTTPStatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+	Body       string
+}
+
+func (e *HTTPStatusError) Error() string {
+	body := strings.TrimSpace(e.Body)
+	if len(body) > 200 {
+		body = body[:200] + "..."
+	}
+	if body == "" {
+		return fmt.Sprintf("unexpected HTTP status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected HTTP status %d: %s", e.StatusCode, body)
+}
+
+// isRetryable 判断错误是否可以重试：限流、5xx、临时错误码以及网络错误，无法分类的错误不重试
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if IsThrottled(err) {
+		return true
+	}
+
+	var sdkErr *tea.SDKError
+	if errors.As(err, &sdkErr) {
+		status := tea.IntValue(sdkErr.StatusCode)
+		if status >= 500 {
+			return true
+		}
+		return hasCodePrefix(tea.StringValue(sdkErr.Code), transientCodes)
+	}
+
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusRequestTimeout
+	}
+
+	// HTTP 客户端返回的 *url.Error 本身实现了 net.Error，需要按其中的错误判断
+	// 读取请求体（本地文件）失败同样包装在 *url.Error 中，重试也不会成功；服务端没有响应就关闭连接时为 io.EOF
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return isTransportError(urlErr.Err) || errors.Is(urlErr.Err, io.EOF)
+	}
+	return isTransportError(err)
+}
+
+// isTransportError 判断是否为网络层错误：连接失败、被重置、超时或响应被截断
+func isTransportError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// callTimeoutError 单次调用超过 retry.call_timeout，作为网络超时处理，可以重试
+type callTimeoutError struct {
+	action  string
+	timeout time.Duration
+}
+
+func (e *callTimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %v", e.action, e.timeout)
+}
+
+func (e *callTimeoutError) Timeout() bool   { return true }
+func (e *callTimeoutError) Temporary() bool { return true }
+
+// hasCodePrefix 判断错误码是否以任一前缀开头
+func hasCodePrefix(code string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(code, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfter 返回服务端要求的等待时间
+// OpenAPI SDK 的错误中不包含响应头，因此只有上传文件内容时能拿到 Retry-After
+func retryAfter(err error) time.Duration {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.RetryAfter
+	}
+	return 0
+}
+
+// parseRetryAfter 解析 Retry-After 头，支持秒数和 HTTP 日期两种格式
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(t); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// backoff 返回第 attempt 次重试前的等待时间（full jitter）
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	limit := p.InitialBackoff << uint(attempt-1)
+	if limit <= 0 || limit > p.MaxBackoff {
+		limit = p.MaxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(limit) + 1))
+}
+
+// Do 按重试策略执行 fn，action 用于日志和判断接口是否幂等
+// ctx 被取消或超时后不再重试，等待中的退避也会立即结束
+func (p *RetryPolicy) Do(ctx context.Context, action string, fn func() error) error {
+	if p == nil {
+		return fn()
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fn()
+		if err == nil {
+			if attempt > 1 {
+				log.Infof("[Retry] %s succeeded after %d attempts", action, attempt)
+			}
+			return nil
+		}
+
+		if ctx.Err() != nil || !isRetryable(err) || (nonIdempotentActions[action] && !IsThrottled(err)) {
+			return err
+		}
+		if attempt >= p.MaxAttempts {
+			log.Warnf("[Retry] %s failed after %d attempts: %v", action, attempt, err)
+			return err
+		}
+
+		wait := p.backoff(attempt)
+		if after := retryAfter(err); after > wait {
+			wait = after
+		}
+		if p.MaxElapsed > 0 && time.Since(start)+wait > p.MaxElapsed {
+			log.Warnf("[Retry] %s giving up after %v (%d attempts): %v", action, time.Since(start).Round(time.Millisecond), attempt, err)
+			return err
+		}
+
+		log.Warnf("[Retry] %s failed (attempt %d/%d), retrying in %v: %v", action, attempt, p.MaxAttempts, wait.Round(time.Millisecond), err)
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// sleepContext 等待 d，ctx 结束时提前返回 ctx 的错误
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// callWithTimeout 在单次调用超时或 ctx 结束时立即返回
+// OpenAPI SDK 不支持 context，被放弃的请求会在 SDK 自身的读超时后结束
+func (p *RetryPolicy) callWithTimeout(ctx context.Context, action string, fn func() error) error {
+	if p == nil || (p.CallTimeout <= 0 && ctx.Done() == nil) {
+		return fn()
+	}
+
+	callCtx := ctx
+	if p.CallTimeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, p.CallTimeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-callCtx.Done():
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return &callTimeoutError{action: action, timeout: p.CallTimeout}
+	}
+}
+
+// retry 按客户端的重试策略调用百炼 API，每次尝试前都会经过限流
+func (client *BailianClient) retry(ctx context.Context, action string, fn func() error) error {
+	return client.retryPolicy.Do(ctx, action, func() error {
+		if err := client.rateLimiter.Wait(ctx, action); err != nil {
+			return err
+		}
+		return client.retryPolicy.callWithTimeout(ctx, action, fn)
+	})
+}
+
+// runtimeOptions 返回 SDK 调用的运行时选项，配置了单次调用超时时同步设置 SDK 的读超时
+func (client *BailianClient) runtimeOptions() *util.RuntimeOptions {
+	runtime := &util.RuntimeOptions{}
+	if client.retryPolicy != nil && client.retryPolicy.CallTimeout > 0 {
+		runtime.ReadTimeout = tea.Int(int(client.retryPolicy.CallTimeout / time.Millisecond))
+	}
+	return runtime
+}
@@ -0,0 +1,218 @@
+package aliyun
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/VillanCh/ragsync/common/backend"
+	"github.com/VillanCh/ragsync/common/bailianmock"
+)
+
+func TestRetryThrottling(t *testing.T) {
+	server, client := newMockClient(t)
+	server.InjectFault("ListFile", bailianmock.Throttling(2))
+
+	if _, err := client.ListFile(10, "", ""); err != nil {
+		t.Fatalf("ListFile after two throttled calls: %v", err)
+	}
+	if calls := server.Calls("ListFile"); calls != 3 {
+		t.Fatalf("ListFile was called %d times, want 3", calls)
+	}
+}
+
+func TestRetryThrottlingExhausted(t *testing.T) {
+	server, client := newMockClient(t)
+	server.InjectFault("ListCategory", bailianmock.Throttling(0))
+
+	_, err := client.ListCategories()
+	if !IsThrottled(err) {
+		t.Fatalf("ListCategories returned %v, want a throttling error", err)
+	}
+	if calls := server.Calls("ListCategory"); calls != 3 {
+		t.Fatalf("ListCategory was called %d times, want MaxAttempts (3)", calls)
+	}
+}
+
+func TestRetryServerError(t *testing.T) {
+	server, client := newMockClient(t)
+	server.InjectFault("ListIndexDocuments", bailianmock.ServerError(1))
+
+	if _, _, err := client.ListIndexDocuments(1, 10); err != nil {
+		t.Fatalf("ListIndexDocuments after a server error: %v", err)
+	}
+	if calls := server.Calls("ListIndexDocuments"); calls != 2 {
+		t.Fatalf("ListIndexDocuments was called %d times, want 2", calls)
+	}
+}
+
+func TestRetryMalformedBody(t *testing.T) {
+	server, client := newMockClient(t)
+	server.InjectFault("ListFile", bailianmock.MalformedBody(1))
+
+	if _, err := client.ListFile(10, "", ""); err != nil {
+		t.Fatalf("ListFile after a malformed response: %v", err)
+	}
+	if calls := server.Calls("ListFile"); calls != 2 {
+		t.Fatalf("ListFile was called %d times, want 2", calls)
+	}
+}
+
+func TestRetryNonIdempotentServerError(t *testing.T) {
+	server, client := newMockClient(t)
+	content := backend.BytesContent([]byte("once"))
+	lease, err := client.ApplyUploadLease("once.md", content)
+	if err != nil {
+		t.Fatalf("ApplyUploadLease: %v", err)
+	}
+	if err := client.UploadContent(lease, "once.md", content); err != nil {
+		t.Fatalf("UploadContent: %v", err)
+	}
+
+	// AddFile 在 5xx 时可能已经生效，不能重试
+	server.InjectFault("AddFile", bailianmock.ServerError(1))
+	if _, err := client.AddFile(lease.LeaseId); err == nil {
+		t.Fatal("AddFile succeeded despite the injected server error")
+	}
+	if calls := server.Calls("AddFile"); calls != 1 {
+		t.Fatalf("AddFile was called %d times, want 1", calls)
+	}
+
+	// 限流时请求没有执行，可以重试
+	server.InjectFault("AddFile", bailianmock.Throttling(1))
+	if _, err := client.AddFile(lease.LeaseId); err != nil {
+		t.Fatalf("AddFile after a throttled call: %v", err)
+	}
+}
+
+func TestRetryNoPermission(t *testing.T) {
+	server, client := newMockClient(t)
+	server.InjectFault("ListFile", bailianmock.NoPermission(1))
+
+	_, err := client.ListFile(10, "", "")
+	if !IsAuthFailure(err) {
+		t.Fatalf("ListFile returned %v, want an auth failure", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Recommend == "" {
+		t.Fatalf("ListFile returned %v, want an APIError with the recommendation", err)
+	}
+	if calls := server.Calls("ListFile"); calls != 1 {
+		t.Fatalf("ListFile was called %d times, want 1", calls)
+	}
+}
+
+func TestRetryUploadServerError(t *testing.T) {
+	server, client := newMockClient(t)
+	server.InjectFault(bailianmock.UploadAction, bailianmock.ServerError(1))
+
+	addMockFile(t, client, "retried.md", []byte("retried"))
+	if calls := server.Calls(bailianmock.UploadAction); calls != 2 {
+		t.Fatalf("upload was called %d times, want 2", calls)
+	}
+}
+
+func TestUploadLeaseExpired(t *testing.T) {
+	server, client := newMockClient(t)
+	server.LeaseTTL = 10 * time.Millisecond
+
+	content := backend.BytesContent([]byte("late"))
+	lease, err := client.ApplyUploadLease("late.md", content)
+	if err != nil {
+		t.Fatalf("ApplyUploadLease: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	err = client.UploadContent(lease, "late.md", content)
+	if !IsLeaseExpired(err) || IsAuthFailure(err) {
+		t.Fatalf("UploadContent with an expired lease returned %v, want a lease expired error", err)
+	}
+	var ossErr *OSSError
+	if !errors.As(err, &ossErr) || ossErr.Code != "AccessDenied" || ossErr.StatusCode != 403 {
+		t.Fatalf("UploadContent returned %v, want the OSS AccessDenied document", err)
+	}
+	if calls := server.Calls(bailianmock.UploadAction); calls != 1 {
+		t.Fatalf("upload was called %d times, want 1", calls)
+	}
+}
+
+func TestUploadOSSError(t *testing.T) {
+	server, client := newMockClient(t)
+
+	lease, err := client.ApplyUploadLease("digest.md", backend.BytesContent([]byte("declared")))
+	if err != nil {
+		t.Fatalf("ApplyUploadLease: %v", err)
+	}
+	// 上传的内容与申请租约时的 MD5 不一致
+	err = client.UploadContent(lease, "digest.md", backend.BytesContent([]byte("modified")))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "InvalidDigest" || apiErr.Action != "Upload" {
+		t.Fatalf("UploadContent returned %v, want an APIError with the OSS code InvalidDigest", err)
+	}
+	if calls := server.Calls(bailianmock.UploadAction); calls != 1 {
+		t.Fatalf("upload was called %d times, want 1", calls)
+	}
+}
+
+func TestUploadLocalErrorNotRetried(t *testing.T) {
+	server, client := newMockClient(t)
+
+	filePath := filepath.Join(t.TempDir(), "gone.md")
+	if err := os.WriteFile(filePath, []byte("gone"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	content, err := backend.FileContent(filePath)
+	if err != nil {
+		t.Fatalf("FileContent: %v", err)
+	}
+	lease, err := client.ApplyUploadLease("gone.md", content)
+	if err != nil {
+		t.Fatalf("ApplyUploadLease: %v", err)
+	}
+	if err := os.Remove(filePath); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.UploadContent(lease, "gone.md", content); err == nil {
+		t.Fatal("UploadContent succeeded after the file was removed")
+	}
+	if calls := server.Calls(bailianmock.UploadAction); calls != 0 {
+		t.Fatalf("upload was called %d times, want 0", calls)
+	}
+}
+
+func TestRetryUploadConnectionDropped(t *testing.T) {
+	server, client := newMockClient(t)
+	server.InjectFault(bailianmock.UploadAction, bailianmock.DropConnection(1))
+
+	addMockFile(t, client, "dropped.md", []byte("dropped"))
+	if calls := server.Calls(bailianmock.UploadAction); calls != 2 {
+		t.Fatalf("upload was called %d times, want 2", calls)
+	}
+}
+
+func TestIsRetryableURLError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection reset", &url.Error{Op: "Put", URL: "http://oss", Err: syscall.ECONNRESET}, true},
+		{"connection closed", &url.Error{Op: "Put", URL: "http://oss", Err: io.EOF}, true},
+		{"truncated response", &url.Error{Op: "Put", URL: "http://oss", Err: io.ErrUnexpectedEOF}, true},
+		{"timeout", &url.Error{Op: "Put", URL: "http://oss", Err: &callTimeoutError{action: "Upload", timeout: time.Second}}, true},
+		{"local read error", &url.Error{Op: "Put", URL: "http://oss", Err: os.ErrPermission}, false},
+		{"wrapped local read error", fmt.Errorf("Failed to upload file: %w", &url.Error{Op: "Put", URL: "http://oss", Err: errors.New("file changed while uploading")}), false},
+	}
+	for _, c := range cases {
+		if got := isRetryable(c.err); got != c.want {
+			t.Errorf("isRetryable(%s) = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
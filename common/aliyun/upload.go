@@ -1,32 +1,132 @@
 package aliyun
 
 import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
 	"path/filepath"
+	"strings"
 
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
-	"github.com/yaklang/yaklang/common/utils/lowhttp/poc"
 )
 
+// uploadHTTPClient 上传文件内容使用的 HTTP 客户端，超时由 ctx 控制
+var uploadHTTPClient = &http.Client{}
+
+// OSSError 上传地址（OSS 预签名 URL）返回的 XML 错误文档，例如租约过期时的 AccessDenied
+type OSSError struct {
+	HTTPStatusError
+	Code      string
+	Message   string
+	RequestId string
+	HostId    string
+}
+
+func (e *OSSError) Error() string {
+	msg := fmt.Sprintf("OSS returned HTTP %d: %s: %s", e.StatusCode, e.Code, e.Message)
+	if e.RequestId != "" {
+		msg += fmt.Sprintf(" (Request ID: %s)", e.RequestId)
+	}
+	return msg
+}
+
+func (e *OSSError) Unwrap() error {
+	return &e.HTTPStatusError
+}
+
+// ossErrorDocument OSS 错误响应体的格式
+type ossErrorDocument struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	RequestId string   `xml:"RequestId"`
+	HostId    string   `xml:"HostId"`
+}
+
+// parseOSSError 将 OSS 的 XML 错误文档解析为 *OSSError，响应体不是错误文档时返回 nil
+func parseOSSError(statusErr *HTTPStatusError) *OSSError {
+	body := strings.TrimSpace(statusErr.Body)
+	if !strings.HasPrefix(body, "<") {
+		return nil
+	}
+	var doc ossErrorDocument
+	if err := xml.Unmarshal([]byte(body), &doc); err != nil || doc.Code == "" {
+		return nil
+	}
+	return &OSSError{
+		HTTPStatusError: *statusErr,
+		Code:            doc.Code,
+		Message:         doc.Message,
+		RequestId:       doc.RequestId,
+		HostId:          doc.HostId,
+	}
+}
+
+// uploadStatusError 根据上传响应的状态码和响应体构造错误，上传成功时返回 nil
+// OSS 的错误文档会被解析为 *OSSError，状态码为 2xx 但响应体是错误文档时同样视为失败
+func uploadStatusError(rsp *http.Response, body []byte) error {
+	statusErr := &HTTPStatusError{
+		StatusCode: rsp.StatusCode,
+		RetryAfter: parseRetryAfter(rsp.Header.Get("Retry-After")),
+		Body:       string(body),
+	}
+	if ossErr := parseOSSError(statusErr); ossErr != nil {
+		return ossErr
+	}
+	if rsp.StatusCode >= 200 && rsp.StatusCode < 300 {
+		return nil
+	}
+	return statusErr
+}
+
 // UploadFile 上传文件到指定URL
 func UploadFile(method string, uploadURL string, fileName string, contentType string, content []byte, bailianExtra string) error {
+	return UploadFileWithContext(context.Background(), method, uploadURL, fileName, contentType, content, bailianExtra)
+}
+
+// UploadFileWithContext 与 UploadFile 相同，ctx 被取消或超时后中断上传
+func UploadFileWithContext(ctx context.Context, method string, uploadURL string, fileName string, contentType string, content []byte, bailianExtra string) error {
+	return UploadFileFromReader(ctx, method, uploadURL, fileName, contentType, bytes.NewReader(content), int64(len(content)), bailianExtra)
+}
+
+// UploadFileFromReader 以流的方式上传文件内容，size 作为 Content-Length，body 不会被整体读入内存
+// ctx 被取消或超时后中断上传
+func UploadFileFromReader(ctx context.Context, method string, uploadURL string, fileName string, contentType string, body io.Reader, size int64, bailianExtra string) error {
 	// 获取文件扩展名
 	ext := filepath.Ext(fileName)
 	if ext == "" {
 		return utils.Errorf("File extension cannot be empty")
 	}
 
-	rsp, req, err := poc.Do(
-		method, uploadURL,
-		poc.WithReplaceHttpPacketBody(content, false),
-		poc.WithReplaceHttpPacketHeader("Content-Type", contentType),
-		poc.WithReplaceHttpPacketHeader("X-bailian-extra", bailianExtra),
-	)
+	req, err := http.NewRequestWithContext(ctx, method, uploadURL, body)
 	if err != nil {
-		return utils.Errorf("Failed to upload file: %v", err)
+		return utils.Errorf("Failed to create upload request: %v", err)
+	}
+	// 预签名地址要求固定长度的请求体，不能使用 chunked 编码
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-bailian-extra", bailianExtra)
+
+	rsp, err := uploadHTTPClient.Do(req)
+	if err != nil {
+		// 保留 *url.Error，重试策略据此区分网络错误和读取本地内容失败
+		return utils.Errorf("Failed to upload file: %w", err)
+	}
+	defer rsp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(rsp.Body, 64<<10))
+
+	// 上传地址返回非 2xx 或错误文档时视为失败，限流和 5xx 由调用方按重试策略重试
+	if err := uploadStatusError(rsp, respBody); err != nil {
+		log.Errorf("Upload file failed: %s: %v", fileName, err)
+		return err
 	}
-	_ = req
-	_ = rsp
-	log.Infof("Upload file success: %s", fileName)
+	log.Infof("Upload file success: %s (HTTP %d)", fileName, rsp.StatusCode)
 	return nil
 }
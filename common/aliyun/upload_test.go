@@ -0,0 +1,81 @@
+package aliyun
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+const expiredDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<Error>
+  <Code>AccessDenied</Code>
+  <Message>Request has expired.</Message>
+  <RequestId>5F1A2B3C</RequestId>
+  <HostId>bucket.oss-cn-beijing.aliyuncs.com</HostId>
+</Error>`
+
+func TestParseOSSError(t *testing.T) {
+	ossErr := parseOSSError(&HTTPStatusError{StatusCode: http.StatusForbidden, Body: "\n" + expiredDocument})
+	if ossErr == nil {
+		t.Fatal("parseOSSError did not parse the OSS error document")
+	}
+	if ossErr.Code != "AccessDenied" || ossErr.Message != "Request has expired." || ossErr.RequestId != "5F1A2B3C" ||
+		ossErr.HostId != "bucket.oss-cn-beijing.aliyuncs.com" || ossErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("OSSError = %+v, want the fields of the document", ossErr)
+	}
+	if want := "OSS returned HTTP 403: AccessDenied: Request has expired. (Request ID: 5F1A2B3C)"; ossErr.Error() != want {
+		t.Fatalf("Error() = %q, want %q", ossErr.Error(), want)
+	}
+
+	for name, body := range map[string]string{
+		"plain text":        "Service Unavailable",
+		"empty":             "",
+		"malformed XML":     "<Error><Code>AccessDenied",
+		"XML without code":  "<Error><Message>no code</Message></Error>",
+		"other XML element": "<Result><Code>OK</Code></Result>",
+	} {
+		if ossErr := parseOSSError(&HTTPStatusError{StatusCode: http.StatusBadRequest, Body: body}); ossErr != nil {
+			t.Errorf("parseOSSError(%s) = %+v, want nil", name, ossErr)
+		}
+	}
+}
+
+func TestUploadStatusError(t *testing.T) {
+	response := func(status int, retryAfter string) *http.Response {
+		rsp := &http.Response{StatusCode: status, Header: http.Header{}}
+		if retryAfter != "" {
+			rsp.Header.Set("Retry-After", retryAfter)
+		}
+		return rsp
+	}
+
+	if err := uploadStatusError(response(http.StatusOK, ""), nil); err != nil {
+		t.Fatalf("uploadStatusError for HTTP 200 = %v, want nil", err)
+	}
+
+	// 状态码为 2xx 但响应体是错误文档时同样视为失败
+	err := uploadStatusError(response(http.StatusOK, ""), []byte(expiredDocument))
+	var ossErr *OSSError
+	if !errors.As(err, &ossErr) || ossErr.Code != "AccessDenied" {
+		t.Fatalf("uploadStatusError for an error document with HTTP 200 = %v, want an OSSError", err)
+	}
+
+	// OSSError 可以按 HTTPStatusError 处理，重试策略据此读取状态码
+	err = uploadStatusError(response(http.StatusForbidden, ""), []byte(expiredDocument))
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("uploadStatusError for HTTP 403 = %v, want it to unwrap to the HTTP status", err)
+	}
+	if !IsLeaseExpired(newAPIError("Upload", err)) {
+		t.Fatalf("%v is not recognised as an expired lease", err)
+	}
+
+	err = uploadStatusError(response(http.StatusServiceUnavailable, "2"), []byte("busy"))
+	if !errors.As(err, &statusErr) || statusErr.RetryAfter != 2*time.Second || statusErr.Body != "busy" {
+		t.Fatalf("uploadStatusError for HTTP 503 = %+v, want the body and Retry-After", err)
+	}
+	if errors.As(err, &ossErr) {
+		t.Fatalf("a plain text error body was parsed as an OSS error: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/VillanCh/ragsync/common/spec"
+
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// DefaultBackend 配置中未指定 backend 时使用的后端
+const DefaultBackend = "bailian"
+
+// ErrNotFound 请求的文件、文档或任务不存在，各后端返回的错误用 errors.Is 判断
+var ErrNotFound = errors.New("not found")
+
+// Backend 知识库后端：同步逻辑只依赖这个接口，不依赖具体的云服务
+// 除 Name 外的方法都接受 ctx，ctx 被取消或超时后应尽快返回
+type Backend interface {
+	// Name 返回后端名称
+	Name() string
+
+	// ListAllFilesWithContext 列出所有文件，fileName 非空时只返回同名文件
+	ListAllFilesWithContext(ctx context.Context, fileName string) ([]*FileInfo, error)
+	// DescribeFileWithContext 查询文件信息
+	DescribeFileWithContext(ctx context.Context, fileId string) (*FileInfo, error)
+	// ApplyUploadLeaseWithContext 按内容的大小和 MD5 申请文件上传租约
+	ApplyUploadLeaseWithContext(ctx context.Context, fileName string, content *Content) (*UploadLease, error)
+	// UploadContentWithContext 按租约以流的方式上传文件内容
+	UploadContentWithContext(ctx context.Context, lease *UploadLease, fileName string, content *Content) error
+	// AddFileWithContext 将已上传的内容添加为文件，返回文件 ID
+	AddFileWithContext(ctx context.Context, leaseId string) (string, error)
+	// DeleteFileExWithContext 删除文件，skipIndexDelete 为 true 时保留索引中的文档
+	DeleteFileExWithContext(ctx context.Context, fileId string, skipIndexDelete bool) error
+
+	// AppendDocumentToIndexWithContext 将单个文件加入知识索引，返回任务 ID
+	AppendDocumentToIndexWithContext(ctx context.Context, documentId string) (string, error)
+	// AppendDocumentsToIndexWithContext 将多个文件作为一个任务加入知识索引，返回任务 ID
+	AppendDocumentsToIndexWithContext(ctx context.Context, documentIds []string) (string, error)
+	// DeleteIndexDocumentWithContext 从知识索引中删除文档
+	DeleteIndexDocumentWithContext(ctx context.Context, documentId string) error
+	// ListAllIndexDocumentsWithContext 列出知识索引中的所有文档
+	ListAllIndexDocumentsWithContext(ctx context.Context) ([]*IndexDocumentRecord, error)
+	// DocumentIndexedWithContext 判断文档是否已在知识索引中（或正在建立索引）
+	DocumentIndexedWithContext(ctx context.Context, documentName string) (bool, error)
+	// IndexJobStatusWithContext 查询索引任务状态
+	IndexJobStatusWithContext(ctx context.Context, jobId string) (*IndexJob, error)
+}
+
+// Factory 根据配置创建后端
+type Factory func(config *spec.Config) (Backend, error)
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]Factory)
+)
+
+// Register 注册一个后端，通常在实现包的 init 中调用
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[strings.ToLower(name)] = factory
+}
+
+// Names 返回所有已注册的后端名称
+func Names() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New 根据配置中的 backend 字段创建后端
+func New(config *spec.Config) (Backend, error) {
+	if config == nil {
+		return nil, utils.Error("Configuration cannot be nil")
+	}
+	name := strings.ToLower(strings.TrimSpace(config.Backend))
+	if name == "" {
+		name = DefaultBackend
+	}
+
+	registryMu.Lock()
+	factory, ok := registry[name]
+	registryMu.Unlock()
+	if !ok {
+		return nil, utils.Errorf("Unknown backend %q, available backends: %s", name, strings.Join(Names(), ", "))
+	}
+	return factory(config)
+}
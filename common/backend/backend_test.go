@@ -0,0 +1,62 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/VillanCh/ragsync/common/spec"
+)
+
+// namedBackend 只实现 Name 的测试后端，其他方法不会被调用
+type namedBackend struct {
+	Backend
+	name string
+}
+
+func (b *namedBackend) Name() string {
+	return b.name
+}
+
+// registerTestBackend 注册返回 namedBackend 的后端
+func registerTestBackend(name string) {
+	Register(name, func(config *spec.Config) (Backend, error) {
+		return &namedBackend{name: name}, nil
+	})
+}
+
+func TestNewSelectsBackend(t *testing.T) {
+	registerTestBackend(DefaultBackend)
+	registerTestBackend("Offline")
+
+	cases := []struct {
+		backend string
+		want    string
+	}{
+		{"", DefaultBackend},
+		{"bailian", DefaultBackend},
+		// 名称不区分大小写，忽略首尾空白
+		{" OFFLINE ", "Offline"},
+		{"offline", "Offline"},
+	}
+	for _, c := range cases {
+		b, err := New(&spec.Config{Backend: c.backend})
+		if err != nil {
+			t.Fatalf("New(%q): %v", c.backend, err)
+		}
+		if b.Name() != c.want {
+			t.Fatalf("New(%q) returned backend %s, want %s", c.backend, b.Name(), c.want)
+		}
+	}
+}
+
+func TestNewUnknownBackend(t *testing.T) {
+	registerTestBackend(DefaultBackend)
+
+	_, err := New(&spec.Config{Backend: "s3"})
+	if err == nil || !strings.Contains(err.Error(), `"s3"`) || !strings.Contains(err.Error(), DefaultBackend) {
+		t.Fatalf("New(s3) returned %v, want an error listing the available backends", err)
+	}
+	if _, err := New(nil); err == nil {
+		t.Fatal("New accepted a nil configuration")
+	}
+}
@@ -0,0 +1,80 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// Content 待上传的文件内容：大小和摘要在申请租约前以流的方式计算，上传时再按需打开读取
+// 文件内容不会一次性读入内存，重试上传时会重新打开
+type Content struct {
+	Size   int64
+	MD5    string
+	SHA256 string
+
+	open func() (io.ReadCloser, error)
+}
+
+// Open 打开内容用于读取，调用方负责关闭
+func (c *Content) Open() (io.ReadCloser, error) {
+	return c.open()
+}
+
+// FileContent 以流的方式计算文件的大小和摘要
+// 上传时重新打开文件，文件大小与计算摘要时不一致说明文件在此期间被修改，返回错误
+func FileContent(filePath string) (*Content, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, utils.Errorf("Failed to open file %s: %v", filePath, err)
+	}
+	defer f.Close()
+
+	md5Hash := md5.New()
+	sha256Hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(md5Hash, sha256Hash), f)
+	if err != nil {
+		return nil, utils.Errorf("Failed to read file %s: %v", filePath, err)
+	}
+
+	return &Content{
+		Size:   size,
+		MD5:    hex.EncodeToString(md5Hash.Sum(nil)),
+		SHA256: hex.EncodeToString(sha256Hash.Sum(nil)),
+		open: func() (io.ReadCloser, error) {
+			f, err := os.Open(filePath)
+			if err != nil {
+				return nil, utils.Errorf("Failed to open file %s: %v", filePath, err)
+			}
+			info, err := f.Stat()
+			if err != nil {
+				f.Close()
+				return nil, utils.Errorf("Failed to get file information of %s: %v", filePath, err)
+			}
+			if info.Size() != size {
+				f.Close()
+				return nil, utils.Errorf("File %s changed while uploading (size %d, expected %d)", filePath, info.Size(), size)
+			}
+			return f, nil
+		},
+	}, nil
+}
+
+// BytesContent 使用内存中的内容
+func BytesContent(content []byte) *Content {
+	md5Sum := md5.Sum(content)
+	sha256Sum := sha256.Sum256(content)
+	return &Content{
+		Size:   int64(len(content)),
+		MD5:    hex.EncodeToString(md5Sum[:]),
+		SHA256: hex.EncodeToString(sha256Sum[:]),
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(content)), nil
+		},
+	}
+}
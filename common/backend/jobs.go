@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yaklang/yaklang/common/log"
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// IndexJob 索引任务状态
+type IndexJob struct {
+	JobId  string `json:"jobId"`
+	Status string `json:"status"` // RUNNING、FINISH、FAILED 等
+	Raw    any    `json:"raw"`
+}
+
+// GetIndexJobsDir 返回本地保存索引任务的目录 ~/.ragsync/index-jobs
+func GetIndexJobsDir() string {
+	return filepath.Join(utils.GetHomeDirDefault("."), ".ragsync", "index-jobs")
+}
+
+// LoadIndexJobDocuments 读取本地任务文件中记录的文档ID
+func LoadIndexJobDocuments(jobId string) ([]string, error) {
+	raw, err := os.ReadFile(filepath.Join(GetIndexJobsDir(), jobId))
+	if err != nil {
+		return nil, err
+	}
+	var documentIds []string
+	for _, line := range strings.Split(string(raw), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			documentIds = append(documentIds, line)
+		}
+	}
+	return documentIds, nil
+}
+
+// SaveIndexJobDocuments 将任务ID保存到本地文件，文件内容为该任务包含的文档ID（每行一个）
+func SaveIndexJobDocuments(jobId string, documentIds []string) error {
+	// 创建 ~/.ragsync/index-jobs/ 目录
+	jobsDir := GetIndexJobsDir()
+	if err := os.MkdirAll(jobsDir, 0755); err != nil {
+		return utils.Errorf("Failed to create directory %s: %v", jobsDir, err)
+	}
+
+	// 创建文件，文件名为任务ID
+	jobFilePath := filepath.Join(jobsDir, jobId)
+
+	// 创建文件并写入文档ID
+	file, err := os.Create(jobFilePath)
+	if err != nil {
+		return utils.Errorf("Failed to create job file %s: %v", jobFilePath, err)
+	}
+	defer file.Close()
+
+	if len(documentIds) > 0 {
+		if _, err := file.WriteString(strings.Join(documentIds, "\n") + "\n"); err != nil {
+			return utils.Errorf("Failed to write job file %s: %v", jobFilePath, err)
+		}
+	}
+
+	log.Infof("Job ID saved to file: %s", jobFilePath)
+	return nil
+}
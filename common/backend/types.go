@@ -0,0 +1,59 @@
+package backend
+
+import (
+	"time"
+
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// FileInfo 文件信息结构体
+type FileInfo struct {
+	FileId      string `json:"fileId"`
+	FileName    string `json:"fileName"`
+	Status      string `json:"status"`
+	CategoryId  string `json:"categoryId"`
+	CreateTime  string `json:"createTime"`        // 文件创建时间
+	SizeInBytes int64  `json:"sizeInBytes"`       // 文件大小
+	Message     string `json:"message,omitempty"` // 服务端返回的附加信息，例如解析失败的原因
+	Raw         any    `json:"raw"`
+}
+
+// UploadLease 文件上传租约信息
+type UploadLease struct {
+	LeaseId   string            `json:"leaseId"`
+	UploadURL string            `json:"uploadUrl"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	Raw       any               `json:"raw"`
+}
+
+// IndexDocumentRecord 索引文档记录
+type IndexDocumentRecord struct {
+	DocumentName string `json:"documentName"`
+	DocumentId   string `json:"documentId"`
+	Status       string `json:"status"`
+	IndexId      string `json:"indexId"`
+	DocumentType string `json:"documentType"`
+	Code         string `json:"code"`
+	Message      string `json:"message"`
+	Size         int32  `json:"size"`
+	SourceId     string `json:"sourceId"`
+	Raw          any    `json:"raw"`
+}
+
+// defaultTimeZone 不带时区信息的时间按北京时间 (UTC+8) 解析，与百炼返回的格式一致
+var defaultTimeZone = time.FixedZone("UTC+8", 8*60*60)
+
+// ParseCreateTime 解析文件创建时间，支持 "2006-01-02 15:04:05"（按 UTC+8 解析）和 RFC3339 格式
+func ParseCreateTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, utils.Error("Create time is empty")
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", value, defaultTimeZone); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, utils.Errorf("Unrecognized create time format: %s", value)
+}
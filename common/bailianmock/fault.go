@@ -0,0 +1,124 @@
+package bailianmock
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Fault 注入到某个接口的故障，Times 次后自动失效（Times 为 0 时一直生效）
+type Fault struct {
+	Status     int           // HTTP 状态码
+	Code       string        // 错误码，例如 Throttling.User
+	Message    string        // 错误信息
+	Recommend  string        // 非空时在错误响应中返回诊断建议
+	RetryAfter time.Duration // 非 0 时返回 Retry-After 头
+	Body       string        // 非空时原样返回该响应体（用于模拟无法解析的响应）
+	Drop       bool          // 为 true 时不返回响应，直接关闭连接（用于模拟连接被重置）
+	Times      int           // 生效次数
+
+	used int
+}
+
+// Throttling 返回 429 限流错误
+func Throttling(times int) *Fault {
+	return &Fault{Status: http.StatusTooManyRequests, Code: "Throttling.User", Message: "Request was denied due to user flow control.", Times: times}
+}
+
+// ServerError 返回 503 服务端错误
+func ServerError(times int) *Fault {
+	return &Fault{Status: http.StatusServiceUnavailable, Code: "ServiceUnavailable", Message: "The request has failed due to a temporary failure of the server.", Times: times}
+}
+
+// NoPermission 返回 403 无权限错误以及诊断建议
+func NoPermission(times int) *Fault {
+	return &Fault{
+		Status:    http.StatusForbidden,
+		Code:      "NoPermission",
+		Message:   "You are not authorized to do this action.",
+		Recommend: "https://api.aliyun.com/troubleshoot?q=NoPermission&product=bailian",
+		Times:     times,
+	}
+}
+
+// MalformedBody 返回状态码 200 但无法解析的响应体
+func MalformedBody(times int) *Fault {
+	return &Fault{Status: http.StatusOK, Body: `{"Success": true, "Data": {`, Times: times}
+}
+
+// DropConnection 读取请求后直接关闭连接，不返回任何响应
+func DropConnection(times int) *Fault {
+	return &Fault{Drop: true, Times: times}
+}
+
+// InjectFault 为接口（例如 "ListFile"、"AddFile" 或 UploadAction）注入故障，多个故障按注入顺序依次生效
+func (s *Server) InjectFault(action string, fault *Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[action] = append(s.faults[action], fault)
+}
+
+// ClearFaults 清除所有注入的故障
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = make(map[string][]*Fault)
+}
+
+// takeFault 取出接口当前生效的故障，调用方需要持有锁
+func (s *Server) takeFault(action string) *Fault {
+	faults := s.faults[action]
+	if len(faults) == 0 {
+		return nil
+	}
+	fault := faults[0]
+	fault.used++
+	if fault.Times > 0 && fault.used >= fault.Times {
+		s.faults[action] = faults[1:]
+	}
+	return fault
+}
+
+// write 写入故障响应，上传地址的故障按 OSS 的 XML 格式返回
+func (f *Fault) write(w http.ResponseWriter, r *http.Request, action string) {
+	if f.Drop {
+		// 读完请求体后关闭连接，客户端会收到 EOF 或连接被重置
+		io.Copy(io.Discard, r.Body)
+		if hijacker, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hijacker.Hijack(); err == nil {
+				conn.Close()
+				return
+			}
+		}
+		panic(http.ErrAbortHandler)
+	}
+	if f.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int((f.RetryAfter+time.Second-1)/time.Second)))
+	}
+	status := f.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	if f.Body != "" {
+		w.Header().Set("Content-Type", "application/json;charset=utf-8")
+		w.WriteHeader(status)
+		w.Write([]byte(f.Body))
+		return
+	}
+	if action == UploadAction {
+		writeOSSError(w, status, f.Code, f.Message)
+		return
+	}
+	if f.Recommend != "" {
+		writeJSON(w, status, map[string]any{
+			"Code":      f.Code,
+			"Message":   f.Message,
+			"Recommend": f.Recommend,
+			"RequestId": fmt.Sprintf("mock-request-%d", time.Now().UnixNano()),
+		})
+		return
+	}
+	writeError(w, status, f.Code, f.Message)
+}
@@ -0,0 +1,484 @@
+package bailianmock
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 与百炼一致的状态值
+const (
+	fileStatusParsing      = "PARSING"
+	fileStatusParseSuccess = "PARSE_SUCCESS"
+	fileStatusParseFailed  = "PARSE_FAILED"
+	jobStatusRunning       = "RUNNING"
+	jobStatusCompleted     = "COMPLETED"
+	documentStatusRunning  = "RUNNING"
+	documentStatusFinish   = "FINISH"
+)
+
+// maxJobDocuments SubmitIndexAddDocumentsJob 单次提交的文档数上限
+const maxJobDocuments = 100
+
+// fileStatus 文件解析状态
+func (s *Server) fileStatus(f *file) string {
+	if time.Since(f.CreatedAt) < s.ParseDelay {
+		return fileStatusParsing
+	}
+	if f.ParseError != "" {
+		return fileStatusParseFailed
+	}
+	return fileStatusParseSuccess
+}
+
+// jobStatus 索引任务状态
+func (s *Server) jobStatus(j *job) string {
+	if time.Since(j.SubmittedAt) < s.JobDelay {
+		return jobStatusRunning
+	}
+	return jobStatusCompleted
+}
+
+// documentStatus 索引文档状态跟随其所属任务
+func (s *Server) documentStatus(d *document) string {
+	if j, ok := s.jobs[d.JobId]; ok && s.jobStatus(j) == jobStatusRunning {
+		return documentStatusRunning
+	}
+	return documentStatusFinish
+}
+
+// fileData 文件在 ListFile/DescribeFile 中的表示
+func (s *Server) fileData(f *file) map[string]any {
+	return map[string]any{
+		"FileId":      f.Id,
+		"FileName":    f.Name,
+		"CategoryId":  f.CategoryId,
+		"Parser":      f.Parser,
+		"FileType":    strings.TrimPrefix(strings.ToLower(extension(f.Name)), "."),
+		"SizeInBytes": f.Size,
+		"Status":      s.fileStatus(f),
+		"CreateTime":  f.CreatedAt.In(time.FixedZone("UTC+8", 8*60*60)).Format("2006-01-02 15:04:05"),
+		"Tags":        []string{},
+	}
+}
+
+// extension 返回文件扩展名
+func extension(name string) string {
+	if i := strings.LastIndex(name, "."); i > 0 {
+		return name[i:]
+	}
+	return ""
+}
+
+// intParam 读取整数参数，缺省或无法解析时返回默认值
+func intParam(r *http.Request, name string, def int) int {
+	if v, err := strconv.Atoi(r.Form.Get(name)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+// listParam 读取以 JSON 数组传递的参数，例如 DocumentIds
+func listParam(r *http.Request, name string) []string {
+	var values []string
+	if raw := r.Form.Get(name); raw != "" {
+		json.Unmarshal([]byte(raw), &values)
+	}
+	return values
+}
+
+// page 返回 [start, end) 分页区间
+func page(total, pageNumber, pageSize int) (int, int) {
+	start := (pageNumber - 1) * pageSize
+	if start > total {
+		start = total
+	}
+	end := start + pageSize
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+func (s *Server) applyFileUploadLease(w http.ResponseWriter, r *http.Request, params []string) {
+	fileName := r.Form.Get("FileName")
+	if fileName == "" || r.Form.Get("Md5") == "" {
+		writeError(w, http.StatusBadRequest, "InvalidParameter", "FileName and Md5 are required")
+		return
+	}
+	size, err := strconv.ParseInt(r.Form.Get("SizeInBytes"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidParameter", "SizeInBytes is invalid")
+		return
+	}
+
+	l := &lease{
+		Id:          s.nextId("lease-"),
+		FileName:    fileName,
+		Md5:         r.Form.Get("Md5"),
+		Size:        size,
+		CategoryId:  params[1],
+		ContentType: "application/octet-stream",
+		CreatedAt:   time.Now(),
+	}
+	s.leases[l.Id] = l
+	s.writeSuccess(w, map[string]any{
+		"FileUploadLeaseId": l.Id,
+		"Type":              "HTTP",
+		"Param": map[string]any{
+			"Method": http.MethodPut,
+			"Url":    s.URL + "/upload/" + l.Id,
+			"Headers": map[string]any{
+				"X-bailian-extra": l.Id,
+				"Content-Type":    l.ContentType,
+			},
+		},
+	})
+}
+
+func (s *Server) upload(w http.ResponseWriter, r *http.Request, params []string) {
+	// 上传地址由 OSS 提供，错误以 OSS 的 XML 格式返回
+	l, ok := s.leases[params[0]]
+	if !ok {
+		writeOSSError(w, http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist.")
+		return
+	}
+	if s.LeaseTTL > 0 && time.Since(l.CreatedAt) > s.LeaseTTL {
+		writeOSSError(w, http.StatusForbidden, "AccessDenied", "Request has expired.")
+		return
+	}
+	if r.Header.Get("X-bailian-extra") != l.Id {
+		writeOSSError(w, http.StatusForbidden, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided.")
+		return
+	}
+	content, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeOSSError(w, http.StatusBadRequest, "InvalidArgument", err.Error())
+		return
+	}
+	sum := md5.Sum(content)
+	if int64(len(content)) != l.Size || hex.EncodeToString(sum[:]) != l.Md5 {
+		writeOSSError(w, http.StatusBadRequest, "InvalidDigest", "The Content-MD5 you specified is not valid.")
+		return
+	}
+	l.Content = content
+	l.Uploaded = true
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) addFile(w http.ResponseWriter, r *http.Request, params []string) {
+	l, ok := s.leases[r.Form.Get("LeaseId")]
+	if !ok || !l.Uploaded {
+		writeError(w, http.StatusBadRequest, "InvalidParameter.LeaseId", "lease does not exist or the file has not been uploaded")
+		return
+	}
+	delete(s.leases, l.Id)
+
+	categoryId := r.Form.Get("CategoryId")
+	if categoryId == "" {
+		categoryId = l.CategoryId
+	}
+	f := &file{
+		Id:         s.nextId("file_"),
+		Name:       l.FileName,
+		CategoryId: categoryId,
+		Parser:     r.Form.Get("Parser"),
+		Size:       l.Size,
+		CreatedAt:  time.Now(),
+		ParseError: s.parseFails[l.FileName],
+	}
+	s.files[f.Id] = f
+	s.fileOrder = append(s.fileOrder, f.Id)
+
+	// AddFile 的 Success 字段在 SDK 中是字符串
+	writeJSON(w, http.StatusOK, map[string]any{
+		"Code":      "Success",
+		"Message":   "success",
+		"RequestId": s.requestId(),
+		"Status":    "200",
+		"Success":   "true",
+		"Data":      map[string]any{"FileId": f.Id, "Parser": f.Parser},
+	})
+}
+
+func (s *Server) listFile(w http.ResponseWriter, r *http.Request, params []string) {
+	categoryId := r.Form.Get("CategoryId")
+	keyword := r.Form.Get("FileName")
+	maxResults := intParam(r, "MaxResults", 20)
+	offset, _ := strconv.Atoi(r.Form.Get("NextToken"))
+
+	var matched []*file
+	for _, id := range s.fileOrder {
+		f, ok := s.files[id]
+		if !ok {
+			continue
+		}
+		if categoryId != "" && f.CategoryId != categoryId {
+			continue
+		}
+		if keyword != "" && !strings.Contains(f.Name, keyword) {
+			continue
+		}
+		matched = append(matched, f)
+	}
+
+	// NextToken 是下一页第一个文件的偏移量
+	start := offset
+	if start < 0 || start > len(matched) {
+		start = len(matched)
+	}
+	end := start + maxResults
+	if end > len(matched) {
+		end = len(matched)
+	}
+	list := make([]map[string]any, 0, end-start)
+	for _, f := range matched[start:end] {
+		list = append(list, s.fileData(f))
+	}
+	nextToken := ""
+	if end < len(matched) {
+		nextToken = strconv.Itoa(end)
+	}
+	s.writeSuccess(w, map[string]any{
+		"FileList":   list,
+		"HasNext":    nextToken != "",
+		"MaxResults": maxResults,
+		"NextToken":  nextToken,
+		"TotalCount": len(matched),
+	})
+}
+
+func (s *Server) describeFile(w http.ResponseWriter, r *http.Request, params []string) {
+	f, ok := s.files[params[1]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "FileNotFound", "file does not exist")
+		return
+	}
+	// 解析失败的原因通过响应的 Message 返回
+	if s.fileStatus(f) == fileStatusParseFailed {
+		s.writeSuccessMessage(w, f.ParseError, s.fileData(f))
+		return
+	}
+	s.writeSuccess(w, s.fileData(f))
+}
+
+func (s *Server) deleteFile(w http.ResponseWriter, r *http.Request, params []string) {
+	if _, ok := s.files[params[1]]; !ok {
+		writeError(w, http.StatusNotFound, "FileNotFound", "file does not exist")
+		return
+	}
+	delete(s.files, params[1])
+	s.writeSuccess(w, map[string]any{"FileId": params[1]})
+}
+
+func (s *Server) addCategory(w http.ResponseWriter, r *http.Request, params []string) {
+	name := r.Form.Get("CategoryName")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "InvalidParameter", "CategoryName is required")
+		return
+	}
+	c := &category{Id: s.nextId("cate_"), Name: name, Type: r.Form.Get("CategoryType")}
+	s.categories = append(s.categories, c)
+	s.writeSuccess(w, map[string]any{"CategoryId": c.Id, "CategoryName": c.Name})
+}
+
+func (s *Server) listCategory(w http.ResponseWriter, r *http.Request, params []string) {
+	list := make([]map[string]any, 0, len(s.categories))
+	for _, c := range s.categories {
+		list = append(list, map[string]any{
+			"CategoryId":   c.Id,
+			"CategoryName": c.Name,
+			"CategoryType": c.Type,
+			"IsDefault":    c.Id == "default",
+		})
+	}
+	s.writeSuccess(w, map[string]any{
+		"CategoryList": list,
+		"HasNext":      false,
+		"MaxResults":   len(list),
+		"TotalCount":   len(list),
+	})
+}
+
+func (s *Server) createIndex(w http.ResponseWriter, r *http.Request, params []string) {
+	name := r.Form.Get("Name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "InvalidParameter", "Name is required")
+		return
+	}
+	idx := &index{
+		Id:          s.nextId("idx_"),
+		Name:        name,
+		SourceType:  r.Form.Get("SourceType"),
+		CategoryIds: listParam(r, "CategoryIds"),
+	}
+	s.indices = append(s.indices, idx)
+	s.documents[idx.Id] = make(map[string]*document)
+	s.writeSuccess(w, map[string]any{"Id": idx.Id})
+}
+
+// AddIndex 直接创建一个知识索引，返回索引 ID
+func (s *Server) AddIndex(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx := &index{Id: s.nextId("idx_"), Name: name, SourceType: "DATA_CENTER_FILE"}
+	s.indices = append(s.indices, idx)
+	s.documents[idx.Id] = make(map[string]*document)
+	return idx.Id
+}
+
+func (s *Server) listIndices(w http.ResponseWriter, r *http.Request, params []string) {
+	name := r.Form.Get("IndexName")
+	var matched []*index
+	for _, idx := range s.indices {
+		if name == "" || strings.Contains(idx.Name, name) {
+			matched = append(matched, idx)
+		}
+	}
+	pageNumber, pageSize := intParam(r, "PageNumber", 1), intParam(r, "PageSize", 10)
+	start, end := page(len(matched), pageNumber, pageSize)
+	list := make([]map[string]any, 0, end-start)
+	for _, idx := range matched[start:end] {
+		list = append(list, map[string]any{
+			"Id":         idx.Id,
+			"Name":       idx.Name,
+			"SourceType": idx.SourceType,
+		})
+	}
+	s.writeSuccess(w, map[string]any{
+		"Indices":    list,
+		"PageNumber": pageNumber,
+		"PageSize":   pageSize,
+		"TotalCount": len(matched),
+	})
+}
+
+func (s *Server) listIndexDocuments(w http.ResponseWriter, r *http.Request, params []string) {
+	docs, ok := s.documents[r.Form.Get("IndexId")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Index.NotFound", "index does not exist")
+		return
+	}
+	name := r.Form.Get("DocumentName")
+	status := r.Form.Get("DocumentStatus")
+
+	var matched []*document
+	for _, d := range docs {
+		if name != "" && !strings.Contains(d.Name, name) {
+			continue
+		}
+		if status != "" && s.documentStatus(d) != status {
+			continue
+		}
+		matched = append(matched, d)
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Id < matched[j].Id })
+
+	pageNumber, pageSize := intParam(r, "PageNumber", 1), intParam(r, "PageSize", 10)
+	start, end := page(len(matched), pageNumber, pageSize)
+	list := make([]map[string]any, 0, end-start)
+	for _, d := range matched[start:end] {
+		list = append(list, map[string]any{
+			"Id":           d.Id,
+			"Name":         d.Name,
+			"Size":         d.Size,
+			"SourceId":     d.Id,
+			"Status":       s.documentStatus(d),
+			"DocumentType": strings.TrimPrefix(extension(d.Name), "."),
+			"Code":         "",
+			"Message":      "",
+		})
+	}
+	s.writeSuccess(w, map[string]any{
+		"Documents":  list,
+		"IndexId":    r.Form.Get("IndexId"),
+		"PageNumber": pageNumber,
+		"PageSize":   pageSize,
+		"TotalCount": len(matched),
+	})
+}
+
+func (s *Server) submitIndexAddDocumentsJob(w http.ResponseWriter, r *http.Request, params []string) {
+	indexId := r.Form.Get("IndexId")
+	docs, ok := s.documents[indexId]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Index.NotFound", "index does not exist")
+		return
+	}
+	documentIds := listParam(r, "DocumentIds")
+	if len(documentIds) == 0 {
+		writeError(w, http.StatusBadRequest, "InvalidParameter", "DocumentIds is required")
+		return
+	}
+	if len(documentIds) > maxJobDocuments {
+		writeError(w, http.StatusBadRequest, "InvalidParameter.DocumentIds", fmt.Sprintf("at most %d documents can be submitted in one job", maxJobDocuments))
+		return
+	}
+	for _, id := range documentIds {
+		if _, ok := s.files[id]; !ok {
+			writeError(w, http.StatusBadRequest, "InvalidParameter.DocumentIds", "file "+id+" does not exist")
+			return
+		}
+	}
+
+	j := &job{Id: s.nextId("job_"), IndexId: indexId, DocumentIds: documentIds, SubmittedAt: time.Now()}
+	s.jobs[j.Id] = j
+	for _, id := range documentIds {
+		f := s.files[id]
+		// 与百炼一致，索引文档名不带扩展名
+		name := f.Name
+		if ext := extension(name); ext != "" {
+			name = strings.TrimSuffix(name, ext)
+		}
+		docs[id] = &document{Id: id, Name: name, Size: f.Size, JobId: j.Id}
+	}
+	s.writeSuccess(w, map[string]any{"Id": j.Id})
+}
+
+func (s *Server) deleteIndexDocument(w http.ResponseWriter, r *http.Request, params []string) {
+	docs, ok := s.documents[r.Form.Get("IndexId")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Index.NotFound", "index does not exist")
+		return
+	}
+	var deleted []string
+	for _, id := range listParam(r, "DocumentIds") {
+		if _, ok := docs[id]; ok {
+			delete(docs, id)
+			deleted = append(deleted, id)
+		}
+	}
+	s.writeSuccess(w, map[string]any{"DeletedDocument": deleted})
+}
+
+func (s *Server) getIndexJobStatus(w http.ResponseWriter, r *http.Request, params []string) {
+	j, ok := s.jobs[r.Form.Get("JobId")]
+	if !ok || j.IndexId != r.Form.Get("IndexId") {
+		writeError(w, http.StatusNotFound, "Index.Job.NotFound", "job does not exist")
+		return
+	}
+	status := s.jobStatus(j)
+	documents := make([]map[string]any, 0, len(j.DocumentIds))
+	for _, id := range j.DocumentIds {
+		doc := map[string]any{"DocId": id, "Status": documentStatusFinish}
+		if status == jobStatusRunning {
+			doc["Status"] = documentStatusRunning
+		}
+		if f, ok := s.files[id]; ok {
+			doc["DocName"] = f.Name
+		}
+		documents = append(documents, doc)
+	}
+	s.writeSuccess(w, map[string]any{
+		"JobId":     j.Id,
+		"Status":    status,
+		"Documents": documents,
+	})
+}
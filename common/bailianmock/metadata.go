@@ -0,0 +1,111 @@
+package bailianmock
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetadataServer 模拟 ECS 实例元数据服务中与 RAM 角色相关的接口，支持普通模式和加固模式（IMDSv2）
+// 将 aliyun_ecs_metadata_endpoint 设置为 Endpoint() 即可在本地测试 ecs_ram_role 凭据
+type MetadataServer struct {
+	*httptest.Server
+
+	// RoleName 实例上绑定的 RAM 角色，为空时表示没有绑定角色
+	RoleName string
+	// CredentialTTL 签发的临时凭据的有效期，默认 1 小时
+	CredentialTTL time.Duration
+	// RequireToken 为 true 时只允许加固模式访问（请求必须携带元数据 Token）
+	RequireToken bool
+
+	mu     sync.Mutex
+	seq    int
+	tokens map[string]bool
+	issued int
+}
+
+// NewMetadataServer 启动模拟的元数据服务，使用完毕后需要调用 Close
+func NewMetadataServer(roleName string) *MetadataServer {
+	m := &MetadataServer{
+		RoleName:      roleName,
+		CredentialTTL: time.Hour,
+		tokens:        make(map[string]bool),
+	}
+	m.Server = httptest.NewServer(http.HandlerFunc(m.serveHTTP))
+	return m
+}
+
+// Endpoint 返回可以填入 aliyun_ecs_metadata_endpoint 的地址
+func (m *MetadataServer) Endpoint() string {
+	return m.URL
+}
+
+// Issued 返回已经签发的临时凭据数量
+func (m *MetadataServer) Issued() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.issued
+}
+
+// SecurityToken 返回第 n 次（从 1 开始）签发的 SecurityToken，用于核对百炼请求使用的凭据
+func (m *MetadataServer) SecurityToken(n int) string {
+	return fmt.Sprintf("mock-security-token-%d", n)
+}
+
+const credentialsPath = "/latest/meta-data/ram/security-credentials/"
+
+func (m *MetadataServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r.Method == http.MethodPut && r.URL.Path == "/latest/api/token" {
+		if r.Header.Get("X-aliyun-ecs-metadata-token-ttl-seconds") == "" {
+			http.Error(w, "missing X-aliyun-ecs-metadata-token-ttl-seconds", http.StatusBadRequest)
+			return
+		}
+		m.seq++
+		token := fmt.Sprintf("mock-metadata-token-%d", m.seq)
+		m.tokens[token] = true
+		fmt.Fprint(w, token)
+		return
+	}
+	if r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, credentialsPath) {
+		http.NotFound(w, r)
+		return
+	}
+	if token := r.Header.Get("X-aliyun-ecs-metadata-token"); token != "" && !m.tokens[token] {
+		http.Error(w, "invalid metadata token", http.StatusUnauthorized)
+		return
+	} else if token == "" && m.RequireToken {
+		http.Error(w, "metadata token required", http.StatusUnauthorized)
+		return
+	}
+
+	roleName := strings.TrimPrefix(r.URL.Path, credentialsPath)
+	if roleName == "" {
+		if m.RoleName == "" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, m.RoleName)
+		return
+	}
+	if roleName != m.RoleName {
+		http.NotFound(w, r)
+		return
+	}
+
+	m.issued++
+	now := time.Now().UTC()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"Code":            "Success",
+		"AccessKeyId":     fmt.Sprintf("STS.mock-access-key-%d", m.issued),
+		"AccessKeySecret": fmt.Sprintf("mock-access-key-secret-%d", m.issued),
+		"SecurityToken":   m.SecurityToken(m.issued),
+		"Expiration":      now.Add(m.CredentialTTL).Format(time.RFC3339),
+		"LastUpdated":     now.Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,287 @@
+package bailianmock
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/VillanCh/ragsync/common/spec"
+)
+
+// Server 基于 httptest 的百炼 OpenAPI（bailian-20231229）模拟服务
+// 支持数据中心文件、类目、知识索引、索引文档和索引任务相关的接口，以及文件上传地址，并可以注入故障
+type Server struct {
+	*httptest.Server
+
+	// ParseDelay 文件从 PARSING 变为 PARSE_SUCCESS 所需的时间，为 0 时添加后立即解析完成
+	ParseDelay time.Duration
+	// JobDelay 索引任务从 RUNNING 变为 COMPLETED 所需的时间，为 0 时提交后立即完成
+	JobDelay time.Duration
+	// LeaseTTL 上传租约的有效期，过期后上传地址返回 403 AccessDenied，为 0 时不过期
+	LeaseTTL time.Duration
+
+	mu         sync.Mutex
+	seq        int
+	leases     map[string]*lease
+	files      map[string]*file
+	fileOrder  []string
+	categories []*category
+	indices    []*index
+	documents  map[string]map[string]*document // indexId -> documentId -> document
+	jobs       map[string]*job
+	parseFails map[string]string // 文件名 -> 解析失败的原因
+	faults     map[string][]*Fault
+	calls      map[string]int
+	callLog    []string
+
+	lastSecurityToken string // 最近一次 API 请求携带的 STS SecurityToken
+}
+
+type lease struct {
+	Id          string
+	CreatedAt   time.Time
+	FileName    string
+	Md5         string
+	Size        int64
+	CategoryId  string
+	Content     []byte
+	Uploaded    bool
+	ContentType string
+}
+
+type file struct {
+	Id         string
+	Name       string
+	CategoryId string
+	Parser     string
+	Size       int64
+	CreatedAt  time.Time
+	ParseError string // 非空时解析失败
+}
+
+type category struct {
+	Id   string
+	Name string
+	Type string
+}
+
+type index struct {
+	Id          string
+	Name        string
+	SourceType  string
+	CategoryIds []string
+}
+
+type document struct {
+	Id    string
+	Name  string
+	Size  int64
+	JobId string
+}
+
+type job struct {
+	Id          string
+	IndexId     string
+	DocumentIds []string
+	SubmittedAt time.Time
+}
+
+// route 一个 OpenAPI 接口：方法、路径和处理函数
+type route struct {
+	Action  string
+	Method  string
+	Pattern *regexp.Regexp
+	Handle  func(s *Server, w http.ResponseWriter, r *http.Request, params []string)
+}
+
+var routes = []route{
+	{"ApplyFileUploadLease", http.MethodPost, regexp.MustCompile(`^/([^/]+)/datacenter/category/([^/]+)$`), (*Server).applyFileUploadLease},
+	{"AddCategory", http.MethodPost, regexp.MustCompile(`^/([^/]+)/datacenter/category/$`), (*Server).addCategory},
+	{"ListCategory", http.MethodPost, regexp.MustCompile(`^/([^/]+)/datacenter/categories$`), (*Server).listCategory},
+	{"AddFile", http.MethodPut, regexp.MustCompile(`^/([^/]+)/datacenter/file$`), (*Server).addFile},
+	{"ListFile", http.MethodGet, regexp.MustCompile(`^/([^/]+)/datacenter/files$`), (*Server).listFile},
+	{"DescribeFile", http.MethodGet, regexp.MustCompile(`^/([^/]+)/datacenter/file/([^/]+)/$`), (*Server).describeFile},
+	{"DeleteFile", http.MethodDelete, regexp.MustCompile(`^/([^/]+)/datacenter/file/([^/]+)/$`), (*Server).deleteFile},
+	{"ListIndices", http.MethodGet, regexp.MustCompile(`^/([^/]+)/index/list_indices$`), (*Server).listIndices},
+	{"CreateIndex", http.MethodPost, regexp.MustCompile(`^/([^/]+)/index/create$`), (*Server).createIndex},
+	{"ListIndexDocuments", http.MethodGet, regexp.MustCompile(`^/([^/]+)/index/list_index_documents$`), (*Server).listIndexDocuments},
+	{"SubmitIndexAddDocumentsJob", http.MethodPost, regexp.MustCompile(`^/([^/]+)/index/add_documents_to_index$`), (*Server).submitIndexAddDocumentsJob},
+	{"DeleteIndexDocument", http.MethodPost, regexp.MustCompile(`^/([^/]+)/index/delete_index_document$`), (*Server).deleteIndexDocument},
+	{"GetIndexJobStatus", http.MethodGet, regexp.MustCompile(`^/([^/]+)/index/job/status$`), (*Server).getIndexJobStatus},
+	{UploadAction, http.MethodPut, regexp.MustCompile(`^/upload/([^/]+)$`), (*Server).upload},
+}
+
+// UploadAction 文件内容上传（ApplyFileUploadLease 返回的地址）在故障注入和调用计数中使用的名称
+const UploadAction = "Upload"
+
+// NewServer 启动模拟服务，使用完毕后需要调用 Close
+func NewServer() *Server {
+	s := &Server{
+		leases:     make(map[string]*lease),
+		files:      make(map[string]*file),
+		categories: []*category{{Id: "default", Name: "default", Type: "UNSTRUCTURED"}},
+		documents:  make(map[string]map[string]*document),
+		jobs:       make(map[string]*job),
+		parseFails: make(map[string]string),
+		faults:     make(map[string][]*Fault),
+		calls:      make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Endpoint 返回可以填入 aliyun_bailian_endpoint 的地址
+func (s *Server) Endpoint() string {
+	return s.URL
+}
+
+// Config 返回指向模拟服务的配置
+func (s *Server) Config(workspaceId, indexId string) *spec.Config {
+	config := spec.GetDefaultConfig()
+	config.AliyunAccessKey = "mock-access-key"
+	config.AliyunSecretKey = "mock-secret-key"
+	config.BailianEndpoint = s.Endpoint()
+	config.BailianWorkspaceId = workspaceId
+	config.BailianKnowledgeIndexId = indexId
+	return &config
+}
+
+// TestConfig 返回用于测试的配置：在 Config 的基础上关闭所有接口的限流，重试的退避缩短到毫秒级
+func (s *Server) TestConfig(workspaceId, indexId string) *spec.Config {
+	config := s.Config(workspaceId, indexId)
+	config.Retry = spec.RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	config.RateLimit = make(map[string]float64, len(routes))
+	for _, rt := range routes {
+		if rt.Action != UploadAction {
+			config.RateLimit[rt.Action] = 0
+		}
+	}
+	return config
+}
+
+// FailParse 让之后添加的同名文件解析失败，DescribeFile 返回 PARSE_FAILED 和 message
+func (s *Server) FailParse(fileName string, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.parseFails[fileName] = message
+}
+
+// LastSecurityToken 返回最近一次 API 请求携带的 STS SecurityToken，用于确认客户端使用了临时凭据
+func (s *Server) LastSecurityToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSecurityToken
+}
+
+// Calls 返回某个接口被调用的次数（包括被注入故障的调用）
+func (s *Server) Calls(action string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[action]
+}
+
+// CallLog 按请求顺序返回所有被调用的接口，用于检查调用的先后关系
+func (s *Server) CallLog() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.callLog...)
+}
+
+// serveHTTP 按方法和路径分发请求
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, rt := range routes {
+		if r.Method != rt.Method {
+			continue
+		}
+		match := rt.Pattern.FindStringSubmatch(r.URL.Path)
+		if match == nil {
+			continue
+		}
+
+		s.mu.Lock()
+		s.calls[rt.Action]++
+		s.callLog = append(s.callLog, rt.Action)
+		if rt.Action != UploadAction {
+			s.lastSecurityToken = r.Header.Get("x-acs-security-token")
+		}
+		fault := s.takeFault(rt.Action)
+		s.mu.Unlock()
+		if fault != nil {
+			fault.write(w, r, rt.Action)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidParameter", err.Error())
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		rt.Handle(s, w, r, match[1:])
+		return
+	}
+	writeError(w, http.StatusNotFound, "NotFound", fmt.Sprintf("%s %s is not supported by the mock server", r.Method, r.URL.Path))
+}
+
+// nextId 生成带前缀的递增 ID，调用方需要持有锁
+func (s *Server) nextId(prefix string) string {
+	s.seq++
+	return prefix + strconv.Itoa(s.seq)
+}
+
+// requestId 生成请求 ID
+func (s *Server) requestId() string {
+	return fmt.Sprintf("mock-request-%d", time.Now().UnixNano())
+}
+
+// writeSuccess 按百炼的响应格式返回成功结果
+func (s *Server) writeSuccess(w http.ResponseWriter, data any) {
+	s.writeSuccessMessage(w, "success", data)
+}
+
+// writeSuccessMessage 与 writeSuccess 相同，但使用指定的 Message
+func (s *Server) writeSuccessMessage(w http.ResponseWriter, message string, data any) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"Code":      "Success",
+		"Message":   message,
+		"RequestId": s.requestId(),
+		"Status":    "200",
+		"Success":   true,
+		"Data":      data,
+	})
+}
+
+// writeError 按百炼的错误格式返回错误
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, map[string]any{
+		"Code":      code,
+		"Message":   message,
+		"RequestId": fmt.Sprintf("mock-request-%d", time.Now().UnixNano()),
+	})
+}
+
+// writeOSSError 按 OSS 的 XML 格式返回上传地址的错误
+func writeOSSError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName   xml.Name `xml:"Error"`
+		Code      string   `xml:"Code"`
+		Message   string   `xml:"Message"`
+		RequestId string   `xml:"RequestId"`
+		HostId    string   `xml:"HostId"`
+	}{Code: code, Message: message, RequestId: fmt.Sprintf("mock-oss-request-%d", time.Now().UnixNano()), HostId: "mock-bucket.oss-cn-beijing.aliyuncs.com"})
+}
+
+// writeJSON 写入 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
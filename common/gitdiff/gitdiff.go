@@ -0,0 +1,120 @@
+package gitdiff
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// ChangeType 文件变化类型
+type ChangeType string
+
+const (
+	Added    ChangeType = "added"
+	Modified ChangeType = "modified"
+	Deleted  ChangeType = "deleted"
+	Renamed  ChangeType = "renamed"
+)
+
+// Change 两个提交之间的一个文件变化，路径相对于 Diff 的目录，以 / 分隔
+type Change struct {
+	Type    ChangeType
+	Path    string
+	OldPath string // 仅 Renamed 时有值
+}
+
+// run 在指定目录下执行 git 命令并返回标准输出
+func run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", utils.Errorf("git %s failed: %s", strings.Join(args, " "), msg)
+	}
+	return stdout.String(), nil
+}
+
+// IsRepository 判断目录是否位于 git 工作区中
+func IsRepository(dir string) bool {
+	out, err := run(dir, "rev-parse", "--is-inside-work-tree")
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// ResolveCommit 将引用（分支、标签、提交哈希、HEAD~3 等）解析为完整的提交哈希
+func ResolveCommit(dir string, ref string) (string, error) {
+	out, err := run(dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", utils.Errorf("Unknown git revision %q in %s", ref, dir)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Diff 返回 from 与 to 两个提交之间 dir 目录下的文件变化（包括重命名检测）
+// pathspecs 可以进一步限制范围，例如只比较某个文件
+func Diff(dir string, from string, to string, pathspecs ...string) ([]*Change, error) {
+	args := []string{"diff", "--name-status", "-z", "-M", "--relative", from, to, "--"}
+	if len(pathspecs) == 0 {
+		pathspecs = []string{"."}
+	}
+	args = append(args, pathspecs...)
+
+	out, err := run(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseNameStatus(out)
+}
+
+// parseNameStatus 解析 git diff --name-status -z 的输出
+func parseNameStatus(out string) ([]*Change, error) {
+	fields := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	if len(fields) == 1 && fields[0] == "" {
+		return nil, nil
+	}
+
+	var changes []*Change
+	for i := 0; i < len(fields); {
+		status := fields[i]
+		if status == "" {
+			i++
+			continue
+		}
+		switch status[0] {
+		case 'R', 'C':
+			if i+2 >= len(fields) {
+				return nil, utils.Errorf("Unexpected git diff output near %q", status)
+			}
+			oldPath, newPath := fields[i+1], fields[i+2]
+			if status[0] == 'R' {
+				changes = append(changes, &Change{Type: Renamed, Path: newPath, OldPath: oldPath})
+			} else {
+				// 复制的文件对于同步来说就是新增
+				changes = append(changes, &Change{Type: Added, Path: newPath})
+			}
+			i += 3
+		default:
+			if i+1 >= len(fields) {
+				return nil, utils.Errorf("Unexpected git diff output near %q", status)
+			}
+			path := fields[i+1]
+			switch status[0] {
+			case 'A':
+				changes = append(changes, &Change{Type: Added, Path: path})
+			case 'D':
+				changes = append(changes, &Change{Type: Deleted, Path: path})
+			default:
+				// M（修改）、T（类型变化）等都按修改处理
+				changes = append(changes, &Change{Type: Modified, Path: path})
+			}
+			i += 2
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,165 @@
+package gitdiff
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseNameStatus(t *testing.T) {
+	cases := []struct {
+		name string
+		out  string
+		want []*Change
+	}{
+		{"empty", "", nil},
+		{"added modified deleted", "A\x00new.md\x00M\x00docs/guide.md\x00D\x00old.md\x00", []*Change{
+			{Type: Added, Path: "new.md"},
+			{Type: Modified, Path: "docs/guide.md"},
+			{Type: Deleted, Path: "old.md"},
+		}},
+		{"rename with score", "R087\x00docs/a.md\x00manual/a.md\x00", []*Change{
+			{Type: Renamed, Path: "manual/a.md", OldPath: "docs/a.md"},
+		}},
+		{"copy is an addition", "C100\x00a.md\x00copy of a.md\x00", []*Change{
+			{Type: Added, Path: "copy of a.md"},
+		}},
+		{"type change is a modification", "T\x00link.md\x00", []*Change{
+			{Type: Modified, Path: "link.md"},
+		}},
+		// -z 输出不转义路径，空格、制表符和换行都原样保留
+		{"unquoted special characters", "A\x00with space.md\x00M\x00tab\there.md\x00D\x00new\nline.md\x00", []*Change{
+			{Type: Added, Path: "with space.md"},
+			{Type: Modified, Path: "tab\there.md"},
+			{Type: Deleted, Path: "new\nline.md"},
+		}},
+		{"rename followed by other changes", "R100\x00a.md\x00b.md\x00D\x00c.md\x00", []*Change{
+			{Type: Renamed, Path: "b.md", OldPath: "a.md"},
+			{Type: Deleted, Path: "c.md"},
+		}},
+		{"without trailing separator", "M\x00a.md", []*Change{
+			{Type: Modified, Path: "a.md"},
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := parseNameStatus(c.out)
+			if err != nil {
+				t.Fatalf("parseNameStatus: %v", err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("parseNameStatus(%q) = %s, want %s", c.out, describe(got), describe(c.want))
+			}
+		})
+	}
+}
+
+func TestParseNameStatusMalformed(t *testing.T) {
+	for _, out := range []string{"M\x00", "R100\x00only-old.md\x00", "C\x00"} {
+		if _, err := parseNameStatus(out); err == nil {
+			t.Errorf("parseNameStatus(%q) succeeded, want an error", out)
+		}
+	}
+}
+
+// describe 以可读的形式输出变化列表
+func describe(changes []*Change) string {
+	s := "["
+	for i, change := range changes {
+		if i > 0 {
+			s += ", "
+		}
+		s += string(change.Type) + " " + change.Path
+		if change.OldPath != "" {
+			s += " from " + change.OldPath
+		}
+	}
+	return s + "]"
+}
+
+// newTestRepository 在临时目录中创建 git 仓库
+func newTestRepository(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(name, "ragsync")
+	}
+	for _, name := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, "ragsync@example.com")
+	}
+	dir := t.TempDir()
+	gitRun(t, dir, "init", "-q")
+	return dir
+}
+
+// gitRun 在仓库中执行 git 命令
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if _, err := run(dir, args...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeFile 在仓库中写入文件
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := newTestRepository(t)
+	writeFile(t, dir, "docs/moved.md", "a long enough document body to be detected as a rename\n")
+	writeFile(t, dir, "docs/deleted.md", "deleted\n")
+	writeFile(t, dir, "docs/changed.md", "before\n")
+	writeFile(t, dir, "outside.md", "outside\n")
+	gitRun(t, dir, "add", "-A")
+	gitRun(t, dir, "commit", "-q", "-m", "first")
+	from, err := ResolveCommit(dir, "HEAD")
+	if err != nil {
+		t.Fatalf("ResolveCommit: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "docs", "manual"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	gitRun(t, dir, "mv", "docs/moved.md", "docs/manual/moved.md")
+	gitRun(t, dir, "rm", "-q", "docs/deleted.md")
+	writeFile(t, dir, "docs/changed.md", "after\n")
+	writeFile(t, dir, "docs/new file.md", "new\n")
+	writeFile(t, dir, "outside.md", "changed outside\n")
+	gitRun(t, dir, "add", "-A")
+	gitRun(t, dir, "commit", "-q", "-m", "second")
+
+	if !IsRepository(filepath.Join(dir, "docs")) {
+		t.Fatal("docs is not recognised as part of the repository")
+	}
+	// 路径相对于 docs，docs 之外的变化不包含在内
+	changes, err := Diff(filepath.Join(dir, "docs"), from, "HEAD")
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	want := []*Change{
+		{Type: Modified, Path: "changed.md"},
+		{Type: Deleted, Path: "deleted.md"},
+		{Type: Renamed, Path: "manual/moved.md", OldPath: "moved.md"},
+		{Type: Added, Path: "new file.md"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("Diff = %s, want %s", describe(changes), describe(want))
+	}
+
+	if _, err := ResolveCommit(dir, "no-such-branch"); err == nil {
+		t.Fatal("ResolveCommit accepted an unknown revision")
+	}
+}
@@ -0,0 +1,196 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/yaklang/yaklang/common/log"
+)
+
+const (
+	// RagsyncIgnoreFile ragsync 专用的忽略文件，语法与 .gitignore 相同
+	RagsyncIgnoreFile = ".ragsyncignore"
+	// GitIgnoreFile git 的忽略文件
+	GitIgnoreFile = ".gitignore"
+)
+
+// Options 匹配器选项
+type Options struct {
+	Root        string   // 查找忽略文件的根目录，规则文件从这里逐级向下加载
+	Base        string   // Excludes 和 Includes 规则相对的目录，默认等于 Root
+	IgnoreFiles []string // 每个目录下读取的忽略文件名，靠后的文件优先级更高
+	Excludes    []string // 额外的排除规则（gitignore 语法）
+	Includes    []string // 包含规则（gitignore 语法），非空时只有匹配的文件才会被同步
+}
+
+// Matcher 按 gitignore 语义判断路径是否被忽略
+// 每个目录下的忽略文件只对该目录及其子目录生效，越深的目录优先级越高，后出现的规则覆盖先出现的规则
+type Matcher struct {
+	root        string
+	ignoreFiles []string
+	excludes    []*Rule
+	includes    []*Rule
+
+	mu       sync.Mutex
+	dirRules map[string][]*Rule
+}
+
+// NewMatcher 创建匹配器
+func NewMatcher(opts Options) (*Matcher, error) {
+	root, err := filepath.Abs(opts.Root)
+	if err != nil {
+		return nil, err
+	}
+	base := root
+	if opts.Base != "" {
+		if base, err = filepath.Abs(opts.Base); err != nil {
+			return nil, err
+		}
+	}
+	baseRel := ""
+	if rel, err := filepath.Rel(root, base); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+		baseRel = filepath.ToSlash(rel)
+	}
+
+	return &Matcher{
+		root:        root,
+		ignoreFiles: opts.IgnoreFiles,
+		excludes:    ParseRules(opts.Excludes, baseRel),
+		includes:    ParseRules(opts.Includes, baseRel),
+		dirRules:    make(map[string][]*Rule),
+	}, nil
+}
+
+// Root 返回匹配器根目录
+func (m *Matcher) Root() string {
+	return m.root
+}
+
+// Ignored 判断路径是否被忽略；父目录被忽略时，其中的所有文件也被忽略
+func (m *Matcher) Ignored(filePath string, isDir bool) bool {
+	rel, ok := m.relative(filePath)
+	if !ok {
+		return false
+	}
+	if rel == "" {
+		return false
+	}
+
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.match(parts[:i], true) {
+			return true
+		}
+	}
+	return m.match(parts, isDir)
+}
+
+// Included 判断文件是否匹配包含规则，没有包含规则时总是返回 true
+// 包含规则从最上级目录开始逐级应用到文件本身，匹配的目录中的所有文件都被包含，更深的路径上匹配的规则覆盖之前的结果
+// 因此 "docs/" 包含 docs 下的所有文件，"!docs/drafts/" 可以再排除其中的子目录
+func (m *Matcher) Included(filePath string) bool {
+	if len(m.includes) == 0 {
+		return true
+	}
+	rel, ok := m.relative(filePath)
+	if !ok || rel == "" {
+		return false
+	}
+
+	parts := strings.Split(rel, "/")
+	included := false
+	for i := 1; i <= len(parts); i++ {
+		sub := strings.Join(parts[:i], "/")
+		for _, rule := range m.includes {
+			if rule.Match(sub, i < len(parts)) {
+				included = !rule.Negate
+			}
+		}
+	}
+	return included
+}
+
+// relative 将路径转换为相对于根目录、以 / 分隔的路径
+func (m *Matcher) relative(filePath string) (string, bool) {
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(m.root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	if rel == "." {
+		return "", true
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// match 按顺序应用额外规则和各级目录的忽略文件，返回最后一条匹配规则的结果
+func (m *Matcher) match(parts []string, isDir bool) bool {
+	rel := strings.Join(parts, "/")
+	ignored := false
+	apply := func(rules []*Rule) {
+		for _, rule := range rules {
+			if rule.Match(rel, isDir) {
+				ignored = !rule.Negate
+			}
+		}
+	}
+
+	apply(m.excludes)
+	apply(m.rulesIn(""))
+	for i := 1; i < len(parts); i++ {
+		apply(m.rulesIn(strings.Join(parts[:i], "/")))
+	}
+	return ignored
+}
+
+// rulesIn 返回目录下忽略文件中的规则，结果会被缓存
+func (m *Matcher) rulesIn(dirRel string) []*Rule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rules, ok := m.dirRules[dirRel]; ok {
+		return rules
+	}
+
+	var rules []*Rule
+	for _, name := range m.ignoreFiles {
+		filePath := filepath.Join(m.root, filepath.FromSlash(dirRel), name)
+		loaded, err := LoadRules(filePath, dirRel)
+		if err != nil {
+			log.Warnf("%v", err)
+			continue
+		}
+		if len(loaded) > 0 {
+			log.Debugf("Loaded %d ignore rules from %s", len(loaded), filePath)
+		}
+		rules = append(rules, loaded...)
+	}
+	m.dirRules[dirRel] = rules
+	return rules
+}
+
+// FindGitRoot 向上查找包含 .git 的目录，返回仓库根目录
+func FindGitRoot(filePath string) (string, bool) {
+	dir, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", false
+	}
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
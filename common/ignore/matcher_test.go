@@ -0,0 +1,139 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestMatcher 在临时目录中写入忽略文件（键为相对路径）并创建匹配器
+func newTestMatcher(t *testing.T, files map[string]string, opts Options) (*Matcher, string) {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts.Root = root
+	if opts.Base != "" {
+		opts.Base = filepath.Join(root, filepath.FromSlash(opts.Base))
+	}
+	m, err := NewMatcher(opts)
+	if err != nil {
+		t.Fatalf("NewMatcher: %v", err)
+	}
+	return m, root
+}
+
+func TestMatcherIncluded(t *testing.T) {
+	cases := []struct {
+		name     string
+		includes []string
+		path     string
+		want     bool
+	}{
+		{"no rules", nil, "anything/file.md", true},
+		{"directory rule nested file", []string{"docs/"}, "docs/api/errors.md", true},
+		{"directory rule direct file", []string{"docs/"}, "docs/guide.md", true},
+		{"directory rule other directory", []string{"docs/"}, "src/guide.md", false},
+		{"directory rule does not match a file", []string{"docs/"}, "docs", false},
+		{"bare name matches directory", []string{"docs"}, "docs/guide.md", true},
+		{"bare name matches nested directory", []string{"docs"}, "site/docs/guide.md", true},
+		{"anchored name only at the root", []string{"/docs"}, "site/docs/guide.md", false},
+		{"anchored name at the root", []string{"/docs"}, "docs/guide.md", true},
+		{"path with slash is anchored", []string{"site/docs/"}, "other/site/docs/guide.md", false},
+		{"extension glob", []string{"*.md"}, "a/b/c.md", true},
+		{"extension glob other extension", []string{"*.md"}, "a/b/c.txt", false},
+		{"anchored glob only direct children", []string{"docs/*.md"}, "docs/api/errors.md", false},
+		{"anchored glob direct child", []string{"docs/*.md"}, "docs/guide.md", true},
+		{"double star any depth", []string{"docs/**/*.md"}, "docs/a/b/c.md", true},
+		{"double star zero directories", []string{"docs/**/*.md"}, "docs/guide.md", true},
+		{"leading double star", []string{"**/api/"}, "x/y/api/errors.md", true},
+		{"negated subdirectory", []string{"docs/", "!docs/drafts/"}, "docs/drafts/wip.md", false},
+		{"negated subdirectory sibling", []string{"docs/", "!docs/drafts/"}, "docs/guide.md", true},
+		{"negated file pattern", []string{"docs/", "!*.tmp.md"}, "docs/api/x.tmp.md", false},
+		{"re-included inside negated directory", []string{"docs/", "!docs/drafts/", "docs/drafts/ready.md"}, "docs/drafts/ready.md", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m, root := newTestMatcher(t, nil, Options{Includes: c.includes})
+			if got := m.Included(filepath.Join(root, filepath.FromSlash(c.path))); got != c.want {
+				t.Fatalf("Included(%s) with %v = %v, want %v", c.path, c.includes, got, c.want)
+			}
+		})
+	}
+}
+
+func TestMatcherIncludedRelativeToBase(t *testing.T) {
+	// include 规则相对于 Base，Root 是 git 仓库根目录
+	m, root := newTestMatcher(t, nil, Options{Base: "site", Includes: []string{"docs/"}})
+	if !m.Included(filepath.Join(root, "site", "docs", "guide.md")) {
+		t.Fatal("site/docs/guide.md is not included by docs/ relative to site")
+	}
+	if m.Included(filepath.Join(root, "docs", "guide.md")) {
+		t.Fatal("docs/guide.md outside the base is included")
+	}
+	if m.Included(filepath.Join(filepath.Dir(root), "elsewhere.md")) {
+		t.Fatal("a file outside the root is included")
+	}
+}
+
+func TestMatcherIgnored(t *testing.T) {
+	files := map[string]string{
+		".ragsyncignore":          "*.log\nbuild/\n/tmp\n!keep.log\n",
+		"docs/.ragsyncignore":     "drafts/\n!important.log\n",
+		"docs/api/.ragsyncignore": "*.md\n",
+	}
+	cases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"keep.log", false, false},
+		{"nested/deep/app.log", false, true},
+		{"build", true, true},
+		{"build/out.md", false, true},
+		{"build", false, false},
+		{"tmp/a.md", false, true},
+		{"docs/tmp/a.md", false, false},
+		{"docs/drafts/wip.md", false, true},
+		{"drafts/wip.md", false, false},
+		{"docs/important.log", false, false},
+		{"other/important.log", false, true},
+		{"docs/api/errors.md", false, true},
+		{"docs/guide.md", false, false},
+	}
+	m, root := newTestMatcher(t, files, Options{IgnoreFiles: []string{RagsyncIgnoreFile}})
+	for _, c := range cases {
+		if got := m.Ignored(filepath.Join(root, filepath.FromSlash(c.path)), c.isDir); got != c.want {
+			t.Errorf("Ignored(%s, dir=%v) = %v, want %v", c.path, c.isDir, got, c.want)
+		}
+	}
+}
+
+func TestMatcherExcludesAndIgnoreFileOrder(t *testing.T) {
+	files := map[string]string{
+		".gitignore":     "*.md\n",
+		".ragsyncignore": "!guide.md\n",
+	}
+	m, root := newTestMatcher(t, files, Options{
+		IgnoreFiles: []string{GitIgnoreFile, RagsyncIgnoreFile},
+		Excludes:    []string{"secret/"},
+	})
+	// .ragsyncignore 在 .gitignore 之后加载，可以重新包含被 .gitignore 忽略的文件
+	if m.Ignored(filepath.Join(root, "guide.md"), false) {
+		t.Fatal("guide.md re-included by .ragsyncignore is ignored")
+	}
+	if !m.Ignored(filepath.Join(root, "other.md"), false) {
+		t.Fatal("other.md from .gitignore is not ignored")
+	}
+	if !m.Ignored(filepath.Join(root, "secret", "a.txt"), false) {
+		t.Fatal("secret/a.txt from the extra excludes is not ignored")
+	}
+}
@@ -0,0 +1,133 @@
+package ignore
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// Rule 一条 gitignore 语法的规则
+type Rule struct {
+	Pattern string // 原始规则文本
+	Base    string // 规则所在目录（相对于匹配器根目录，根目录为空字符串）
+	Negate  bool   // 以 ! 开头，重新包含之前被排除的路径
+	DirOnly bool   // 以 / 结尾，只匹配目录
+
+	segments []string
+}
+
+// ParseRule 解析一行 gitignore 语法的规则，空行和注释返回 nil
+func ParseRule(line string, base string) *Rule {
+	line = strings.TrimRight(line, "\r")
+	// 行尾空格会被忽略，除非使用反斜杠转义
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	rule := &Rule{Pattern: line, Base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.Negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+	line = strings.ReplaceAll(line, "\\ ", " ")
+
+	if strings.HasSuffix(line, "/") {
+		rule.DirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return nil
+	}
+
+	// 不含 / 的规则匹配任意层级的同名文件或目录，含 / 的规则相对于所在目录
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	rule.segments = strings.Split(line, "/")
+	if !anchored {
+		rule.segments = append([]string{"**"}, rule.segments...)
+	}
+	return rule
+}
+
+// ParseRules 解析多条规则
+func ParseRules(lines []string, base string) []*Rule {
+	var rules []*Rule
+	for _, line := range lines {
+		if rule := ParseRule(line, base); rule != nil {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// LoadRules 从忽略文件中读取规则，文件不存在时返回空
+func LoadRules(filePath string, base string) ([]*Rule, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, utils.Errorf("Failed to open ignore file %s: %v", filePath, err)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, utils.Errorf("Failed to read ignore file %s: %v", filePath, err)
+	}
+	return ParseRules(lines, base), nil
+}
+
+// Match 判断相对于匹配器根目录的路径是否匹配该规则
+func (r *Rule) Match(relPath string, isDir bool) bool {
+	if r.DirOnly && !isDir {
+		return false
+	}
+	if r.Base != "" {
+		if !strings.HasPrefix(relPath, r.Base+"/") {
+			return false
+		}
+		relPath = strings.TrimPrefix(relPath, r.Base+"/")
+	}
+	return matchSegments(r.segments, strings.Split(relPath, "/"))
+}
+
+// matchSegments 逐段匹配路径，** 匹配零个或多个目录
+func matchSegments(pattern []string, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		ok, err := path.Match(pattern[0], name[0])
+		if err != nil || !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+	return len(name) == 0
+}
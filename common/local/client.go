@@ -0,0 +1,137 @@
+package local
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/VillanCh/ragsync/common/backend"
+	"github.com/VillanCh/ragsync/common/spec"
+
+	"github.com/yaklang/yaklang/common/log"
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// BackendName 本地后端在配置中的名称
+const BackendName = "local"
+
+func init() {
+	backend.Register(BackendName, func(config *spec.Config) (backend.Backend, error) {
+		return NewLocalClientFromConfig(config)
+	})
+}
+
+// 确保 LocalClient 实现了 backend.Backend
+var _ backend.Backend = (*LocalClient)(nil)
+
+// LocalClient 在本地磁盘上模拟百炼数据中心和知识索引的后端，用于离线开发、测试和预览同步结果
+type LocalClient struct {
+	config *spec.Config
+	dir    string
+
+	// mu 串行化本进程内的读写，跨进程的修改由 store.json.lock 锁文件保护
+	mu sync.Mutex
+}
+
+// DefaultDir 返回本地后端的默认数据目录 ~/.ragsync/local
+func DefaultDir() string {
+	return filepath.Join(utils.GetHomeDirDefault("."), ".ragsync", "local")
+}
+
+// NewLocalClientFromConfig 从配置创建本地后端，数据保存在 <local_backend_dir>/<workspace>
+func NewLocalClientFromConfig(config *spec.Config) (*LocalClient, error) {
+	if config == nil {
+		return nil, utils.Error("Configuration cannot be nil")
+	}
+	if config.BailianWorkspaceId == "" {
+		return nil, utils.Error("Workspace ID is not set")
+	}
+
+	baseDir := config.LocalBackendDir
+	if baseDir == "" {
+		baseDir = DefaultDir()
+	}
+	if strings.HasPrefix(baseDir, "~/") {
+		baseDir = filepath.Join(utils.GetHomeDirDefault("."), baseDir[2:])
+	}
+
+	client := &LocalClient{
+		config: config,
+		dir:    filepath.Join(baseDir, config.BailianWorkspaceId),
+	}
+	log.Infof("Local backend data directory: %s", client.dir)
+	return client, nil
+}
+
+// Name 返回后端名称
+func (client *LocalClient) Name() string {
+	return BackendName
+}
+
+// Dir 返回当前工作空间的数据目录
+func (client *LocalClient) Dir() string {
+	return client.dir
+}
+
+// storePath 返回 store.json 的路径
+func (client *LocalClient) storePath() string {
+	return filepath.Join(client.dir, "store.json")
+}
+
+// contentPath 返回已添加文件内容的保存路径
+func (client *LocalClient) contentPath(fileId string) string {
+	return filepath.Join(client.dir, "files", fileId)
+}
+
+// uploadPath 返回租约上传内容的临时保存路径
+func (client *LocalClient) uploadPath(leaseId string) string {
+	return filepath.Join(client.dir, "uploads", leaseId)
+}
+
+// view 读取数据，ctx 已结束时直接返回
+// store.json 总是通过重命名整体替换，读取时不需要锁文件
+func (client *LocalClient) view(ctx context.Context, fn func(s *store) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	s, err := loadStore(client.storePath())
+	if err != nil {
+		return err
+	}
+	return fn(s)
+}
+
+// update 持有锁文件读取数据，修改成功后写回磁盘，ctx 已结束时直接返回
+func (client *LocalClient) update(ctx context.Context, fn func(s *store) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	unlock, err := lockStore(ctx, client.storePath())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s, err := loadStore(client.storePath())
+	if err != nil {
+		return err
+	}
+	if err := fn(s); err != nil {
+		return err
+	}
+	return s.save(client.storePath())
+}
+
+// removeFileExtension 移除文件名中的扩展名，与百炼索引中的文档名一致
+func removeFileExtension(fileName string) string {
+	if i := strings.LastIndex(fileName, "."); i > 0 {
+		return fileName[:i]
+	}
+	return fileName
+}
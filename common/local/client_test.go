@@ -0,0 +1,248 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VillanCh/ragsync/common/backend"
+	"github.com/VillanCh/ragsync/common/spec"
+)
+
+// newTestConfig 返回数据目录在临时目录中的本地后端配置，HOME 也指向临时目录
+func newTestConfig(t *testing.T) *spec.Config {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	config := spec.GetDefaultConfig()
+	config.Backend = BackendName
+	config.LocalBackendDir = t.TempDir()
+	config.BailianWorkspaceId = "ws-test"
+	config.BailianKnowledgeIndexId = "idx-test"
+	return &config
+}
+
+// newTestClient 创建使用临时数据目录的本地后端
+func newTestClient(t *testing.T) *LocalClient {
+	t.Helper()
+	client, err := NewLocalClientFromConfig(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewLocalClientFromConfig: %v", err)
+	}
+	return client
+}
+
+// addTestFile 按 sync 的顺序申请租约、上传内容并添加文件，返回文件 ID
+func addTestFile(t *testing.T, client *LocalClient, fileName, content string) string {
+	t.Helper()
+	ctx := context.Background()
+	body := backend.BytesContent([]byte(content))
+	lease, err := client.ApplyUploadLeaseWithContext(ctx, fileName, body)
+	if err != nil {
+		t.Fatalf("ApplyUploadLease: %v", err)
+	}
+	if err := client.UploadContentWithContext(ctx, lease, fileName, body); err != nil {
+		t.Fatalf("UploadContent: %v", err)
+	}
+	fileId, err := client.AddFileWithContext(ctx, lease.LeaseId)
+	if err != nil {
+		t.Fatalf("AddFile: %v", err)
+	}
+	return fileId
+}
+
+func TestLocalFileLifecycle(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+
+	if _, err := client.ApplyUploadLeaseWithContext(ctx, "README", backend.BytesContent([]byte("x"))); err == nil {
+		t.Fatal("ApplyUploadLease accepted a file name without an extension")
+	}
+
+	body := backend.BytesContent([]byte("# guide\n"))
+	lease, err := client.ApplyUploadLeaseWithContext(ctx, "docs/guide.md", body)
+	if err != nil {
+		t.Fatalf("ApplyUploadLease: %v", err)
+	}
+	// 内容上传之前不能添加文件
+	if _, err := client.AddFileWithContext(ctx, lease.LeaseId); err == nil || !strings.Contains(err.Error(), "has not been uploaded") {
+		t.Fatalf("AddFile before the upload returned %v, want a not uploaded error", err)
+	}
+	// 上传的内容必须与申请租约时一致
+	if err := client.UploadContentWithContext(ctx, lease, "docs/guide.md", backend.BytesContent([]byte("other"))); err == nil {
+		t.Fatal("UploadContent accepted content that does not match the lease")
+	}
+	if err := client.UploadContentWithContext(ctx, lease, "docs/guide.md", body); err != nil {
+		t.Fatalf("UploadContent: %v", err)
+	}
+
+	fileId, err := client.AddFileWithContext(ctx, lease.LeaseId)
+	if err != nil {
+		t.Fatalf("AddFile: %v", err)
+	}
+	// 租约在添加文件后失效
+	if _, err := client.AddFileWithContext(ctx, lease.LeaseId); err == nil {
+		t.Fatal("AddFile accepted a lease that was already used")
+	}
+
+	info, err := client.DescribeFileWithContext(ctx, fileId)
+	if err != nil {
+		t.Fatalf("DescribeFile: %v", err)
+	}
+	if info.Status != fileStatusParsing || info.FileName != "docs/guide.md" || info.SizeInBytes != body.Size {
+		t.Fatalf("new file = %+v, want PARSING docs/guide.md of %d bytes", info, body.Size)
+	}
+	if raw, err := os.ReadFile(client.contentPath(fileId)); err != nil || string(raw) != "# guide\n" {
+		t.Fatalf("stored content = %q, %v, want the uploaded content", raw, err)
+	}
+
+	addTestFile(t, client, "docs/faq.md", "# faq\n")
+	files, err := client.ListAllFilesWithContext(ctx, "guide.md")
+	if err != nil || len(files) != 1 || files[0].FileId != fileId {
+		t.Fatalf("ListAllFiles(guide.md) = %d files, %v, want only %s", len(files), err, fileId)
+	}
+
+	if err := client.DeleteFileExWithContext(ctx, fileId, false); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	if _, err := os.Stat(client.contentPath(fileId)); !os.IsNotExist(err) {
+		t.Fatalf("content of the deleted file is still stored: %v", err)
+	}
+	// 已删除的文件返回 backend.ErrNotFound
+	if _, err := client.DescribeFileWithContext(ctx, fileId); !errors.Is(err, backend.ErrNotFound) {
+		t.Fatalf("DescribeFile of a deleted file returned %v, want ErrNotFound", err)
+	}
+	if err := client.DeleteFileExWithContext(ctx, fileId, false); !errors.Is(err, backend.ErrNotFound) {
+		t.Fatalf("deleting the file twice returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalIndexLifecycle(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	guide := addTestFile(t, client, "docs/guide.md", "guide")
+	faq := addTestFile(t, client, "docs/faq.md", "faq")
+
+	if _, err := client.AppendDocumentsToIndexWithContext(ctx, []string{guide, "file_missing"}); !errors.Is(err, backend.ErrNotFound) {
+		t.Fatalf("AppendDocumentsToIndex with a missing file returned %v, want ErrNotFound", err)
+	}
+	if records, _ := client.ListAllIndexDocumentsWithContext(ctx); len(records) != 0 {
+		t.Fatalf("a failed job added %d index documents, want 0", len(records))
+	}
+
+	jobId, err := client.AppendDocumentsToIndexWithContext(ctx, []string{guide, faq})
+	if err != nil {
+		t.Fatalf("AppendDocumentsToIndex: %v", err)
+	}
+	job, err := client.IndexJobStatusWithContext(ctx, jobId)
+	if err != nil || job.Status != jobStatusRunning {
+		t.Fatalf("IndexJobStatus = %+v, %v, want RUNNING", job, err)
+	}
+	if documentIds, err := backend.LoadIndexJobDocuments(jobId); err != nil || len(documentIds) != 2 {
+		t.Fatalf("saved job documents = %v, %v, want both files", documentIds, err)
+	}
+
+	records, err := client.ListAllIndexDocumentsWithContext(ctx)
+	if err != nil {
+		t.Fatalf("ListAllIndexDocuments: %v", err)
+	}
+	if len(records) != 2 || records[0].DocumentName != "docs/faq" || records[0].Status != jobStatusRunning {
+		t.Fatalf("index documents = %d, first %+v, want docs/faq and docs/guide without extensions, RUNNING", len(records), records[0])
+	}
+	if indexed, err := client.DocumentIndexedWithContext(ctx, "docs/guide.md"); err != nil || !indexed {
+		t.Fatalf("DocumentIndexed(docs/guide.md) = %v, %v, want true", indexed, err)
+	}
+	// 已在索引中的文件不会再次提交
+	if jobId, err := client.AppendDocumentToIndexWithContext(ctx, guide); err != nil || jobId != "" {
+		t.Fatalf("AppendDocumentToIndex of an indexed file = %q, %v, want no new job", jobId, err)
+	}
+
+	// 保留索引文档时只删除文件
+	if err := client.DeleteFileExWithContext(ctx, faq, true); err != nil {
+		t.Fatalf("DeleteFileEx with skipIndexDelete: %v", err)
+	}
+	if records, _ := client.ListAllIndexDocumentsWithContext(ctx); len(records) != 2 {
+		t.Fatalf("index has %d documents after deleting a file but keeping its document, want 2", len(records))
+	}
+	if err := client.DeleteIndexDocumentWithContext(ctx, faq); err != nil {
+		t.Fatalf("DeleteIndexDocument: %v", err)
+	}
+	if err := client.DeleteFileExWithContext(ctx, guide, false); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	if records, _ := client.ListAllIndexDocumentsWithContext(ctx); len(records) != 0 {
+		t.Fatalf("index has %d documents after deleting both, want 0", len(records))
+	}
+
+	if _, err := client.IndexJobStatusWithContext(ctx, "job_missing"); !errors.Is(err, backend.ErrNotFound) {
+		t.Fatalf("IndexJobStatus of a missing job returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalStatusTransitions(t *testing.T) {
+	created := time.Now()
+	file := &storedFile{CreatedAt: created}
+	if file.Status(created) != fileStatusParsing || file.Status(created.Add(parseDelay)) != fileStatusParseSuccess {
+		t.Fatalf("file status = %s then %s, want PARSING then PARSE_SUCCESS", file.Status(created), file.Status(created.Add(parseDelay)))
+	}
+
+	job := &storedJob{JobId: "job_1", SubmittedAt: created}
+	if job.Status(created) != jobStatusRunning || job.Status(created.Add(indexDelay)) != jobStatusFinish {
+		t.Fatalf("job status = %s then %s, want RUNNING then FINISH", job.Status(created), job.Status(created.Add(indexDelay)))
+	}
+
+	// 文档状态跟随其所属的任务，任务记录不存在时视为已完成
+	s := newStore()
+	s.Jobs[job.JobId] = job
+	if status := s.documentStatus(&storedDocument{JobId: "job_1"}, created); status != jobStatusRunning {
+		t.Fatalf("status of a document in a running job = %s, want RUNNING", status)
+	}
+	if status := s.documentStatus(&storedDocument{JobId: "job_gone"}, created); status != jobStatusFinish {
+		t.Fatalf("status of a document without a job = %s, want FINISH", status)
+	}
+}
+
+func TestLocalStorePersists(t *testing.T) {
+	config := newTestConfig(t)
+	config.LocalBackendDir = "~/backend"
+	client, err := NewLocalClientFromConfig(config)
+	if err != nil {
+		t.Fatalf("NewLocalClientFromConfig: %v", err)
+	}
+	// ~/ 展开为 HOME，数据按工作空间分目录保存
+	if want := filepath.Join(os.Getenv("HOME"), "backend", "ws-test"); client.Dir() != want {
+		t.Fatalf("data directory = %s, want %s", client.Dir(), want)
+	}
+	fileId := addTestFile(t, client, "a.md", "a")
+
+	// 另一个客户端从 store.json 读取同样的数据
+	reopened, err := NewLocalClientFromConfig(config)
+	if err != nil {
+		t.Fatalf("NewLocalClientFromConfig: %v", err)
+	}
+	if _, err := reopened.DescribeFileWithContext(context.Background(), fileId); err != nil {
+		t.Fatalf("DescribeFile after reopening: %v", err)
+	}
+
+	if err := os.WriteFile(client.storePath(), []byte("{broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.ListAllFilesWithContext(context.Background(), ""); err == nil {
+		t.Fatal("ListAllFiles accepted a corrupted store")
+	}
+}
+
+func TestLocalCancelledContext(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.ApplyUploadLeaseWithContext(ctx, "a.md", backend.BytesContent([]byte("a"))); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ApplyUploadLease with a cancelled context returned %v, want context.Canceled", err)
+	}
+	if _, err := client.ListAllFilesWithContext(ctx, ""); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ListAllFiles with a cancelled context returned %v, want context.Canceled", err)
+	}
+}
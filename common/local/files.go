@@ -0,0 +1,260 @@
+package local
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/VillanCh/ragsync/common/backend"
+
+	"github.com/yaklang/yaklang/common/log"
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// toFileInfo 将本地文件记录转换为通用的文件信息
+func (f *storedFile) toFileInfo(now time.Time) *backend.FileInfo {
+	return &backend.FileInfo{
+		FileId:      f.FileId,
+		FileName:    f.FileName,
+		Status:      f.Status(now),
+		CategoryId:  f.CategoryId,
+		CreateTime:  f.CreatedAt.Format(time.RFC3339),
+		SizeInBytes: f.Size,
+		Raw:         f,
+	}
+}
+
+// ListAllFilesWithContext 列出所有文件，fileName 非空时按去掉扩展名后的文件名模糊匹配
+func (client *LocalClient) ListAllFilesWithContext(ctx context.Context, fileName string) ([]*backend.FileInfo, error) {
+	keyword := removeFileExtension(fileName)
+
+	var files []*backend.FileInfo
+	err := client.view(ctx, func(s *store) error {
+		now := time.Now()
+		for _, file := range s.Files {
+			if keyword != "" && !strings.Contains(file.FileName, keyword) {
+				continue
+			}
+			files = append(files, file.toFileInfo(now))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].CreateTime != files[j].CreateTime {
+			return files[i].CreateTime > files[j].CreateTime
+		}
+		return files[i].FileId < files[j].FileId
+	})
+	log.Infof("Retrieved %d files in total", len(files))
+	return files, nil
+}
+
+// DescribeFileWithContext 查询文件信息
+func (client *LocalClient) DescribeFileWithContext(ctx context.Context, fileId string) (*backend.FileInfo, error) {
+	if fileId == "" {
+		return nil, utils.Error("File ID cannot be empty")
+	}
+
+	var info *backend.FileInfo
+	err := client.view(ctx, func(s *store) error {
+		file, ok := s.Files[fileId]
+		if !ok {
+			return utils.Errorf("Failed to describe file: file %s %w", fileId, backend.ErrNotFound)
+		}
+		info = file.toFileInfo(time.Now())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("File information retrieved successfully, file ID: %s, name: %s", info.FileId, info.FileName)
+	return info, nil
+}
+
+// ApplyUploadLeaseWithContext 申请文件上传租约，上传地址为本地数据目录中的临时文件
+func (client *LocalClient) ApplyUploadLeaseWithContext(ctx context.Context, fileName string, content *backend.Content) (*backend.UploadLease, error) {
+	if filepath.Ext(fileName) == "" {
+		return nil, utils.Error("File extension cannot be empty")
+	}
+	if content == nil {
+		return nil, utils.Error("File content cannot be nil")
+	}
+
+	lease := &storedLease{
+		LeaseId:   newId("lease_"),
+		FileName:  fileName,
+		MD5:       content.MD5,
+		Size:      content.Size,
+		CreatedAt: time.Now(),
+	}
+	err := client.update(ctx, func(s *store) error {
+		s.Leases[lease.LeaseId] = lease
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &backend.UploadLease{
+		LeaseId:   lease.LeaseId,
+		UploadURL: "file://" + filepath.ToSlash(client.uploadPath(lease.LeaseId)),
+		Method:    "PUT",
+		Headers:   map[string]string{"Content-Type": "application/octet-stream"},
+		Raw:       lease,
+	}, nil
+}
+
+// UploadContentWithContext 按租约将文件内容以流的方式写入本地数据目录，内容的大小和 MD5 必须与申请租约时一致
+func (client *LocalClient) UploadContentWithContext(ctx context.Context, lease *backend.UploadLease, fileName string, content *backend.Content) error {
+	if lease == nil {
+		return utils.Error("Upload lease cannot be nil")
+	}
+	if content == nil {
+		return utils.Error("File content cannot be nil")
+	}
+
+	var expected storedLease
+	err := client.view(ctx, func(s *store) error {
+		stored, ok := s.Leases[lease.LeaseId]
+		if !ok {
+			return utils.Errorf("Upload lease %s not found", lease.LeaseId)
+		}
+		expected = *stored
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 写入临时文件时不持有存储锁，大文件不会阻塞其他操作
+	path := client.uploadPath(lease.LeaseId)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return utils.Errorf("Failed to create upload directory: %v", err)
+	}
+	size, md5Str, err := writeContent(path, content)
+	if err != nil {
+		return err
+	}
+	if size != expected.Size || md5Str != expected.MD5 {
+		os.Remove(path)
+		return utils.Errorf("Uploaded content of %s does not match the lease", fileName)
+	}
+
+	return client.update(ctx, func(s *store) error {
+		stored, ok := s.Leases[lease.LeaseId]
+		if !ok {
+			return utils.Errorf("Upload lease %s not found", lease.LeaseId)
+		}
+		stored.Uploaded = true
+		return nil
+	})
+}
+
+// writeContent 将内容写入 path，返回写入的大小和 MD5
+func writeContent(path string, content *backend.Content) (int64, string, error) {
+	body, err := content.Open()
+	if err != nil {
+		return 0, "", err
+	}
+	defer body.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, "", utils.Errorf("Failed to upload file: %v", err)
+	}
+	hash := md5.New()
+	size, err := io.Copy(io.MultiWriter(f, hash), body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, "", utils.Errorf("Failed to upload file: %v", err)
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// AddFileWithContext 将已上传的内容添加为文件，新文件的状态为 PARSING
+func (client *LocalClient) AddFileWithContext(ctx context.Context, leaseId string) (string, error) {
+	if leaseId == "" {
+		return "", utils.Error("Lease ID cannot be empty")
+	}
+
+	fileId := newId("file_")
+	err := client.update(ctx, func(s *store) error {
+		lease, ok := s.Leases[leaseId]
+		if !ok {
+			return utils.Errorf("Failed to add file: upload lease %s not found", leaseId)
+		}
+		if !lease.Uploaded {
+			return utils.Errorf("Failed to add file: content of lease %s has not been uploaded", leaseId)
+		}
+
+		path := client.contentPath(fileId)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return utils.Errorf("Failed to create files directory: %v", err)
+		}
+		if err := os.Rename(client.uploadPath(leaseId), path); err != nil {
+			return utils.Errorf("Failed to add file: %v", err)
+		}
+
+		s.Files[fileId] = &storedFile{
+			FileId:     fileId,
+			FileName:   lease.FileName,
+			CategoryId: client.config.BailianFilesDefaultCategoryId,
+			Parser:     client.config.BailianAddFileParser,
+			MD5:        lease.MD5,
+			Size:       lease.Size,
+			CreatedAt:  time.Now(),
+		}
+		delete(s.Leases, leaseId)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	log.Infof("File added successfully, file ID: %s", fileId)
+	return fileId, nil
+}
+
+// DeleteFileExWithContext 删除文件，skipDeleteIndex 为 false 时同时删除索引中的文档
+func (client *LocalClient) DeleteFileExWithContext(ctx context.Context, fileId string, skipDeleteIndex bool) error {
+	if fileId == "" {
+		return utils.Error("File ID cannot be empty")
+	}
+
+	indexId := client.config.BailianKnowledgeIndexId
+	err := client.update(ctx, func(s *store) error {
+		if _, ok := s.Files[fileId]; !ok {
+			return utils.Errorf("Failed to delete file: file %s %w", fileId, backend.ErrNotFound)
+		}
+		if indexId != "" && !skipDeleteIndex {
+			delete(s.Documents, documentKey(indexId, fileId))
+		} else if skipDeleteIndex {
+			log.Infof("Skipping index document deletion step as requested")
+		}
+		delete(s.Files, fileId)
+		if err := os.Remove(client.contentPath(fileId)); err != nil && !os.IsNotExist(err) {
+			log.Warnf("Failed to remove content of file %s: %v", fileId, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("File deleted successfully: %s", fileId)
+	return nil
+}
@@ -0,0 +1,199 @@
+package local
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/VillanCh/ragsync/common/backend"
+
+	"github.com/yaklang/yaklang/common/log"
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// indexId 返回配置中的知识索引 ID
+func (client *LocalClient) indexId() (string, error) {
+	if client.config.BailianKnowledgeIndexId == "" {
+		return "", utils.Error("Knowledge Index ID is not set")
+	}
+	return client.config.BailianKnowledgeIndexId, nil
+}
+
+// documentStatus 文档状态跟随其所属索引任务
+func (s *store) documentStatus(doc *storedDocument, now time.Time) string {
+	if job, ok := s.Jobs[doc.JobId]; ok {
+		return job.Status(now)
+	}
+	return jobStatusFinish
+}
+
+// AppendDocumentsToIndexWithContext 将文件作为一个索引任务加入知识索引，任务在 indexDelay 后完成
+func (client *LocalClient) AppendDocumentsToIndexWithContext(ctx context.Context, documentIds []string) (string, error) {
+	indexId, err := client.indexId()
+	if err != nil {
+		return "", err
+	}
+
+	job := &storedJob{
+		JobId:       newId("job_"),
+		IndexId:     indexId,
+		DocumentIds: documentIds,
+		SubmittedAt: time.Now(),
+	}
+	err = client.update(ctx, func(s *store) error {
+		for _, id := range documentIds {
+			if _, ok := s.Files[id]; !ok {
+				return utils.Errorf("Failed to add documents to index: file %s %w", id, backend.ErrNotFound)
+			}
+		}
+		for _, id := range documentIds {
+			file := s.Files[id]
+			s.Documents[documentKey(indexId, id)] = &storedDocument{
+				DocumentId: id,
+				Name:       removeFileExtension(file.FileName),
+				IndexId:    indexId,
+				Size:       file.Size,
+				JobId:      job.JobId,
+			}
+		}
+		s.Jobs[job.JobId] = job
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	log.Infof("Added %d documents to knowledge index: %s, job ID: %s", len(documentIds), indexId, job.JobId)
+	if err := backend.SaveIndexJobDocuments(job.JobId, documentIds); err != nil {
+		log.Warnf("Failed to save job ID to file: %v", err)
+	}
+	return job.JobId, nil
+}
+
+// AppendDocumentToIndexWithContext 将单个文件加入知识索引，文档已在索引中时跳过
+func (client *LocalClient) AppendDocumentToIndexWithContext(ctx context.Context, documentId string) (string, error) {
+	fileInfo, err := client.DescribeFileWithContext(ctx, documentId)
+	if err != nil {
+		return "", utils.Errorf("Failed to get file info for document ID %s: %v", documentId, err)
+	}
+
+	indexed, err := client.DocumentIndexedWithContext(ctx, fileInfo.FileName)
+	if err != nil {
+		log.Warnf("Failed to check if document is already indexed: %v", err)
+	} else if indexed {
+		log.Infof("Document '%s' (ID: %s) is already being indexed or has been indexed. Skipping index addition.",
+			fileInfo.FileName, documentId)
+		return "", nil
+	}
+
+	return client.AppendDocumentsToIndexWithContext(ctx, []string{documentId})
+}
+
+// DeleteIndexDocumentWithContext 从知识索引中删除文档
+func (client *LocalClient) DeleteIndexDocumentWithContext(ctx context.Context, documentId string) error {
+	indexId, err := client.indexId()
+	if err != nil {
+		return err
+	}
+	if documentId == "" {
+		return utils.Error("Document ID cannot be empty")
+	}
+
+	err = client.update(ctx, func(s *store) error {
+		delete(s.Documents, documentKey(indexId, documentId))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Document deleted successfully from index: %s", documentId)
+	return nil
+}
+
+// ListAllIndexDocumentsWithContext 列出知识索引中的所有文档
+func (client *LocalClient) ListAllIndexDocumentsWithContext(ctx context.Context) ([]*backend.IndexDocumentRecord, error) {
+	indexId, err := client.indexId()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*backend.IndexDocumentRecord
+	err = client.view(ctx, func(s *store) error {
+		now := time.Now()
+		for _, doc := range s.Documents {
+			if doc.IndexId != indexId {
+				continue
+			}
+			records = append(records, &backend.IndexDocumentRecord{
+				DocumentName: doc.Name,
+				DocumentId:   doc.DocumentId,
+				Status:       s.documentStatus(doc, now),
+				IndexId:      doc.IndexId,
+				DocumentType: "file",
+				Size:         int32(doc.Size),
+				SourceId:     doc.DocumentId,
+				Raw:          doc,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].DocumentName < records[j].DocumentName
+	})
+	log.Infof("Retrieved %d index documents in total", len(records))
+	return records, nil
+}
+
+// DocumentIndexedWithContext 判断文档是否已在知识索引中（或正在建立索引），按去掉扩展名后的文件名精确匹配
+func (client *LocalClient) DocumentIndexedWithContext(ctx context.Context, documentName string) (bool, error) {
+	indexId, err := client.indexId()
+	if err != nil {
+		return false, err
+	}
+	if documentName == "" {
+		return false, utils.Error("Document name cannot be empty")
+	}
+	name := removeFileExtension(documentName)
+
+	var indexed bool
+	err = client.view(ctx, func(s *store) error {
+		for _, doc := range s.Documents {
+			if doc.IndexId == indexId && doc.Name == name {
+				indexed = true
+				return nil
+			}
+		}
+		return nil
+	})
+	if indexed {
+		log.Warnf("Document '%s' is already being indexed or has been indexed", name)
+	}
+	return indexed, err
+}
+
+// IndexJobStatusWithContext 查询索引任务状态
+func (client *LocalClient) IndexJobStatusWithContext(ctx context.Context, jobId string) (*backend.IndexJob, error) {
+	if jobId == "" {
+		return nil, utils.Error("Job ID cannot be empty")
+	}
+
+	var result *backend.IndexJob
+	err := client.view(ctx, func(s *store) error {
+		job, ok := s.Jobs[jobId]
+		if !ok {
+			return utils.Errorf("Index job %s %w", jobId, backend.ErrNotFound)
+		}
+		result = &backend.IndexJob{
+			JobId:  job.JobId,
+			Status: job.Status(time.Now()),
+			Raw:    job,
+		}
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,207 @@
+package local
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/yaklang/yaklang/common/log"
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+const (
+	// parseDelay 文件从 PARSING 变为 PARSE_SUCCESS 所需的时间
+	parseDelay = 2 * time.Second
+	// indexDelay 索引任务从 RUNNING 变为 FINISH 所需的时间
+	indexDelay = 5 * time.Second
+)
+
+const (
+	// lockRetryInterval 锁文件被其他进程持有时重试的间隔
+	lockRetryInterval = 10 * time.Millisecond
+	// lockStaleAfter 锁文件超过这个时间没有释放时视为持有者已退出，单次读写远小于这个时间
+	lockStaleAfter = 30 * time.Second
+	// lockTimeout 等待锁文件的最长时间，大于 lockStaleAfter 以便清理遗留的锁文件
+	lockTimeout = time.Minute
+)
+
+// 与百炼一致的状态值
+const (
+	fileStatusParsing      = "PARSING"
+	fileStatusParseSuccess = "PARSE_SUCCESS"
+	jobStatusRunning       = "RUNNING"
+	jobStatusFinish        = "FINISH"
+)
+
+// storedLease 已申请的上传租约
+type storedLease struct {
+	LeaseId   string    `json:"leaseId"`
+	FileName  string    `json:"fileName"`
+	MD5       string    `json:"md5"`
+	Size      int64     `json:"size"`
+	Uploaded  bool      `json:"uploaded"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// storedFile 数据中心中的文件
+type storedFile struct {
+	FileId     string    `json:"fileId"`
+	FileName   string    `json:"fileName"`
+	CategoryId string    `json:"categoryId"`
+	Parser     string    `json:"parser"`
+	MD5        string    `json:"md5"`
+	Size       int64     `json:"size"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Status 文件解析状态，添加后经过 parseDelay 解析完成
+func (f *storedFile) Status(now time.Time) string {
+	if now.Sub(f.CreatedAt) < parseDelay {
+		return fileStatusParsing
+	}
+	return fileStatusParseSuccess
+}
+
+// storedDocument 知识索引中的文档
+type storedDocument struct {
+	DocumentId string `json:"documentId"`
+	Name       string `json:"name"`
+	IndexId    string `json:"indexId"`
+	Size       int64  `json:"size"`
+	JobId      string `json:"jobId"`
+}
+
+// storedJob 索引任务
+type storedJob struct {
+	JobId       string    `json:"jobId"`
+	IndexId     string    `json:"indexId"`
+	DocumentIds []string  `json:"documentIds"`
+	SubmittedAt time.Time `json:"submittedAt"`
+}
+
+// Status 任务状态，提交后经过 indexDelay 完成
+func (j *storedJob) Status(now time.Time) string {
+	if now.Sub(j.SubmittedAt) < indexDelay {
+		return jobStatusRunning
+	}
+	return jobStatusFinish
+}
+
+// store 本地后端的全部数据，保存在 <dir>/store.json，文件内容保存在 <dir>/files/<fileId>
+type store struct {
+	Leases    map[string]*storedLease    `json:"leases"`
+	Files     map[string]*storedFile     `json:"files"`
+	Documents map[string]*storedDocument `json:"documents"` // 键为 indexId/documentId
+	Jobs      map[string]*storedJob      `json:"jobs"`
+}
+
+// newStore 创建空的数据
+func newStore() *store {
+	return &store{
+		Leases:    make(map[string]*storedLease),
+		Files:     make(map[string]*storedFile),
+		Documents: make(map[string]*storedDocument),
+		Jobs:      make(map[string]*storedJob),
+	}
+}
+
+// documentKey 返回文档在 store.Documents 中的键
+func documentKey(indexId, documentId string) string {
+	return indexId + "/" + documentId
+}
+
+// loadStore 从磁盘读取数据，文件不存在时返回空数据
+func loadStore(path string) (*store, error) {
+	s := newStore()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, utils.Errorf("Failed to read local backend store %s: %v", path, err)
+	}
+	if err := json.Unmarshal(raw, s); err != nil {
+		return nil, utils.Errorf("Failed to parse local backend store %s: %v", path, err)
+	}
+	if s.Leases == nil {
+		s.Leases = make(map[string]*storedLease)
+	}
+	if s.Files == nil {
+		s.Files = make(map[string]*storedFile)
+	}
+	if s.Documents == nil {
+		s.Documents = make(map[string]*storedDocument)
+	}
+	if s.Jobs == nil {
+		s.Jobs = make(map[string]*storedJob)
+	}
+	return s, nil
+}
+
+// save 将数据写回磁盘（先写临时文件再重命名）
+func (s *store) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return utils.Errorf("Failed to create local backend directory: %v", err)
+	}
+	raw, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return utils.Errorf("Failed to serialize local backend store: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return utils.Errorf("Failed to write local backend store: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return utils.Errorf("Failed to replace local backend store: %v", err)
+	}
+	return nil
+}
+
+// lockStore 创建 <path>.lock 锁文件，保证多个 ragsync 进程对 store.json 的读取、修改和写回不会交错
+// 锁文件已存在时等待，超过 lockStaleAfter 的锁文件被视为遗留并删除；返回释放锁的函数
+func lockStore(ctx context.Context, path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, utils.Errorf("Failed to create local backend directory: %v", err)
+	}
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, utils.Errorf("Failed to lock local backend store: %v", err)
+		}
+
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > lockStaleAfter {
+			log.Warnf("Removing stale local backend lock %s (created %s)", lockPath, info.ModTime().Format(time.RFC3339))
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, utils.Errorf("Timed out waiting for the local backend lock %s, remove it if no other ragsync process is running", lockPath)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// newId 生成带前缀的随机 ID
+func newId(prefix string) string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return prefix + hex.EncodeToString([]byte(time.Now().Format(time.RFC3339Nano)))
+	}
+	return prefix + hex.EncodeToString(buf)
+}
@@ -0,0 +1,111 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VillanCh/ragsync/common/backend"
+)
+
+func TestStoreUpdatesFromSeparateClients(t *testing.T) {
+	config := newTestConfig(t)
+	// 两个客户端的互斥锁相互独立，相当于两个 ragsync 进程同时修改同一个 store.json
+	var clients []*LocalClient
+	for i := 0; i < 2; i++ {
+		client, err := NewLocalClientFromConfig(config)
+		if err != nil {
+			t.Fatalf("NewLocalClientFromConfig: %v", err)
+		}
+		clients = append(clients, client)
+	}
+
+	const perClient = 20
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		for i := 0; i < perClient; i++ {
+			wg.Add(1)
+			go func(client *LocalClient) {
+				defer wg.Done()
+				if _, err := client.ApplyUploadLeaseWithContext(context.Background(), "a.md", backend.BytesContent([]byte("a"))); err != nil {
+					t.Errorf("ApplyUploadLease: %v", err)
+				}
+			}(client)
+		}
+	}
+	wg.Wait()
+
+	s, err := loadStore(clients[0].storePath())
+	if err != nil {
+		t.Fatalf("loadStore: %v", err)
+	}
+	if len(s.Leases) != 2*perClient {
+		t.Fatalf("store has %d leases, want %d: concurrent updates were lost", len(s.Leases), 2*perClient)
+	}
+	if _, err := os.Stat(clients[0].storePath() + ".lock"); !os.IsNotExist(err) {
+		t.Fatalf("lock file left behind: %v", err)
+	}
+}
+
+func TestLockStoreWaitsForHolder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	unlock, err := lockStore(context.Background(), path)
+	if err != nil {
+		t.Fatalf("lockStore: %v", err)
+	}
+
+	// 锁被持有时等待，ctx 结束后返回 ctx 的错误
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if _, err := lockStore(ctx, path); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("lockStore while the lock is held returned %v, want context.DeadlineExceeded", err)
+	}
+
+	// 持有者释放后可以立即获得锁
+	acquired := make(chan error, 1)
+	go func() {
+		unlock, err := lockStore(context.Background(), path)
+		if err == nil {
+			unlock()
+		}
+		acquired <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	unlock()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatalf("lockStore after the release: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("lockStore did not acquire the released lock")
+	}
+}
+
+func TestLockStoreRemovesStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	lockPath := path + ".lock"
+	if err := os.WriteFile(lockPath, []byte("12345\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// 退出的进程遗留的锁文件超过 lockStaleAfter 后被删除
+	old := time.Now().Add(-2 * lockStaleAfter)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	unlock, err := lockStore(ctx, path)
+	if err != nil {
+		t.Fatalf("lockStore with a stale lock: %v", err)
+	}
+	unlock()
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Fatalf("lock file left behind after unlock: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package secret
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"strings"
+
+	"github.com/yaklang/yaklang/common/utils"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// 提供口令的环境变量，口令文件可以是随机生成的密钥文件
+const (
+	EnvPassphrase     = "RAGSYNC_SECRET_PASSPHRASE"
+	EnvPassphraseFile = "RAGSYNC_SECRET_PASSPHRASE_FILE"
+)
+
+// scrypt 参数，派生一次密钥约需 32MB 内存
+const (
+	scryptN       = 1 << 15
+	scryptR       = 8
+	scryptP       = 1
+	scryptSaltLen = 16
+)
+
+// passphrase 从环境变量或口令文件读取口令
+func passphrase() ([]byte, error) {
+	if value := os.Getenv(EnvPassphrase); value != "" {
+		return []byte(value), nil
+	}
+	if path := os.Getenv(EnvPassphraseFile); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, utils.Errorf("Failed to read passphrase file %s: %v", path, err)
+		}
+		value := strings.TrimRight(string(raw), "\r\n")
+		if value == "" {
+			return nil, utils.Errorf("Passphrase file %s is empty", path)
+		}
+		return []byte(value), nil
+	}
+	return nil, utils.Errorf("No passphrase for encrypted secrets: set %s or %s", EnvPassphrase, EnvPassphraseFile)
+}
+
+// HasPassphrase 是否配置了加密口令
+func HasPassphrase() bool {
+	return os.Getenv(EnvPassphrase) != "" || os.Getenv(EnvPassphraseFile) != ""
+}
+
+// deriveKey 使用 scrypt 从口令派生密钥
+func deriveKey(pass []byte, salt []byte) ([]byte, error) {
+	key, err := scrypt.Key(pass, salt, scryptN, scryptR, scryptP, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, utils.Errorf("Failed to derive encryption key: %v", err)
+	}
+	return key, nil
+}
+
+// Encrypt 使用口令加密明文，返回 enc:v1:<base64(salt | nonce | ciphertext)>
+// 密钥由 scrypt 派生，使用 XChaCha20-Poly1305 加密
+func Encrypt(plaintext string) (string, error) {
+	pass, err := passphrase()
+	if err != nil {
+		return "", err
+	}
+
+	salt := make([]byte, scryptSaltLen)
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := rand.Read(salt); err != nil {
+		return "", utils.Errorf("Failed to generate salt: %v", err)
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return "", utils.Errorf("Failed to generate nonce: %v", err)
+	}
+	key, err := deriveKey(pass, salt)
+	if err != nil {
+		return "", err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return "", utils.Errorf("Failed to create cipher: %v", err)
+	}
+
+	data := append(salt, nonce...)
+	data = aead.Seal(data, nonce, []byte(plaintext), []byte(encryptedPrefix))
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// Decrypt 解密 Encrypt 返回的内容，口令错误或内容被篡改时返回错误
+func Decrypt(value string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(data) < scryptSaltLen+chacha20poly1305.NonceSizeX+chacha20poly1305.Overhead {
+		return "", utils.Errorf("Malformed encrypted secret")
+	}
+	pass, err := passphrase()
+	if err != nil {
+		return "", err
+	}
+
+	salt := data[:scryptSaltLen]
+	nonce := data[scryptSaltLen : scryptSaltLen+chacha20poly1305.NonceSizeX]
+	ciphertext := data[scryptSaltLen+chacha20poly1305.NonceSizeX:]
+	key, err := deriveKey(pass, salt)
+	if err != nil {
+		return "", err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return "", utils.Errorf("Failed to create cipher: %v", err)
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(encryptedPrefix))
+	if err != nil {
+		return "", utils.Errorf("Failed to decrypt secret: wrong passphrase or corrupted value")
+	}
+	return string(plaintext), nil
+}
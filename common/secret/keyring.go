@@ -0,0 +1,198 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/yaklang/yaklang/common/log"
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// keyringService 系统密钥环中使用的服务名
+const keyringService = "ragsync"
+
+// systemKeyring 系统密钥环：Linux 上通过 secret-tool 访问 Secret Service，macOS 上通过 security 访问钥匙串
+type systemKeyring struct {
+	name string
+	get  func(account string) (string, error)
+	set  func(account string, value string) error
+}
+
+// detectSystemKeyring 返回当前系统可用的密钥环，没有时返回 nil
+func detectSystemKeyring() *systemKeyring {
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd":
+		// Secret Service 需要 D-Bus 会话总线，SSH 会话和容器中通常没有
+		if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+			return nil
+		}
+		if _, err := exec.LookPath("secret-tool"); err != nil {
+			return nil
+		}
+		return &systemKeyring{
+			name: "Secret Service",
+			get: func(account string) (string, error) {
+				return runKeyringCommand(nil, "secret-tool", "lookup", "service", keyringService, "account", account)
+			},
+			set: func(account string, value string) error {
+				_, err := runKeyringCommand(strings.NewReader(value), "secret-tool", "store", "--label", keyringService+" "+account, "service", keyringService, "account", account)
+				return err
+			},
+		}
+	case "darwin":
+		if _, err := exec.LookPath("security"); err != nil {
+			return nil
+		}
+		return &systemKeyring{
+			name: "macOS Keychain",
+			get: func(account string) (string, error) {
+				return runKeyringCommand(nil, "security", "find-generic-password", "-s", keyringService, "-a", account, "-w")
+			},
+			set: func(account string, value string) error {
+				// 通过标准输入把命令交给 security -i，密码不会出现在进程的命令行参数中
+				_, err := runKeyringCommand(strings.NewReader(keychainAddCommand(account, value)), "security", "-i")
+				return err
+			},
+		}
+	default:
+		return nil
+	}
+}
+
+// keychainAddCommand 返回 security -i 中保存密码的命令，密码以 -X 十六进制传递，不需要转义
+func keychainAddCommand(account string, value string) string {
+	return fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n",
+		keychainQuote(keyringService), keychainQuote(account), hex.EncodeToString([]byte(value)))
+}
+
+// keychainQuote 为 security -i 的参数加上双引号
+func keychainQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// runKeyringCommand 执行密钥环命令，返回去掉末尾换行的标准输出
+func runKeyringCommand(stdin *strings.Reader, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", utils.Errorf("%s: %v: %s", name, err, msg)
+		}
+		return "", utils.Errorf("%s: %v", name, err)
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+// keyringGet 先从系统密钥环读取，找不到时再从文件读取
+func keyringGet(account string) (string, error) {
+	if keyring := detectSystemKeyring(); keyring != nil {
+		value, err := keyring.get(account)
+		if err == nil && value != "" {
+			return value, nil
+		}
+		log.Debugf("Secret %s not found in %s, trying %s: %v", account, keyring.name, fileKeyringPath(), err)
+	}
+	return fileKeyringGet(account)
+}
+
+// keyringSet 保存到系统密钥环，系统密钥环不可用时保存到文件
+func keyringSet(account string, value string) error {
+	if keyring := detectSystemKeyring(); keyring != nil {
+		err := keyring.set(account, value)
+		if err == nil {
+			log.Infof("Secret %s stored in %s", account, keyring.name)
+			return nil
+		}
+		log.Warnf("Failed to store secret %s in %s, falling back to %s: %v", account, keyring.name, fileKeyringPath(), err)
+	}
+	return fileKeyringSet(account, value)
+}
+
+// fileKeyringMu 保护文件密钥环的读写
+var fileKeyringMu sync.Mutex
+
+// fileKeyringPath 没有系统密钥环时使用的文件，权限为 0600
+// 配置了加密口令时其中的值会被加密保存
+func fileKeyringPath() string {
+	return filepath.Join(utils.GetHomeDirDefault("."), ".ragsync", "secrets.json")
+}
+
+// loadFileKeyring 读取文件密钥环，文件不存在时返回空表
+func loadFileKeyring() (map[string]string, error) {
+	entries := make(map[string]string)
+	raw, err := os.ReadFile(fileKeyringPath())
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, utils.Errorf("Failed to read %s: %v", fileKeyringPath(), err)
+	}
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, utils.Errorf("Failed to parse %s: %v", fileKeyringPath(), err)
+	}
+	return entries, nil
+}
+
+func fileKeyringGet(account string) (string, error) {
+	fileKeyringMu.Lock()
+	defer fileKeyringMu.Unlock()
+
+	entries, err := loadFileKeyring()
+	if err != nil {
+		return "", err
+	}
+	value, ok := entries[account]
+	if !ok {
+		return "", utils.Errorf("secret %s not found in the system keyring or %s", account, fileKeyringPath())
+	}
+	if strings.HasPrefix(value, encryptedPrefix) {
+		return Decrypt(value)
+	}
+	return value, nil
+}
+
+func fileKeyringSet(account string, value string) error {
+	fileKeyringMu.Lock()
+	defer fileKeyringMu.Unlock()
+
+	entries, err := loadFileKeyring()
+	if err != nil {
+		return err
+	}
+	if HasPassphrase() {
+		encrypted, err := Encrypt(value)
+		if err != nil {
+			return err
+		}
+		value = encrypted
+	} else {
+		log.Warnf("No system keyring available and %s is not set, secret %s is stored unencrypted in %s (mode 0600)", EnvPassphrase, account, fileKeyringPath())
+	}
+	entries[account] = value
+
+	raw, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return utils.Errorf("Failed to serialize secrets: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(fileKeyringPath()), 0700); err != nil {
+		return utils.Errorf("Failed to create secrets directory: %v", err)
+	}
+	if err := WriteFile(fileKeyringPath(), raw); err != nil {
+		return utils.Errorf("Failed to write %s: %v", fileKeyringPath(), err)
+	}
+	log.Infof("Secret %s stored in %s", account, fileKeyringPath())
+	return nil
+}
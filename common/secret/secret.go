@@ -0,0 +1,94 @@
+// Package secret 保存和读取配置文件中的敏感信息（例如 aliyun_secret_key）
+// 配置文件中只保存引用：keyring:<account> 表示保存在系统密钥环（没有系统密钥环时保存在 ~/.ragsync/secrets.json），
+// enc:v1:<data> 表示使用口令加密后的内容
+package secret
+
+import (
+	"os"
+	"strings"
+
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// 引用的前缀
+const (
+	keyringPrefix   = "keyring:"
+	encryptedPrefix = "enc:v1:"
+)
+
+// 保存敏感信息的方式
+const (
+	StoreKeyring   = "keyring"   // 系统密钥环，不可用时退回到文件
+	StoreEncrypted = "encrypted" // 使用口令加密后直接保存在配置文件中
+)
+
+// Stores 支持的保存方式
+var Stores = []string{StoreKeyring, StoreEncrypted}
+
+// IsReference 判断配置中的值是否为引用而不是明文
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, keyringPrefix) || strings.HasPrefix(value, encryptedPrefix)
+}
+
+// ReferenceStore 返回引用对应的保存方式（见 Stores），不是引用时返回空字符串
+func ReferenceStore(value string) string {
+	switch {
+	case strings.HasPrefix(value, keyringPrefix):
+		return StoreKeyring
+	case strings.HasPrefix(value, encryptedPrefix):
+		return StoreEncrypted
+	default:
+		return ""
+	}
+}
+
+// Resolve 将引用解析为明文，不是引用的值原样返回
+func Resolve(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, keyringPrefix):
+		account := strings.TrimPrefix(value, keyringPrefix)
+		secret, err := keyringGet(account)
+		if err != nil {
+			return "", utils.Errorf("Failed to read secret %s from the keyring: %v", account, err)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, encryptedPrefix):
+		return Decrypt(value)
+	default:
+		return value, nil
+	}
+}
+
+// Store 按 store 指定的方式保存明文，返回写入配置文件的引用
+// account 用于在密钥环中区分不同的值，例如 aliyun_secret_key/<AccessKeyId>
+func Store(store string, account string, value string) (string, error) {
+	switch store {
+	case StoreKeyring:
+		if err := keyringSet(account, value); err != nil {
+			return "", err
+		}
+		return keyringPrefix + account, nil
+	case StoreEncrypted:
+		return Encrypt(value)
+	default:
+		return "", utils.Errorf("Unsupported secret store %q, expected one of: %s", store, strings.Join(Stores, ", "))
+	}
+}
+
+// WriteFile 以 0600 权限写入包含敏感信息的文件，已存在的文件也会被改为 0600
+// 先写入临时文件再替换，避免中途失败时留下不完整的文件
+func WriteFile(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0600); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package secret
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// setPassphrase 设置加密口令，清除口令文件
+func setPassphrase(t *testing.T, value string) {
+	t.Helper()
+	t.Setenv(EnvPassphrase, value)
+	t.Setenv(EnvPassphraseFile, "")
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	setPassphrase(t, "correct horse battery staple")
+
+	encrypted, err := Encrypt("my-secret-key")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !strings.HasPrefix(encrypted, encryptedPrefix) || strings.Contains(encrypted, "my-secret-key") {
+		t.Fatalf("Encrypt returned %q, want an enc:v1: value without the plaintext", encrypted)
+	}
+	if ReferenceStore(encrypted) != StoreEncrypted || !IsReference(encrypted) {
+		t.Fatalf("%q is not recognised as an encrypted reference", encrypted)
+	}
+
+	decrypted, err := Decrypt(encrypted)
+	if err != nil || decrypted != "my-secret-key" {
+		t.Fatalf("Decrypt = %q, %v, want my-secret-key", decrypted, err)
+	}
+	if resolved, err := Resolve(encrypted); err != nil || resolved != "my-secret-key" {
+		t.Fatalf("Resolve = %q, %v, want my-secret-key", resolved, err)
+	}
+
+	// 每次加密使用新的盐和随机数
+	again, err := Encrypt("my-secret-key")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if again == encrypted {
+		t.Fatal("encrypting the same value twice returned the same ciphertext")
+	}
+}
+
+func TestDecryptWrongPassphrase(t *testing.T) {
+	setPassphrase(t, "right")
+	encrypted, err := Encrypt("value")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	setPassphrase(t, "wrong")
+	if _, err := Decrypt(encrypted); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Fatalf("Decrypt with the wrong passphrase returned %v, want a wrong passphrase error", err)
+	}
+
+	setPassphrase(t, "")
+	if _, err := Decrypt(encrypted); err == nil || !strings.Contains(err.Error(), EnvPassphrase) {
+		t.Fatalf("Decrypt without a passphrase returned %v, want an error naming %s", err, EnvPassphrase)
+	}
+}
+
+func TestDecryptTamperedValue(t *testing.T) {
+	setPassphrase(t, "right")
+	encrypted, err := Encrypt("value")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	tampered := []byte(encrypted)
+	last := len(tampered) - 3
+	if tampered[last] == 'A' {
+		tampered[last] = 'B'
+	} else {
+		tampered[last] = 'A'
+	}
+	if _, err := Decrypt(string(tampered)); err == nil {
+		t.Fatal("Decrypt accepted a tampered value")
+	}
+	if _, err := Decrypt(encryptedPrefix + "c2hvcnQ="); err == nil || !strings.Contains(err.Error(), "Malformed") {
+		t.Fatalf("Decrypt of a short value returned %v, want a malformed error", err)
+	}
+}
+
+func TestPassphraseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passphrase")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvPassphrase, "")
+	t.Setenv(EnvPassphraseFile, path)
+
+	encrypted, err := Encrypt("value")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	// 口令文件末尾的换行不属于口令
+	setPassphrase(t, "from-file")
+	if decrypted, err := Decrypt(encrypted); err != nil || decrypted != "value" {
+		t.Fatalf("Decrypt with the same passphrase from the environment = %q, %v, want value", decrypted, err)
+	}
+}
+
+func TestWriteFileMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFile(path, []byte("new")); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Fatalf("file mode after WriteFile = %o, want 600", mode)
+	}
+	if raw, _ := os.ReadFile(path); string(raw) != "new" {
+		t.Fatalf("file content = %q, want new", raw)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("temporary file left behind: %v", err)
+	}
+}
+
+func TestFileKeyringStore(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("the macOS keychain is always available")
+	}
+	// 没有 D-Bus 会话时不使用 Secret Service，保存到 ~/.ragsync/secrets.json
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "")
+	t.Setenv("HOME", t.TempDir())
+	setPassphrase(t, "file-keyring")
+
+	ref, err := Store(StoreKeyring, "aliyun_secret_key/AK", "stored-secret")
+	if err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if ref != "keyring:aliyun_secret_key/AK" || ReferenceStore(ref) != StoreKeyring {
+		t.Fatalf("Store returned reference %q, want keyring:aliyun_secret_key/AK", ref)
+	}
+
+	info, err := os.Stat(fileKeyringPath())
+	if err != nil {
+		t.Fatalf("secrets file: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Fatalf("secrets file mode = %o, want 600", mode)
+	}
+	raw, err := os.ReadFile(fileKeyringPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := map[string]string{}
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		t.Fatalf("secrets file is not JSON: %v", err)
+	}
+	if value := entries["aliyun_secret_key/AK"]; !strings.HasPrefix(value, encryptedPrefix) {
+		t.Fatalf("secret stored as %q, want it encrypted with the passphrase", value)
+	}
+
+	if resolved, err := Resolve(ref); err != nil || resolved != "stored-secret" {
+		t.Fatalf("Resolve = %q, %v, want stored-secret", resolved, err)
+	}
+	if _, err := Resolve("keyring:missing"); err == nil {
+		t.Fatal("Resolve of a missing keyring entry succeeded")
+	}
+}
+
+func TestKeychainAddCommand(t *testing.T) {
+	command := keychainAddCommand(`profile "a"\b`, "s3cr3t value")
+	if strings.Contains(command, "s3cr3t") {
+		t.Fatalf("keychain command %q contains the plaintext secret", command)
+	}
+	if !strings.Contains(command, "-X "+hex.EncodeToString([]byte("s3cr3t value"))) {
+		t.Fatalf("keychain command %q does not pass the secret as hex", command)
+	}
+	if !strings.Contains(command, `-a "profile \"a\"\\b"`) || !strings.HasSuffix(command, "\n") {
+		t.Fatalf("keychain command %q does not quote the account", command)
+	}
+}
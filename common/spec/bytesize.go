@@ -0,0 +1,77 @@
+package spec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yaklang/yaklang/common/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// ByteSize 以字节为单位的大小，配置文件中可以写成 104857600、"100MB" 或 "1.5GiB"
+type ByteSize int64
+
+// byteSizeUnits 支持的单位，KB/MB/GB 与 KiB/MiB/GiB 都按 1024 进制计算
+var byteSizeUnits = []struct {
+	Suffix string
+	Size   int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+// ParseByteSize 解析带单位的大小
+func ParseByteSize(value string) (ByteSize, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	if s == "" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(s, unit.Suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.Suffix))
+			multiplier = unit.Size
+			break
+		}
+	}
+	number, err := strconv.ParseFloat(s, 64)
+	if err != nil || number < 0 {
+		return 0, utils.Errorf("Invalid size %q, expected a number of bytes or a value such as 100MB", value)
+	}
+	return ByteSize(number * float64(multiplier)), nil
+}
+
+// String 返回便于阅读的大小，例如 100MB
+func (b ByteSize) String() string {
+	switch {
+	case b >= 1<<30 && b%(1<<30) == 0:
+		return fmt.Sprintf("%dGB", b>>30)
+	case b >= 1<<20 && b%(1<<20) == 0:
+		return fmt.Sprintf("%dMB", b>>20)
+	case b >= 1<<10 && b%(1<<10) == 0:
+		return fmt.Sprintf("%dKB", b>>10)
+	case b >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(b)/(1<<20))
+	default:
+		return fmt.Sprintf("%dB", int64(b))
+	}
+}
+
+// UnmarshalYAML 支持数字和带单位的字符串
+func (b *ByteSize) UnmarshalYAML(node *yaml.Node) error {
+	size, err := ParseByteSize(node.Value)
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// MarshalYAML 保存为带单位的字符串
+func (b ByteSize) MarshalYAML() (interface{}, error) {
+	return b.String(), nil
+}
@@ -0,0 +1,94 @@
+package spec
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseByteSize(t *testing.T) {
+	cases := []struct {
+		value string
+		want  ByteSize
+	}{
+		{"", 0},
+		{"0", 0},
+		{"104857600", 100 << 20},
+		{"512B", 512},
+		{"100MB", 100 << 20},
+		{"100mb", 100 << 20},
+		{" 2 GiB ", 2 << 30},
+		{"1.5GiB", 3 << 29},
+		{"64k", 64 << 10},
+		{"10KiB", 10 << 10},
+	}
+	for _, c := range cases {
+		got, err := ParseByteSize(c.value)
+		if err != nil || got != c.want {
+			t.Errorf("ParseByteSize(%q) = %d, %v, want %d", c.value, got, err, c.want)
+		}
+	}
+	for _, value := range []string{"MB", "-1", "ten MB", "1TB"} {
+		if _, err := ParseByteSize(value); err == nil {
+			t.Errorf("ParseByteSize(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestByteSizeString(t *testing.T) {
+	for size, want := range map[ByteSize]string{
+		100:             "100B",
+		4 << 10:         "4KB",
+		100 << 20:       "100MB",
+		2 << 30:         "2GB",
+		(3 << 20) + 100: "3.0MB",
+	} {
+		if got := size.String(); got != want {
+			t.Errorf("ByteSize(%d).String() = %q, want %q", int64(size), got, want)
+		}
+	}
+}
+
+func TestByteSizeYAML(t *testing.T) {
+	var config struct {
+		Size ByteSize `yaml:"size"`
+	}
+	if err := yaml.Unmarshal([]byte("size: 1.5GiB\n"), &config); err != nil || config.Size != 3<<29 {
+		t.Fatalf("unmarshal 1.5GiB = %d, %v, want %d", config.Size, err, int64(3<<29))
+	}
+	if err := yaml.Unmarshal([]byte("size: 2048\n"), &config); err != nil || config.Size != 2048 {
+		t.Fatalf("unmarshal 2048 = %d, %v, want 2048", config.Size, err)
+	}
+	if err := yaml.Unmarshal([]byte("size: lots\n"), &config); err == nil {
+		t.Fatal("unmarshal accepted an invalid size")
+	}
+
+	config.Size = 100 << 20
+	raw, err := yaml.Marshal(config)
+	if err != nil || strings.TrimSpace(string(raw)) != "size: 100MB" {
+		t.Fatalf("marshal = %q, %v, want size: 100MB", raw, err)
+	}
+}
+
+func TestValidateLargeFilePolicy(t *testing.T) {
+	config := GetDefaultConfig()
+	config.Backend = "local"
+	config.BailianWorkspaceId = "local"
+	config.MaxFileSize = 100 << 20
+	for _, policy := range []string{"", LargeFilePolicySkip, LargeFilePolicyWarn} {
+		config.LargeFilePolicy = policy
+		if err := config.Validate(); err != nil {
+			t.Errorf("Validate with large_file_policy %q: %v", policy, err)
+		}
+	}
+	config.LargeFilePolicy = "truncate"
+	if err := config.Validate(); err == nil {
+		t.Fatal("Validate accepted an unknown large_file_policy")
+	}
+	config.LargeFilePolicy = ""
+	config.MaxFileSize = -1
+	if err := config.Validate(); err == nil {
+		t.Fatal("Validate accepted a negative max_file_size")
+	}
+}
@@ -1,25 +1,110 @@
 package spec
 
 import (
-	"io/ioutil"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/VillanCh/ragsync/common/secret"
 	"github.com/yaklang/yaklang/common/log"
 	"github.com/yaklang/yaklang/common/utils"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
+	Backend         string `yaml:"backend,omitempty"`           // knowledge base backend, defaults to bailian
+	LocalBackendDir string `yaml:"local_backend_dir,omitempty"` // data directory of the local backend, defaults to ~/.ragsync/local
+
 	AliyunAccessKey    string `yaml:"aliyun_access_key"`
 	AliyunSecretKey    string `yaml:"aliyun_secret_key"`
 	BailianWorkspaceId string `yaml:"bailian_workspace_id"` // fetch from bailian.console.aliyun.com
 
+	CredentialType            string `yaml:"credential_type,omitempty"`              // how to obtain Alibaba Cloud credentials, see CredentialType* below
+	AliyunSecurityToken       string `yaml:"aliyun_security_token,omitempty"`        // STS token used with credential_type sts
+	AliyunRoleArn             string `yaml:"aliyun_role_arn,omitempty"`              // role assumed by ram_role_arn and oidc_role_arn
+	AliyunRoleSessionName     string `yaml:"aliyun_role_session_name,omitempty"`     // session name of the assumed role, defaults to ragsync
+	AliyunOIDCProviderArn     string `yaml:"aliyun_oidc_provider_arn,omitempty"`     // OIDC identity provider used with oidc_role_arn
+	AliyunOIDCTokenFile       string `yaml:"aliyun_oidc_token_file,omitempty"`       // file containing the OIDC token issued by the CI
+	AliyunECSRoleName         string `yaml:"aliyun_ecs_role_name,omitempty"`         // RAM role attached to the ECS instance, looked up from the metadata service when empty
+	AliyunECSMetadataEndpoint string `yaml:"aliyun_ecs_metadata_endpoint,omitempty"` // ECS metadata service, defaults to http://100.100.100.200
+	AliyunCLIProfile          string `yaml:"aliyun_cli_profile,omitempty"`           // profile in ~/.aliyun/config.json, defaults to the current profile
+	AliyunSTSEndpoint         string `yaml:"aliyun_sts_endpoint,omitempty"`          // STS endpoint used to assume roles, defaults to sts.aliyuncs.com
+
 	BailianEndpoint               string   `yaml:"aliyun_bailian_endpoint"`           // bailian.cn-beijing.aliyuncs.com
 	BailianCategoryType           string   `yaml:"bailian_category_type"`             // UNSTRUCTURED
 	BailianAddFileParser          string   `yaml:"bailian_add_file_parser"`           // DASHSCOPE_DOCMIND
 	BailianFilesDefaultCategoryId string   `yaml:"bailian_files_default_category_id"` // default
 	BailianKnowledgeIndexId       string   `yaml:"bailian_knowledge_index_id"`        // knowledge index id for RAG
 	IncludePaths                  []string `yaml:"include_paths"`                     // paths to include for sync
+	Include                       []string `yaml:"include,omitempty"`                 // gitignore-style globs, only matching files are synced
+	Exclude                       []string `yaml:"exclude,omitempty"`                 // gitignore-style globs excluded from sync
+
+	Retry RetryConfig `yaml:"retry,omitempty"` // retry policy for Bailian API calls and uploads
+
+	RateLimit map[string]float64 `yaml:"rate_limit,omitempty"` // per-action QPS overrides for Bailian API calls, 0 disables the limit
+
+	MaxFileSize     ByteSize `yaml:"max_file_size,omitempty"`     // files larger than this are skipped or warned about before leasing, 0 means no limit
+	LargeFilePolicy string   `yaml:"large_file_policy,omitempty"` // skip (default) or warn
+
+	Profiles       map[string]*ProfileConfig `yaml:"profiles,omitempty"`        // named profiles overriding workspace, index, category, endpoint and include paths
+	DefaultProfile string                    `yaml:"default_profile,omitempty"` // profile used when --profile and RAGSYNC_PROFILE are not set
+
+	// Profile 当前使用的档案，为空时表示没有使用档案
+	Profile string `yaml:"-"`
+	// profileBase 应用档案前的顶层配置，保存时用于将档案中的值与共用的默认值分开
+	profileBase *Config
+
+	// secretRefs 敏感字段在配置文件中的引用（密钥环或加密内容），保存时写回引用而不是明文
+	secretRefs map[string]secretRef
+	// sources 配置项的来源（配置文件、档案、环境变量或命令行参数），见 Source
+	sources map[string]string
+}
+
+// 超过 max_file_size 时的处理方式
+const (
+	LargeFilePolicySkip = "skip" // 跳过，不申请租约也不上传
+	LargeFilePolicyWarn = "warn" // 输出警告后照常上传
+)
+
+// 阿里云凭据的获取方式（credential_type）
+const (
+	CredentialTypeDefault     = "default"       // 依次尝试环境变量、OIDC、~/.aliyun/config.json 和 ECS 实例角色
+	CredentialTypeAccessKey   = "access_key"    // 配置文件或环境变量中的 AccessKey
+	CredentialTypeSTS         = "sts"           // STS 临时凭据（AccessKey 加 SecurityToken）
+	CredentialTypeRAMRoleArn  = "ram_role_arn"  // 使用 AccessKey 扮演 RAM 角色
+	CredentialTypeECSRAMRole  = "ecs_ram_role"  // 通过元数据服务获取 ECS 实例 RAM 角色的临时凭据
+	CredentialTypeOIDCRoleArn = "oidc_role_arn" // 使用 CI 签发的 OIDC Token 扮演 RAM 角色
+	CredentialTypeCLIProfile  = "cli_profile"   // 阿里云 CLI 配置文件 ~/.aliyun/config.json 中的 profile
+)
+
+// CredentialTypes 支持的凭据类型
+var CredentialTypes = []string{
+	CredentialTypeDefault, CredentialTypeAccessKey, CredentialTypeSTS, CredentialTypeRAMRoleArn,
+	CredentialTypeECSRAMRole, CredentialTypeOIDCRoleArn, CredentialTypeCLIProfile,
+}
+
+// ResolvedCredentialType 返回实际使用的凭据类型
+// 未配置 credential_type 时，配置文件中有 AccessKey 则直接使用（有 SecurityToken 时作为 STS 凭据），否则使用默认凭据链
+func (c *Config) ResolvedCredentialType() string {
+	if c.CredentialType != "" {
+		return c.CredentialType
+	}
+	if c.AliyunAccessKey != "" && c.AliyunSecretKey != "" {
+		if c.AliyunSecurityToken != "" {
+			return CredentialTypeSTS
+		}
+		return CredentialTypeAccessKey
+	}
+	return CredentialTypeDefault
+}
+
+// RetryConfig 百炼 API 调用的重试策略，未配置的项使用默认值
+type RetryConfig struct {
+	MaxAttempts    int           `yaml:"max_attempts,omitempty"`    // attempts including the first one, default 5
+	InitialBackoff time.Duration `yaml:"initial_backoff,omitempty"` // upper bound of the first backoff, default 500ms
+	MaxBackoff     time.Duration `yaml:"max_backoff,omitempty"`     // upper bound of a single backoff, default 30s
+	MaxElapsed     time.Duration `yaml:"max_elapsed,omitempty"`     // total time budget per call, default 2m
+	CallTimeout    time.Duration `yaml:"call_timeout,omitempty"`    // timeout of a single attempt, default: the SDK's own timeouts
 }
 
 // 默认配置值
@@ -31,8 +116,49 @@ var defaultConfig = Config{
 	IncludePaths:                  []string{"./docs"}, // 默认包含 docs 目录
 }
 
+// UsesBailian 是否使用百炼后端（未配置 backend 时默认使用百炼）
+func (c *Config) UsesBailian() bool {
+	return c.Backend == "" || strings.EqualFold(c.Backend, "bailian")
+}
+
+// applyBackendDefaults 非百炼后端不需要真实的工作空间和索引 ID，未配置时使用后端名称作为命名空间
+func (c *Config) applyBackendDefaults() {
+	if c.UsesBailian() {
+		return
+	}
+	name := strings.ToLower(c.Backend)
+	if c.BailianWorkspaceId == "" {
+		c.BailianWorkspaceId = name
+	}
+	if c.BailianKnowledgeIndexId == "" {
+		c.BailianKnowledgeIndexId = name
+	}
+}
+
 // Validate 验证配置是否有效
 func (c *Config) Validate() error {
+	// 上传策略与后端无关
+	if c.MaxFileSize < 0 {
+		return utils.Errorf("Max file size (MaxFileSize) cannot be negative: %d", c.MaxFileSize)
+	}
+	switch c.LargeFilePolicy {
+	case "", LargeFilePolicySkip, LargeFilePolicyWarn:
+	default:
+		return utils.Errorf("Invalid large file policy (LargeFilePolicy) %q, expected '%s' or '%s'", c.LargeFilePolicy, LargeFilePolicySkip, LargeFilePolicyWarn)
+	}
+	if !c.UsesBailian() {
+		// 其他后端只需要工作空间 ID 作为同步状态的命名空间
+		if c.BailianWorkspaceId == "" {
+			return utils.Errorf("Workspace ID (BailianWorkspaceId) cannot be empty")
+		}
+		return nil
+	}
+	if c.CredentialType != "" && !utils.StringArrayContains(CredentialTypes, c.CredentialType) {
+		return utils.Errorf("Invalid credential type (CredentialType) %q, expected one of: %s", c.CredentialType, strings.Join(CredentialTypes, ", "))
+	}
+	if c.CredentialType == CredentialTypeRAMRoleArn && c.AliyunRoleArn == "" {
+		return utils.Errorf("Role ARN (AliyunRoleArn) cannot be empty when the credential type is %s", CredentialTypeRAMRoleArn)
+	}
 	if c.BailianEndpoint == "" {
 		return utils.Errorf("Bailian endpoint (BailianEndpoint) cannot be empty")
 	}
@@ -48,6 +174,11 @@ func (c *Config) Validate() error {
 	if c.BailianFilesDefaultCategoryId == "" {
 		return utils.Errorf("Bailian default category ID (BailianFilesDefaultCategoryId) cannot be empty")
 	}
+	for action, qps := range c.RateLimit {
+		if qps < 0 {
+			return utils.Errorf("Rate limit for %s (RateLimit) cannot be negative: %v", action, qps)
+		}
+	}
 	if len(c.IncludePaths) == 0 {
 		log.Warn("Include paths cannot be empty")
 	}
@@ -59,19 +190,35 @@ func GetDefaultConfig() Config {
 	return defaultConfig
 }
 
-// LoadConfig 从YAML文件加载配置
+// LoadConfig 从YAML文件加载配置，使用 default_profile 指定的档案（如果有）
 func LoadConfig(configPath string) (*Config, error) {
+	return LoadConfigWithOptions(configPath, LoadOptions{})
+}
+
+// LoadConfigWithProfile 从YAML文件加载配置并应用档案 profile，profile 为空时使用 default_profile
+func LoadConfigWithProfile(configPath string, profile string) (*Config, error) {
+	return LoadConfigWithOptions(configPath, LoadOptions{Profile: profile})
+}
+
+// LoadOptions 加载配置的选项
+type LoadOptions struct {
+	Profile string            // 使用的档案，为空时使用 default_profile
+	Flags   map[string]string // 命令行参数覆盖的配置项（键见 Overrides），优先级最高
+
+	SkipValidation bool // 不验证配置，用于展示不完整的配置
+}
+
+// ReadConfig 读取配置文件但不应用档案、不解析密钥也不验证，用于修改后重新保存配置文件
+// 文件不存在时返回默认配置
+func ReadConfig(configPath string) (*Config, error) {
 	// 使用默认配置作为基础
 	config := defaultConfig
 
-	// 检查文件是否存在
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		log.Warnf("Configuration file %s does not exist, using default configuration", configPath)
-		return &config, nil
-	}
-
 	// 读取配置文件
 	yamlFile, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return &config, nil
+	}
 	if err != nil {
 		return nil, utils.Errorf("Failed to read configuration file: %v", err)
 	}
@@ -82,36 +229,97 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, utils.Errorf("Failed to parse YAML configuration: %v", err)
 	}
 
-	// 检查必要的配置项
-	if config.AliyunAccessKey == "" || config.AliyunSecretKey == "" {
-		log.Warnf("Aliyun access key not set in configuration file, will try to get from environment variables")
-		return nil, utils.Errorf("Aliyun access key not set in configuration file, will try to get from environment variables")
+	// 记录配置文件中出现的键
+	var keys map[string]interface{}
+	if err := yaml.Unmarshal(yamlFile, &keys); err == nil {
+		for key := range keys {
+			config.setSource(key, SourceFile)
+		}
+	}
+	return &config, nil
+}
+
+// LoadConfigWithOptions 按默认值、配置文件、档案、RAGSYNC_* 环境变量、命令行参数的顺序加载配置，后者覆盖前者
+// 配置文件不存在时从默认值开始，所有配置都可以由环境变量和命令行参数提供
+func LoadConfigWithOptions(configPath string, opts LoadOptions) (*Config, error) {
+	// 检查文件是否存在
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		if opts.Profile != "" {
+			return nil, utils.Errorf("Configuration file %s does not exist, cannot use profile %q", configPath, opts.Profile)
+		}
+		log.Warnf("Configuration file %s does not exist, using default configuration, environment variables and flags", configPath)
+	}
+
+	loaded, err := ReadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	config := *loaded
+
+	if err := config.UseProfile(opts.Profile, false); err != nil {
+		return nil, err
+	}
+	if config.Profile != "" {
+		log.Infof("Using profile: %s", config.Profile)
+	}
+
+	if err := config.applyEnvOverrides(); err != nil {
+		return nil, err
+	}
+	if err := config.applyFlagOverrides(opts.Flags); err != nil {
+		return nil, err
+	}
+
+	config.applyBackendDefaults()
+
+	// 敏感字段可以是密钥环引用或加密内容，只有百炼后端需要解析
+	if config.UsesBailian() {
+		if err := config.ResolveSecrets(); err != nil {
+			return nil, err
+		}
+		if plaintext := config.PlaintextSecrets(); len(plaintext) > 0 {
+			log.Warnf("Configuration file %s stores %s in plaintext, run 'ragsync config migrate-secrets' to move it to the keyring or encrypt it", configPath, strings.Join(plaintext, ", "))
+		}
+	}
+
+	// 配置文件中没有 AccessKey 时由凭据链（环境变量、~/.aliyun/config.json、ECS 实例角色等）提供，创建客户端时才会解析
+	if config.UsesBailian() && config.ResolvedCredentialType() == CredentialTypeDefault {
+		log.Infof("Aliyun access key not set in configuration file, credentials will be resolved from the default credential chain")
 	}
 
 	if config.BailianWorkspaceId == "" {
-		log.Warnf("Bailian workspace ID not set, please specify in configuration file or through environment variables")
+		log.Warnf("Bailian workspace ID not set, please specify bailian_workspace_id in configuration file, RAGSYNC_WORKSPACE_ID or --workspace-id")
 	}
 
 	if config.BailianKnowledgeIndexId == "" {
-		log.Warnf("Bailian knowledge index ID not set, please specify in configuration file or through environment variables")
+		log.Warnf("Bailian knowledge index ID not set, please specify bailian_knowledge_index_id in configuration file, RAGSYNC_INDEX_ID or --index-id")
 	}
 
 	// 验证配置
+	if opts.SkipValidation {
+		return &config, nil
+	}
 	if err := config.Validate(); err != nil {
+		if config.Profile == "" && len(config.Profiles) > 0 {
+			return nil, utils.Errorf("%v (no profile selected, available profiles: %s; select one with --profile or RAGSYNC_PROFILE)", err, strings.Join(config.ProfileNames(), ", "))
+		}
 		return nil, err
 	}
 
 	return &config, nil
 }
 
-// SaveConfig 将配置保存到YAML文件
+// SaveConfig 将配置保存到YAML文件，文件权限为 0600
+// 从密钥环或加密内容解析出的敏感字段保存为原来的引用，使用档案时档案中的值写回档案
 func SaveConfig(config *Config, configPath string) error {
-	yamlData, err := yaml.Marshal(config)
+	out := config.withSecretReferences()
+	out = out.withProfileSeparated()
+	yamlData, err := yaml.Marshal(&out)
 	if err != nil {
 		return utils.Errorf("Failed to serialize configuration: %v", err)
 	}
 
-	err = ioutil.WriteFile(configPath, yamlData, 0644)
+	err = secret.WriteFile(configPath, yamlData)
 	if err != nil {
 		return utils.Errorf("Failed to write configuration file: %v", err)
 	}
@@ -0,0 +1,207 @@
+package spec
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/VillanCh/ragsync/common/secret"
+	"github.com/yaklang/yaklang/common/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// 配置值的来源，优先级从低到高：默认值、配置文件（及档案）、环境变量、命令行参数
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+)
+
+// Override 可以通过环境变量（RAGSYNC_*）和全局命令行参数覆盖的配置项
+type Override struct {
+	Key   string // 配置文件中的键，嵌套的键用 . 连接
+	Env   string // 环境变量
+	Flag  string // 全局命令行参数
+	Usage string
+	List  bool // 值为列表，环境变量中用逗号分隔，命令行参数可以重复
+
+	set func(c *Config, value string) error
+}
+
+// Overrides 支持覆盖的配置项
+var Overrides = []Override{
+	{
+		Key: "bailian_workspace_id", Env: "RAGSYNC_WORKSPACE_ID", Flag: "workspace-id",
+		Usage: "Bailian workspace ID, overrides bailian_workspace_id",
+		set:   setString(func(c *Config) *string { return &c.BailianWorkspaceId }),
+	},
+	{
+		Key: "bailian_knowledge_index_id", Env: "RAGSYNC_INDEX_ID", Flag: "index-id",
+		Usage: "Bailian knowledge index ID, overrides bailian_knowledge_index_id",
+		set:   setString(func(c *Config) *string { return &c.BailianKnowledgeIndexId }),
+	},
+	{
+		Key: "aliyun_bailian_endpoint", Env: "RAGSYNC_ENDPOINT", Flag: "endpoint",
+		Usage: "Bailian API endpoint, overrides aliyun_bailian_endpoint",
+		set:   setString(func(c *Config) *string { return &c.BailianEndpoint }),
+	},
+	{
+		Key: "bailian_files_default_category_id", Env: "RAGSYNC_CATEGORY_ID", Flag: "category-id",
+		Usage: "Bailian category ID for uploaded files, overrides bailian_files_default_category_id",
+		set:   setString(func(c *Config) *string { return &c.BailianFilesDefaultCategoryId }),
+	},
+	{
+		Key: "bailian_category_type", Env: "RAGSYNC_CATEGORY_TYPE", Flag: "category-type",
+		Usage: "Bailian category type, overrides bailian_category_type",
+		set:   setString(func(c *Config) *string { return &c.BailianCategoryType }),
+	},
+	{
+		Key: "bailian_add_file_parser", Env: "RAGSYNC_PARSER", Flag: "parser",
+		Usage: "Bailian file parser, overrides bailian_add_file_parser",
+		set:   setString(func(c *Config) *string { return &c.BailianAddFileParser }),
+	},
+	{
+		Key: "include_paths", Env: "RAGSYNC_INCLUDE_PATHS", Flag: "include-path", List: true,
+		Usage: "Path to sync when sync is given no --file or --dir (repeatable), overrides include_paths",
+		set: func(c *Config, value string) error {
+			var paths []string
+			for _, path := range strings.Split(value, ",") {
+				if path = strings.TrimSpace(path); path != "" {
+					paths = append(paths, path)
+				}
+			}
+			c.IncludePaths = paths
+			return nil
+		},
+	},
+	{
+		Key: "retry.call_timeout", Env: "RAGSYNC_CALL_TIMEOUT", Flag: "call-timeout",
+		Usage: "Timeout of a single Bailian API call or upload attempt (e.g. 60s), overrides retry.call_timeout",
+		set: func(c *Config, value string) error {
+			timeout, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			c.Retry.CallTimeout = timeout
+			return nil
+		},
+	},
+}
+
+func setString(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+// setSource 记录配置项的来源
+func (c *Config) setSource(key string, source string) {
+	if c.sources == nil {
+		c.sources = make(map[string]string)
+	}
+	c.sources[key] = source
+}
+
+// Source 返回配置项（配置文件中的键）的来源，没有记录时为默认值
+func (c *Config) Source(key string) string {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	return SourceDefault
+}
+
+// applyEnvOverrides 使用 RAGSYNC_* 环境变量覆盖配置
+func (c *Config) applyEnvOverrides() error {
+	for _, o := range Overrides {
+		value, ok := os.LookupEnv(o.Env)
+		if !ok || value == "" {
+			continue
+		}
+		if err := o.set(c, value); err != nil {
+			return utils.Errorf("Invalid %s=%q: %v", o.Env, value, err)
+		}
+		c.setSource(o.Key, "env "+o.Env)
+	}
+	return nil
+}
+
+// applyFlagOverrides 使用命令行参数覆盖配置，flags 为配置项的键到参数值的映射，列表用逗号分隔
+func (c *Config) applyFlagOverrides(flags map[string]string) error {
+	for _, o := range Overrides {
+		value, ok := flags[o.Key]
+		if !ok {
+			continue
+		}
+		if err := o.set(c, value); err != nil {
+			return utils.Errorf("Invalid --%s %q: %v", o.Flag, value, err)
+		}
+		c.setSource(o.Key, "flag --"+o.Flag)
+	}
+	return nil
+}
+
+// Describe 返回用于展示的 YAML，AccessKey 和明文敏感字段经过 mask 脱敏，密钥环引用和加密内容原样输出
+// resolved 为 true 时输出生效的配置（不包含档案定义），每个键的行尾注释为值的来源
+func (c *Config) Describe(mask func(string) string, resolved bool) ([]byte, error) {
+	// 从密钥环或加密内容解析出的明文换回引用，避免脱敏后仍输出部分明文
+	out := c.withSecretReferences()
+	if out.AliyunAccessKey != "" {
+		out.AliyunAccessKey = mask(out.AliyunAccessKey)
+	}
+	for _, field := range out.secretFields() {
+		if *field != "" && !secret.IsReference(*field) {
+			*field = mask(*field)
+		}
+	}
+	if resolved {
+		out.Profiles = nil
+		out.DefaultProfile = ""
+	}
+
+	var doc yaml.Node
+	if err := doc.Encode(&out); err != nil {
+		return nil, utils.Errorf("Failed to serialize configuration: %v", err)
+	}
+	if resolved {
+		c.annotateSources(&doc, "")
+	}
+	raw, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, utils.Errorf("Failed to serialize configuration: %v", err)
+	}
+	return raw, nil
+}
+
+// annotateSources 在 YAML 映射的键上添加来源注释，嵌套的键只在单独记录了来源时添加
+func (c *Config) annotateSources(node *yaml.Node, prefix string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		path := key.Value
+		if prefix != "" {
+			path = prefix + "." + key.Value
+		}
+
+		source, ok := c.sources[path]
+		if prefix == "" && !ok {
+			source = SourceDefault
+			if value.Kind == yaml.ScalarNode && value.Value == "" {
+				source = "not set"
+			}
+		}
+		if ref, isSecret := c.secretRefs[path]; isSecret {
+			source += " (" + secret.ReferenceStore(ref.Ref) + ")"
+		}
+		if source != "" {
+			// 映射和列表的注释放在键上，标量的注释放在值后面
+			if value.Kind == yaml.ScalarNode {
+				value.LineComment = source
+			} else {
+				key.LineComment = source
+			}
+		}
+		c.annotateSources(value, path)
+	}
+}
@@ -0,0 +1,159 @@
+package spec
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VillanCh/ragsync/common/secret"
+)
+
+// clearOverrideEnv 清除所有 RAGSYNC_* 覆盖环境变量，避免受运行测试的环境影响
+func clearOverrideEnv(t *testing.T) {
+	t.Helper()
+	for _, o := range Overrides {
+		t.Setenv(o.Env, "")
+	}
+}
+
+func TestLoadConfigLayering(t *testing.T) {
+	clearOverrideEnv(t)
+	path := writeTestConfig(t, profilesConfig)
+	t.Setenv("RAGSYNC_INDEX_ID", "idx-env")
+	t.Setenv("RAGSYNC_WORKSPACE_ID", "ws-env")
+	t.Setenv("RAGSYNC_INCLUDE_PATHS", " docs , manual,,")
+
+	// 命令行参数覆盖环境变量，环境变量覆盖档案，档案覆盖配置文件
+	config, err := LoadConfigWithOptions(path, LoadOptions{
+		Profile: "prod",
+		Flags:   map[string]string{"bailian_workspace_id": "ws-flag", "retry.call_timeout": "90s"},
+	})
+	if err != nil {
+		t.Fatalf("LoadConfigWithOptions: %v", err)
+	}
+	cases := []struct {
+		key, value, source string
+	}{
+		{"bailian_workspace_id", config.BailianWorkspaceId, "ws-flag"},
+		{"bailian_knowledge_index_id", config.BailianKnowledgeIndexId, "idx-env"},
+		{"bailian_files_default_category_id", config.BailianFilesDefaultCategoryId, "default"},
+		{"bailian_category_type", config.BailianCategoryType, "UNSTRUCTURED"},
+	}
+	for _, c := range cases {
+		if c.value != c.source {
+			t.Errorf("%s = %q, want %q", c.key, c.value, c.source)
+		}
+	}
+	if !reflect.DeepEqual(config.IncludePaths, []string{"docs", "manual"}) {
+		t.Errorf("include_paths = %q, want [docs manual]", config.IncludePaths)
+	}
+	if config.Retry.CallTimeout != 90*time.Second {
+		t.Errorf("retry.call_timeout = %v, want 90s", config.Retry.CallTimeout)
+	}
+
+	sources := map[string]string{
+		"bailian_workspace_id":              "flag --workspace-id",
+		"bailian_knowledge_index_id":        "env RAGSYNC_INDEX_ID",
+		"include_paths":                     "env RAGSYNC_INCLUDE_PATHS",
+		"retry.call_timeout":                "flag --call-timeout",
+		"backend":                           SourceFile,
+		"bailian_files_default_category_id": SourceDefault,
+	}
+	for key, want := range sources {
+		if got := config.Source(key); got != want {
+			t.Errorf("Source(%s) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestLoadConfigWithoutFile(t *testing.T) {
+	clearOverrideEnv(t)
+	t.Setenv("RAGSYNC_WORKSPACE_ID", "ws-env")
+	t.Setenv("RAGSYNC_INDEX_ID", "idx-env")
+
+	// 配置文件不存在时从默认值开始，由环境变量提供配置
+	path := writeTestConfig(t, "") + ".missing"
+	config, err := LoadConfigWithOptions(path, LoadOptions{SkipValidation: true})
+	if err != nil {
+		t.Fatalf("LoadConfigWithOptions: %v", err)
+	}
+	if config.BailianWorkspaceId != "ws-env" || config.BailianKnowledgeIndexId != "idx-env" || config.BailianEndpoint != defaultConfig.BailianEndpoint {
+		t.Fatalf("configuration without a file = workspace %s, index %s, endpoint %s, want the environment and the default endpoint", config.BailianWorkspaceId, config.BailianKnowledgeIndexId, config.BailianEndpoint)
+	}
+	if source := config.Source("aliyun_bailian_endpoint"); source != SourceDefault {
+		t.Fatalf("Source(aliyun_bailian_endpoint) = %q, want %s", source, SourceDefault)
+	}
+
+	if _, err := LoadConfigWithOptions(path, LoadOptions{Profile: "prod"}); err == nil {
+		t.Fatal("LoadConfigWithOptions accepted a profile without a configuration file")
+	}
+}
+
+func TestLoadConfigInvalidOverride(t *testing.T) {
+	clearOverrideEnv(t)
+	path := writeTestConfig(t, "backend: local\nbailian_workspace_id: local\n")
+
+	t.Setenv("RAGSYNC_CALL_TIMEOUT", "soon")
+	_, err := LoadConfigWithOptions(path, LoadOptions{})
+	if err == nil || !strings.Contains(err.Error(), "RAGSYNC_CALL_TIMEOUT") {
+		t.Fatalf("invalid RAGSYNC_CALL_TIMEOUT returned %v, want an error naming the variable", err)
+	}
+
+	t.Setenv("RAGSYNC_CALL_TIMEOUT", "")
+	_, err = LoadConfigWithOptions(path, LoadOptions{Flags: map[string]string{"retry.call_timeout": "later"}})
+	if err == nil || !strings.Contains(err.Error(), "--call-timeout") {
+		t.Fatalf("invalid --call-timeout returned %v, want an error naming the flag", err)
+	}
+}
+
+func TestDescribeResolved(t *testing.T) {
+	clearOverrideEnv(t)
+	t.Setenv(secret.EnvPassphrase, "describe-test")
+	t.Setenv(secret.EnvPassphraseFile, "")
+	encrypted, err := secret.Encrypt("my-secret-key")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	// 只有百炼后端解析敏感字段的引用
+	content := strings.TrimPrefix(profilesConfig, "backend: local\n")
+	path := writeTestConfig(t, content+"aliyun_access_key: AKIDEXAMPLE\naliyun_secret_key: "+encrypted+"\n")
+	t.Setenv("RAGSYNC_INDEX_ID", "idx-env")
+
+	config, err := LoadConfigWithOptions(path, LoadOptions{
+		Profile: "prod",
+		Flags:   map[string]string{"retry.call_timeout": "90s"},
+	})
+	if err != nil {
+		t.Fatalf("LoadConfigWithOptions: %v", err)
+	}
+	if config.AliyunSecretKey != "my-secret-key" {
+		t.Fatalf("aliyun_secret_key resolved to %q, want my-secret-key", config.AliyunSecretKey)
+	}
+	raw, err := config.Describe(func(string) string { return "***" }, true)
+	if err != nil {
+		t.Fatalf("Describe: %v", err)
+	}
+	out := string(raw)
+
+	// 每个顶层键的行尾注释为值的来源，嵌套的键只在单独覆盖时注释
+	for _, line := range []string{
+		"bailian_workspace_id: ws-prod # profile prod",
+		"bailian_knowledge_index_id: idx-env # env RAGSYNC_INDEX_ID",
+		"aliyun_bailian_endpoint: bailian.cn-beijing.aliyuncs.com # default",
+		"aliyun_access_key: '***' # file",
+		"include_paths: # profile prod",
+		"call_timeout: 1m30s # flag --call-timeout",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("resolved configuration does not contain %q:\n%s", line, out)
+		}
+	}
+	// 加密的敏感字段原样输出并注明保存方式，不输出明文
+	if !strings.Contains(out, encrypted+" # file (encrypted)") || strings.Contains(out, "my-secret-key") {
+		t.Errorf("resolved configuration does not show the encrypted reference of aliyun_secret_key:\n%s", out)
+	}
+	if strings.Contains(out, "profiles:") || strings.Contains(out, "default_profile:") {
+		t.Errorf("resolved configuration contains the profile definitions:\n%s", out)
+	}
+}
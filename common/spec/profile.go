@@ -0,0 +1,115 @@
+package spec
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// ProfileConfig 命名档案，非空的字段覆盖顶层配置中的同名字段，其余配置（凭据、重试、限流等）与顶层共用
+type ProfileConfig struct {
+	BailianWorkspaceId            string   `yaml:"bailian_workspace_id,omitempty"`
+	BailianKnowledgeIndexId       string   `yaml:"bailian_knowledge_index_id,omitempty"`
+	BailianFilesDefaultCategoryId string   `yaml:"bailian_files_default_category_id,omitempty"`
+	BailianEndpoint               string   `yaml:"aliyun_bailian_endpoint,omitempty"`
+	IncludePaths                  []string `yaml:"include_paths,omitempty"`
+}
+
+// values 档案中可以覆盖的字符串字段，顺序与 Config.profileValues 一致
+func (p *ProfileConfig) values() []*string {
+	return []*string{&p.BailianWorkspaceId, &p.BailianKnowledgeIndexId, &p.BailianFilesDefaultCategoryId, &p.BailianEndpoint}
+}
+
+// profileKeys 档案中可以覆盖的字符串字段在配置文件中的键，顺序与 values 一致
+var profileKeys = []string{"bailian_workspace_id", "bailian_knowledge_index_id", "bailian_files_default_category_id", "aliyun_bailian_endpoint"}
+
+// profileValues 顶层配置中可以被档案覆盖的字符串字段
+func (c *Config) profileValues() []*string {
+	return []*string{&c.BailianWorkspaceId, &c.BailianKnowledgeIndexId, &c.BailianFilesDefaultCategoryId, &c.BailianEndpoint}
+}
+
+// ProfileNames 返回按名称排序的档案
+func (c *Config) ProfileNames() []string {
+	var names []string
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UseProfile 使用档案 name 覆盖顶层配置，name 为空时使用 default_profile，两者都为空时不使用档案
+// name 对应的档案不存在时，create 为 true 则创建空档案，否则返回错误
+func (c *Config) UseProfile(name string, create bool) error {
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" {
+		return nil
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok || profile == nil {
+		if !create {
+			if len(c.Profiles) == 0 {
+				return utils.Errorf("Profile %q not found: the configuration file has no profiles", name)
+			}
+			return utils.Errorf("Profile %q not found, available profiles: %s", name, strings.Join(c.ProfileNames(), ", "))
+		}
+		profile = &ProfileConfig{}
+		if c.Profiles == nil {
+			c.Profiles = make(map[string]*ProfileConfig)
+		}
+		c.Profiles[name] = profile
+	}
+
+	// 记录覆盖前的顶层配置，保存时将修改写回档案
+	base := *c
+	c.profileBase = &base
+	c.Profile = name
+
+	source := "profile " + name
+	overrides := profile.values()
+	for i, field := range c.profileValues() {
+		if *overrides[i] != "" {
+			*field = *overrides[i]
+			c.setSource(profileKeys[i], source)
+		}
+	}
+	if len(profile.IncludePaths) > 0 {
+		c.IncludePaths = profile.IncludePaths
+		c.setSource("include_paths", source)
+	}
+	return nil
+}
+
+// withProfileSeparated 返回用于保存的副本：当前档案中与顶层不同的值写回档案，顶层恢复为使用档案前的值
+func (c *Config) withProfileSeparated() Config {
+	out := *c
+	if c.Profile == "" || c.profileBase == nil {
+		return out
+	}
+	base := c.profileBase
+
+	profile := &ProfileConfig{}
+	values := profile.values()
+	baseValues := base.profileValues()
+	for i, field := range out.profileValues() {
+		if *field != *baseValues[i] {
+			*values[i] = *field
+		}
+		*field = *baseValues[i]
+	}
+	if strings.Join(out.IncludePaths, "\x00") != strings.Join(base.IncludePaths, "\x00") {
+		profile.IncludePaths = out.IncludePaths
+	}
+	out.IncludePaths = base.IncludePaths
+
+	out.Profiles = make(map[string]*ProfileConfig, len(c.Profiles))
+	for name, p := range c.Profiles {
+		out.Profiles[name] = p
+	}
+	out.Profiles[c.Profile] = profile
+	return out
+}
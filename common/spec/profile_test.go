@@ -0,0 +1,190 @@
+package spec
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const profilesConfig = `backend: local
+bailian_workspace_id: ws-shared
+bailian_knowledge_index_id: idx-shared
+include_paths:
+  - docs
+default_profile: staging
+profiles:
+  staging:
+    bailian_knowledge_index_id: idx-staging
+  prod:
+    bailian_workspace_id: ws-prod
+    bailian_knowledge_index_id: idx-prod
+    include_paths:
+      - manual
+`
+
+// writeTestConfig 将配置写入临时文件并返回路径
+func writeTestConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestUseProfile(t *testing.T) {
+	path := writeTestConfig(t, profilesConfig)
+
+	// 没有指定档案时使用 default_profile，档案中没有的字段使用顶层的值
+	config, err := LoadConfigWithOptions(path, LoadOptions{})
+	if err != nil {
+		t.Fatalf("LoadConfigWithOptions: %v", err)
+	}
+	if config.Profile != "staging" || config.BailianKnowledgeIndexId != "idx-staging" || config.BailianWorkspaceId != "ws-shared" {
+		t.Fatalf("default profile resolved to %s with workspace %s and index %s, want staging, ws-shared and idx-staging", config.Profile, config.BailianWorkspaceId, config.BailianKnowledgeIndexId)
+	}
+	if source := config.Source("bailian_knowledge_index_id"); source != "profile staging" {
+		t.Fatalf("source of bailian_knowledge_index_id = %q, want profile staging", source)
+	}
+	if source := config.Source("bailian_workspace_id"); source != SourceFile {
+		t.Fatalf("source of bailian_workspace_id = %q, want %s", source, SourceFile)
+	}
+
+	config, err = LoadConfigWithOptions(path, LoadOptions{Profile: "prod"})
+	if err != nil {
+		t.Fatalf("LoadConfigWithOptions: %v", err)
+	}
+	if config.BailianWorkspaceId != "ws-prod" || !reflect.DeepEqual(config.IncludePaths, []string{"manual"}) {
+		t.Fatalf("profile prod resolved to workspace %s and include paths %v, want ws-prod and [manual]", config.BailianWorkspaceId, config.IncludePaths)
+	}
+
+	_, err = LoadConfigWithOptions(path, LoadOptions{Profile: "dev"})
+	if err == nil || !strings.Contains(err.Error(), "prod, staging") {
+		t.Fatalf("unknown profile returned %v, want an error listing the available profiles", err)
+	}
+}
+
+func TestSaveConfigWritesBackToProfile(t *testing.T) {
+	path := writeTestConfig(t, profilesConfig)
+	config, err := LoadConfigWithOptions(path, LoadOptions{Profile: "staging"})
+	if err != nil {
+		t.Fatalf("LoadConfigWithOptions: %v", err)
+	}
+
+	// 修改后的值保存到当前档案，顶层和其他档案保持不变
+	config.BailianFilesDefaultCategoryId = "cate-staging"
+	config.IncludePaths = []string{"docs", "guides"}
+	if err := SaveConfig(config, path); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+
+	saved, err := ReadConfig(path)
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	if saved.BailianWorkspaceId != "ws-shared" || saved.BailianKnowledgeIndexId != "idx-shared" || saved.BailianFilesDefaultCategoryId != defaultConfig.BailianFilesDefaultCategoryId {
+		t.Fatalf("top level saved as workspace %s, index %s, category %q, want the values before the profile was applied", saved.BailianWorkspaceId, saved.BailianKnowledgeIndexId, saved.BailianFilesDefaultCategoryId)
+	}
+	if !reflect.DeepEqual(saved.IncludePaths, []string{"docs"}) {
+		t.Fatalf("top level include paths saved as %v, want [docs]", saved.IncludePaths)
+	}
+	want := &ProfileConfig{
+		BailianKnowledgeIndexId:       "idx-staging",
+		BailianFilesDefaultCategoryId: "cate-staging",
+		IncludePaths:                  []string{"docs", "guides"},
+	}
+	if got := saved.Profiles["staging"]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("profile staging saved as %+v, want %+v", got, want)
+	}
+	if got := saved.Profiles["prod"]; got == nil || got.BailianWorkspaceId != "ws-prod" || got.BailianKnowledgeIndexId != "idx-prod" {
+		t.Fatalf("profile prod saved as %+v, want it unchanged", got)
+	}
+	if saved.DefaultProfile != "staging" {
+		t.Fatalf("default_profile saved as %q, want staging", saved.DefaultProfile)
+	}
+
+	// 再次加载得到相同的生效配置
+	reloaded, err := LoadConfigWithOptions(path, LoadOptions{Profile: "staging"})
+	if err != nil {
+		t.Fatalf("LoadConfigWithOptions: %v", err)
+	}
+	if reloaded.BailianKnowledgeIndexId != "idx-staging" || reloaded.BailianFilesDefaultCategoryId != "cate-staging" || !reflect.DeepEqual(reloaded.IncludePaths, []string{"docs", "guides"}) {
+		t.Fatalf("reloaded profile staging = index %s, category %s, include paths %v, want the saved values", reloaded.BailianKnowledgeIndexId, reloaded.BailianFilesDefaultCategoryId, reloaded.IncludePaths)
+	}
+}
+
+func TestSaveConfigDropsValuesEqualToTopLevel(t *testing.T) {
+	path := writeTestConfig(t, profilesConfig)
+	config, err := ReadConfig(path)
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	if err := config.UseProfile("prod", false); err != nil {
+		t.Fatalf("UseProfile: %v", err)
+	}
+
+	// 改回与顶层相同的值后，档案不再单独保存该字段
+	config.BailianWorkspaceId = "ws-shared"
+	config.IncludePaths = []string{"docs"}
+	if err := SaveConfig(config, path); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+	saved, err := ReadConfig(path)
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	want := &ProfileConfig{BailianKnowledgeIndexId: "idx-prod"}
+	if got := saved.Profiles["prod"]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("profile prod saved as %+v, want %+v", got, want)
+	}
+}
+
+func TestSaveConfigCreatesProfile(t *testing.T) {
+	path := writeTestConfig(t, profilesConfig)
+	config, err := ReadConfig(path)
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	if err := config.UseProfile("dev", false); err == nil {
+		t.Fatal("UseProfile accepted an unknown profile without create")
+	}
+	if err := config.UseProfile("dev", true); err != nil {
+		t.Fatalf("UseProfile with create: %v", err)
+	}
+
+	config.BailianKnowledgeIndexId = "idx-dev"
+	if err := SaveConfig(config, path); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+	saved, err := ReadConfig(path)
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	if got := saved.Profiles["dev"]; got == nil || got.BailianKnowledgeIndexId != "idx-dev" {
+		t.Fatalf("profile dev saved as %+v, want index idx-dev", got)
+	}
+	if saved.BailianKnowledgeIndexId != "idx-shared" || len(saved.Profiles) != 3 {
+		t.Fatalf("saved top-level index %s with %d profiles, want idx-shared and 3 profiles", saved.BailianKnowledgeIndexId, len(saved.Profiles))
+	}
+}
+
+func TestSaveConfigWithoutProfile(t *testing.T) {
+	path := writeTestConfig(t, "backend: local\nbailian_workspace_id: ws-shared\n")
+	config, err := ReadConfig(path)
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	config.BailianKnowledgeIndexId = "idx-new"
+	if err := SaveConfig(config, path); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+	saved, err := ReadConfig(path)
+	if err != nil {
+		t.Fatalf("ReadConfig: %v", err)
+	}
+	if saved.BailianKnowledgeIndexId != "idx-new" || len(saved.Profiles) != 0 {
+		t.Fatalf("saved index %s with profiles %v, want idx-new at the top level", saved.BailianKnowledgeIndexId, saved.Profiles)
+	}
+}
@@ -0,0 +1,179 @@
+package spec
+
+import (
+	"os"
+	"sort"
+
+	"github.com/VillanCh/ragsync/common/secret"
+	"github.com/yaklang/yaklang/common/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// secretRef 敏感字段在配置文件中的引用以及解析出的明文
+type secretRef struct {
+	Ref   string
+	Value string
+}
+
+// secretFields 配置中的敏感字段（YAML 键 -> 字段）
+func (c *Config) secretFields() map[string]*string {
+	return map[string]*string{
+		"aliyun_secret_key":     &c.AliyunSecretKey,
+		"aliyun_security_token": &c.AliyunSecurityToken,
+	}
+}
+
+// secretKeys 按名称排序的敏感字段
+func (c *Config) secretKeys() []string {
+	var keys []string
+	for key := range c.secretFields() {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *Config) setSecretRef(key string, ref string, value string) {
+	if c.secretRefs == nil {
+		c.secretRefs = make(map[string]secretRef)
+	}
+	c.secretRefs[key] = secretRef{Ref: ref, Value: value}
+}
+
+// ResolveSecrets 将敏感字段中的引用解析为明文，并记录引用以便保存时写回引用
+func (c *Config) ResolveSecrets() error {
+	fields := c.secretFields()
+	for _, key := range c.secretKeys() {
+		field := fields[key]
+		if !secret.IsReference(*field) {
+			continue
+		}
+		value, err := secret.Resolve(*field)
+		if err != nil {
+			return utils.Errorf("Failed to resolve %s: %v", key, err)
+		}
+		c.setSecretRef(key, *field, value)
+		*field = value
+	}
+	return nil
+}
+
+// PlaintextSecrets 返回以明文保存的敏感字段
+func (c *Config) PlaintextSecrets() []string {
+	fields := c.secretFields()
+	var plaintext []string
+	for _, key := range c.secretKeys() {
+		value := *fields[key]
+		if value == "" || secret.IsReference(value) {
+			continue
+		}
+		if ref, ok := c.secretRefs[key]; ok && ref.Value == value {
+			continue
+		}
+		plaintext = append(plaintext, key)
+	}
+	return plaintext
+}
+
+// StoreSecrets 将明文保存的敏感字段保存到密钥环或加密（store 见 secret.Stores），之后 SaveConfig 只写入引用
+// 返回被处理的字段
+func (c *Config) StoreSecrets(store string) ([]string, error) {
+	keys := c.PlaintextSecrets()
+	fields := c.secretFields()
+	for _, key := range keys {
+		value := *fields[key]
+		account := secretAccount(key, c.AliyunAccessKey, c.BailianWorkspaceId)
+		ref, err := secret.Store(store, account, value)
+		if err != nil {
+			return nil, utils.Errorf("Failed to store %s: %v", key, err)
+		}
+		c.setSecretRef(key, ref, value)
+	}
+	return keys, nil
+}
+
+// MigrateFileSecrets 将配置文件顶层以明文保存的敏感字段保存到 store 并原地改写为引用，返回被处理的字段
+// 只修改这些字段的值，文件中的其他键、注释和顺序保持不变，也不会写入文件中没有的默认值
+func MigrateFileSecrets(configPath string, store string) ([]string, error) {
+	raw, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, utils.Errorf("Failed to read configuration file: %v", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, utils.Errorf("Failed to parse YAML configuration: %v", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, utils.Errorf("Failed to parse YAML configuration: the top level is not a mapping")
+	}
+
+	values := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		values[root.Content[i].Value] = root.Content[i+1]
+	}
+	scalar := func(key string) string {
+		if node := values[key]; node != nil && node.Kind == yaml.ScalarNode {
+			return node.Value
+		}
+		return ""
+	}
+
+	var migrated []string
+	for _, key := range (&Config{}).secretKeys() {
+		node := values[key]
+		if node == nil || node.Kind != yaml.ScalarNode || node.Value == "" || secret.IsReference(node.Value) {
+			continue
+		}
+		ref, err := secret.Store(store, secretAccount(key, scalar("aliyun_access_key"), scalar("bailian_workspace_id")), node.Value)
+		if err != nil {
+			return nil, utils.Errorf("Failed to store %s: %v", key, err)
+		}
+		node.Value = ref
+		node.Tag = "!!str"
+		node.Style = 0
+		migrated = append(migrated, key)
+	}
+	if len(migrated) == 0 {
+		return nil, nil
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, utils.Errorf("Failed to serialize configuration: %v", err)
+	}
+	if err := secret.WriteFile(configPath, out); err != nil {
+		return nil, utils.Errorf("Failed to write configuration file: %v", err)
+	}
+	return migrated, nil
+}
+
+// secretAccount 返回敏感字段在密钥环中的账号名，同一个 AccessKey 的密钥在密钥环中只保存一份
+func secretAccount(key string, accessKey string, workspaceId string) string {
+	return key + "/" + firstNonEmpty(accessKey, workspaceId, "default")
+}
+
+// withSecretReferences 返回用于保存的副本，从引用解析出且未被修改的敏感字段恢复为引用
+func (c *Config) withSecretReferences() Config {
+	out := *c
+	fields := out.secretFields()
+	for key, ref := range c.secretRefs {
+		if field := fields[key]; *field == ref.Value {
+			*field = ref.Ref
+		}
+	}
+	return out
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
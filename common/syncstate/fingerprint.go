@@ -0,0 +1,46 @@
+package syncstate
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// Digest 文件内容摘要
+type Digest struct {
+	MD5    string `json:"md5"`
+	SHA256 string `json:"sha256"`
+}
+
+// FileDigest 以流的方式计算文件内容的 MD5 和 SHA-256
+func FileDigest(filePath string) (Digest, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return Digest{}, utils.Errorf("Failed to open file %s: %v", filePath, err)
+	}
+	defer f.Close()
+
+	md5Hash := md5.New()
+	sha256Hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(md5Hash, sha256Hash), f); err != nil {
+		return Digest{}, utils.Errorf("Failed to hash file %s: %v", filePath, err)
+	}
+	return Digest{
+		MD5:    hex.EncodeToString(md5Hash.Sum(nil)),
+		SHA256: hex.EncodeToString(sha256Hash.Sum(nil)),
+	}, nil
+}
+
+// ContentDigest 计算内存中文件内容的 MD5 和 SHA-256
+func ContentDigest(content []byte) Digest {
+	md5Sum := md5.Sum(content)
+	sha256Sum := sha256.Sum256(content)
+	return Digest{
+		MD5:    hex.EncodeToString(md5Sum[:]),
+		SHA256: hex.EncodeToString(sha256Sum[:]),
+	}
+}
@@ -0,0 +1,286 @@
+package syncstate
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yaklang/yaklang/common/log"
+	"github.com/yaklang/yaklang/common/utils"
+)
+
+// Entry 单个文件的本地同步状态
+type Entry struct {
+	LocalPath        string    `json:"localPath"`        // 本地路径（同时也是远程文件名）
+	MD5              string    `json:"md5"`              // 上次同步时的内容 MD5
+	SHA256           string    `json:"sha256"`           // 上次同步时的内容 SHA-256
+	Size             int64     `json:"size"`             // 上次同步时的文件大小
+	ModTime          time.Time `json:"modTime"`          // 上次同步时的修改时间
+	FileId           string    `json:"fileId"`           // 远程文件 ID
+	FileStatus       string    `json:"fileStatus"`       // 远程文件解析状态
+	RemoteCreateTime string    `json:"remoteCreateTime"` // 远程文件创建时间
+	IndexStatus      string    `json:"indexStatus"`      // 索引文档状态
+	LastJobId        string    `json:"lastJobId"`        // 最近一次索引任务 ID
+	UpdatedAt        time.Time `json:"updatedAt"`        // 记录更新时间
+}
+
+// HasFingerprint 记录中是否包含本地文件指纹（rebuild 生成的记录没有）
+func (e *Entry) HasFingerprint() bool {
+	return e.MD5 != "" && !e.ModTime.IsZero()
+}
+
+// MatchesStat 文件大小和修改时间是否与记录一致
+func (e *Entry) MatchesStat(info os.FileInfo) bool {
+	if !e.HasFingerprint() {
+		return false
+	}
+	return e.Size == info.Size() && e.ModTime.Equal(info.ModTime())
+}
+
+// SetDigest 记录内容摘要
+func (e *Entry) SetDigest(d Digest) {
+	e.MD5 = d.MD5
+	e.SHA256 = d.SHA256
+}
+
+// SameContent 比较内容摘要，known 为 false 表示记录中没有可比较的摘要
+func (e *Entry) SameContent(d Digest) (same bool, known bool) {
+	if e.SHA256 != "" && d.SHA256 != "" {
+		return e.SHA256 == d.SHA256, true
+	}
+	if e.MD5 != "" && d.MD5 != "" {
+		return e.MD5 == d.MD5, true
+	}
+	return false, false
+}
+
+// IsIndexed 记录是否已提交到索引（或已在索引中）
+func (e *Entry) IsIndexed() bool {
+	switch e.IndexStatus {
+	case "", "DELETED", "INSERT_ERROR":
+		return false
+	}
+	return true
+}
+
+// Manifest 某个工作空间/索引下的本地同步状态清单
+type Manifest struct {
+	WorkspaceId string            `json:"workspaceId"`
+	IndexId     string            `json:"indexId"`
+	RemoteSync  time.Time         `json:"remoteSync"` // 最近一次与远程完整对账的时间，零值表示清单不完整
+	Entries     map[string]*Entry `json:"entries"`
+	GitCommits  map[string]string `json:"gitCommits,omitempty"` // 每个同步路径最近一次成功同步的 git 提交
+
+	path string
+	mu   sync.Mutex
+}
+
+// GetStateDir 返回同步状态的根目录 ~/.ragsync/state
+func GetStateDir() string {
+	return filepath.Join(utils.GetHomeDirDefault("."), ".ragsync", "state")
+}
+
+// ManifestPath 返回指定工作空间和索引对应的清单文件路径
+func ManifestPath(workspaceId, indexId string) string {
+	if indexId == "" {
+		indexId = "no-index"
+	}
+	return filepath.Join(GetStateDir(), workspaceId, indexId+".json")
+}
+
+// NormalizePath 规范化路径，作为清单的键
+func NormalizePath(p string) string {
+	return filepath.ToSlash(filepath.Clean(p))
+}
+
+// New 创建一个空清单
+func New(workspaceId, indexId string) *Manifest {
+	return &Manifest{
+		WorkspaceId: workspaceId,
+		IndexId:     indexId,
+		Entries:     make(map[string]*Entry),
+		path:        ManifestPath(workspaceId, indexId),
+	}
+}
+
+// Load 加载指定工作空间和索引的清单，文件不存在时返回空清单
+func Load(workspaceId, indexId string) (*Manifest, error) {
+	if workspaceId == "" {
+		return nil, utils.Error("Workspace ID cannot be empty")
+	}
+
+	m := New(workspaceId, indexId)
+	raw, err := os.ReadFile(m.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Infof("No sync state found at %s, starting with an empty manifest", m.path)
+			return m, nil
+		}
+		return nil, utils.Errorf("Failed to read sync state %s: %v", m.path, err)
+	}
+
+	if err := json.Unmarshal(raw, m); err != nil {
+		return nil, utils.Errorf("Failed to parse sync state %s: %v", m.path, err)
+	}
+	if m.Entries == nil {
+		m.Entries = make(map[string]*Entry)
+	}
+	log.Infof("Loaded sync state with %d entries from %s", len(m.Entries), m.path)
+	return m, nil
+}
+
+// Path 返回清单文件路径
+func (m *Manifest) Path() string {
+	return m.path
+}
+
+// Save 将清单写回磁盘（先写临时文件再重命名）
+func (m *Manifest) Save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return utils.Errorf("Failed to create sync state directory: %v", err)
+	}
+
+	raw, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return utils.Errorf("Failed to serialize sync state: %v", err)
+	}
+
+	tmpPath := m.path + ".tmp"
+	if err := os.WriteFile(tmpPath, raw, 0644); err != nil {
+		return utils.Errorf("Failed to write sync state: %v", err)
+	}
+	if err := os.Rename(tmpPath, m.path); err != nil {
+		return utils.Errorf("Failed to replace sync state: %v", err)
+	}
+	return nil
+}
+
+// IsComplete 清单是否已与远程完整对账过，完整的清单可以代替 ListFile 调用
+func (m *Manifest) IsComplete() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return !m.RemoteSync.IsZero()
+}
+
+// ReconciledWithin 清单是否在 maxAge 之内与远程完整对账过
+// 对账之后在控制台或其他机器上删除的文件，只有重新对账后才能从清单中消失
+func (m *Manifest) ReconciledWithin(maxAge time.Duration) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return !m.RemoteSync.IsZero() && time.Since(m.RemoteSync) < maxAge
+}
+
+// MarkComplete 标记清单已与远程完整对账
+func (m *Manifest) MarkComplete() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.RemoteSync = time.Now()
+}
+
+// GitCommit 返回同步路径最近一次成功同步的 git 提交，没有记录时返回空字符串
+func (m *Manifest) GitCommit(syncPath string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.GitCommits[NormalizePath(syncPath)]
+}
+
+// SetGitCommit 记录同步路径最近一次成功同步的 git 提交
+func (m *Manifest) SetGitCommit(syncPath string, commit string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.GitCommits == nil {
+		m.GitCommits = make(map[string]string)
+	}
+	m.GitCommits[NormalizePath(syncPath)] = commit
+}
+
+// Get 获取指定路径的记录，不存在时返回 nil
+func (m *Manifest) Get(localPath string) *Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.Entries[NormalizePath(localPath)]
+	if !ok {
+		return nil
+	}
+	copied := *entry
+	return &copied
+}
+
+// Put 写入或替换一条记录
+func (m *Manifest) Put(entry *Entry) {
+	if entry == nil || entry.LocalPath == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *entry
+	copied.LocalPath = NormalizePath(entry.LocalPath)
+	copied.UpdatedAt = time.Now()
+	m.Entries[copied.LocalPath] = &copied
+}
+
+// Update 在锁内修改一条记录，记录不存在时会先创建
+func (m *Manifest) Update(localPath string, fn func(entry *Entry)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := NormalizePath(localPath)
+	entry, ok := m.Entries[key]
+	if !ok {
+		entry = &Entry{LocalPath: key}
+		m.Entries[key] = entry
+	}
+	fn(entry)
+	entry.UpdatedAt = time.Now()
+}
+
+// Remove 删除指定路径的记录
+func (m *Manifest) Remove(localPath string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.Entries, NormalizePath(localPath))
+}
+
+// RemoveByFileId 删除远程文件 ID 对应的所有记录
+func (m *Manifest) RemoveByFileId(fileId string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, entry := range m.Entries {
+		if entry.FileId == fileId {
+			delete(m.Entries, key)
+		}
+	}
+}
+
+// EntriesUnder 返回指定目录下的所有记录，按路径排序
+func (m *Manifest) EntriesUnder(dirPath string) []*Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prefix := NormalizePath(dirPath)
+	var result []*Entry
+	for key, entry := range m.Entries {
+		if prefix != "." && key != prefix && !strings.HasPrefix(key, prefix+"/") {
+			continue
+		}
+		copied := *entry
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].LocalPath < result[j].LocalPath
+	})
+	return result
+}
+
+// Len 返回记录数量
+func (m *Manifest) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.Entries)
+}
@@ -0,0 +1,196 @@
+package syncstate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestManifest 在临时 HOME 下创建空清单
+func newTestManifest(t *testing.T) *Manifest {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	return New("ws-test", "idx-test")
+}
+
+func TestManifestSaveAndLoad(t *testing.T) {
+	m := newTestManifest(t)
+	m.Put(&Entry{LocalPath: "docs/./guide.md", FileId: "file_1", MD5: "md5", SHA256: "sha"})
+	m.SetGitCommit("docs/", "abc123")
+	m.MarkComplete()
+	if err := m.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := os.Stat(m.Path() + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("temporary file %s.tmp left behind: %v", m.Path(), err)
+	}
+
+	loaded, err := Load("ws-test", "idx-test")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	entry := loaded.Get("docs/guide.md")
+	if entry == nil || entry.FileId != "file_1" || entry.SHA256 != "sha" {
+		t.Fatalf("loaded entry = %+v, want file_1 with its digest", entry)
+	}
+	if commit := loaded.GitCommit("docs"); commit != "abc123" {
+		t.Fatalf("GitCommit(docs) = %q, want abc123", commit)
+	}
+	if !loaded.IsComplete() {
+		t.Fatal("loaded manifest is not complete")
+	}
+}
+
+func TestManifestLoadMissing(t *testing.T) {
+	newTestManifest(t)
+	m, err := Load("ws-test", "")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if m.Len() != 0 || m.IsComplete() {
+		t.Fatalf("missing manifest loaded with %d entries, complete %v, want an empty incomplete manifest", m.Len(), m.IsComplete())
+	}
+	if filepath.Base(m.Path()) != "no-index.json" {
+		t.Fatalf("manifest without an index is stored at %s, want no-index.json", m.Path())
+	}
+	if _, err := Load("", "idx-test"); err == nil {
+		t.Fatal("Load accepted an empty workspace ID")
+	}
+}
+
+func TestManifestLoadCorrupted(t *testing.T) {
+	m := newTestManifest(t)
+	if err := os.MkdirAll(filepath.Dir(m.Path()), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(m.Path(), []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load("ws-test", "idx-test"); err == nil {
+		t.Fatal("Load accepted a corrupted manifest")
+	}
+}
+
+func TestManifestReconciledWithin(t *testing.T) {
+	m := newTestManifest(t)
+	if m.ReconciledWithin(time.Hour) {
+		t.Fatal("a manifest that was never reconciled is reported as reconciled")
+	}
+	m.MarkComplete()
+	if !m.ReconciledWithin(time.Hour) {
+		t.Fatal("a manifest reconciled just now is reported as stale")
+	}
+	// 对账时间过久的清单仍然完整，但需要重新对账
+	m.RemoteSync = time.Now().Add(-2 * time.Hour)
+	if m.ReconciledWithin(time.Hour) || !m.IsComplete() {
+		t.Fatalf("manifest reconciled two hours ago: within an hour %v, complete %v, want false and true", m.ReconciledWithin(time.Hour), m.IsComplete())
+	}
+}
+
+func TestManifestEntriesUnder(t *testing.T) {
+	m := newTestManifest(t)
+	for _, path := range []string{"docs/b.md", "docs/a.md", "docs/api/c.md", "docs-old/d.md", "docs"} {
+		m.Put(&Entry{LocalPath: path, FileId: "id-" + path})
+	}
+
+	var paths []string
+	for _, entry := range m.EntriesUnder("./docs/") {
+		paths = append(paths, entry.LocalPath)
+	}
+	want := []string{"docs", "docs/a.md", "docs/api/c.md", "docs/b.md"}
+	if len(paths) != len(want) {
+		t.Fatalf("EntriesUnder(docs) = %v, want %v", paths, want)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Fatalf("EntriesUnder(docs) = %v, want %v", paths, want)
+		}
+	}
+	if n := len(m.EntriesUnder(".")); n != 5 {
+		t.Fatalf("EntriesUnder(.) returned %d entries, want 5", n)
+	}
+}
+
+func TestManifestRemoveByFileId(t *testing.T) {
+	m := newTestManifest(t)
+	m.Put(&Entry{LocalPath: "a.md", FileId: "file_a"})
+	m.Put(&Entry{LocalPath: "b.md", FileId: "file_b"})
+
+	m.RemoveByFileId("file_a")
+	if m.Get("a.md") != nil || m.Get("b.md") == nil {
+		t.Fatalf("after RemoveByFileId(file_a) a.md = %+v, b.md = %+v, want only b.md", m.Get("a.md"), m.Get("b.md"))
+	}
+	m.Remove("./b.md")
+	if m.Len() != 0 {
+		t.Fatalf("manifest has %d entries after removing both, want 0", m.Len())
+	}
+}
+
+func TestManifestGetReturnsCopy(t *testing.T) {
+	m := newTestManifest(t)
+	m.Put(&Entry{LocalPath: "a.md", FileId: "file_a"})
+
+	entry := m.Get("a.md")
+	entry.FileId = "changed"
+	if got := m.Get("a.md").FileId; got != "file_a" {
+		t.Fatalf("modifying the returned entry changed the manifest to %q", got)
+	}
+
+	m.Update("a.md", func(e *Entry) { e.IndexStatus = "FINISH" })
+	m.Update("new.md", func(e *Entry) { e.FileId = "file_new" })
+	if !m.Get("a.md").IsIndexed() || m.Get("new.md") == nil {
+		t.Fatal("Update did not modify the existing entry or create the missing one")
+	}
+}
+
+func TestEntryFingerprint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.md")
+	if err := os.WriteFile(path, []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := FileDigest(path)
+	if err != nil {
+		t.Fatalf("FileDigest: %v", err)
+	}
+	if digest != ContentDigest([]byte("content")) {
+		t.Fatalf("FileDigest = %+v, want the digest of the content", digest)
+	}
+
+	// rebuild 生成的记录没有指纹，不能只凭大小和修改时间跳过
+	entry := &Entry{LocalPath: path, Size: info.Size(), ModTime: info.ModTime()}
+	if entry.MatchesStat(info) {
+		t.Fatal("an entry without a digest matches the file stat")
+	}
+	if _, known := entry.SameContent(digest); known {
+		t.Fatal("an entry without a digest reports a known comparison")
+	}
+
+	entry.SetDigest(digest)
+	if !entry.MatchesStat(info) {
+		t.Fatal("an entry with the same size and modification time does not match")
+	}
+	if same, known := entry.SameContent(digest); !same || !known {
+		t.Fatalf("SameContent = %v, %v, want true, true", same, known)
+	}
+	if same, _ := entry.SameContent(ContentDigest([]byte("other"))); same {
+		t.Fatal("SameContent reports different content as the same")
+	}
+	// 只有 MD5 时使用 MD5 比较
+	md5Only := &Entry{MD5: digest.MD5}
+	if same, known := md5Only.SameContent(Digest{MD5: digest.MD5}); !same || !known {
+		t.Fatalf("SameContent with only MD5 = %v, %v, want true, true", same, known)
+	}
+}
+
+func TestEntryIsIndexed(t *testing.T) {
+	for status, want := range map[string]bool{"": false, "DELETED": false, "INSERT_ERROR": false, "RUNNING": true, "FINISH": true} {
+		if got := (&Entry{IndexStatus: status}).IsIndexed(); got != want {
+			t.Errorf("IsIndexed with status %q = %v, want %v", status, got, want)
+		}
+	}
+}